@@ -1,40 +1,274 @@
 package di
 
 import (
+	"backend/app/domain/repository"
 	"backend/app/domain/service"
 	"backend/app/interface/CLI"
 	"backend/app/interface/handler"
+	"compress/gzip"
 	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"backend/app/infrastructure/cache"
 	"backend/app/infrastructure/postgresql"
+	"backend/app/infrastructure/storage"
 )
 
-func InitCategory(db *sql.DB) handler.ICategoryHandler {
-	r := postgresql.NewCategoryRepository(db)
-	s := service.NewCategoryService(r)
-	return handler.NewCategoryHandler(s)
+func InitCategory(db *sql.DB, queries *cache.ReadThrough) handler.ICategoryHandler {
+	s := service.NewCategoryService(categoryRepository(db, queries))
+	return handler.NewCategoryHandler(s, cachePolicy())
 }
 
-func InitSubCategory(db *sql.DB) handler.ISubCategoryHandler {
-	r := postgresql.NewSubcategoryRepository(db)
-	s := service.NewSubCategoryService(r)
-	return handler.NewSubCategoryHandler(s)
+func InitSubCategory(db *sql.DB, queries *cache.ReadThrough) handler.ISubCategoryHandler {
+	s := service.NewSubCategoryService(subCategoryRepository(db, queries))
+	return handler.NewSubCategoryHandler(s, cachePolicy())
 }
 
-func InitPost(db *sql.DB) handler.IPostHandler {
-	r := postgresql.NewPostRepository(db)
-	s := service.NewPostService(r)
-	return handler.NewPostHandler(s)
+func InitPost(db *sql.DB, queries *cache.ReadThrough) handler.IPostHandler {
+	s := service.NewPostService(postRepository(db, queries))
+	return handler.NewPostHandler(s, cachePolicy())
 }
 
-func InitUser(db *sql.DB) handler.IUserHandler {
-	r := postgresql.NewUserRepository(db)
-	s := service.NewUserService(r)
+// InitUser is called for every request, so it takes the TokenConfig loaded once
+// by InitTokenConfig.
+func InitUser(db *sql.DB, queries *cache.ReadThrough, tokens service.TokenConfig) handler.IUserHandler {
+	s := service.NewUserService(userRepository(db, queries), postgresql.NewRefreshTokenRepository(db), postgresql.NewLoginAttemptRepository(db), postgresql.NewTwoFactorRepository(db), postgresql.NewApiKeyRepository(db), bcryptCost(), tokens)
 	return handler.NewUserHandler(s)
 }
 
-func InitUserCLI(db *sql.DB) CLI.IUserCLI {
-	r := postgresql.NewUserRepository(db)
-	s := service.NewUserService(r)
-	return CLI.NewUserCLI(s)
+// InitMedia builds the media handler over the storage selected by MEDIA_STORAGE.
+// The server builds it once at startup, so a misconfigured storage stops it early.
+// MEDIA_VARIANTS=true serves the WebP and precompressed variants of the files.
+func InitMedia(db *sql.DB, queries *cache.ReadThrough) handler.IMediaHandler {
+	serveVariants, _ := strconv.ParseBool(os.Getenv("MEDIA_VARIANTS"))
+	return handler.NewMediaHandler(mediaService(db, queries), serveVariants)
+}
+
+// InitCompression configures the compression of responses. COMPRESSION lists the
+// encodings in the order the server prefers them, default br,gzip,deflate, or is off;
+// COMPRESSION_LEVEL (1-9) trades speed for size and COMPRESSION_MIN_BYTES (default
+// 1024) is the smallest body compressed.
+func InitCompression() (compression handler.Compression) {
+	level := gzip.DefaultCompression
+	if value := os.Getenv("COMPRESSION_LEVEL"); value != "" {
+		var err error
+		level, err = strconv.Atoi(value)
+		if err != nil || level < gzip.BestSpeed || level > gzip.BestCompression {
+			panic(fmt.Sprintf("malformed COMPRESSION_LEVEL %q, expected 1 to 9", value))
+		}
+	}
+	compression.MinSize, _ = strconv.Atoi(os.Getenv("COMPRESSION_MIN_BYTES"))
+	names, ok := os.LookupEnv("COMPRESSION")
+	if !ok {
+		names = "br,gzip,deflate"
+	}
+	if names == "off" {
+		return
+	}
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "gzip":
+			compression.Encodings = append(compression.Encodings, handler.GzipEncoding(level))
+		case "deflate":
+			compression.Encodings = append(compression.Encodings, handler.DeflateEncoding(level))
+		case "br":
+			compression.Encodings = append(compression.Encodings, handler.BrotliEncoding(level))
+		default:
+			panic(fmt.Sprintf("unknown encoding %q in COMPRESSION, expected br, gzip or deflate", name))
+		}
+	}
+	return
+}
+
+func InitUserCLI(db *sql.DB, queries *cache.ReadThrough, console *CLI.Console) CLI.IUserCLI {
+	// the CLI never signs or checks tokens, so it also works without keys
+	config, _ := service.TokenConfigFromEnv()
+	s := service.NewUserService(userRepository(db, queries), postgresql.NewRefreshTokenRepository(db), postgresql.NewLoginAttemptRepository(db), postgresql.NewTwoFactorRepository(db), postgresql.NewApiKeyRepository(db), bcryptCost(), config)
+	return CLI.NewUserCLI(s, console)
+}
+
+func InitPostCLI(db *sql.DB, queries *cache.ReadThrough, console *CLI.Console) CLI.IPostCLI {
+	s := service.NewPostService(postRepository(db, queries))
+	return CLI.NewPostCLI(s, console)
+}
+
+func InitCategoryCLI(db *sql.DB, queries *cache.ReadThrough, console *CLI.Console) CLI.ICategoryCLI {
+	s := service.NewCategoryService(categoryRepository(db, queries))
+	return CLI.NewCategoryCLI(s, console)
+}
+
+func InitSubCategoryCLI(db *sql.DB, queries *cache.ReadThrough, console *CLI.Console) CLI.ISubCategoryCLI {
+	s := service.NewSubCategoryService(subCategoryRepository(db, queries))
+	return CLI.NewSubCategoryCLI(s, console)
+}
+
+func InitImportCLI(db *sql.DB, queries *cache.ReadThrough, console *CLI.Console) CLI.IImportCLI {
+	s := service.NewImportService(
+		service.NewPostService(postRepository(db, queries)),
+		service.NewCategoryService(categoryRepository(db, queries)),
+		service.NewSubCategoryService(subCategoryRepository(db, queries)),
+	)
+	return CLI.NewImportCLI(s, console)
+}
+
+func InitMediaCLI(db *sql.DB, queries *cache.ReadThrough, console *CLI.Console) CLI.IMediaCLI {
+	return CLI.NewMediaCLI(mediaService(db, queries), console)
+}
+
+func InitArchiveCLI(db *sql.DB, queries *cache.ReadThrough, console *CLI.Console) CLI.IArchiveCLI {
+	var r repository.IArchiveRepository = postgresql.NewArchiveRepository(db)
+	if queries != nil {
+		r = cache.NewArchiveRepository(r, queries)
+	}
+	s := service.NewArchiveService(r)
+	return CLI.NewArchiveCLI(s, console)
+}
+
+// bcryptCost reads BCRYPT_COST from the environment. An unset or malformed value
+// yields 0, which NewUserService replaces with bcrypt's default.
+func bcryptCost() (cost int) {
+	cost, _ = strconv.Atoi(os.Getenv("BCRYPT_COST"))
+	return
+}
+
+// InitTokenConfig loads the access token keys when the server starts. The server
+// cannot authenticate anyone without them, so a missing or malformed
+// configuration stops it before it listens.
+func InitTokenConfig() (config service.TokenConfig) {
+	config, err := service.TokenConfigFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	return
+}
+
+// cachePolicy reads the Cache-Control header of each read route from
+// CACHE_CONTROL_<ROUTE>, e.g. CACHE_CONTROL_SUB_CATEGORIES; unset routes keep the default.
+func cachePolicy() handler.CachePolicy {
+	policy := make(handler.CachePolicy)
+	for _, route := range handler.Routes {
+		name := "CACHE_CONTROL_" + strings.ToUpper(strings.ReplaceAll(route, "-", "_"))
+		if cacheControl, ok := os.LookupEnv(name); ok {
+			policy[route] = cacheControl
+		}
+	}
+	return policy
+}
+
+func mediaService(db *sql.DB, queries *cache.ReadThrough) service.IMediaService {
+	maxSize, _ := strconv.ParseInt(os.Getenv("MEDIA_MAX_BYTES"), 10, 64)
+	var derivatives repository.IImageDerivativeRepository = postgresql.NewImageDerivativeRepository(db)
+	if queries != nil {
+		derivatives = cache.NewImageDerivativeRepository(derivatives, queries)
+	}
+	return service.NewMediaService(mediaStorage(), postgresql.NewMediaRepository(db), derivatives, maxSize)
+}
+
+// mediaStorage keeps the media files in the MEDIA_DIR directory (default media),
+// or in an S3 bucket with MEDIA_STORAGE=s3. A misconfigured storage stops the server.
+func mediaStorage() repository.IMediaStorage {
+	switch os.Getenv("MEDIA_STORAGE") {
+	case "", "local":
+		dir := os.Getenv("MEDIA_DIR")
+		if dir == "" {
+			dir = "media"
+		}
+		return storage.NewLocalMediaStorage(dir)
+	case "s3":
+		mediaStorage, err := storage.NewS3MediaStorage(storage.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyId:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		})
+		if err != nil {
+			panic(err)
+		}
+		return mediaStorage
+	default:
+		panic(fmt.Sprintf("unknown MEDIA_STORAGE %q, expected local or s3", os.Getenv("MEDIA_STORAGE")))
+	}
+}
+
+// InitQueryCache builds the cache the category, sub-category and post queries are
+// read through, selected by QUERY_CACHE: off (the default), memory or redis. It is
+// nil when caching is off. The process builds it once and shares it, so that
+// concurrent requests share its entries, and a misconfigured cache stops it early.
+//
+// QUERY_CACHE_TTL (default 1m) bounds how long an entry is served; QUERY_CACHE_SIZE
+// (default 1000) is the number of entries kept in memory. Redis is located by
+// REDIS_ADDR (default localhost:6379), REDIS_USERNAME, REDIS_PASSWORD, REDIS_DB
+// and REDIS_KEY_PREFIX (default blog:).
+func InitQueryCache() *cache.ReadThrough {
+	var ttl time.Duration
+	if value := os.Getenv("QUERY_CACHE_TTL"); value != "" {
+		var err error
+		ttl, err = time.ParseDuration(value)
+		if err != nil {
+			panic(fmt.Sprintf("malformed QUERY_CACHE_TTL %q: %v", value, err))
+		}
+	}
+	switch os.Getenv("QUERY_CACHE") {
+	case "", "off":
+		return nil
+	case "memory":
+		size, _ := strconv.Atoi(os.Getenv("QUERY_CACHE_SIZE"))
+		return cache.NewReadThrough(cache.NewLRUQueryCache(size), ttl)
+	case "redis":
+		db, _ := strconv.Atoi(os.Getenv("REDIS_DB"))
+		prefix, ok := os.LookupEnv("REDIS_KEY_PREFIX")
+		if !ok {
+			prefix = "blog:"
+		}
+		queryCache, err := cache.NewRedisQueryCache(cache.RedisConfig{
+			Address:   os.Getenv("REDIS_ADDR"),
+			Username:  os.Getenv("REDIS_USERNAME"),
+			Password:  os.Getenv("REDIS_PASSWORD"),
+			DB:        db,
+			KeyPrefix: prefix,
+		})
+		if err != nil {
+			panic(err)
+		}
+		return cache.NewReadThrough(queryCache, ttl)
+	default:
+		panic(fmt.Sprintf("unknown QUERY_CACHE %q, expected off, memory or redis", os.Getenv("QUERY_CACHE")))
+	}
+}
+
+func categoryRepository(db *sql.DB, queries *cache.ReadThrough) (r repository.ICategoryRepository) {
+	r = postgresql.NewCategoryRepository(db)
+	if queries != nil {
+		r = cache.NewCategoryRepository(r, queries)
+	}
+	return
+}
+
+func subCategoryRepository(db *sql.DB, queries *cache.ReadThrough) (r repository.ISubCategoryRepository) {
+	r = postgresql.NewSubcategoryRepository(db)
+	if queries != nil {
+		r = cache.NewSubCategoryRepository(r, queries)
+	}
+	return
+}
+
+func postRepository(db *sql.DB, queries *cache.ReadThrough) (r repository.IPostRepository) {
+	r = postgresql.NewPostRepository(db)
+	if queries != nil {
+		r = cache.NewPostRepository(r, queries)
+	}
+	return
+}
+
+func userRepository(db *sql.DB, queries *cache.ReadThrough) (r repository.IUserRepository) {
+	r = postgresql.NewUserRepository(db)
+	if queries != nil {
+		r = cache.NewUserRepository(r, queries)
+	}
+	return
 }
@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+// ApiKeyModel describes an API key without the key itself. LastUsedAt is nil for
+// a key that was never used.
+type ApiKeyModel struct {
+	Prefix     string     `json:"prefix"`
+	Name       string     `json:"name"`
+	Username   string     `json:"username"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Revoked    bool       `json:"revoked"`
+}
+
+// ApiKeyInputModel carries the fields of a new API key.
+type ApiKeyInputModel struct {
+	Username string   `json:"username"`
+	Name     string   `json:"name"`
+	Scopes   []string `json:"scopes"`
+}
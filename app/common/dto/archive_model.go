@@ -0,0 +1,12 @@
+package dto
+
+import "time"
+
+// ArchiveManifestModel is the manifest.json at the start of an archive. Counts
+// holds the number of records of each kind, e.g. "posts", and of media files.
+type ArchiveManifestModel struct {
+	Format    string         `json:"format"`
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Counts    map[string]int `json:"counts"`
+}
@@ -0,0 +1,19 @@
+package dto
+
+// AuthorModel is the public profile of a user. It never carries credentials.
+type AuthorModel struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio,omitempty"`
+	AvatarUrl   string `json:"avatar_url"`
+}
+
+func NewAuthorModel(username string, displayName string, bio string, avatarUrl string) (authorModel AuthorModel) {
+	authorModel = AuthorModel{
+		Username:    username,
+		DisplayName: displayName,
+		Bio:         bio,
+		AvatarUrl:   avatarUrl,
+	}
+	return
+}
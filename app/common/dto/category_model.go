@@ -1,16 +1,29 @@
 package dto
 
 type CategoryModel struct {
-	Id   int    `json:"id"`
-	Name string `json:"name"`
-	Slug string `json:"slug"`
+	Id          int                 `json:"id"`
+	Name        string              `json:"name"`
+	Slug        string              `json:"slug"`
+	ParentId    int                 `json:"parent_id"`
+	Position    int                 `json:"position"`
+	Description string              `json:"description"`
+	CoverImage  string              `json:"cover_image"`
+	Children    []CategoryModel     `json:"children,omitempty"`
+	Stats       *CategoryStatsModel `json:"stats,omitempty"`
+	Version     int                 `json:"version"`
 }
 
-func NewCategoryModel(id int, name string, slug string) (categoryModel CategoryModel) {
+// CategoryOrderModel lists category ids in the order they should be displayed.
+type CategoryOrderModel struct {
+	Ids []int `json:"ids"`
+}
+
+func NewCategoryModel(id int, name string, slug string, parentId int) (categoryModel CategoryModel) {
 	categoryModel = CategoryModel{
-		Id:   id,
-		Name: name,
-		Slug: slug,
+		Id:       id,
+		Name:     name,
+		Slug:     slug,
+		ParentId: parentId,
 	}
 	return
 }
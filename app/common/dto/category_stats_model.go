@@ -0,0 +1,8 @@
+package dto
+
+import "time"
+
+type CategoryStatsModel struct {
+	PostCount    int        `json:"post_count"`
+	LatestPostAt *time.Time `json:"latest_post_at"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+// ImageModel is one resized copy of an image.
+type ImageModel struct {
+	Url    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// ImagesModel holds the derivatives generated for an uploaded image: a square
+// thumbnail, a medium copy that fits 800x800 and an Open Graph image of 1200x630.
+// Images smaller than a size get a smaller copy, never an enlarged one.
+type ImagesModel struct {
+	Thumbnail *ImageModel `json:"thumbnail"`
+	Medium    *ImageModel `json:"medium"`
+	Og        *ImageModel `json:"og"`
+}
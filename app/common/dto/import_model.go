@@ -0,0 +1,53 @@
+package dto
+
+import "time"
+
+// PostImportModel is a post read from an import source. It names its category and
+// sub-category by slug; the importer resolves them to ids.
+type PostImportModel struct {
+	// Source tells the operator where the post came from, e.g. the file name.
+	Source          string
+	Title           string
+	Slug            string
+	Content         string
+	MetaDescription string
+	EyeCatchingImg  string
+	CategorySlug    string
+	SubCategorySlug string
+	Tags            []string
+	IsPublic        bool
+	// Date is the creation date of new posts; zero means now.
+	Date time.Time
+}
+
+// ImportOptionsModel controls an import. With DryRun nothing is written and the
+// plan tells what would happen.
+type ImportOptionsModel struct {
+	DryRun           bool
+	CreateCategories bool
+}
+
+// Import actions, in the order an import may take them for one post.
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionError     = "error"
+	// ImportActionUnmapped marks source items the import has no place for.
+	ImportActionUnmapped = "unmapped"
+)
+
+// ImportActionModel is one line of an import plan: what happens, or would happen,
+// to one post, category or sub-category.
+type ImportActionModel struct {
+	Action string `json:"action"`
+	Kind   string `json:"kind"`
+	Slug   string `json:"slug"`
+	Source string `json:"source,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type ImportPlanModel struct {
+	DryRun  bool                `json:"dry_run"`
+	Actions []ImportActionModel `json:"actions"`
+}
@@ -0,0 +1,57 @@
+package dto
+
+import "time"
+
+// MediaModel describes a stored media file. Url is the path it is served at;
+// eye_catching_img and the post content refer to it by Name and Url respectively.
+// Images lists the derivatives of an image, DerivativeOf names the image a
+// derivative was made of, VariantOf the file a variant (e.g. a WebP or a
+// compressed copy) stands in for, which is served in ContentEncoding. The fields
+// from Width on come from the upload record, which files stored by other means lack.
+type MediaModel struct {
+	Name            string       `json:"name"`
+	Url             string       `json:"url"`
+	Size            int64        `json:"size"`
+	ContentType     string       `json:"content_type"`
+	ModifiedAt      time.Time    `json:"modified_at"`
+	Images          *ImagesModel `json:"images,omitempty"`
+	DerivativeOf    string       `json:"derivative_of,omitempty"`
+	VariantOf       string       `json:"variant_of,omitempty"`
+	ContentEncoding string       `json:"content_encoding,omitempty"`
+	Width           int          `json:"width,omitempty"`
+	Height          int          `json:"height,omitempty"`
+	AltText         string       `json:"alt_text"`
+	UploadedBy      int          `json:"uploaded_by,omitempty"`
+	CreatedAt       *time.Time   `json:"created_at,omitempty"`
+}
+
+// MediaInputModel holds the fields of a media file that can be changed.
+type MediaInputModel struct {
+	AltText string `json:"alt_text"`
+}
+
+// MediaUsageModel lists the posts, categories and users using a media file,
+// directly or through one of its derivatives; UsedBy is empty for an orphaned file.
+type MediaUsageModel struct {
+	Name   string          `json:"name"`
+	Url    string          `json:"url"`
+	Size   int64           `json:"size"`
+	UsedBy []MediaUseModel `json:"used_by"`
+}
+
+// MediaUseModel is a reference of a post, category or user to a media file, with
+// the fields of the one referring set. Field is "eye_catching_img" or "content"
+// for a post, "cover_image" for a category and "avatar_url" for a user; Name is
+// the file referred to, which is a derivative when it differs from the file used.
+type MediaUseModel struct {
+	PostId       int    `json:"post_id,omitempty"`
+	PostSlug     string `json:"post_slug,omitempty"`
+	PostTitle    string `json:"post_title,omitempty"`
+	CategoryId   int    `json:"category_id,omitempty"`
+	CategorySlug string `json:"category_slug,omitempty"`
+	CategoryName string `json:"category_name,omitempty"`
+	UserId       int    `json:"user_id,omitempty"`
+	Username     string `json:"username,omitempty"`
+	Field        string `json:"field"`
+	Name         string `json:"name"`
+}
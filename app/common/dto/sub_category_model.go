@@ -1,12 +1,18 @@
 package dto
 
 type SubCategoryModel struct {
-	Id                 int    `json:"id"`
-	Name               string `json:"name"`
-	Slug               string `json:"slug"`
-	ParentCategoryId   int    `json:"parent_category_id"`
-	ParentCategoryName string `json:"parent_category_name"`
-	ParentCategorySlug string `json:"parent_category_slug"`
+	Id                 int                 `json:"id"`
+	Name               string              `json:"name"`
+	Slug               string              `json:"slug"`
+	ParentCategoryId   int                 `json:"parent_category_id"`
+	ParentCategoryName string              `json:"parent_category_name"`
+	ParentCategorySlug string              `json:"parent_category_slug"`
+	Position           int                 `json:"position"`
+	Description        string              `json:"description"`
+	CoverImage         string              `json:"cover_image"`
+	Children           []SubCategoryModel  `json:"children,omitempty"`
+	Stats              *CategoryStatsModel `json:"stats,omitempty"`
+	Version            int                 `json:"version"`
 }
 
 func NewSubCategoryModel(id int, name string, slug string, parentCategoryId int, parentCategoryName string, parentCategorySlug string) (subCategoryModel SubCategoryModel) {
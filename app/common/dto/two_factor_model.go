@@ -0,0 +1,25 @@
+package dto
+
+// TwoFactorLoginModel is the second login step: the mfa_token of the challenge and
+// either a TOTP code or a recovery code.
+type TwoFactorLoginModel struct {
+	MfaToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+	// RemoteAddr is the client IP, as in CredentialsModel.
+	RemoteAddr string `json:"-"`
+}
+
+// TwoFactorChallengeModel answers a correct password of a user with 2FA enabled.
+type TwoFactorChallengeModel struct {
+	Required  bool   `json:"mfa_required"`
+	MfaToken  string `json:"mfa_token"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// TwoFactorEnrollmentModel is a freshly generated TOTP secret and its otpauth URI,
+// not yet stored.
+type TwoFactorEnrollmentModel struct {
+	Secret string
+	Uri    string
+}
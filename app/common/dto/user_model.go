@@ -1,27 +1,73 @@
 package dto
 
+// UserModel is the output representation of a user. It has no password field,
+// so a hash can never be serialized.
 type UserModel struct {
-	Id       int    `json:"id"`
-	Name     string `json:"name"`
-	Password string `json:"password"`
-	IsAdmin  bool   `json:"is_admin"`
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	Role        string `json:"role"`
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	AvatarUrl   string `json:"avatar_url"`
+	// Scopes limits a request authenticated with an API key. It is nil for
+	// access tokens, which carry every permission of the role.
+	Scopes []string `json:"-"`
+}
+
+// UserInputModel carries the fields accepted when creating or updating a user.
+// Password is plaintext and is hashed by the service; an empty password on
+// update keeps the current one.
+type UserInputModel struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	Password    string `json:"password"`
+	Role        string `json:"role"`
+	DisplayName string `json:"display_name"`
+	Bio         string `json:"bio"`
+	AvatarUrl   string `json:"avatar_url"`
 }
 
 type CredentialsModel struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// RemoteAddr is the client IP of a login over HTTP, empty for the CLI.
+	RemoteAddr string `json:"-"`
 }
 
+// AuthTokenModel is the short-lived access token, plus the refresh token
+// and access token lifetime in seconds when it was just issued.
 type AuthTokenModel struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+}
+
+type RefreshTokenModel struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RoleModel struct {
+	Role string `json:"role"`
 }
 
-func NewUserModel(id int, name string, password string) (userModel UserModel) {
+func NewUserModel(id int, name string, role string) (userModel UserModel) {
 	userModel = UserModel{
-		Id:       id,
-		Name:     name,
-		Password: password,
-		IsAdmin:  true,
+		Id:   id,
+		Name: name,
+		Role: role,
+	}
+	return
+}
+
+// NewUserInputModel starts an input from an existing user, leaving the password unchanged.
+func NewUserInputModel(userModel UserModel) (userInputModel UserInputModel) {
+	userInputModel = UserInputModel{
+		Id:          userModel.Id,
+		Name:        userModel.Name,
+		Role:        userModel.Role,
+		DisplayName: userModel.DisplayName,
+		Bio:         userModel.Bio,
+		AvatarUrl:   userModel.AvatarUrl,
 	}
 	return
 }
@@ -0,0 +1,30 @@
+package entity
+
+import "time"
+
+// ApiKey is a long-lived credential of a machine client acting as UserId.
+// Only the SHA-256 hash of the key is stored; Prefix is kept in clear to tell
+// keys apart. LastUsedAt is zero for a key that was never used.
+type ApiKey struct {
+	Id         int
+	UserId     int
+	Username   string
+	Name       string
+	Prefix     string
+	KeyHash    string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	Revoked    bool
+}
+
+func NewApiKey(userId int, name string, prefix string, keyHash string, scopes []string) (apiKey ApiKey) {
+	apiKey = ApiKey{
+		UserId:  userId,
+		Name:    name,
+		Prefix:  prefix,
+		KeyHash: keyHash,
+		Scopes:  scopes,
+	}
+	return
+}
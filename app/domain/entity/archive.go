@@ -0,0 +1,9 @@
+package entity
+
+// Archive is the content of a backup: the category tree with parents before their
+// children, the users with their password hashes, and the posts, all with their ids.
+type Archive struct {
+	Categories []Category
+	Users      []User
+	Posts      []Post
+}
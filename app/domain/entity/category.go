@@ -1,16 +1,27 @@
 package entity
 
+// Category is a node of the category tree. ParentId is 0 for root categories.
+// Siblings are listed in ascending Position.
 type Category struct {
-	Id   int
-	Name string
-	Slug string
+	Id          int
+	Name        string
+	Slug        string
+	ParentId    int
+	Position    int
+	Description string
+	CoverImage  string
+	Children    []Category
+	Stats       *CategoryStats
+	// Version counts the updates of the category, as for Post.
+	Version int
 }
 
-func NewCategory(id int, name string, slug string) (category Category) {
+func NewCategory(id int, name string, slug string, parentId int) (category Category) {
 	category = Category{
-		Id:   id,
-		Name: name,
-		Slug: slug,
+		Id:       id,
+		Name:     name,
+		Slug:     slug,
+		ParentId: parentId,
 	}
 	return
 }
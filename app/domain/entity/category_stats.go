@@ -0,0 +1,10 @@
+package entity
+
+import "time"
+
+// CategoryStats summarizes the public posts of a category and all of its descendants.
+// LatestPostAt is the zero time when the category has no public post.
+type CategoryStats struct {
+	PostCount    int
+	LatestPostAt time.Time
+}
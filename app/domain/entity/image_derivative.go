@@ -0,0 +1,11 @@
+package entity
+
+// ImageDerivative is a resized copy of the media file Source, stored as the media
+// file Name. Kind tells which of the sizes it is, e.g. "thumbnail".
+type ImageDerivative struct {
+	Source string
+	Kind   string
+	Name   string
+	Width  int
+	Height int
+}
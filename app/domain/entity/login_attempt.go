@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// LoginAttempt counts the recent failed logins of one subject, a username or a
+// client IP. LockedUntil is zero while the subject may try again.
+type LoginAttempt struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// Media is the record of an uploaded media file. Width and Height are 0 for files
+// that are not images, UploadedBy is 0 when the uploader is unknown or deleted.
+type Media struct {
+	Name        string
+	ContentType string
+	Size        int64
+	Width       int
+	Height      int
+	AltText     string
+	UploadedBy  int
+	CreatedAt   time.Time
+}
@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// MediaObject is a file in the media storage. Name is its slash separated path
+// below the storage root, which is also its path below /api/v1/media/.
+type MediaObject struct {
+	Name        string
+	Size        int64
+	ContentType string
+	ModifiedAt  time.Time
+}
@@ -15,16 +15,22 @@ type Post struct {
 	CategoryId      int
 	CategoryName    string
 	CategorySlug    string
-	SubCategoryId   int
-	SubCategoryName string
-	SubCategorySlug string
+	AuthorId        int
+	// Author* describe the author for display and are empty for posts without one.
+	AuthorName        string
+	AuthorDisplayName string
+	AuthorAvatarUrl   string
+	// Images are the derivatives of EyeCatchingImg.
+	Images []ImageDerivative
+	// Version counts the updates of the post. Update only applies to the version
+	// given, or unconditionally when it is 0.
+	Version int
 }
 
-func NewPost(id int, categoryId int, subCategoryId int, title string, slug string, eyeCatchingImg string, content string, metaDescription string, isPublic bool, createdAt time.Time, updatedAt time.Time) (post Post) {
+func NewPost(id int, categoryId int, title string, slug string, eyeCatchingImg string, content string, metaDescription string, isPublic bool, createdAt time.Time, updatedAt time.Time) (post Post) {
 	post = Post{
 		Id:              id,
 		CategoryId:      categoryId,
-		SubCategoryId:   subCategoryId,
 		Title:           title,
 		Slug:            slug,
 		EyeCatchingImg:  eyeCatchingImg,
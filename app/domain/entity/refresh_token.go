@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// RefreshToken is one link of a rotation chain. Tokens issued from the same login
+// share a FamilyId; only the SHA-256 hash of the opaque token is stored.
+type RefreshToken struct {
+	Id        int
+	UserId    int
+	FamilyId  string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	Revoked   bool
+}
+
+func NewRefreshToken(userId int, familyId string, tokenHash string, expiresAt time.Time) (refreshToken RefreshToken) {
+	refreshToken = RefreshToken{
+		UserId:    userId,
+		FamilyId:  familyId,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}
+	return
+}
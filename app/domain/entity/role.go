@@ -0,0 +1,106 @@
+package entity
+
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"
+	RoleEditor Role = "editor"
+	RoleAuthor Role = "author"
+	RoleViewer Role = "viewer"
+)
+
+type Permission string
+
+const (
+	PermissionCreatePost       Permission = "posts:create"
+	PermissionEditOwnPost      Permission = "posts:edit-own"
+	PermissionEditAnyPost      Permission = "posts:edit-any"
+	PermissionDeleteOwnPost    Permission = "posts:delete-own"
+	PermissionDeleteAnyPost    Permission = "posts:delete-any"
+	PermissionPublishPost      Permission = "posts:publish"
+	PermissionManageCategories Permission = "categories:manage"
+	PermissionManageUsers      Permission = "users:manage"
+	PermissionUploadMedia      Permission = "media:upload"
+	PermissionDeleteMedia      Permission = "media:delete"
+)
+
+var authorPermissions = []Permission{
+	PermissionCreatePost,
+	PermissionEditOwnPost,
+	PermissionDeleteOwnPost,
+	PermissionUploadMedia,
+}
+
+var editorPermissions = append([]Permission{
+	PermissionEditAnyPost,
+	PermissionDeleteAnyPost,
+	PermissionPublishPost,
+	PermissionManageCategories,
+	PermissionDeleteMedia,
+}, authorPermissions...)
+
+var adminPermissions = append([]Permission{
+	PermissionManageUsers,
+}, editorPermissions...)
+
+// rolePermissions is the permission matrix. Viewers are read-only.
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin:  adminPermissions,
+	RoleEditor: editorPermissions,
+	RoleAuthor: authorPermissions,
+	RoleViewer: {},
+}
+
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+func (r Role) Can(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Scope is a grant of an API key. A key can never do more than the role of its
+// user allows; scopes only narrow it down.
+type Scope string
+
+const (
+	ScopePostsWrite      Scope = "posts:write"
+	ScopePostsPublish    Scope = "posts:publish"
+	ScopeCategoriesWrite Scope = "categories:write"
+	ScopeUsersWrite      Scope = "users:write"
+	ScopeMediaWrite      Scope = "media:write"
+)
+
+var scopePermissions = map[Scope][]Permission{
+	ScopePostsWrite: {
+		PermissionCreatePost,
+		PermissionEditOwnPost,
+		PermissionEditAnyPost,
+		PermissionDeleteOwnPost,
+		PermissionDeleteAnyPost,
+	},
+	ScopePostsPublish:    {PermissionPublishPost},
+	ScopeCategoriesWrite: {PermissionManageCategories},
+	ScopeUsersWrite:      {PermissionManageUsers},
+	ScopeMediaWrite:      {PermissionUploadMedia, PermissionDeleteMedia},
+}
+
+func (s Scope) IsValid() bool {
+	_, ok := scopePermissions[s]
+	return ok
+}
+
+func (s Scope) Allows(permission Permission) bool {
+	for _, p := range scopePermissions[s] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
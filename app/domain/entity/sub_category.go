@@ -7,6 +7,13 @@ type SubCategory struct {
 	ParentCategoryId   int
 	ParentCategoryName string
 	ParentCategorySlug string
+	Position           int
+	Description        string
+	CoverImage         string
+	Children           []SubCategory
+	Stats              *CategoryStats
+	// Version counts the updates of the category, as for Post.
+	Version int
 }
 
 func NewSubCategory(id int, name string, slug string, parentCategoryId int, parentCategoryName string, parentCategorySlug string) (subCategory SubCategory) {
@@ -0,0 +1,17 @@
+package entity
+
+// TwoFactor is the TOTP enrollment of a user. LastUsedStep is the time step of the
+// last accepted code, so that a code cannot be used twice.
+type TwoFactor struct {
+	UserId       int
+	Secret       string
+	LastUsedStep int64
+}
+
+func NewTwoFactor(userId int, secret string) (twoFactor TwoFactor) {
+	twoFactor = TwoFactor{
+		UserId: userId,
+		Secret: secret,
+	}
+	return
+}
@@ -4,7 +4,11 @@ type User struct {
 	Id       int
 	Name     string
 	Password string
-	IsAdmin  bool
+	Role     Role
+	// DisplayName, Bio and AvatarUrl make up the public author profile.
+	DisplayName string
+	Bio         string
+	AvatarUrl   string
 }
 
 type Credentials struct {
@@ -16,12 +20,12 @@ type AuthToken struct {
 	Token string
 }
 
-func NewUser(id int, name string, password string) (user User) {
+func NewUser(id int, name string, password string, role Role) (user User) {
 	user = User{
 		Id:       id,
 		Name:     name,
 		Password: password,
-		IsAdmin:  true,
+		Role:     role,
 	}
 	return
 }
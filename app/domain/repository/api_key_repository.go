@@ -0,0 +1,13 @@
+package repository
+
+import "backend/app/domain/entity"
+
+type IApiKeyRepository interface {
+	GetAll() (apiKeys []entity.ApiKey, err error)
+	GetByPrefix(prefix string) (apiKey entity.ApiKey, err error)
+	Create(apiKey entity.ApiKey) (err error)
+	// Revoke returns sql.ErrNoRows when no active key has the prefix.
+	Revoke(prefix string) (err error)
+	// Touch records that the key was used just now.
+	Touch(id int) (err error)
+}
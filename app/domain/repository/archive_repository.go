@@ -0,0 +1,12 @@
+package repository
+
+import "backend/app/domain/entity"
+
+type IArchiveRepository interface {
+	// Dump reads the whole archive from one consistent snapshot.
+	Dump() (archive entity.Archive, err error)
+	// Restore writes the archive in one transaction, keeping its ids and timestamps.
+	// Rows with the same id are overwritten; with replace, rows missing from the
+	// archive are deleted as well.
+	Restore(archive entity.Archive, replace bool) (err error)
+}
@@ -4,8 +4,13 @@ import "backend/app/domain/entity"
 
 type ICategoryRepository interface {
 	GetAll() (categories []entity.Category, err error)
+	GetTree() (categories []entity.Category, err error)
+	GetChildren(parentId int) (categories []entity.Category, err error)
 	GetBySlug(slug string) (category entity.Category, err error)
+	GetStats(ids []int) (stats map[int]entity.CategoryStats, err error)
+	GetAncestors(id int) (categories []entity.Category, err error)
 	Create(category entity.Category) (err error)
 	Update(entity.Category) (err error)
 	Delete(entity.Category) (err error)
+	Reorder(ids []int) (err error)
 }
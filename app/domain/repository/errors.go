@@ -0,0 +1,15 @@
+package repository
+
+import "errors"
+
+// ErrConflict is returned by an update that was given a version the stored row no
+// longer has: someone else updated it since it was read.
+var ErrConflict = errors.New("the resource was changed by someone else; read it again")
+
+// ErrMixedParents is returned by a reorder listing categories of different parents,
+// whose positions do not compare.
+var ErrMixedParents = errors.New("category order must list children of one parent")
+
+// ErrIncompleteOrder is returned by a reorder leaving out some children of the
+// parent, whose positions would collide with the new ones.
+var ErrIncompleteOrder = errors.New("category order must list every child of the parent")
@@ -0,0 +1,12 @@
+package repository
+
+import "backend/app/domain/entity"
+
+type IImageDerivativeRepository interface {
+	GetAll() (derivatives []entity.ImageDerivative, err error)
+	GetBySource(source string) (derivatives []entity.ImageDerivative, err error)
+	// Save replaces the derivatives recorded for source.
+	Save(source string, derivatives []entity.ImageDerivative) (err error)
+	// Delete forgets the derivatives of source and returns them.
+	Delete(source string) (derivatives []entity.ImageDerivative, err error)
+}
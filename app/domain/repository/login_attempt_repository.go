@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"backend/app/domain/entity"
+	"time"
+)
+
+type ILoginAttemptRepository interface {
+	Get(key string) (loginAttempt entity.LoginAttempt, err error)
+	// RecordFailure counts a failed login and returns the number of failures since
+	// windowStart; older failures are forgotten.
+	RecordFailure(key string, windowStart time.Time) (failures int, err error)
+	Lock(key string, until time.Time) (err error)
+	Reset(key string) (err error)
+}
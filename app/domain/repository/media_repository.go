@@ -0,0 +1,19 @@
+package repository
+
+import "backend/app/domain/entity"
+
+type IMediaRepository interface {
+	GetAll() (media []entity.Media, err error)
+	GetByName(name string) (media entity.Media, err error)
+	// Save inserts the record or updates it, keeping who uploaded it and when.
+	Save(media entity.Media) (err error)
+	Delete(name string) (err error)
+	// GetReferringPosts returns every post with the fields that can refer to media
+	// files set: Id, Slug, Title, EyeCatchingImg and Content.
+	GetReferringPosts() (posts []entity.Post, err error)
+	// GetReferringCategories returns every category and sub-category with Id, Slug,
+	// Name and CoverImage set.
+	GetReferringCategories() (categories []entity.Category, err error)
+	// GetReferringUsers returns every user with Id, Name and AvatarUrl set.
+	GetReferringUsers() (users []entity.User, err error)
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"backend/app/domain/entity"
+	"io"
+)
+
+// IMediaStorage stores the media files. Names are slash separated paths; a name
+// that is not stored yields an error wrapping fs.ErrNotExist.
+type IMediaStorage interface {
+	// Put stores size bytes read from r under name, replacing a file of that name.
+	Put(name string, r io.Reader, size int64, contentType string) (err error)
+	// Open returns the content of the file; the caller closes it. The content is
+	// an io.ReadSeeker where the storage can provide one.
+	Open(name string) (content io.ReadCloser, object entity.MediaObject, err error)
+	Stat(name string) (object entity.MediaObject, err error)
+	// List returns every stored file ordered by name.
+	List() (objects []entity.MediaObject, err error)
+	Delete(name string) (err error)
+}
@@ -0,0 +1,14 @@
+package repository
+
+import "time"
+
+// IQueryCache keeps encoded query results for a while. Keys are strings such as
+// "posts:slug:hello"; a key that was never set, has expired or was evicted is not
+// found. Implementations are safe for concurrent use.
+type IQueryCache interface {
+	Get(key string) (value []byte, found bool, err error)
+	Set(key string, value []byte, ttl time.Duration) (err error)
+	// DeletePrefix removes every key that starts with prefix, which ends in a
+	// colon, such as "posts:" or "categories:stats:".
+	DeletePrefix(prefix string) (err error)
+}
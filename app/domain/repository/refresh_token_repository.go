@@ -0,0 +1,13 @@
+package repository
+
+import "backend/app/domain/entity"
+
+type IRefreshTokenRepository interface {
+	GetByHash(tokenHash string) (refreshToken entity.RefreshToken, err error)
+	Create(refreshToken entity.RefreshToken) (err error)
+	// Revoke revokes a single token and reports whether it was still active,
+	// so that two concurrent refreshes cannot both succeed.
+	Revoke(id int) (revoked bool, err error)
+	RevokeFamily(familyId string) (err error)
+	RevokeAllForUser(userId int) (err error)
+}
@@ -5,6 +5,10 @@ import "backend/app/domain/entity"
 type ISubCategoryRepository interface {
 	GetSubCategories(map[string][]string) ([]entity.SubCategory, error)
 	GetSubCategoryBySlug(string) (entity.SubCategory, error)
+	GetStats([]int) (map[int]entity.CategoryStats, error)
+	// GetAncestorIds returns the ids on the path from the root down to the
+	// category with the given id, the category itself included.
+	GetAncestorIds(int) ([]int, error)
 	Create(entity.SubCategory) error
 	Update(entity.SubCategory) error
 	Delete(entity.SubCategory) error
@@ -0,0 +1,16 @@
+package repository
+
+import "backend/app/domain/entity"
+
+type ITwoFactorRepository interface {
+	// GetByUserId returns sql.ErrNoRows when the user has not enabled 2FA.
+	GetByUserId(userId int) (twoFactor entity.TwoFactor, err error)
+	// Enable stores the secret and replaces the user's recovery codes.
+	Enable(twoFactor entity.TwoFactor, recoveryCodeHashes []string) (err error)
+	Disable(userId int) (err error)
+	// UseStep records the time step of an accepted code and reports false when that
+	// step or a later one was already used.
+	UseStep(userId int, step int64) (used bool, err error)
+	// UseRecoveryCode consumes an unused recovery code and reports whether there was one.
+	UseRecoveryCode(userId int, codeHash string) (used bool, err error)
+}
@@ -4,9 +4,9 @@ import "backend/app/domain/entity"
 
 type IUserRepository interface {
 	GetAll() ([]entity.User, error)
-	ValidateUser(entity.Credentials) (entity.User, error)
+	GetById(int) (entity.User, error)
+	GetByUsername(string) (entity.User, error)
 	Create(entity.User) error
 	Update(entity.User) error
 	Delete(entity.User) error
-	IsAdmin(int) (bool, error)
 }
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// apiKeyTag starts every API key, which tells them apart from JWTs in the
+// Authorization header. A key reads "bk_<prefix>_<secret>".
+const (
+	apiKeyTag          = "bk_"
+	apiKeyPrefixLength = 8
+)
+
+// ErrInvalidScope is returned when an API key would be created without scopes or
+// with a scope outside the known set.
+var ErrInvalidScope = errors.New("unknown or missing API key scope")
+
+func hashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func newApiKey() (key string, prefix string, err error) {
+	b := make([]byte, apiKeyPrefixLength/2)
+	_, err = rand.Read(b)
+	if err != nil {
+		return
+	}
+	prefix = hex.EncodeToString(b)
+	secret, err := newOpaqueToken()
+	if err != nil {
+		return
+	}
+	key = apiKeyTag + prefix + "_" + secret
+	return
+}
+
+// apiKeyPrefix extracts the prefix of a key, reporting false for anything that
+// is not shaped like an API key.
+func apiKeyPrefix(key string) (prefix string, ok bool) {
+	rest := strings.TrimPrefix(key, apiKeyTag)
+	if rest == key || len(rest) <= apiKeyPrefixLength || rest[apiKeyPrefixLength] != '_' {
+		return
+	}
+	prefix, ok = rest[:apiKeyPrefixLength], true
+	return
+}
+
+func (s *UserService) convertToDtoFromApiKey(apiKey entity.ApiKey) (apiKeyDto dto.ApiKeyModel) {
+	apiKeyDto = dto.ApiKeyModel{
+		Prefix:    apiKey.Prefix,
+		Name:      apiKey.Name,
+		Username:  apiKey.Username,
+		Scopes:    apiKey.Scopes,
+		CreatedAt: apiKey.CreatedAt,
+		Revoked:   apiKey.Revoked,
+	}
+	if !apiKey.LastUsedAt.IsZero() {
+		lastUsedAt := apiKey.LastUsedAt
+		apiKeyDto.LastUsedAt = &lastUsedAt
+	}
+	return
+}
+
+// CreateApiKey creates a key acting as the named user, limited to the scopes.
+// The key is returned only this once.
+func (s *UserService) CreateApiKey(actor dto.UserModel, apiKeyInputDto dto.ApiKeyInputModel) (key string, err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
+	if len(apiKeyInputDto.Scopes) == 0 {
+		err = ErrInvalidScope
+		return
+	}
+	for _, scope := range apiKeyInputDto.Scopes {
+		if !entity.Scope(scope).IsValid() {
+			err = fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+			return
+		}
+	}
+	user, err := s.IUserRepository.GetByUsername(apiKeyInputDto.Username)
+	if err != nil {
+		return
+	}
+	key, prefix, err := newApiKey()
+	if err != nil {
+		return
+	}
+	err = s.IApiKeyRepository.Create(entity.NewApiKey(user.Id, apiKeyInputDto.Name, prefix, hashApiKey(key), apiKeyInputDto.Scopes))
+	if err != nil {
+		key = ""
+	}
+	return
+}
+
+func (s *UserService) GetApiKeys(actor dto.UserModel) (apiKeyDtos []dto.ApiKeyModel, err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
+	apiKeys, err := s.IApiKeyRepository.GetAll()
+	if err != nil {
+		return
+	}
+	for _, apiKey := range apiKeys {
+		apiKeyDtos = append(apiKeyDtos, s.convertToDtoFromApiKey(apiKey))
+	}
+	return
+}
+
+func (s *UserService) RevokeApiKey(actor dto.UserModel, prefix string) (err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
+	err = s.IApiKeyRepository.Revoke(prefix)
+	return
+}
+
+// validateApiKey resolves a key to its user, restricted to the key's scopes.
+// Every failure wraps ErrInvalidToken.
+func (s *UserService) validateApiKey(key string) (userDto dto.UserModel, err error) {
+	prefix, ok := apiKeyPrefix(key)
+	if !ok {
+		err = fmt.Errorf("%w: malformed API key", ErrInvalidToken)
+		return
+	}
+	apiKey, err := s.IApiKeyRepository.GetByPrefix(prefix)
+	if err != nil || subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(hashApiKey(key))) != 1 {
+		err = fmt.Errorf("%w: unknown API key", ErrInvalidToken)
+		return
+	}
+	if apiKey.Revoked {
+		err = fmt.Errorf("%w: API key is revoked", ErrInvalidToken)
+		return
+	}
+	user, err := s.IUserRepository.GetById(apiKey.UserId)
+	if err != nil {
+		err = fmt.Errorf("%w: unknown user", ErrInvalidToken)
+		return
+	}
+	err = s.IApiKeyRepository.Touch(apiKey.Id)
+	if err != nil {
+		return
+	}
+	userDto = s.convertToDtoFromEntity(user)
+	userDto.Scopes = apiKey.Scopes
+	return
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	mocks "backend/mocks/repository"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestApiKeyPrefix(t *testing.T) {
+	key, prefix, err := newApiKey()
+	assert.NoError(t, err)
+
+	ret, ok := apiKeyPrefix(key)
+	assert.True(t, ok)
+	assert.Equal(t, prefix, ret)
+
+	for _, malformed := range []string{"bk_", "bk_abcd", "bk_abcdefghXsecret", "eyJhbGciOi"} {
+		_, ok = apiKeyPrefix(malformed)
+		assert.False(t, ok, malformed)
+	}
+}
+
+func TestUserService_CreateApiKey(t *testing.T) {
+	t.Run(
+		"stores the hash",
+		func(t *testing.T) {
+			r := new(mocks.IUserRepository)
+			ar := new(mocks.IApiKeyRepository)
+
+			r.On("GetByUsername", "testci").Return(entity.NewUser(6, "testci", "hashed", entity.RoleEditor), nil)
+			ar.On("Create", mock.MatchedBy(func(apiKey entity.ApiKey) bool {
+				return apiKey.UserId == 6 && apiKey.Name == "ci" && len(apiKey.Prefix) == apiKeyPrefixLength
+			})).Return(nil)
+
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), ar, bcrypt.MinCost, testTokenConfig)
+
+			key, err := s.CreateApiKey(admin, dto.ApiKeyInputModel{Username: "testci", Name: "ci", Scopes: []string{"posts:write"}})
+
+			assert.NoError(t, err)
+			ar.AssertExpectations(t)
+			stored := ar.Calls[0].Arguments.Get(0).(entity.ApiKey)
+			assert.Equal(t, stored.KeyHash, hashApiKey(key))
+			assert.NotContains(t, stored.KeyHash, key)
+			assert.Contains(t, key, apiKeyTag+stored.Prefix+"_")
+		},
+	)
+
+	t.Run(
+		"unknown scope",
+		func(t *testing.T) {
+			ar := new(mocks.IApiKeyRepository)
+
+			s := NewUserService(new(mocks.IUserRepository), new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), ar, bcrypt.MinCost, testTokenConfig)
+
+			_, err := s.CreateApiKey(admin, dto.ApiKeyInputModel{Username: "testci", Name: "ci", Scopes: []string{"posts:everything"}})
+			assert.ErrorIs(t, err, ErrInvalidScope)
+
+			_, err = s.CreateApiKey(admin, dto.ApiKeyInputModel{Username: "testci", Name: "ci"})
+			assert.ErrorIs(t, err, ErrInvalidScope)
+
+			ar.AssertNotCalled(t, "Create", mock.Anything)
+		},
+	)
+}
+
+func TestUserService_ValidateToken_ApiKey(t *testing.T) {
+	key := "bk_0123abcd_secret"
+	apiKey := entity.NewApiKey(6, "ci", "0123abcd", hashApiKey(key), []string{"posts:write"})
+	apiKey.Id = 3
+	user := entity.NewUser(6, "testci", "hashed", entity.RoleEditor)
+
+	t.Run(
+		"valid key",
+		func(t *testing.T) {
+			r := new(mocks.IUserRepository)
+			ar := new(mocks.IApiKeyRepository)
+
+			ar.On("GetByPrefix", "0123abcd").Return(apiKey, nil)
+			r.On("GetById", 6).Return(user, nil)
+			ar.On("Touch", 3).Return(nil)
+
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), ar, bcrypt.MinCost, testTokenConfig)
+
+			userDto, err := s.ValidateToken(dto.NewAuthTokenModel(key))
+
+			assert.NoError(t, err)
+			assert.Equal(t, userDto.Id, 6)
+			assert.Equal(t, userDto.Scopes, []string{"posts:write"})
+			ar.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"wrong secret",
+		func(t *testing.T) {
+			ar := new(mocks.IApiKeyRepository)
+
+			ar.On("GetByPrefix", "0123abcd").Return(apiKey, nil)
+
+			s := NewUserService(new(mocks.IUserRepository), new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), ar, bcrypt.MinCost, testTokenConfig)
+
+			_, err := s.ValidateToken(dto.NewAuthTokenModel("bk_0123abcd_guess"))
+
+			assert.ErrorIs(t, err, ErrInvalidToken)
+			ar.AssertNotCalled(t, "Touch", mock.Anything)
+		},
+	)
+
+	t.Run(
+		"unknown prefix",
+		func(t *testing.T) {
+			ar := new(mocks.IApiKeyRepository)
+
+			ar.On("GetByPrefix", "ffffffff").Return(entity.ApiKey{}, sql.ErrNoRows)
+
+			s := NewUserService(new(mocks.IUserRepository), new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), ar, bcrypt.MinCost, testTokenConfig)
+
+			_, err := s.ValidateToken(dto.NewAuthTokenModel("bk_ffffffff_secret"))
+
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		},
+	)
+
+	t.Run(
+		"revoked key",
+		func(t *testing.T) {
+			revoked := apiKey
+			revoked.Revoked = true
+			ar := new(mocks.IApiKeyRepository)
+
+			ar.On("GetByPrefix", "0123abcd").Return(revoked, nil)
+
+			s := NewUserService(new(mocks.IUserRepository), new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), ar, bcrypt.MinCost, testTokenConfig)
+
+			_, err := s.ValidateToken(dto.NewAuthTokenModel(key))
+
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		},
+	)
+}
+
+func TestAuthorize_Scopes(t *testing.T) {
+	scoped := func(role string, scopes ...string) dto.UserModel {
+		userDto := dto.NewUserModel(6, "testci", role)
+		userDto.Scopes = scopes
+		return userDto
+	}
+
+	assert.NoError(t, authorize(scoped("editor", "posts:write"), entity.PermissionCreatePost))
+	assert.ErrorIs(t, authorize(scoped("editor", "posts:write"), entity.PermissionPublishPost), ErrForbidden)
+	assert.NoError(t, authorize(scoped("editor", "posts:write", "posts:publish"), entity.PermissionPublishPost))
+	assert.ErrorIs(t, authorize(scoped("editor", "posts:write"), entity.PermissionManageCategories), ErrForbidden)
+	// a scope never grants more than the role
+	assert.ErrorIs(t, authorize(scoped("author", "users:write"), entity.PermissionManageUsers), ErrForbidden)
+	assert.ErrorIs(t, authorize(scoped("author", "posts:publish"), entity.PermissionPublishPost), ErrForbidden)
+}
+
+func TestUserService_RevokeApiKey(t *testing.T) {
+	ar := new(mocks.IApiKeyRepository)
+
+	ar.On("Revoke", "0123abcd").Return(nil)
+	ar.On("Revoke", "ffffffff").Return(sql.ErrNoRows)
+
+	s := NewUserService(new(mocks.IUserRepository), new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), ar, bcrypt.MinCost, testTokenConfig)
+
+	assert.NoError(t, s.RevokeApiKey(admin, "0123abcd"))
+	assert.ErrorIs(t, s.RevokeApiKey(admin, "ffffffff"), sql.ErrNoRows)
+	assert.ErrorIs(t, s.RevokeApiKey(editor, "0123abcd"), ErrForbidden)
+	ar.AssertExpectations(t)
+}
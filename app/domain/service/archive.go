@@ -0,0 +1,412 @@
+package service
+
+import (
+	"archive/tar"
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// The archive is a gzipped tar holding manifest.json first, then one NDJSON file
+// per kind of record and the files of the media directory below media/. Readers
+// accept every version up to archiveVersion and ignore entries they do not know.
+const (
+	archiveFormat   = "golang-blog-archive"
+	archiveVersion  = 1
+	archiveManifest = "manifest.json"
+	archiveMedia    = "media/"
+)
+
+var (
+	// ErrInvalidArchive is returned for an archive that is damaged or not one of ours.
+	ErrInvalidArchive = errors.New("invalid archive")
+	// ErrUnsupportedArchive is returned for an archive written by a newer version.
+	ErrUnsupportedArchive = errors.New("unsupported archive version")
+)
+
+// The records of the NDJSON files. Sub-categories are categories with a parent_id.
+type (
+	archiveCategory struct {
+		Id          int    `json:"id"`
+		Name        string `json:"name"`
+		Slug        string `json:"slug"`
+		ParentId    int    `json:"parent_id,omitempty"`
+		Position    int    `json:"position"`
+		Description string `json:"description"`
+		CoverImage  string `json:"cover_image"`
+		Version     int    `json:"version,omitempty"`
+	}
+	archiveUser struct {
+		Id           int    `json:"id"`
+		Username     string `json:"username"`
+		PasswordHash string `json:"password_hash"`
+		Role         string `json:"role"`
+		DisplayName  string `json:"display_name"`
+		Bio          string `json:"bio"`
+		AvatarUrl    string `json:"avatar_url"`
+	}
+	archivePost struct {
+		Id              int       `json:"id"`
+		Title           string    `json:"title"`
+		Slug            string    `json:"slug"`
+		EyeCatchingImg  string    `json:"eye_catching_img"`
+		Content         string    `json:"content"`
+		MetaDescription string    `json:"meta_description"`
+		IsPublic        bool      `json:"is_public"`
+		CreatedAt       time.Time `json:"created_at"`
+		UpdatedAt       time.Time `json:"updated_at"`
+		CategoryId      int       `json:"category_id,omitempty"`
+		AuthorId        int       `json:"author_id,omitempty"`
+		Version         int       `json:"version,omitempty"`
+	}
+)
+
+type IArchiveService interface {
+	Export(actor dto.UserModel, w io.Writer, mediaDir string) (manifestDto dto.ArchiveManifestModel, err error)
+	Import(actor dto.UserModel, r io.Reader, mediaDir string, replace bool) (manifestDto dto.ArchiveManifestModel, err error)
+}
+
+type ArchiveService struct {
+	repository.IArchiveRepository
+}
+
+func NewArchiveService(repo repository.IArchiveRepository) (archiveService IArchiveService) {
+	archiveService = &ArchiveService{repo}
+	return
+}
+
+// Export writes the whole blog and the files below mediaDir to w. The archive
+// holds password hashes, so only admins may take one.
+func (s *ArchiveService) Export(actor dto.UserModel, w io.Writer, mediaDir string) (manifestDto dto.ArchiveManifestModel, err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
+	archive, err := s.IArchiveRepository.Dump()
+	if err != nil {
+		return
+	}
+	mediaFiles, err := listMedia(mediaDir)
+	if err != nil {
+		return
+	}
+
+	subCategoryCount := 0
+	for _, category := range archive.Categories {
+		if category.ParentId != 0 {
+			subCategoryCount++
+		}
+	}
+	manifestDto = dto.ArchiveManifestModel{
+		Format:    archiveFormat,
+		Version:   archiveVersion,
+		CreatedAt: time.Now().UTC(),
+		Counts: map[string]int{
+			"categories":     len(archive.Categories) - subCategoryCount,
+			"sub_categories": subCategoryCount,
+			"users":          len(archive.Users),
+			"posts":          len(archive.Posts),
+			"media":          len(mediaFiles),
+		},
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifest, err := json.MarshalIndent(manifestDto, "", "\t")
+	if err != nil {
+		return
+	}
+	err = writeTarFile(tw, archiveManifest, manifest, manifestDto.CreatedAt)
+	if err != nil {
+		return
+	}
+	for _, file := range []struct {
+		name   string
+		encode func(encoder *json.Encoder) error
+	}{
+		{"categories.ndjson", func(encoder *json.Encoder) (err error) {
+			for _, category := range archive.Categories {
+				err = encoder.Encode(archiveCategory{category.Id, category.Name, category.Slug, category.ParentId, category.Position,
+					category.Description, category.CoverImage, category.Version})
+				if err != nil {
+					return
+				}
+			}
+			return
+		}},
+		{"users.ndjson", func(encoder *json.Encoder) (err error) {
+			for _, user := range archive.Users {
+				err = encoder.Encode(archiveUser{user.Id, user.Name, user.Password, string(user.Role), user.DisplayName, user.Bio, user.AvatarUrl})
+				if err != nil {
+					return
+				}
+			}
+			return
+		}},
+		{"posts.ndjson", func(encoder *json.Encoder) (err error) {
+			for _, post := range archive.Posts {
+				err = encoder.Encode(archivePost{post.Id, post.Title, post.Slug, post.EyeCatchingImg, post.Content, post.MetaDescription, post.IsPublic,
+					post.CreatedAt, post.UpdatedAt, post.CategoryId, post.AuthorId, post.Version})
+				if err != nil {
+					return
+				}
+			}
+			return
+		}},
+	} {
+		err = writeRecords(tw, file.name, file.encode, manifestDto.CreatedAt)
+		if err != nil {
+			return
+		}
+	}
+	for _, name := range mediaFiles {
+		err = writeMediaFile(tw, mediaDir, name)
+		if err != nil {
+			return
+		}
+	}
+	err = tw.Close()
+	if err != nil {
+		return
+	}
+	err = gz.Close()
+	return
+}
+
+// listMedia returns the slash separated paths of the regular files below mediaDir.
+// A missing directory holds no files.
+func listMedia(mediaDir string) (names []string, err error) {
+	err = filepath.WalkDir(mediaDir, func(name string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && name == mediaDir {
+			return nil
+		}
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(mediaDir, name)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	return
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) (err error) {
+	err = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: modTime})
+	if err != nil {
+		return
+	}
+	_, err = tw.Write(data)
+	return
+}
+
+// writeRecords writes an NDJSON file of the records encode encodes. A tar header
+// needs the size up front, so the records are encoded twice: once to count the
+// bytes and once into the entry, rather than held in memory as a whole.
+func writeRecords(tw *tar.Writer, name string, encode func(encoder *json.Encoder) error, modTime time.Time) (err error) {
+	var size byteCounter
+	err = encode(json.NewEncoder(&size))
+	if err != nil {
+		return
+	}
+	err = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644, Size: int64(size), ModTime: modTime})
+	if err != nil {
+		return
+	}
+	err = encode(json.NewEncoder(tw))
+	return
+}
+
+// byteCounter counts the bytes written to it.
+type byteCounter int64
+
+func (c *byteCounter) Write(data []byte) (n int, err error) {
+	*c += byteCounter(len(data))
+	return len(data), nil
+}
+
+func writeMediaFile(tw *tar.Writer, mediaDir string, name string) (err error) {
+	file, err := os.Open(filepath.Join(mediaDir, filepath.FromSlash(name)))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+	err = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: archiveMedia + name, Mode: 0o644, Size: info.Size(), ModTime: info.ModTime()})
+	if err != nil {
+		return
+	}
+	_, err = io.Copy(tw, file)
+	return
+}
+
+// Import restores an archive written by Export. The records are restored in one
+// transaction, merged by id into what is there or, with replace, replacing it.
+// Media files are written below mediaDir only once the records are in; existing
+// files of the same name are overwritten, others are kept.
+func (s *ArchiveService) Import(actor dto.UserModel, r io.Reader, mediaDir string, replace bool) (manifestDto dto.ArchiveManifestModel, err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+		return
+	}
+	defer gz.Close()
+
+	// media files are staged next to mediaDir, so moving them in is a rename
+	parent := filepath.Dir(filepath.Clean(mediaDir))
+	err = os.MkdirAll(parent, 0o755)
+	if err != nil {
+		return
+	}
+	staging, err := os.MkdirTemp(parent, ".import-")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(staging)
+
+	var archive entity.Archive
+	var mediaFiles []string
+	tr := tar.NewReader(gz)
+	for first := true; ; first = false {
+		var header *tar.Header
+		header, err = tr.Next()
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+			return
+		}
+		if first != (header.Name == archiveManifest) {
+			err = fmt.Errorf("%w: %s must come first", ErrInvalidArchive, archiveManifest)
+			return
+		}
+		switch {
+		case header.Name == archiveManifest:
+			manifestDto, err = readManifest(tr)
+		case header.Name == "categories.ndjson":
+			err = readRecords(tr, header.Name, func(decoder *json.Decoder) (err error) {
+				var record archiveCategory
+				err = decoder.Decode(&record)
+				archive.Categories = append(archive.Categories, entity.Category{Id: record.Id, Name: record.Name, Slug: record.Slug,
+					ParentId: record.ParentId, Position: record.Position, Description: record.Description, CoverImage: record.CoverImage, Version: record.Version})
+				return
+			})
+		case header.Name == "users.ndjson":
+			err = readRecords(tr, header.Name, func(decoder *json.Decoder) (err error) {
+				var record archiveUser
+				err = decoder.Decode(&record)
+				archive.Users = append(archive.Users, entity.User{Id: record.Id, Name: record.Username, Password: record.PasswordHash,
+					Role: entity.Role(record.Role), DisplayName: record.DisplayName, Bio: record.Bio, AvatarUrl: record.AvatarUrl})
+				return
+			})
+		case header.Name == "posts.ndjson":
+			err = readRecords(tr, header.Name, func(decoder *json.Decoder) (err error) {
+				var record archivePost
+				err = decoder.Decode(&record)
+				archive.Posts = append(archive.Posts, entity.Post{Id: record.Id, Title: record.Title, Slug: record.Slug,
+					EyeCatchingImg: record.EyeCatchingImg, Content: record.Content, MetaDescription: record.MetaDescription, IsPublic: record.IsPublic,
+					CreatedAt: record.CreatedAt, UpdatedAt: record.UpdatedAt, CategoryId: record.CategoryId, AuthorId: record.AuthorId, Version: record.Version})
+				return
+			})
+		case strings.HasPrefix(header.Name, archiveMedia) && header.Typeflag != tar.TypeDir:
+			var name string
+			name, err = stageMediaFile(tr, header, staging)
+			mediaFiles = append(mediaFiles, name)
+		}
+		if err != nil {
+			return
+		}
+	}
+	if manifestDto.Format == "" {
+		err = fmt.Errorf("%w: %s is missing", ErrInvalidArchive, archiveManifest)
+		return
+	}
+
+	err = s.IArchiveRepository.Restore(archive, replace)
+	if err != nil {
+		return
+	}
+	for _, name := range mediaFiles {
+		target := filepath.Join(mediaDir, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(target), 0o755)
+		if err != nil {
+			return
+		}
+		err = os.Rename(filepath.Join(staging, filepath.FromSlash(name)), target)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func readManifest(r io.Reader) (manifestDto dto.ArchiveManifestModel, err error) {
+	err = json.NewDecoder(r).Decode(&manifestDto)
+	switch {
+	case err != nil:
+		err = fmt.Errorf("%w: %s: %s", ErrInvalidArchive, archiveManifest, err)
+	case manifestDto.Format != archiveFormat:
+		err = fmt.Errorf("%w: format %q", ErrInvalidArchive, manifestDto.Format)
+	case manifestDto.Version < 1 || manifestDto.Version > archiveVersion:
+		err = fmt.Errorf("%w: %d, this version reads up to %d", ErrUnsupportedArchive, manifestDto.Version, archiveVersion)
+	}
+	return
+}
+
+// readRecords calls decode for each record of an NDJSON file.
+func readRecords(r io.Reader, name string, decode func(decoder *json.Decoder) error) (err error) {
+	decoder := json.NewDecoder(r)
+	for line := 1; decoder.More(); line++ {
+		err = decode(decoder)
+		if err != nil {
+			return fmt.Errorf("%w: %s record %d: %s", ErrInvalidArchive, name, line, err)
+		}
+	}
+	return
+}
+
+// stageMediaFile writes a media entry below staging and returns its path relative
+// to the media directory. Entries that would land outside of it are rejected.
+func stageMediaFile(r io.Reader, header *tar.Header, staging string) (name string, err error) {
+	name = path.Clean(strings.TrimPrefix(header.Name, archiveMedia))
+	if header.Typeflag != tar.TypeReg || name == "." || name == ".." || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+		err = fmt.Errorf("%w: unexpected entry %s", ErrInvalidArchive, header.Name)
+		return
+	}
+	target := filepath.Join(staging, filepath.FromSlash(name))
+	err = os.MkdirAll(filepath.Dir(target), 0o755)
+	if err != nil {
+		return
+	}
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return
+	}
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"archive/tar"
+	"backend/app/domain/entity"
+	mocks "backend/mocks/repository"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestArchiveService(t *testing.T) {
+	createdAt := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	updatedAt := time.Date(2022, 5, 2, 10, 0, 0, 0, time.UTC)
+	archive := entity.Archive{
+		Categories: []entity.Category{
+			{Id: 1, Name: "testCategory1", Slug: "test-category-1", Version: 1},
+			{Id: 2, Name: "testSubCategory1", Slug: "test-sub-category-1", ParentId: 1, Position: 1, Version: 4},
+		},
+		Users: []entity.User{
+			{Id: 3, Name: "testuser1", Password: "$2a$10$hash", Role: entity.RoleAuthor},
+		},
+		Posts: []entity.Post{
+			{Id: 4, Title: "testTitle1", Slug: "test-slug-1", Content: "testContent1", IsPublic: true, CreatedAt: createdAt, UpdatedAt: updatedAt, CategoryId: 2, AuthorId: 3, Version: 2},
+		},
+	}
+
+	writeMedia := func(t *testing.T, dir string) {
+		if err := os.MkdirAll(filepath.Join(dir, "2022"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "2022", "test.jpeg"), []byte("testImage1"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run(
+		"round trip",
+		func(t *testing.T) {
+			sourceMedia := filepath.Join(t.TempDir(), "media")
+			writeMedia(t, sourceMedia)
+			r := new(mocks.IArchiveRepository)
+			r.On("Dump").Return(archive, nil).Once()
+			r.On("Restore", archive, true).Return(nil).Once()
+			s := NewArchiveService(r)
+
+			var buf bytes.Buffer
+			manifestDto, err := s.Export(admin, &buf, sourceMedia)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]int{"categories": 1, "sub_categories": 1, "users": 1, "posts": 1, "media": 1}, manifestDto.Counts)
+
+			targetMedia := filepath.Join(t.TempDir(), "media")
+			ret, err := s.Import(admin, &buf, targetMedia, true)
+			assert.NoError(t, err)
+			assert.Equal(t, manifestDto.Counts, ret.Counts)
+			data, err := os.ReadFile(filepath.Join(targetMedia, "2022", "test.jpeg"))
+			assert.NoError(t, err)
+			assert.Equal(t, "testImage1", string(data))
+			r.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"missing media directory",
+		func(t *testing.T) {
+			r := new(mocks.IArchiveRepository)
+			r.On("Dump").Return(entity.Archive{}, nil).Once()
+			s := NewArchiveService(r)
+
+			manifestDto, err := s.Export(admin, new(bytes.Buffer), filepath.Join(t.TempDir(), "media"))
+
+			assert.NoError(t, err)
+			assert.Equal(t, 0, manifestDto.Counts["media"])
+		},
+	)
+
+	writeArchive := func(entries map[string]string, order []string) *bytes.Buffer {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for _, name := range order {
+			tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644, Size: int64(len(entries[name]))})
+			tw.Write([]byte(entries[name]))
+		}
+		tw.Close()
+		gz.Close()
+		return &buf
+	}
+	manifest := `{"format": "golang-blog-archive", "version": 1}`
+
+	t.Run(
+		"rejects invalid archives",
+		func(t *testing.T) {
+			r := new(mocks.IArchiveRepository)
+			s := NewArchiveService(r)
+			media := filepath.Join(t.TempDir(), "media")
+
+			_, err := s.Import(admin, bytes.NewBufferString("not gzip"), media, false)
+			assert.ErrorIs(t, err, ErrInvalidArchive)
+
+			_, err = s.Import(admin, writeArchive(map[string]string{"manifest.json": `{"format": "golang-blog-archive", "version": 2}`}, []string{"manifest.json"}), media, false)
+			assert.ErrorIs(t, err, ErrUnsupportedArchive)
+
+			_, err = s.Import(admin, writeArchive(map[string]string{"posts.ndjson": "", "manifest.json": manifest}, []string{"posts.ndjson", "manifest.json"}), media, false)
+			assert.ErrorIs(t, err, ErrInvalidArchive)
+
+			_, err = s.Import(admin, writeArchive(map[string]string{"manifest.json": manifest, "posts.ndjson": "{\"id\": 1}\n{"}, []string{"manifest.json", "posts.ndjson"}), media, false)
+			assert.ErrorContains(t, err, "posts.ndjson record 2")
+
+			_, err = s.Import(admin, writeArchive(map[string]string{"manifest.json": manifest, "media/../../escape": "x"}, []string{"manifest.json", "media/../../escape"}), media, false)
+			assert.ErrorIs(t, err, ErrInvalidArchive)
+			assert.NoFileExists(t, filepath.Join(filepath.Dir(filepath.Dir(media)), "escape"))
+
+			r.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+		},
+	)
+
+	t.Run(
+		"keeps media when the restore fails",
+		func(t *testing.T) {
+			r := new(mocks.IArchiveRepository)
+			r.On("Restore", entity.Archive{}, false).Return(assert.AnError).Once()
+			s := NewArchiveService(r)
+			media := filepath.Join(t.TempDir(), "media")
+
+			_, err := s.Import(admin, writeArchive(map[string]string{"manifest.json": manifest, "media/test.jpeg": "x"}, []string{"manifest.json", "media/test.jpeg"}), media, false)
+
+			assert.ErrorIs(t, err, assert.AnError)
+			assert.NoFileExists(t, filepath.Join(media, "test.jpeg"))
+		},
+	)
+
+	t.Run(
+		"admins only",
+		func(t *testing.T) {
+			s := NewArchiveService(new(mocks.IArchiveRepository))
+
+			_, err := s.Export(editor, new(bytes.Buffer), "media")
+			assert.ErrorIs(t, err, ErrForbidden)
+			_, err = s.Import(editor, new(bytes.Buffer), "media", true)
+			assert.ErrorIs(t, err, ErrForbidden)
+		},
+	)
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	"errors"
+)
+
+var (
+	// ErrUnauthenticated is returned when a write is attempted without a valid token.
+	ErrUnauthenticated = errors.New("authentication required")
+	// ErrForbidden is returned when the acting user's role lacks the permission.
+	ErrForbidden = errors.New("you don't have permission")
+	// ErrInvalidRole is returned when a user is given a role outside the permission matrix.
+	ErrInvalidRole = errors.New("unknown role")
+)
+
+// authorize checks that the acting user holds the permission.
+// An actor without a role is an anonymous request; an actor with scopes came
+// with an API key and also needs a scope that allows the permission.
+func authorize(actor dto.UserModel, permission entity.Permission) (err error) {
+	if actor.Role == "" {
+		return ErrUnauthenticated
+	}
+	if !entity.Role(actor.Role).Can(permission) {
+		return ErrForbidden
+	}
+	if actor.Scopes != nil && !scopesAllow(actor.Scopes, permission) {
+		return ErrForbidden
+	}
+	return
+}
+
+func scopesAllow(scopes []string, permission entity.Permission) bool {
+	for _, scope := range scopes {
+		if entity.Scope(scope).Allows(permission) {
+			return true
+		}
+	}
+	return false
+}
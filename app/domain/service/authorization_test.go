@@ -0,0 +1,268 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	mocks "backend/mocks/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	anonymous = dto.UserModel{}
+	admin     = dto.NewUserModel(1, "testadmin", string(entity.RoleAdmin))
+	editor    = dto.NewUserModel(2, "testeditor", string(entity.RoleEditor))
+	author    = dto.NewUserModel(3, "testauthor", string(entity.RoleAuthor))
+	viewer    = dto.NewUserModel(4, "testviewer", string(entity.RoleViewer))
+)
+
+// otherAuthorId owns posts that none of the test actors wrote.
+const otherAuthorId = 99
+
+// permissionCase is one row of the matrix: the error expected for each actor.
+type permissionCase struct {
+	name   string
+	call   func(actor dto.UserModel) error
+	expect map[string]error
+}
+
+func runPermissionCases(t *testing.T, cases []permissionCase) {
+	actors := []dto.UserModel{anonymous, admin, editor, author, viewer}
+	for _, c := range cases {
+		for _, actor := range actors {
+			want := c.expect[actor.Role]
+			if actor.Role == "" {
+				want = ErrUnauthenticated
+			}
+			t.Run(c.name+"/"+actor.Name, func(t *testing.T) {
+				err := c.call(actor)
+				if want == nil {
+					assert.NoError(t, err)
+				} else {
+					assert.ErrorIs(t, err, want)
+				}
+			})
+		}
+	}
+}
+
+func newPermissivePostRepository(stored entity.Post) (r *mocks.IPostRepository) {
+	r = new(mocks.IPostRepository)
+	r.On("GetPostById", mock.Anything).Return(stored, nil).Maybe()
+	r.On("Create", mock.Anything).Return(nil).Maybe()
+	r.On("Update", mock.Anything).Return(nil).Maybe()
+	r.On("Delete", mock.Anything).Return(nil).Maybe()
+	return
+}
+
+func TestAuthorization_Posts(t *testing.T) {
+	everyWriter := map[string]error{"admin": nil, "editor": nil, "author": nil, "viewer": ErrForbidden}
+	publishers := map[string]error{"admin": nil, "editor": nil, "author": ErrForbidden, "viewer": ErrForbidden}
+
+	runPermissionCases(t, []permissionCase{
+		{
+			name: "create draft",
+			call: func(actor dto.UserModel) error {
+				s := NewPostService(newPermissivePostRepository(entity.Post{}))
+				return s.Create(actor, dto.PostModel{Title: "draft", Slug: "draft", CategoryId: 1})
+			},
+			expect: everyWriter,
+		},
+		{
+			name: "create public",
+			call: func(actor dto.UserModel) error {
+				s := NewPostService(newPermissivePostRepository(entity.Post{}))
+				return s.Create(actor, dto.PostModel{Title: "public", Slug: "public", CategoryId: 1, IsPublic: true})
+			},
+			expect: publishers,
+		},
+		{
+			name: "edit own",
+			call: func(actor dto.UserModel) error {
+				s := NewPostService(newPermissivePostRepository(entity.Post{Id: 1, AuthorId: actor.Id}))
+				return s.Update(actor, dto.PostModel{Id: 1, Title: "edited", Slug: "edited", CategoryId: 1})
+			},
+			expect: everyWriter,
+		},
+		{
+			name: "edit other's",
+			call: func(actor dto.UserModel) error {
+				s := NewPostService(newPermissivePostRepository(entity.Post{Id: 1, AuthorId: otherAuthorId}))
+				return s.Update(actor, dto.PostModel{Id: 1, Title: "edited", Slug: "edited", CategoryId: 1})
+			},
+			expect: publishers,
+		},
+		{
+			name: "publish own",
+			call: func(actor dto.UserModel) error {
+				s := NewPostService(newPermissivePostRepository(entity.Post{Id: 1, AuthorId: actor.Id}))
+				return s.Update(actor, dto.PostModel{Id: 1, Title: "published", Slug: "published", CategoryId: 1, IsPublic: true})
+			},
+			expect: publishers,
+		},
+		{
+			name: "delete own",
+			call: func(actor dto.UserModel) error {
+				s := NewPostService(newPermissivePostRepository(entity.Post{Id: 1, AuthorId: actor.Id}))
+				return s.Delete(actor, dto.PostModel{Id: 1})
+			},
+			expect: everyWriter,
+		},
+		{
+			name: "delete other's",
+			call: func(actor dto.UserModel) error {
+				s := NewPostService(newPermissivePostRepository(entity.Post{Id: 1, AuthorId: otherAuthorId}))
+				return s.Delete(actor, dto.PostModel{Id: 1})
+			},
+			expect: publishers,
+		},
+	})
+}
+
+func TestAuthorization_Categories(t *testing.T) {
+	managers := map[string]error{"admin": nil, "editor": nil, "author": ErrForbidden, "viewer": ErrForbidden}
+
+	newCategoryService := func() ICategoryService {
+		r := new(mocks.ICategoryRepository)
+		r.On("Create", mock.Anything).Return(nil).Maybe()
+		r.On("Update", mock.Anything).Return(nil).Maybe()
+		r.On("Delete", mock.Anything).Return(nil).Maybe()
+		r.On("Reorder", mock.Anything).Return(nil).Maybe()
+		return NewCategoryService(r)
+	}
+	newSubCategoryService := func() ISubCategoryService {
+		r := new(mocks.ISubCategoryRepository)
+		r.On("Create", mock.Anything).Return(nil).Maybe()
+		r.On("GetAncestorIds", mock.Anything).Return([]int{1}, nil).Maybe()
+		r.On("Update", mock.Anything).Return(nil).Maybe()
+		r.On("Delete", mock.Anything).Return(nil).Maybe()
+		return NewSubCategoryService(r)
+	}
+
+	runPermissionCases(t, []permissionCase{
+		{
+			name: "create category",
+			call: func(actor dto.UserModel) error {
+				return newCategoryService().Create(actor, dto.NewCategoryModel(0, "testCategory1", "test-category-1", 0))
+			},
+			expect: managers,
+		},
+		{
+			name: "update category",
+			call: func(actor dto.UserModel) error {
+				return newCategoryService().Update(actor, dto.NewCategoryModel(1, "testCategory1", "test-category-1", 0))
+			},
+			expect: managers,
+		},
+		{
+			name: "delete category",
+			call: func(actor dto.UserModel) error {
+				return newCategoryService().Delete(actor, dto.NewCategoryModel(1, "testCategory1", "test-category-1", 0))
+			},
+			expect: managers,
+		},
+		{
+			name: "reorder categories",
+			call: func(actor dto.UserModel) error {
+				return newCategoryService().Reorder(actor, dto.CategoryOrderModel{Ids: []int{2, 1}})
+			},
+			expect: managers,
+		},
+		{
+			name: "create sub-category",
+			call: func(actor dto.UserModel) error {
+				return newSubCategoryService().Create(actor, dto.SubCategoryModel{Name: "testSubCategory1", Slug: "test-sub-category-1", ParentCategoryId: 1})
+			},
+			expect: managers,
+		},
+		{
+			name: "update sub-category",
+			call: func(actor dto.UserModel) error {
+				return newSubCategoryService().Update(actor, dto.SubCategoryModel{Id: 2, Name: "testSubCategory1", Slug: "test-sub-category-1", ParentCategoryId: 1})
+			},
+			expect: managers,
+		},
+		{
+			name: "delete sub-category",
+			call: func(actor dto.UserModel) error {
+				return newSubCategoryService().Delete(actor, dto.SubCategoryModel{Id: 2})
+			},
+			expect: managers,
+		},
+	})
+}
+
+func TestAuthorization_Users(t *testing.T) {
+	admins := map[string]error{"admin": nil, "editor": ErrForbidden, "author": ErrForbidden, "viewer": ErrForbidden}
+
+	newUserService := func() IUserService {
+		r := new(mocks.IUserRepository)
+		r.On("GetAll").Return([]entity.User{}, nil).Maybe()
+		r.On("GetById", mock.Anything).Return(entity.NewUser(5, "testuser5", "", entity.RoleViewer), nil).Maybe()
+		r.On("Create", mock.Anything).Return(nil).Maybe()
+		r.On("Update", mock.Anything).Return(nil).Maybe()
+		r.On("Delete", mock.Anything).Return(nil).Maybe()
+		lr := new(mocks.ILoginAttemptRepository)
+		lr.On("Reset", mock.Anything).Return(nil).Maybe()
+		return NewUserService(r, new(mocks.IRefreshTokenRepository), lr, new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+	}
+
+	runPermissionCases(t, []permissionCase{
+		{
+			name: "list users",
+			call: func(actor dto.UserModel) (err error) {
+				_, err = newUserService().GetAll(actor)
+				return
+			},
+			expect: admins,
+		},
+		{
+			name: "create user",
+			call: func(actor dto.UserModel) error {
+				return newUserService().Create(actor, dto.UserInputModel{Name: "testuser5", Password: "testpass5", Role: "author"})
+			},
+			expect: admins,
+		},
+		{
+			name: "change role",
+			call: func(actor dto.UserModel) error {
+				return newUserService().ChangeRole(actor, 5, "editor")
+			},
+			expect: admins,
+		},
+		{
+			name: "delete user",
+			call: func(actor dto.UserModel) error {
+				return newUserService().Delete(actor, dto.NewUserModel(5, "testuser5", "viewer"))
+			},
+			expect: admins,
+		},
+		{
+			name: "unlock user",
+			call: func(actor dto.UserModel) error {
+				return newUserService().UnlockUser(actor, "testuser5")
+			},
+			expect: admins,
+		},
+		{
+			name: "unlock address",
+			call: func(actor dto.UserModel) error {
+				return newUserService().UnlockAddress(actor, "192.0.2.5")
+			},
+			expect: admins,
+		},
+	})
+}
+
+func TestRole_Can(t *testing.T) {
+	assert.True(t, entity.RoleAdmin.Can(entity.PermissionManageUsers))
+	assert.False(t, entity.RoleEditor.Can(entity.PermissionManageUsers))
+	assert.True(t, entity.RoleEditor.Can(entity.PermissionPublishPost))
+	assert.False(t, entity.RoleAuthor.Can(entity.PermissionPublishPost))
+	assert.True(t, entity.RoleAuthor.Can(entity.PermissionEditOwnPost))
+	assert.False(t, entity.RoleViewer.Can(entity.PermissionCreatePost))
+	assert.False(t, entity.Role("owner").IsValid())
+}
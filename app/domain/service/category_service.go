@@ -4,14 +4,31 @@ import (
 	"backend/app/common/dto"
 	"backend/app/domain/entity"
 	"backend/app/domain/repository"
+	"errors"
+)
+
+var (
+	// ErrCategoryCycle is returned when a category would become its own ancestor.
+	ErrCategoryCycle = errors.New("category cannot be moved under itself or one of its descendants")
+	// ErrInvalidCategoryOrder is returned when a reorder request is empty or lists a category twice.
+	ErrInvalidCategoryOrder = errors.New("category order must list each category exactly once")
+	// ErrMixedParents is returned when a reorder request lists categories of different parents.
+	ErrMixedParents = repository.ErrMixedParents
+	// ErrIncompleteOrder is returned when a reorder request leaves out some children of the parent.
+	ErrIncompleteOrder = repository.ErrIncompleteOrder
 )
 
 type ICategoryService interface {
 	GetAll() (categories []dto.CategoryModel, err error)
+	GetTree() (categories []dto.CategoryModel, err error)
+	GetAllWithCounts() (categories []dto.CategoryModel, err error)
 	GetBySlug(slug string) (category dto.CategoryModel, err error)
-	Create(categoryDto dto.CategoryModel) (err error)
-	Update(dto.CategoryModel) (err error)
-	Delete(dto.CategoryModel) (err error)
+	GetDetailBySlug(slug string) (category dto.CategoryModel, err error)
+	GetBreadcrumbs(slug string) (categories []dto.CategoryModel, err error)
+	Create(actor dto.UserModel, categoryDto dto.CategoryModel) (err error)
+	Update(dto.UserModel, dto.CategoryModel) (err error)
+	Delete(dto.UserModel, dto.CategoryModel) (err error)
+	Reorder(dto.UserModel, dto.CategoryOrderModel) (err error)
 }
 
 type CategoryService struct {
@@ -24,31 +41,89 @@ func NewCategoryService(repo repository.ICategoryRepository) (categoryService IC
 }
 
 func (s *CategoryService) convertToDtoFromEntity(category entity.Category) (categoryDto dto.CategoryModel) {
-	categoryDto = dto.NewCategoryModel(category.Id, category.Name, category.Slug)
+	categoryDto = dto.CategoryModel{
+		Id:          category.Id,
+		Name:        category.Name,
+		Slug:        category.Slug,
+		ParentId:    category.ParentId,
+		Position:    category.Position,
+		Description: category.Description,
+		CoverImage:  category.CoverImage,
+		Children:    s.convertToDtosFromEntities(category.Children),
+		Stats:       convertToStatsDtoFromEntity(category.Stats),
+		Version:     category.Version,
+	}
 	return
 }
 
 func (s *CategoryService) convertToDtosFromEntities(categories []entity.Category) (categoryDtos []dto.CategoryModel) {
 	for _, category := range categories {
-		categoryDto := dto.NewCategoryModel(category.Id, category.Name, category.Slug)
+		categoryDto := s.convertToDtoFromEntity(category)
 		categoryDtos = append(categoryDtos, categoryDto)
 	}
 	return
 }
 
 func (s *CategoryService) convertToEntityFromDto(categoryDto dto.CategoryModel) (category entity.Category) {
-	category = entity.NewCategory(categoryDto.Id, categoryDto.Name, categoryDto.Slug)
+	category = entity.Category{
+		Id:          categoryDto.Id,
+		Name:        categoryDto.Name,
+		Slug:        categoryDto.Slug,
+		ParentId:    categoryDto.ParentId,
+		Position:    categoryDto.Position,
+		Description: categoryDto.Description,
+		CoverImage:  categoryDto.CoverImage,
+		Version:     categoryDto.Version,
+	}
 	return
 }
 
 func (s *CategoryService) convertToEntitiesFromDtos(categoryDtos []dto.CategoryModel) (categories []entity.Category) {
 	for _, categoryDto := range categoryDtos {
-		category := entity.NewCategory(categoryDto.Id, categoryDto.Name, categoryDto.Slug)
+		category := s.convertToEntityFromDto(categoryDto)
 		categories = append(categories, category)
 	}
 	return
 }
 
+func convertToStatsDtoFromEntity(stats *entity.CategoryStats) (statsDto *dto.CategoryStatsModel) {
+	if stats == nil {
+		return
+	}
+	statsDto = &dto.CategoryStatsModel{PostCount: stats.PostCount}
+	if !stats.LatestPostAt.IsZero() {
+		latestPostAt := stats.LatestPostAt
+		statsDto.LatestPostAt = &latestPostAt
+	}
+	return
+}
+
+// buildTree nests the flat list of nodes under their parents and returns the roots.
+// Nodes whose parent is missing from the list are treated as roots.
+func (s *CategoryService) buildTree(nodes []entity.Category) (roots []entity.Category) {
+	childrenOf := make(map[int][]entity.Category)
+	exists := make(map[int]bool)
+	for _, node := range nodes {
+		exists[node.Id] = true
+	}
+	for _, node := range nodes {
+		if node.ParentId != 0 && exists[node.ParentId] {
+			childrenOf[node.ParentId] = append(childrenOf[node.ParentId], node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	var attach func(categories []entity.Category)
+	attach = func(categories []entity.Category) {
+		for i := range categories {
+			categories[i].Children = childrenOf[categories[i].Id]
+			attach(categories[i].Children)
+		}
+	}
+	attach(roots)
+	return
+}
+
 func (s *CategoryService) GetBySlug(slug string) (categoryDto dto.CategoryModel, err error) {
 	category, err := s.ICategoryRepository.GetBySlug(slug)
 	if err != nil {
@@ -67,20 +142,148 @@ func (s *CategoryService) GetAll() (categoryDtos []dto.CategoryModel, err error)
 	return
 }
 
-func (s *CategoryService) Create(categoryDto dto.CategoryModel) (err error) {
+// GetAllWithCounts returns the root categories together with the public post
+// statistics of each subtree.
+func (s *CategoryService) GetAllWithCounts() (categoryDtos []dto.CategoryModel, err error) {
+	categories, err := s.ICategoryRepository.GetAll()
+	if err != nil {
+		return
+	}
+	var ids []int
+	for _, category := range categories {
+		ids = append(ids, category.Id)
+	}
+	stats, err := s.ICategoryRepository.GetStats(ids)
+	if err != nil {
+		return
+	}
+	for i := range categories {
+		stat := stats[categories[i].Id]
+		categories[i].Stats = &stat
+	}
+	categoryDtos = s.convertToDtosFromEntities(categories)
+	return
+}
+
+// GetDetailBySlug returns the category with its public post statistics and direct children.
+func (s *CategoryService) GetDetailBySlug(slug string) (categoryDto dto.CategoryModel, err error) {
+	category, err := s.ICategoryRepository.GetBySlug(slug)
+	if err != nil {
+		return
+	}
+	stats, err := s.ICategoryRepository.GetStats([]int{category.Id})
+	if err != nil {
+		return
+	}
+	stat := stats[category.Id]
+	category.Stats = &stat
+	category.Children, err = s.ICategoryRepository.GetChildren(category.Id)
+	if err != nil {
+		return
+	}
+	categoryDto = s.convertToDtoFromEntity(category)
+	return
+}
+
+func (s *CategoryService) GetTree() (categoryDtos []dto.CategoryModel, err error) {
+	nodes, err := s.ICategoryRepository.GetTree()
+	if err != nil {
+		return
+	}
+	categoryDtos = s.convertToDtosFromEntities(s.buildTree(nodes))
+	return
+}
+
+func (s *CategoryService) GetBreadcrumbs(slug string) (categoryDtos []dto.CategoryModel, err error) {
+	category, err := s.ICategoryRepository.GetBySlug(slug)
+	if err != nil {
+		return
+	}
+	ancestors, err := s.ICategoryRepository.GetAncestors(category.Id)
+	if err != nil {
+		return
+	}
+	categoryDtos = s.convertToDtosFromEntities(ancestors)
+	return
+}
+
+func (s *CategoryService) Create(actor dto.UserModel, categoryDto dto.CategoryModel) (err error) {
+	err = authorize(actor, entity.PermissionManageCategories)
+	if err != nil {
+		return
+	}
 	category := s.convertToEntityFromDto(categoryDto)
+	err = validateCategory(category.Name, category.Slug)
+	if err != nil {
+		return
+	}
 	err = s.ICategoryRepository.Create(category)
 	return
 }
 
-func (s *CategoryService) Update(categoryDto dto.CategoryModel) (err error) {
+func (s *CategoryService) Update(actor dto.UserModel, categoryDto dto.CategoryModel) (err error) {
+	err = authorize(actor, entity.PermissionManageCategories)
+	if err != nil {
+		return
+	}
 	category := s.convertToEntityFromDto(categoryDto)
+	err = validateCategory(category.Name, category.Slug)
+	if err != nil {
+		return
+	}
+	err = s.checkParent(category)
+	if err != nil {
+		return
+	}
 	err = s.ICategoryRepository.Update(category)
 	return
 }
 
-func (s *CategoryService) Delete(categoryDto dto.CategoryModel) (err error) {
+// checkParent rejects a reparenting that would make the category its own ancestor.
+func (s *CategoryService) checkParent(category entity.Category) (err error) {
+	if category.ParentId == 0 {
+		return
+	}
+	if category.ParentId == category.Id {
+		return ErrCategoryCycle
+	}
+	ancestors, err := s.ICategoryRepository.GetAncestors(category.ParentId)
+	if err != nil {
+		return
+	}
+	for _, ancestor := range ancestors {
+		if ancestor.Id == category.Id {
+			return ErrCategoryCycle
+		}
+	}
+	return
+}
+
+func (s *CategoryService) Delete(actor dto.UserModel, categoryDto dto.CategoryModel) (err error) {
+	err = authorize(actor, entity.PermissionManageCategories)
+	if err != nil {
+		return
+	}
 	category := s.convertToEntityFromDto(categoryDto)
 	err = s.ICategoryRepository.Delete(category)
 	return
 }
+
+func (s *CategoryService) Reorder(actor dto.UserModel, orderDto dto.CategoryOrderModel) (err error) {
+	err = authorize(actor, entity.PermissionManageCategories)
+	if err != nil {
+		return
+	}
+	if len(orderDto.Ids) == 0 {
+		return ErrInvalidCategoryOrder
+	}
+	seen := make(map[int]bool)
+	for _, id := range orderDto.Ids {
+		if seen[id] {
+			return ErrInvalidCategoryOrder
+		}
+		seen[id] = true
+	}
+	err = s.ICategoryRepository.Reorder(orderDto.Ids)
+	return
+}
@@ -5,6 +5,7 @@ import (
 	"backend/app/domain/entity"
 	mocks "backend/mocks/repository"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -78,13 +79,13 @@ func TestCategoryService_Create(t *testing.T) {
 
 	s := NewCategoryService(r)
 
-	assert.NoError(t, s.Create(categoryDto))
+	assert.NoError(t, s.Create(editor, categoryDto))
 	r.AssertExpectations(t)
 }
 
 func TestCategoryService_Update(t *testing.T) {
-	category := entity.NewCategory(1, "testCategory1", "test-category-1")
-	categoryDto := dto.NewCategoryModel(1, "testCategory1", "test-category-1")
+	category := entity.NewCategory(1, "testCategory1", "test-category-1", 0)
+	categoryDto := dto.NewCategoryModel(1, "testCategory1", "test-category-1", 0)
 
 	r := new(mocks.ICategoryRepository)
 
@@ -92,13 +93,13 @@ func TestCategoryService_Update(t *testing.T) {
 
 	s := NewCategoryService(r)
 
-	assert.NoError(t, s.Update(categoryDto))
+	assert.NoError(t, s.Update(editor, categoryDto))
 	r.AssertExpectations(t)
 }
 
 func TestCategoryService_Delete(t *testing.T) {
-	category := entity.NewCategory(1, "testCategory1", "test-category-1")
-	categoryDto := dto.NewCategoryModel(1, "testCategory1", "test-category-1")
+	category := entity.NewCategory(1, "testCategory1", "test-category-1", 0)
+	categoryDto := dto.NewCategoryModel(1, "testCategory1", "test-category-1", 0)
 
 	r := new(mocks.ICategoryRepository)
 
@@ -106,6 +107,205 @@ func TestCategoryService_Delete(t *testing.T) {
 
 	s := NewCategoryService(r)
 
-	assert.NoError(t, s.Delete(categoryDto))
+	assert.NoError(t, s.Delete(editor, categoryDto))
+	r.AssertExpectations(t)
+}
+
+func TestCategoryService_GetTree(t *testing.T) {
+	nodes := []entity.Category{
+		entity.NewCategory(1, "testCategory1", "test-category-1", 0),
+		entity.NewCategory(2, "testSubCategory1", "test-sub-category-1", 1),
+		entity.NewCategory(3, "testSubSubCategory1", "test-sub-sub-category-1", 2),
+		entity.NewCategory(4, "testCategory2", "test-category-2", 0),
+	}
+
+	r := new(mocks.ICategoryRepository)
+
+	r.On("GetTree").Return(nodes, nil)
+
+	s := NewCategoryService(r)
+
+	ret, err := s.GetTree()
+
+	assert.NoError(t, err)
+	assert.Len(t, ret, 2)
+	assert.Equal(t, ret[0].Slug, "test-category-1")
+	assert.Equal(t, ret[1].Slug, "test-category-2")
+	assert.Len(t, ret[0].Children, 1)
+	assert.Equal(t, ret[0].Children[0].Slug, "test-sub-category-1")
+	assert.Len(t, ret[0].Children[0].Children, 1)
+	assert.Equal(t, ret[0].Children[0].Children[0].Slug, "test-sub-sub-category-1")
+	assert.Empty(t, ret[1].Children)
+	r.AssertExpectations(t)
+}
+
+func TestCategoryService_GetBreadcrumbs(t *testing.T) {
+	category := entity.NewCategory(2, "testSubCategory1", "test-sub-category-1", 1)
+	ancestors := []entity.Category{
+		entity.NewCategory(1, "testCategory1", "test-category-1", 0),
+		category,
+	}
+
+	r := new(mocks.ICategoryRepository)
+
+	r.On("GetBySlug", category.Slug).Return(category, nil)
+	r.On("GetAncestors", category.Id).Return(ancestors, nil)
+
+	s := NewCategoryService(r)
+
+	ret, err := s.GetBreadcrumbs(category.Slug)
+
+	assert.NoError(t, err)
+	for i, r := range ret {
+		assert.Equal(t, r.Id, ancestors[i].Id)
+		assert.Equal(t, r.Slug, ancestors[i].Slug)
+	}
+	r.AssertExpectations(t)
+}
+
+func TestCategoryService_UpdateParent(t *testing.T) {
+	t.Run(
+		"move under another branch",
+		func(t *testing.T) {
+			category := entity.NewCategory(2, "testSubCategory1", "test-sub-category-1", 3)
+			categoryDto := dto.NewCategoryModel(2, "testSubCategory1", "test-sub-category-1", 3)
+
+			r := new(mocks.ICategoryRepository)
+
+			r.On("GetAncestors", 3).Return([]entity.Category{
+				entity.NewCategory(4, "testCategory2", "test-category-2", 0),
+				entity.NewCategory(3, "testSubCategory2", "test-sub-category-2", 4),
+			}, nil)
+			r.On("Update", category).Return(nil)
+
+			s := NewCategoryService(r)
+
+			assert.NoError(t, s.Update(editor, categoryDto))
+			r.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"move under itself",
+		func(t *testing.T) {
+			categoryDto := dto.NewCategoryModel(1, "testCategory1", "test-category-1", 1)
+
+			r := new(mocks.ICategoryRepository)
+
+			s := NewCategoryService(r)
+
+			assert.ErrorIs(t, s.Update(editor, categoryDto), ErrCategoryCycle)
+			r.AssertNotCalled(t, "Update", entity.NewCategory(1, "testCategory1", "test-category-1", 1))
+		},
+	)
+
+	t.Run(
+		"move under a descendant",
+		func(t *testing.T) {
+			categoryDto := dto.NewCategoryModel(1, "testCategory1", "test-category-1", 3)
+
+			r := new(mocks.ICategoryRepository)
+
+			r.On("GetAncestors", 3).Return([]entity.Category{
+				entity.NewCategory(1, "testCategory1", "test-category-1", 0),
+				entity.NewCategory(2, "testSubCategory1", "test-sub-category-1", 1),
+				entity.NewCategory(3, "testSubSubCategory1", "test-sub-sub-category-1", 2),
+			}, nil)
+
+			s := NewCategoryService(r)
+
+			assert.ErrorIs(t, s.Update(editor, categoryDto), ErrCategoryCycle)
+			r.AssertExpectations(t)
+		},
+	)
+}
+
+func TestCategoryService_Reorder(t *testing.T) {
+	t.Run(
+		"valid order",
+		func(t *testing.T) {
+			r := new(mocks.ICategoryRepository)
+
+			r.On("Reorder", []int{3, 1, 2}).Return(nil)
+
+			s := NewCategoryService(r)
+
+			assert.NoError(t, s.Reorder(editor, dto.CategoryOrderModel{Ids: []int{3, 1, 2}}))
+			r.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"duplicated id",
+		func(t *testing.T) {
+			r := new(mocks.ICategoryRepository)
+
+			s := NewCategoryService(r)
+
+			assert.ErrorIs(t, s.Reorder(editor, dto.CategoryOrderModel{Ids: []int{3, 1, 3}}), ErrInvalidCategoryOrder)
+			r.AssertNotCalled(t, "Reorder", []int{3, 1, 3})
+		},
+	)
+
+	t.Run(
+		"empty order",
+		func(t *testing.T) {
+			r := new(mocks.ICategoryRepository)
+
+			s := NewCategoryService(r)
+
+			assert.ErrorIs(t, s.Reorder(editor, dto.CategoryOrderModel{}), ErrInvalidCategoryOrder)
+		},
+	)
+}
+
+func TestCategoryService_GetAllWithCounts(t *testing.T) {
+	latestPostAt, _ := time.Parse("2006-01-02 15:04:05.999999-07", "2006-01-02 15:04:05.999999-07")
+
+	categories := []entity.Category{
+		entity.NewCategory(1, "testCategory1", "test-category-1", 0),
+		entity.NewCategory(2, "testCategory2", "test-category-2", 0),
+	}
+
+	r := new(mocks.ICategoryRepository)
+
+	r.On("GetAll").Return(categories, nil)
+	r.On("GetStats", []int{1, 2}).Return(map[int]entity.CategoryStats{
+		1: {PostCount: 5, LatestPostAt: latestPostAt},
+	}, nil)
+
+	s := NewCategoryService(r)
+
+	ret, err := s.GetAllWithCounts()
+
+	assert.NoError(t, err)
+	assert.Equal(t, ret[0].Stats.PostCount, 5)
+	assert.Equal(t, *ret[0].Stats.LatestPostAt, latestPostAt)
+	assert.Equal(t, ret[1].Stats.PostCount, 0)
+	assert.Nil(t, ret[1].Stats.LatestPostAt)
+	r.AssertExpectations(t)
+}
+
+func TestCategoryService_GetDetailBySlug(t *testing.T) {
+	category := entity.NewCategory(1, "testCategory1", "test-category-1", 0)
+	children := []entity.Category{
+		entity.NewCategory(2, "testSubCategory1", "test-sub-category-1", 1),
+	}
+
+	r := new(mocks.ICategoryRepository)
+
+	r.On("GetBySlug", category.Slug).Return(category, nil)
+	r.On("GetStats", []int{1}).Return(map[int]entity.CategoryStats{1: {PostCount: 1}}, nil)
+	r.On("GetChildren", 1).Return(children, nil)
+
+	s := NewCategoryService(r)
+
+	ret, err := s.GetDetailBySlug(category.Slug)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ret.Id, category.Id)
+	assert.Equal(t, ret.Stats.PostCount, 1)
+	assert.Len(t, ret.Children, 1)
+	assert.Equal(t, ret.Children[0].Slug, children[0].Slug)
 	r.AssertExpectations(t)
 }
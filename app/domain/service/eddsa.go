@@ -0,0 +1,48 @@
+package service
+
+import (
+	"crypto/ed25519"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SigningMethodEdDSA signs tokens with Ed25519 (RFC 8037), which jwt-go v3 lacks.
+var SigningMethodEdDSA = &signingMethodEd25519{}
+
+type signingMethodEd25519 struct{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+func (m *signingMethodEd25519) Alg() string {
+	return "EdDSA"
+}
+
+func (m *signingMethodEd25519) Sign(signingString string, key interface{}) (signature string, err error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		err = jwt.ErrInvalidKeyType
+		return
+	}
+	signature = jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString)))
+	return
+}
+
+func (m *signingMethodEd25519) Verify(signingString string, signature string, key interface{}) (err error) {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		err = jwt.ErrInvalidKeyType
+		return
+	}
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return
+	}
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		err = jwt.ErrSignatureInvalid
+	}
+	return
+}
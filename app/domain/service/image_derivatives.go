@@ -0,0 +1,414 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"path"
+	"strings"
+)
+
+// The sizes generated for every uploaded image. Cropped sizes are filled entirely,
+// cutting off what does not fit around the center; the others fit into the box.
+const (
+	ImageThumbnail = "thumbnail"
+	ImageMedium    = "medium"
+	ImageOg        = "og"
+)
+
+type derivativeSize struct {
+	kind          string
+	width, height int
+	crop          bool
+}
+
+var derivativeSizes = []derivativeSize{
+	{ImageThumbnail, 150, 150, true},
+	{ImageMedium, 800, 800, false},
+	{ImageOg, 1200, 630, true},
+}
+
+const (
+	// maxImagePixels keeps a small file that decodes to a huge image from taking
+	// the memory of the server.
+	maxImagePixels = 40_000_000
+	jpegQuality    = 85
+	// uprightQuality is used for originals that have to be re-encoded to apply
+	// their orientation, which costs quality only once.
+	uprightQuality = 92
+)
+
+// imageTypes are the uploaded types derivatives are made of. WebP has no decoder
+// in the standard library and is stored as it is.
+var imageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+var errMalformedImage = errors.New("malformed image")
+
+// prepareImage decodes an uploaded image and removes its metadata: JPEG and PNG
+// files lose their EXIF, XMP, IPTC and text chunks, keeping color profiles. A JPEG
+// whose EXIF orientation is not upright is re-encoded the right way up instead.
+// It returns the file to store and the upright image.
+func prepareImage(data []byte, contentType string) (stored []byte, img *image.RGBA, err error) {
+	img, orientation, err := decodeUpright(data, contentType)
+	if err != nil {
+		return
+	}
+	switch {
+	case orientation > 1:
+		var buf bytes.Buffer
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: uprightQuality})
+		stored = buf.Bytes()
+		return
+	case contentType == "image/jpeg":
+		stored, err = stripJPEGMetadata(data)
+	case contentType == "image/png":
+		stored, err = stripPNGMetadata(data)
+	default:
+		stored = data
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrUnsupportedMediaType, err)
+	}
+	return
+}
+
+// decodeUpright decodes the image and turns it by its EXIF orientation, which it
+// also returns.
+func decodeUpright(data []byte, contentType string) (img *image.RGBA, orientation int, err error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrUnsupportedMediaType, err)
+		return
+	}
+	if config.Width*config.Height > maxImagePixels {
+		err = fmt.Errorf("%w: %dx%d pixels, the limit is %d", ErrMediaTooLarge, config.Width, config.Height, maxImagePixels)
+		return
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrUnsupportedMediaType, err)
+		return
+	}
+	img, orientation = toRGBA(decoded), 1
+	if contentType == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	if orientation > 1 {
+		img = orient(img, orientation)
+	}
+	return
+}
+
+// renderDerivatives encodes every size of the image. Derivatives of JPEG images are
+// JPEG files, the others PNG files to keep transparency. They are named after the
+// source with the kind appended.
+func renderDerivatives(source string, contentType string, img *image.RGBA) (derivatives []entity.ImageDerivative, files [][]byte, err error) {
+	extension := ".png"
+	if contentType == "image/jpeg" {
+		extension = ".jpg"
+	}
+	for _, size := range derivativeSizes {
+		resized := resizeTo(img, size)
+		var buf bytes.Buffer
+		if extension == ".jpg" {
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: jpegQuality})
+		} else {
+			err = png.Encode(&buf, resized)
+		}
+		if err != nil {
+			return
+		}
+		bounds := resized.Bounds()
+		derivatives = append(derivatives, entity.ImageDerivative{
+			Source: source,
+			Kind:   size.kind,
+			Name:   strings.TrimSuffix(source, path.Ext(source)) + "-" + size.kind + extension,
+			Width:  bounds.Dx(),
+			Height: bounds.Dy(),
+		})
+		files = append(files, buf.Bytes())
+	}
+	return
+}
+
+func convertToImagesDto(derivatives []entity.ImageDerivative) (imagesDto *dto.ImagesModel) {
+	for _, derivative := range derivatives {
+		if imagesDto == nil {
+			imagesDto = &dto.ImagesModel{}
+		}
+		imageDto := &dto.ImageModel{Url: mediaUrlPath + derivative.Name, Width: derivative.Width, Height: derivative.Height}
+		switch derivative.Kind {
+		case ImageThumbnail:
+			imagesDto.Thumbnail = imageDto
+		case ImageMedium:
+			imagesDto.Medium = imageDto
+		case ImageOg:
+			imagesDto.Og = imageDto
+		}
+	}
+	return
+}
+
+// toRGBA converts the image to premultiplied RGBA with its origin at 0,0. GIFs
+// are read through image.Decode, which yields their first frame.
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// resizeTo scales the image into the size, cropping it to the aspect ratio of the
+// size first when the size is cropped. Images are never enlarged.
+func resizeTo(img *image.RGBA, size derivativeSize) *image.RGBA {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if size.crop {
+		cropWidth, cropHeight := width, height
+		if width*size.height > height*size.width {
+			cropWidth = maxInt(1, int(math.Round(float64(height*size.width)/float64(size.height))))
+		} else {
+			cropHeight = maxInt(1, int(math.Round(float64(width*size.height)/float64(size.width))))
+		}
+		x, y := (width-cropWidth)/2, (height-cropHeight)/2
+		img = img.SubImage(image.Rect(x, y, x+cropWidth, y+cropHeight)).(*image.RGBA)
+		scale := math.Min(1, float64(cropWidth)/float64(size.width))
+		return resize(img, maxInt(1, int(math.Round(float64(size.width)*scale))), maxInt(1, int(math.Round(float64(size.height)*scale))))
+	}
+	scale := math.Min(1, math.Min(float64(size.width)/float64(width), float64(size.height)/float64(height)))
+	return resize(img, maxInt(1, int(math.Round(float64(width)*scale))), maxInt(1, int(math.Round(float64(height)*scale))))
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// contribution is the weight a source row or column has in a target one.
+type contribution struct {
+	index  int
+	weight float64
+}
+
+// boxWeights spreads n source pixels over m target pixels: every target pixel
+// averages the source pixels it covers, weighted by how much of each it covers.
+func boxWeights(n int, m int) [][]contribution {
+	weights := make([][]contribution, m)
+	scale := float64(n) / float64(m)
+	for i := range weights {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for j := int(start); j < n && float64(j) < end; j++ {
+			weight := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+			if weight > 0 {
+				weights[i] = append(weights[i], contribution{j, weight / scale})
+			}
+		}
+	}
+	return weights
+}
+
+// resize scales the image to width x height with a box filter, columns first.
+// Averaging premultiplied values keeps transparent pixels from bleeding color.
+func resize(img *image.RGBA, width int, height int) *image.RGBA {
+	bounds := img.Bounds()
+	if bounds.Dx() == width && bounds.Dy() == height && bounds.Min == (image.Point{}) {
+		return img
+	}
+	columns, rows := boxWeights(bounds.Dx(), width), boxWeights(bounds.Dy(), height)
+	horizontal := make([]float64, width*bounds.Dy()*4)
+	for y := 0; y < bounds.Dy(); y++ {
+		line := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+		for x, contributions := range columns {
+			out := horizontal[(y*width+x)*4:]
+			for _, c := range contributions {
+				for k := 0; k < 4; k++ {
+					out[k] += float64(line[c.index*4+k]) * c.weight
+				}
+			}
+		}
+	}
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y, contributions := range rows {
+		for x := 0; x < width; x++ {
+			var sum [4]float64
+			for _, c := range contributions {
+				in := horizontal[(c.index*width+x)*4:]
+				for k := 0; k < 4; k++ {
+					sum[k] += in[k] * c.weight
+				}
+			}
+			out := resized.Pix[resized.PixOffset(x, y):]
+			for k := 0; k < 4; k++ {
+				out[k] = uint8(math.Min(255, math.Round(sum[k])))
+			}
+		}
+	}
+	return resized
+}
+
+// orient turns the image the right way up for an EXIF orientation of 2 to 8.
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	outWidth, outHeight := width, height
+	if orientation >= 5 {
+		outWidth, outHeight = height, width
+	}
+	oriented := image.NewRGBA(image.Rect(0, 0, outWidth, outHeight))
+	for y := 0; y < outHeight; y++ {
+		for x := 0; x < outWidth; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = width-1-x, y
+			case 3:
+				sx, sy = width-1-x, height-1-y
+			case 4:
+				sx, sy = x, height-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, height-1-x
+			case 7:
+				sx, sy = width-1-y, height-1-x
+			case 8:
+				sx, sy = width-1-y, x
+			default:
+				sx, sy = x, y
+			}
+			copy(oriented.Pix[oriented.PixOffset(x, y):oriented.PixOffset(x, y)+4], img.Pix[img.PixOffset(sx, sy):img.PixOffset(sx, sy)+4])
+		}
+	}
+	return oriented
+}
+
+// jpegSegments calls visit with the marker and payload of every segment before the
+// image data, and returns the offset where the image data starts.
+func jpegSegments(data []byte, visit func(marker byte, segment []byte)) (scan int, err error) {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return 0, errMalformedImage
+	}
+	offset := 2
+	for offset+4 <= len(data) {
+		if data[offset] != 0xff {
+			return 0, errMalformedImage
+		}
+		marker := data[offset+1]
+		if marker == 0xff {
+			// fill byte before a marker
+			offset++
+			continue
+		}
+		if marker == 0xda {
+			return offset, nil
+		}
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		if length < 2 || offset+2+length > len(data) {
+			return 0, errMalformedImage
+		}
+		visit(marker, data[offset:offset+2+length])
+		offset += 2 + length
+	}
+	return 0, errMalformedImage
+}
+
+// jpegOrientation reads the orientation tag of the EXIF data, 1 when there is none.
+func jpegOrientation(data []byte) (orientation int) {
+	orientation = 1
+	jpegSegments(data, func(marker byte, segment []byte) {
+		if marker != 0xe1 || len(segment) < 10 || string(segment[4:10]) != "Exif\x00\x00" {
+			return
+		}
+		tiff := segment[10:]
+		if len(tiff) < 8 {
+			return
+		}
+		var order binary.ByteOrder
+		switch string(tiff[:2]) {
+		case "II":
+			order = binary.LittleEndian
+		case "MM":
+			order = binary.BigEndian
+		default:
+			return
+		}
+		ifd := int(order.Uint32(tiff[4:]))
+		if ifd+2 > len(tiff) || ifd < 8 {
+			return
+		}
+		entries := int(order.Uint16(tiff[ifd:]))
+		for i := 0; i < entries; i++ {
+			entry := ifd + 2 + i*12
+			if entry+12 > len(tiff) {
+				return
+			}
+			// tag 0x0112 of type SHORT
+			if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+				if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+					orientation = value
+				}
+				return
+			}
+		}
+	})
+	return
+}
+
+// stripJPEGMetadata drops the EXIF/XMP (APP1), IPTC (APP13), other application
+// segments and comments, keeping JFIF (APP0), ICC profiles (APP2) and the Adobe
+// segment (APP14) that tells how colors are encoded.
+func stripJPEGMetadata(data []byte) (stripped []byte, err error) {
+	stripped = append(stripped, 0xff, 0xd8)
+	scan, err := jpegSegments(data, func(marker byte, segment []byte) {
+		isApp := marker >= 0xe0 && marker <= 0xef
+		if marker == 0xfe || isApp && marker != 0xe0 && marker != 0xe2 && marker != 0xee {
+			return
+		}
+		stripped = append(stripped, segment...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	stripped = append(stripped, data[scan:]...)
+	return
+}
+
+// pngMetadataChunks are the ancillary chunks holding text, EXIF and timestamps.
+var pngMetadataChunks = map[string]bool{"tEXt": true, "zTXt": true, "iTXt": true, "eXIf": true, "tIME": true}
+
+// stripPNGMetadata drops the metadata chunks and keeps the others as they are.
+func stripPNGMetadata(data []byte) (stripped []byte, err error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return nil, errMalformedImage
+	}
+	stripped = append(stripped, signature...)
+	for offset := len(signature); offset < len(data); {
+		if offset+12 > len(data) {
+			return nil, errMalformedImage
+		}
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		end := offset + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, errMalformedImage
+		}
+		if !pngMetadataChunks[string(data[offset+4:offset+8])] {
+			stripped = append(stripped, data[offset:end]...)
+		}
+		offset = end
+	}
+	return
+}
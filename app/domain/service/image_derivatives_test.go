@@ -0,0 +1,178 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testImage is width x height with a red left and a blue right half.
+func testImage(width int, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	return img
+}
+
+// testPNGFile encodes the image with a tEXt chunk after the header.
+func testPNGFile(img image.Image) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	data := buf.Bytes()
+	text := []byte("tEXtComment\x00testSecret")
+	chunk := make([]byte, 4, 12+len(text))
+	binary.BigEndian.PutUint32(chunk, uint32(len(text)-4))
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(text))
+	// the signature and the IHDR chunk take 33 bytes
+	return append(append(append([]byte{}, data[:33]...), chunk...), data[33:]...)
+}
+
+// testJPEGFile encodes the image with an EXIF segment giving the orientation.
+func testJPEGFile(img image.Image, orientation uint16) []byte {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100})
+	data := buf.Bytes()
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01")
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xff, 0xe1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func TestPrepareImage(t *testing.T) {
+	t.Run(
+		"strips PNG text",
+		func(t *testing.T) {
+			data := testPNGFile(testImage(4, 2))
+
+			stored, img, err := prepareImage(data, "image/png")
+
+			assert.NoError(t, err)
+			assert.Contains(t, string(data), "testSecret")
+			assert.NotContains(t, string(stored), "testSecret")
+			decoded, err := png.Decode(bytes.NewReader(stored))
+			assert.NoError(t, err)
+			assert.Equal(t, image.Rect(0, 0, 4, 2), decoded.Bounds())
+			assert.Equal(t, image.Rect(0, 0, 4, 2), img.Bounds())
+		},
+	)
+
+	t.Run(
+		"strips upright JPEG EXIF losslessly",
+		func(t *testing.T) {
+			data := testJPEGFile(testImage(8, 4), 1)
+
+			stored, _, err := prepareImage(data, "image/jpeg")
+
+			assert.NoError(t, err)
+			assert.NotContains(t, string(stored), "Exif")
+			assert.Equal(t, len(data)-len("Exif")-2-2-2-26, len(stored))
+		},
+	)
+
+	t.Run(
+		"turns JPEGs by their orientation",
+		func(t *testing.T) {
+			// orientation 6: the camera was turned, the image has to be turned clockwise
+			data := testJPEGFile(testImage(8, 4), 6)
+
+			stored, img, err := prepareImage(data, "image/jpeg")
+
+			assert.NoError(t, err)
+			assert.Equal(t, 6, jpegOrientation(data))
+			assert.Equal(t, 1, jpegOrientation(stored))
+			assert.Equal(t, image.Rect(0, 0, 4, 8), img.Bounds())
+			// the red left half is on top now
+			r, _, b, _ := img.At(2, 1).RGBA()
+			assert.Greater(t, r, b)
+			r, _, b, _ = img.At(2, 6).RGBA()
+			assert.Less(t, r, b)
+		},
+	)
+
+	t.Run(
+		"rejects what does not decode",
+		func(t *testing.T) {
+			_, _, err := prepareImage([]byte("\x89PNG\r\n\x1a\ntestImage"), "image/png")
+
+			assert.ErrorIs(t, err, ErrUnsupportedMediaType)
+		},
+	)
+}
+
+func TestOrient(t *testing.T) {
+	// 3x2 with distinct pixels
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i := 0; i < 6; i++ {
+		img.Pix[i*4] = uint8(i)
+	}
+	at := func(img *image.RGBA, x int, y int) uint8 { return img.Pix[img.PixOffset(x, y)] }
+	// the pixel that ends up at the top left corner
+	corners := map[int]uint8{1: 0, 2: 2, 3: 5, 4: 3, 5: 0, 6: 3, 7: 5, 8: 2}
+	for orientation, corner := range corners {
+		oriented := orient(img, orientation)
+		assert.Equal(t, corner, at(oriented, 0, 0), orientation)
+		if orientation >= 5 {
+			assert.Equal(t, image.Rect(0, 0, 2, 3), oriented.Bounds(), orientation)
+		}
+	}
+}
+
+func TestRenderDerivatives(t *testing.T) {
+	derivatives, files, err := renderDerivatives("2019/05/abc.jpg", "image/jpeg", testImage(1600, 1000))
+
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+	sizes := map[string][2]int{}
+	for i, derivative := range derivatives {
+		sizes[derivative.Kind] = [2]int{derivative.Width, derivative.Height}
+		config, err := jpeg.DecodeConfig(bytes.NewReader(files[i]))
+		assert.NoError(t, err)
+		assert.Equal(t, derivative.Width, config.Width)
+		assert.Equal(t, derivative.Height, config.Height)
+		assert.Equal(t, "2019/05/abc.jpg", derivative.Source)
+	}
+	assert.Equal(t, map[string][2]int{"thumbnail": {150, 150}, "medium": {800, 500}, "og": {1200, 630}}, sizes)
+	assert.Equal(t, "2019/05/abc-thumbnail.jpg", derivatives[0].Name)
+
+	t.Run(
+		"small images are not enlarged",
+		func(t *testing.T) {
+			derivatives, _, err := renderDerivatives("abc.png", "image/png", testImage(400, 100))
+
+			assert.NoError(t, err)
+			assert.Equal(t, "abc-og.png", derivatives[2].Name)
+			assert.Equal(t, [2]int{100, 100}, [2]int{derivatives[0].Width, derivatives[0].Height})
+			assert.Equal(t, [2]int{400, 100}, [2]int{derivatives[1].Width, derivatives[1].Height})
+			assert.Equal(t, [2]int{190, 100}, [2]int{derivatives[2].Width, derivatives[2].Height})
+		},
+	)
+}
+
+func TestResize(t *testing.T) {
+	resized := resize(testImage(4, 2), 2, 1)
+
+	// every target pixel averages two pixels of one half
+	assert.Equal(t, []uint8{255, 0, 0, 255, 0, 0, 255, 255}, resized.Pix)
+
+	resized = resize(testImage(4, 2), 1, 1)
+	assert.Equal(t, []uint8{128, 0, 128, 255}, resized.Pix)
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrImportIncomplete is returned when some posts of an import failed. The plan
+// tells which ones and why; the others were imported.
+var ErrImportIncomplete = errors.New("import incomplete")
+
+// plannedId stands for the id of a category a dry run would create.
+const plannedId = -1
+
+type IImportService interface {
+	ImportPosts(actor dto.UserModel, postImportDtos []dto.PostImportModel, options dto.ImportOptionsModel) (planDto dto.ImportPlanModel, err error)
+	ImportWordPress(actor dto.UserModel, r io.Reader, uploadsDir string, mediaDir string, options dto.ImportOptionsModel) (planDto dto.ImportPlanModel, err error)
+}
+
+// ImportService upserts posts from an import source by slug through the post and
+// category services, so every write is checked like one made through the API.
+type ImportService struct {
+	IPostService
+	ICategoryService
+	ISubCategoryService
+}
+
+func NewImportService(postService IPostService, categoryService ICategoryService, subCategoryService ISubCategoryService) (importService IImportService) {
+	importService = &ImportService{postService, categoryService, subCategoryService}
+	return
+}
+
+// importRun is the state of one ImportPosts call.
+type importRun struct {
+	*ImportService
+	actor   dto.UserModel
+	options dto.ImportOptionsModel
+	plan    dto.ImportPlanModel
+	// categoryIds caches the ids of resolved category and sub-category slugs,
+	// parentIds the parent ids of resolved sub-categories.
+	categoryIds map[string]int
+	parentIds   map[string]int
+	// sources maps the post slugs seen so far to the source that used them.
+	sources map[string]string
+	// posts and failures count the posts tried and failed.
+	posts, failures int
+	// uploadsDir and mediaDir are set for imports that bring media files along;
+	// media remembers the uploads already handled and whether they are available.
+	uploadsDir, mediaDir string
+	media                map[string]bool
+}
+
+// postFields are the fields an import sets; a post whose fields match is left alone.
+type postFields struct {
+	title, content, metaDescription, eyeCatchingImg string
+	isPublic                                        bool
+	categoryId                                      int
+}
+
+func fieldsOf(postDto dto.PostModel) postFields {
+	return postFields{postDto.Title, postDto.Content, postDto.MetaDescription, postDto.EyeCatchingImg, postDto.IsPublic, postDto.CategoryId}
+}
+
+// ImportPosts creates the posts whose slug is new and updates the others where they
+// differ, so running the same import twice changes nothing the second time. Missing
+// categories and sub-categories are created with CreateCategories and fail the post
+// otherwise. A failing post does not stop the import; ErrImportIncomplete reports it.
+func (s *ImportService) ImportPosts(actor dto.UserModel, postImportDtos []dto.PostImportModel, options dto.ImportOptionsModel) (planDto dto.ImportPlanModel, err error) {
+	// a dry run writes nothing, so check up front what the actual run would be refused
+	err = authorize(actor, entity.PermissionCreatePost)
+	if err == nil && options.CreateCategories {
+		err = authorize(actor, entity.PermissionManageCategories)
+	}
+	if err != nil {
+		return
+	}
+	run := newImportRun(s, actor, options)
+	for _, postImportDto := range postImportDtos {
+		run.tryPost(postImportDto)
+	}
+	planDto, err = run.result()
+	return
+}
+
+func newImportRun(s *ImportService, actor dto.UserModel, options dto.ImportOptionsModel) *importRun {
+	return &importRun{
+		ImportService: s,
+		actor:         actor,
+		options:       options,
+		plan:          dto.ImportPlanModel{DryRun: options.DryRun},
+		categoryIds:   make(map[string]int),
+		parentIds:     make(map[string]int),
+		sources:       make(map[string]string),
+	}
+}
+
+// tryPost imports the post and records a failure in the plan.
+func (r *importRun) tryPost(postImportDto dto.PostImportModel) {
+	r.posts++
+	err := r.importPost(postImportDto)
+	if err != nil {
+		r.add(dto.ImportActionError, "post", postImportDto.Slug, postImportDto.Source, err.Error())
+		r.failures++
+	}
+}
+
+func (r *importRun) result() (planDto dto.ImportPlanModel, err error) {
+	planDto = r.plan
+	if r.failures > 0 {
+		err = fmt.Errorf("%w: %d of %d posts failed", ErrImportIncomplete, r.failures, r.posts)
+	}
+	return
+}
+
+func (r *importRun) add(action string, kind string, slug string, source string, detail string) {
+	r.plan.Actions = append(r.plan.Actions, dto.ImportActionModel{Action: action, Kind: kind, Slug: slug, Source: source, Detail: detail})
+}
+
+func (r *importRun) importPost(postImportDto dto.PostImportModel) (err error) {
+	switch {
+	case postImportDto.Slug == "":
+		return errors.New("slug is missing")
+	case postImportDto.Title == "":
+		return errors.New("title is missing")
+	case postImportDto.CategorySlug == "":
+		return errors.New("category is missing")
+	}
+	if source, ok := r.sources[postImportDto.Slug]; ok {
+		return fmt.Errorf("slug is also used by %s", source)
+	}
+	r.sources[postImportDto.Slug] = postImportDto.Source
+
+	categoryId, err := r.resolveCategory(postImportDto)
+	if err != nil {
+		return
+	}
+	var detail string
+	if len(postImportDto.Tags) > 0 {
+		detail = "tags are not stored"
+	}
+
+	stored, err := r.IPostService.GetPostBySlug(postImportDto.Slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+		postDto := dto.PostModel{
+			Title:           postImportDto.Title,
+			Slug:            postImportDto.Slug,
+			Content:         postImportDto.Content,
+			MetaDescription: postImportDto.MetaDescription,
+			EyeCatchingImg:  postImportDto.EyeCatchingImg,
+			IsPublic:        postImportDto.IsPublic,
+			CategoryId:      categoryId,
+			CreatedAt:       postImportDto.Date,
+		}
+		if !r.options.DryRun {
+			err = r.IPostService.Create(r.actor, postDto)
+			if err != nil {
+				return
+			}
+		}
+		r.add(dto.ImportActionCreate, "post", postImportDto.Slug, postImportDto.Source, detail)
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	postDto := stored
+	postDto.Title = postImportDto.Title
+	postDto.Content = postImportDto.Content
+	postDto.IsPublic = postImportDto.IsPublic
+	postDto.CategoryId = categoryId
+	// the source may leave these out, e.g. when the image was uploaded later
+	if postImportDto.MetaDescription != "" {
+		postDto.MetaDescription = postImportDto.MetaDescription
+	}
+	if postImportDto.EyeCatchingImg != "" {
+		postDto.EyeCatchingImg = postImportDto.EyeCatchingImg
+	}
+	if fieldsOf(postDto) == fieldsOf(stored) {
+		r.add(dto.ImportActionUnchanged, "post", postImportDto.Slug, postImportDto.Source, detail)
+		return
+	}
+	if !r.options.DryRun {
+		err = r.IPostService.Update(r.actor, postDto)
+		if err != nil {
+			return
+		}
+	}
+	r.add(dto.ImportActionUpdate, "post", postImportDto.Slug, postImportDto.Source, detail)
+	return
+}
+
+// resolveCategory returns the id of the node the post is filed under: the
+// sub-category when it names one, the category otherwise.
+func (r *importRun) resolveCategory(postImportDto dto.PostImportModel) (id int, err error) {
+	categoryId, err := r.category(postImportDto.CategorySlug, postImportDto.Source)
+	if err != nil || postImportDto.SubCategorySlug == "" {
+		id = categoryId
+		return
+	}
+	id, err = r.subCategory(postImportDto.SubCategorySlug, categoryId, postImportDto.CategorySlug, postImportDto.Source)
+	return
+}
+
+func (r *importRun) category(slug string, source string) (id int, err error) {
+	if id, ok := r.categoryIds[slug]; ok {
+		return id, nil
+	}
+	categoryDto, err := r.ICategoryService.GetBySlug(slug)
+	if err == nil {
+		id = categoryDto.Id
+		r.categoryIds[slug] = id
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if !r.options.CreateCategories {
+		err = fmt.Errorf("category %s does not exist", slug)
+		return
+	}
+	id, err = r.createCategory("category", slug, source, func() error {
+		return r.ICategoryService.Create(r.actor, dto.NewCategoryModel(0, slug, slug, 0))
+	})
+	return
+}
+
+func (r *importRun) subCategory(slug string, parentId int, parentSlug string, source string) (id int, err error) {
+	id, ok := r.categoryIds[slug]
+	if !ok {
+		var subCategoryDto dto.SubCategoryModel
+		subCategoryDto, err = r.ISubCategoryService.GetSubCategoryBySlug(slug)
+		switch {
+		case err == nil:
+			id = subCategoryDto.Id
+			r.categoryIds[slug] = id
+			r.parentIds[slug] = subCategoryDto.ParentCategoryId
+		case !errors.Is(err, sql.ErrNoRows):
+			return
+		case !r.options.CreateCategories:
+			err = fmt.Errorf("sub-category %s does not exist", slug)
+			return
+		default:
+			r.parentIds[slug] = parentId
+			id, err = r.createCategory("sub-category", slug, source, func() error {
+				return r.ISubCategoryService.Create(r.actor, dto.SubCategoryModel{Name: slug, Slug: slug, ParentCategoryId: parentId})
+			})
+			return
+		}
+	}
+	if r.parentIds[slug] != parentId {
+		err = fmt.Errorf("sub-category %s is not under category %s", slug, parentSlug)
+	}
+	return
+}
+
+// createCategory runs create, unless this is a dry run, and returns the id of the
+// new category. New categories are named after their slug.
+func (r *importRun) createCategory(kind string, slug string, source string, create func() error) (id int, err error) {
+	id = plannedId
+	if !r.options.DryRun {
+		err = create()
+		if err != nil {
+			return
+		}
+		var categoryDto dto.CategoryModel
+		categoryDto, err = r.ICategoryService.GetBySlug(slug)
+		if err != nil {
+			return
+		}
+		id = categoryDto.Id
+	}
+	r.categoryIds[slug] = id
+	r.add(dto.ImportActionCreate, kind, slug, source, "")
+	return
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	mocks "backend/mocks/service"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestImportService_ImportPosts(t *testing.T) {
+	imported := []dto.PostImportModel{
+		{Source: "new.md", Title: "testTitle1", Slug: "test-slug-1", Content: "testContent1", CategorySlug: "test-category-1", Tags: []string{"go"}},
+		{Source: "changed.md", Title: "testTitle2", Slug: "test-slug-2", Content: "testContent2", CategorySlug: "test-category-1", SubCategorySlug: "test-sub-category-1"},
+		{Source: "same.md", Title: "testTitle3", Slug: "test-slug-3", Content: "testContent3", CategorySlug: "test-category-1"},
+	}
+	stored2 := dto.PostModel{Id: 2, Title: "testTitle2", Slug: "test-slug-2", Content: "old", CategoryId: 2, MetaDescription: "testDescription2"}
+	stored3 := dto.PostModel{Id: 3, Title: "testTitle3", Slug: "test-slug-3", Content: "testContent3", CategoryId: 1}
+
+	newServices := func() (ps *mocks.IPostService, cs *mocks.ICategoryService, ss *mocks.ISubCategoryService) {
+		ps, cs, ss = new(mocks.IPostService), new(mocks.ICategoryService), new(mocks.ISubCategoryService)
+		ps.On("GetPostBySlug", "test-slug-1").Return(dto.PostModel{}, sql.ErrNoRows)
+		ps.On("GetPostBySlug", "test-slug-2").Return(stored2, nil)
+		ps.On("GetPostBySlug", "test-slug-3").Return(stored3, nil)
+		cs.On("GetBySlug", "test-category-1").Return(dto.NewCategoryModel(1, "testCategory1", "test-category-1", 0), nil).Once()
+		ss.On("GetSubCategoryBySlug", "test-sub-category-1").Return(dto.NewSubCategoryModel(2, "testSubCategory1", "test-sub-category-1", 1, "testCategory1", "test-category-1"), nil).Once()
+		return
+	}
+
+	t.Run(
+		"upserts by slug",
+		func(t *testing.T) {
+			ps, cs, ss := newServices()
+			ps.On("Create", editor, mock.MatchedBy(func(postDto dto.PostModel) bool {
+				return postDto.Slug == "test-slug-1" && postDto.CategoryId == 1
+			})).Return(nil).Once()
+			updated := stored2
+			updated.Content = "testContent2"
+			ps.On("Update", editor, updated).Return(nil).Once()
+
+			s := NewImportService(ps, cs, ss)
+			ret, err := s.ImportPosts(editor, imported, dto.ImportOptionsModel{})
+
+			assert.NoError(t, err)
+			assert.Equal(t, []dto.ImportActionModel{
+				{Action: dto.ImportActionCreate, Kind: "post", Slug: "test-slug-1", Source: "new.md", Detail: "tags are not stored"},
+				{Action: dto.ImportActionUpdate, Kind: "post", Slug: "test-slug-2", Source: "changed.md"},
+				{Action: dto.ImportActionUnchanged, Kind: "post", Slug: "test-slug-3", Source: "same.md"},
+			}, ret.Actions)
+			ps.AssertExpectations(t)
+			cs.AssertExpectations(t)
+			ss.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"dry run writes nothing",
+		func(t *testing.T) {
+			ps, cs, ss := newServices()
+
+			s := NewImportService(ps, cs, ss)
+			ret, err := s.ImportPosts(editor, imported, dto.ImportOptionsModel{DryRun: true})
+
+			assert.NoError(t, err)
+			assert.True(t, ret.DryRun)
+			assert.Len(t, ret.Actions, 3)
+			ps.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			ps.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		},
+	)
+
+	t.Run(
+		"creates missing categories",
+		func(t *testing.T) {
+			ps, cs, ss := new(mocks.IPostService), new(mocks.ICategoryService), new(mocks.ISubCategoryService)
+			cs.On("GetBySlug", "test-category-2").Return(dto.CategoryModel{}, sql.ErrNoRows).Once()
+			cs.On("Create", editor, dto.NewCategoryModel(0, "test-category-2", "test-category-2", 0)).Return(nil).Once()
+			cs.On("GetBySlug", "test-category-2").Return(dto.NewCategoryModel(5, "test-category-2", "test-category-2", 0), nil).Once()
+			ss.On("GetSubCategoryBySlug", "test-sub-category-2").Return(dto.SubCategoryModel{}, sql.ErrNoRows).Once()
+			ss.On("Create", editor, dto.SubCategoryModel{Name: "test-sub-category-2", Slug: "test-sub-category-2", ParentCategoryId: 5}).Return(nil).Once()
+			cs.On("GetBySlug", "test-sub-category-2").Return(dto.NewCategoryModel(6, "test-sub-category-2", "test-sub-category-2", 5), nil).Once()
+			ps.On("GetPostBySlug", "test-slug-4").Return(dto.PostModel{}, sql.ErrNoRows)
+			ps.On("Create", editor, mock.MatchedBy(func(postDto dto.PostModel) bool { return postDto.CategoryId == 6 })).Return(nil).Once()
+
+			s := NewImportService(ps, cs, ss)
+			ret, err := s.ImportPosts(editor, []dto.PostImportModel{
+				{Source: "a.md", Title: "testTitle4", Slug: "test-slug-4", CategorySlug: "test-category-2", SubCategorySlug: "test-sub-category-2"},
+			}, dto.ImportOptionsModel{CreateCategories: true})
+
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"category", "sub-category", "post"}, []string{ret.Actions[0].Kind, ret.Actions[1].Kind, ret.Actions[2].Kind})
+			ps.AssertExpectations(t)
+			cs.AssertExpectations(t)
+			ss.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"reports failing posts and continues",
+		func(t *testing.T) {
+			ps, cs, ss := new(mocks.IPostService), new(mocks.ICategoryService), new(mocks.ISubCategoryService)
+			cs.On("GetBySlug", "missing").Return(dto.CategoryModel{}, sql.ErrNoRows)
+			cs.On("GetBySlug", "test-category-1").Return(dto.NewCategoryModel(1, "testCategory1", "test-category-1", 0), nil)
+			ps.On("GetPostBySlug", "test-slug-3").Return(stored3, nil)
+
+			s := NewImportService(ps, cs, ss)
+			ret, err := s.ImportPosts(editor, []dto.PostImportModel{
+				{Source: "a.md", Title: "testTitle1", Slug: "test-slug-1", CategorySlug: "missing"},
+				{Source: "b.md", Slug: "test-slug-2", CategorySlug: "test-category-1"},
+				imported[2],
+				{Source: "c.md", Title: "testTitle3", Slug: "test-slug-3", CategorySlug: "test-category-1"},
+			}, dto.ImportOptionsModel{})
+
+			assert.ErrorIs(t, err, ErrImportIncomplete)
+			assert.EqualError(t, err, "import incomplete: 3 of 4 posts failed")
+			assert.Equal(t, "category missing does not exist", ret.Actions[0].Detail)
+			assert.Equal(t, "title is missing", ret.Actions[1].Detail)
+			assert.Equal(t, dto.ImportActionUnchanged, ret.Actions[2].Action)
+			assert.Equal(t, "slug is also used by same.md", ret.Actions[3].Detail)
+		},
+	)
+
+	t.Run(
+		"sub-category under another category",
+		func(t *testing.T) {
+			ps, cs, ss := new(mocks.IPostService), new(mocks.ICategoryService), new(mocks.ISubCategoryService)
+			cs.On("GetBySlug", "test-category-2").Return(dto.NewCategoryModel(5, "testCategory2", "test-category-2", 0), nil)
+			ss.On("GetSubCategoryBySlug", "test-sub-category-1").Return(dto.NewSubCategoryModel(2, "testSubCategory1", "test-sub-category-1", 1, "testCategory1", "test-category-1"), nil)
+
+			s := NewImportService(ps, cs, ss)
+			ret, err := s.ImportPosts(editor, []dto.PostImportModel{
+				{Source: "a.md", Title: "testTitle1", Slug: "test-slug-1", CategorySlug: "test-category-2", SubCategorySlug: "test-sub-category-1"},
+			}, dto.ImportOptionsModel{})
+
+			assert.ErrorIs(t, err, ErrImportIncomplete)
+			assert.Equal(t, "sub-category test-sub-category-1 is not under category test-category-2", ret.Actions[0].Detail)
+		},
+	)
+
+	t.Run(
+		"viewer",
+		func(t *testing.T) {
+			s := NewImportService(new(mocks.IPostService), new(mocks.ICategoryService), new(mocks.ISubCategoryService))
+			_, err := s.ImportPosts(viewer, imported, dto.ImportOptionsModel{DryRun: true})
+
+			assert.ErrorIs(t, err, ErrForbidden)
+		},
+	)
+}
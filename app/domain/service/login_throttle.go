@@ -0,0 +1,213 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidCredentials is returned for an unknown username or a wrong password alike.
+	ErrInvalidCredentials = fmt.Errorf("%w: invalid username or password", ErrUnauthenticated)
+	// ErrTooManyAttempts is wrapped by ThrottledError.
+	ErrTooManyAttempts = errors.New("too many failed login attempts")
+)
+
+// ThrottledError is returned while a username or client IP is backing off after
+// failed logins. The credentials are not checked during that time.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// failureWindow is how long a failed login is remembered.
+const failureWindow = 24 * time.Hour
+
+// throttlePolicy allows freeAttempts failures and then doubles the delay before the
+// next attempt with every failure, starting at baseDelay. Reaching maxDelay is the
+// lockout; it lasts until it expires or an admin runs `users unlock`.
+type throttlePolicy struct {
+	freeAttempts int
+	baseDelay    time.Duration
+	maxDelay     time.Duration
+}
+
+var (
+	usernamePolicy = throttlePolicy{freeAttempts: 3, baseDelay: time.Second, maxDelay: 15 * time.Minute}
+	// a single address may serve many users, e.g. behind a NAT, so it gets more attempts
+	ipPolicy = throttlePolicy{freeAttempts: 20, baseDelay: time.Second, maxDelay: 15 * time.Minute}
+)
+
+func (p throttlePolicy) delay(failures int) (delay time.Duration) {
+	over := failures - p.freeAttempts
+	if over <= 0 {
+		return
+	}
+	delay = p.maxDelay
+	// beyond 30 doublings the shift would overflow; the cap is long reached by then
+	if over <= 30 {
+		if backoff := p.baseDelay << (over - 1); backoff < p.maxDelay {
+			delay = backoff
+		}
+	}
+	return
+}
+
+type throttleSubject struct {
+	key    string
+	policy throttlePolicy
+}
+
+func usernameKey(username string) string {
+	return "username:" + username
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func loginSubjects(credsDto dto.CredentialsModel) (subjects []throttleSubject) {
+	subjects = append(subjects, throttleSubject{usernameKey(credsDto.Username), usernamePolicy})
+	if credsDto.RemoteAddr != "" {
+		subjects = append(subjects, throttleSubject{ipKey(credsDto.RemoteAddr), ipPolicy})
+	}
+	return
+}
+
+// checkThrottle returns a ThrottledError with the longest remaining delay of the subjects.
+func (s *UserService) checkThrottle(subjects []throttleSubject, now time.Time) (err error) {
+	var retryAfter time.Duration
+	for _, subject := range subjects {
+		loginAttempt, getErr := s.ILoginAttemptRepository.Get(subject.key)
+		if errors.Is(getErr, sql.ErrNoRows) {
+			continue
+		}
+		if getErr != nil {
+			err = getErr
+			return
+		}
+		if remaining := loginAttempt.LockedUntil.Sub(now); remaining > retryAfter {
+			retryAfter = remaining
+		}
+	}
+	if retryAfter > 0 {
+		err = &ThrottledError{RetryAfter: retryAfter}
+	}
+	return
+}
+
+func (s *UserService) recordFailure(subjects []throttleSubject, now time.Time) (err error) {
+	for _, subject := range subjects {
+		var failures int
+		failures, err = s.ILoginAttemptRepository.RecordFailure(subject.key, now.Add(-failureWindow))
+		if err != nil {
+			return
+		}
+		if delay := subject.policy.delay(failures); delay > 0 {
+			err = s.ILoginAttemptRepository.Lock(subject.key, now.Add(delay))
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// dummyHashes holds one hash per bcrypt cost to compare unknown usernames against.
+var dummyHashes sync.Map
+
+// dummyHash returns a hash of the service's cost, so that checking an unknown
+// username takes as long as checking a wrong password.
+func (s *UserService) dummyHash() (hash []byte) {
+	if cached, ok := dummyHashes.Load(s.bcryptCost); ok {
+		hash = cached.([]byte)
+		return
+	}
+	hash, _ = bcrypt.GenerateFromPassword([]byte("not a password"), s.bcryptCost)
+	dummyHashes.Store(s.bcryptCost, hash)
+	return
+}
+
+// ValidateUser checks the credentials of a login. Failures are counted per username
+// and per client IP; once a subject exceeds its free attempts it must wait before
+// trying again, and attempts during the wait fail with a ThrottledError.
+func (s *UserService) ValidateUser(credsDto dto.CredentialsModel) (userDto dto.UserModel, err error) {
+	now := time.Now()
+	subjects := loginSubjects(credsDto)
+	err = s.checkThrottle(subjects, now)
+	if err != nil {
+		return
+	}
+
+	user, err := s.IUserRepository.GetByUsername(credsDto.Username)
+	found := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	hash := []byte(user.Password)
+	if !found {
+		hash = s.dummyHash()
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(credsDto.Password)) != nil || !found {
+		err = s.recordFailure(subjects, now)
+		if err == nil {
+			err = ErrInvalidCredentials
+		}
+		return
+	}
+
+	// only the username is forgiven: a valid login must not clear the failures an
+	// address collected against other accounts. With 2FA enabled the password is only
+	// the first step, and the counter is cleared by CompleteTwoFactor instead.
+	enabled, err := s.twoFactorEnabled(user.Id)
+	if err != nil {
+		return
+	}
+	if !enabled {
+		err = s.ILoginAttemptRepository.Reset(usernameKey(user.Name))
+		if err != nil {
+			return
+		}
+	}
+	userDto = s.convertToDtoFromEntity(user)
+	return
+}
+
+// UnlockUser clears the failed logins of a username.
+func (s *UserService) UnlockUser(actor dto.UserModel, username string) (err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
+	err = s.ILoginAttemptRepository.Reset(usernameKey(username))
+	return
+}
+
+// UnlockAddress clears the failed logins of a client IP.
+func (s *UserService) UnlockAddress(actor dto.UserModel, ip string) (err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
+	address := net.ParseIP(ip)
+	if address == nil {
+		err = fmt.Errorf("%w: %q is not an IP address", ErrInvalidInput, ip)
+		return
+	}
+	err = s.ILoginAttemptRepository.Reset(ipKey(address.String()))
+	return
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	mocks "backend/mocks/repository"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserService_ValidateUser(t *testing.T) {
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("testpass1"), bcrypt.MinCost)
+	user := entity.NewUser(1, "testuser1", string(hashed), entity.RoleAuthor)
+
+	t.Run(
+		"valid credentials",
+		func(t *testing.T) {
+			r := new(mocks.IUserRepository)
+			lr := new(mocks.ILoginAttemptRepository)
+			tfr := new(mocks.ITwoFactorRepository)
+
+			lr.On("Get", "username:testuser1").Return(entity.LoginAttempt{}, sql.ErrNoRows)
+			lr.On("Get", "ip:192.0.2.1").Return(entity.LoginAttempt{}, sql.ErrNoRows)
+			r.On("GetByUsername", "testuser1").Return(user, nil)
+			tfr.On("GetByUserId", 1).Return(entity.TwoFactor{}, sql.ErrNoRows)
+			lr.On("Reset", "username:testuser1").Return(nil)
+
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), lr, tfr, new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			credsDto := dto.NewCredsModel("testuser1", "testpass1")
+			credsDto.RemoteAddr = "192.0.2.1"
+			ret, err := s.ValidateUser(credsDto)
+
+			assert.NoError(t, err)
+			assert.Equal(t, ret.Id, user.Id)
+			assert.Equal(t, ret.Role, string(user.Role))
+			r.AssertExpectations(t)
+			lr.AssertExpectations(t)
+			lr.AssertNotCalled(t, "Reset", "ip:192.0.2.1")
+		},
+	)
+
+	t.Run(
+		"two-factor users keep their counter until the second step",
+		func(t *testing.T) {
+			r := new(mocks.IUserRepository)
+			lr := new(mocks.ILoginAttemptRepository)
+			tfr := new(mocks.ITwoFactorRepository)
+
+			lr.On("Get", "username:testuser1").Return(entity.LoginAttempt{}, sql.ErrNoRows)
+			r.On("GetByUsername", "testuser1").Return(user, nil)
+			tfr.On("GetByUserId", 1).Return(entity.NewTwoFactor(1, "JBSWY3DPEHPK3PXP"), nil)
+
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), lr, tfr, new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			_, err := s.ValidateUser(dto.NewCredsModel("testuser1", "testpass1"))
+
+			assert.NoError(t, err)
+			lr.AssertNotCalled(t, "Reset", mock.Anything)
+		},
+	)
+
+	t.Run(
+		"wrong password",
+		func(t *testing.T) {
+			r := new(mocks.IUserRepository)
+			lr := new(mocks.ILoginAttemptRepository)
+
+			lr.On("Get", mock.Anything).Return(entity.LoginAttempt{}, sql.ErrNoRows)
+			r.On("GetByUsername", "testuser1").Return(user, nil)
+			lr.On("RecordFailure", "username:testuser1", mock.Anything).Return(1, nil)
+			lr.On("RecordFailure", "ip:192.0.2.1", mock.Anything).Return(1, nil)
+
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), lr, new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			credsDto := dto.NewCredsModel("testuser1", "wrongpass")
+			credsDto.RemoteAddr = "192.0.2.1"
+			_, err := s.ValidateUser(credsDto)
+
+			assert.ErrorIs(t, err, ErrInvalidCredentials)
+			assert.ErrorIs(t, err, ErrUnauthenticated)
+			lr.AssertExpectations(t)
+			lr.AssertNotCalled(t, "Lock", mock.Anything, mock.Anything)
+		},
+	)
+
+	t.Run(
+		"unknown username",
+		func(t *testing.T) {
+			r := new(mocks.IUserRepository)
+			lr := new(mocks.ILoginAttemptRepository)
+
+			lr.On("Get", "username:nobody").Return(entity.LoginAttempt{}, sql.ErrNoRows)
+			r.On("GetByUsername", "nobody").Return(entity.User{}, sql.ErrNoRows)
+			lr.On("RecordFailure", "username:nobody", mock.Anything).Return(1, nil)
+
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), lr, new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			_, err := s.ValidateUser(dto.NewCredsModel("nobody", ""))
+
+			assert.ErrorIs(t, err, ErrInvalidCredentials)
+			lr.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"backs off after the free attempts",
+		func(t *testing.T) {
+			r := new(mocks.IUserRepository)
+			lr := new(mocks.ILoginAttemptRepository)
+
+			lr.On("Get", "username:testuser1").Return(entity.LoginAttempt{}, sql.ErrNoRows)
+			r.On("GetByUsername", "testuser1").Return(user, nil)
+			lr.On("RecordFailure", "username:testuser1", mock.Anything).Return(usernamePolicy.freeAttempts+2, nil)
+			lr.On("Lock", "username:testuser1", mock.MatchedBy(func(until time.Time) bool {
+				remaining := time.Until(until)
+				return remaining > time.Second && remaining <= 2*time.Second
+			})).Return(nil)
+
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), lr, new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			_, err := s.ValidateUser(dto.NewCredsModel("testuser1", "wrongpass"))
+
+			assert.ErrorIs(t, err, ErrInvalidCredentials)
+			lr.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"locked",
+		func(t *testing.T) {
+			r := new(mocks.IUserRepository)
+			lr := new(mocks.ILoginAttemptRepository)
+
+			lr.On("Get", "username:testuser1").Return(entity.LoginAttempt{LockedUntil: time.Now().Add(time.Minute)}, nil)
+			lr.On("Get", "ip:192.0.2.1").Return(entity.LoginAttempt{LockedUntil: time.Now().Add(10 * time.Minute)}, nil)
+
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), lr, new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			credsDto := dto.NewCredsModel("testuser1", "testpass1")
+			credsDto.RemoteAddr = "192.0.2.1"
+			_, err := s.ValidateUser(credsDto)
+
+			var throttled *ThrottledError
+			assert.ErrorAs(t, err, &throttled)
+			assert.ErrorIs(t, err, ErrTooManyAttempts)
+			assert.Greater(t, throttled.RetryAfter, 9*time.Minute)
+			r.AssertNotCalled(t, "GetByUsername", mock.Anything)
+		},
+	)
+}
+
+func TestThrottlePolicy_Delay(t *testing.T) {
+	policy := throttlePolicy{freeAttempts: 3, baseDelay: time.Second, maxDelay: time.Minute}
+
+	assert.Equal(t, time.Duration(0), policy.delay(3))
+	assert.Equal(t, time.Second, policy.delay(4))
+	assert.Equal(t, 2*time.Second, policy.delay(5))
+	assert.Equal(t, 32*time.Second, policy.delay(9))
+	assert.Equal(t, time.Minute, policy.delay(10))
+	assert.Equal(t, time.Minute, policy.delay(1000))
+}
+
+func TestUserService_Unlock(t *testing.T) {
+	lr := new(mocks.ILoginAttemptRepository)
+
+	lr.On("Reset", "username:testuser1").Return(nil)
+	lr.On("Reset", "ip:192.0.2.1").Return(nil)
+
+	s := NewUserService(new(mocks.IUserRepository), new(mocks.IRefreshTokenRepository), lr, new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+	assert.NoError(t, s.UnlockUser(admin, "testuser1"))
+	assert.NoError(t, s.UnlockAddress(admin, "192.0.2.1"))
+	assert.ErrorIs(t, s.UnlockAddress(admin, "testuser1"), ErrInvalidInput)
+	lr.AssertExpectations(t)
+	lr.AssertNumberOfCalls(t, "Reset", 2)
+}
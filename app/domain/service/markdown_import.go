@@ -0,0 +1,92 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoFrontMatter is returned for a Markdown file that does not start with a
+// "---" line opening the YAML front matter.
+var ErrNoFrontMatter = errors.New("no front matter")
+
+const frontMatterDelimiter = "---"
+
+// frontMatter lists the keys a Markdown post may set. Unknown keys are rejected
+// so that a misspelt key is not silently ignored.
+type frontMatter struct {
+	Title           string    `yaml:"title"`
+	Slug            string    `yaml:"slug"`
+	Category        string    `yaml:"category"`
+	SubCategory     string    `yaml:"sub-category"`
+	Tags            []string  `yaml:"tags"`
+	IsPublic        bool      `yaml:"is_public"`
+	Date            time.Time `yaml:"date"`
+	MetaDescription string    `yaml:"meta_description"`
+	EyeCatchingImg  string    `yaml:"eye_catching_img"`
+}
+
+// ParseMarkdown reads a post from a Markdown file with YAML front matter. The body
+// after the front matter becomes the content. Without a slug in the front matter
+// the file name without its extension is used.
+func ParseMarkdown(source string, data []byte) (postImportDto dto.PostImportModel, err error) {
+	text := strings.ReplaceAll(string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), "\r\n", "\n")
+	if !strings.HasPrefix(text, frontMatterDelimiter+"\n") {
+		err = fmt.Errorf("%s: %w", source, ErrNoFrontMatter)
+		return
+	}
+	text = text[len(frontMatterDelimiter)+1:]
+	var header, body string
+	if strings.HasPrefix(text, frontMatterDelimiter+"\n") || text == frontMatterDelimiter {
+		body = strings.TrimPrefix(text, frontMatterDelimiter)
+	} else {
+		end := strings.Index(text, "\n"+frontMatterDelimiter+"\n")
+		if end < 0 {
+			if !strings.HasSuffix(text, "\n"+frontMatterDelimiter) {
+				err = fmt.Errorf("%s: front matter is not closed by a %q line", source, frontMatterDelimiter)
+				return
+			}
+			end = len(text) - len(frontMatterDelimiter) - 1
+		}
+		header = text[:end]
+		body = text[end+len(frontMatterDelimiter)+1:]
+	}
+
+	var matter frontMatter
+	decoder := yaml.NewDecoder(strings.NewReader(header))
+	decoder.KnownFields(true)
+	err = decoder.Decode(&matter)
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	if err != nil {
+		err = fmt.Errorf("%s: front matter: %w", source, err)
+		return
+	}
+
+	slug := matter.Slug
+	if slug == "" {
+		slug = strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	}
+	postImportDto = dto.PostImportModel{
+		Source:          source,
+		Title:           matter.Title,
+		Slug:            slug,
+		Content:         strings.TrimLeft(body, "\n"),
+		MetaDescription: matter.MetaDescription,
+		EyeCatchingImg:  matter.EyeCatchingImg,
+		CategorySlug:    matter.Category,
+		SubCategorySlug: matter.SubCategory,
+		Tags:            matter.Tags,
+		IsPublic:        matter.IsPublic,
+		Date:            matter.Date,
+	}
+	return
+}
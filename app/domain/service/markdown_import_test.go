@@ -0,0 +1,58 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	t.Run(
+		"front matter and body",
+		func(t *testing.T) {
+			data := "---\r\ntitle: testTitle1\r\ncategory: test-category-1\r\nsub-category: test-sub-category-1\r\ntags: [go, sql]\r\nis_public: true\r\ndate: 2022-05-01\r\n---\r\n\r\n# testContent1\r\n"
+
+			ret, err := ParseMarkdown("posts/test-slug-1.md", []byte(data))
+
+			assert.NoError(t, err)
+			assert.Equal(t, dto.PostImportModel{
+				Source:          "posts/test-slug-1.md",
+				Title:           "testTitle1",
+				Slug:            "test-slug-1",
+				Content:         "# testContent1\n",
+				CategorySlug:    "test-category-1",
+				SubCategorySlug: "test-sub-category-1",
+				Tags:            []string{"go", "sql"},
+				IsPublic:        true,
+				Date:            time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+			}, ret)
+		},
+	)
+
+	t.Run(
+		"slug from front matter",
+		func(t *testing.T) {
+			ret, err := ParseMarkdown("a.md", []byte("---\ntitle: testTitle1\nslug: test-slug-2\n---\n"))
+
+			assert.NoError(t, err)
+			assert.Equal(t, "test-slug-2", ret.Slug)
+			assert.Equal(t, "", ret.Content)
+		},
+	)
+
+	t.Run(
+		"invalid",
+		func(t *testing.T) {
+			_, err := ParseMarkdown("a.md", []byte("# no front matter\n"))
+			assert.ErrorIs(t, err, ErrNoFrontMatter)
+
+			_, err = ParseMarkdown("a.md", []byte("---\ntitle: testTitle1\n"))
+			assert.ErrorContains(t, err, "not closed")
+
+			_, err = ParseMarkdown("a.md", []byte("---\ntitel: testTitle1\n---\n"))
+			assert.ErrorContains(t, err, "titel")
+		},
+	)
+}
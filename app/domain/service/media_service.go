@@ -0,0 +1,430 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// mediaUrlPath is where the server serves the media files.
+const mediaUrlPath = "/api/v1/media/"
+
+// defaultMaxMediaSize applies when NewMediaService is given no limit.
+const defaultMaxMediaSize = 10 << 20
+
+// mediaExtensions lists the content types that may be uploaded, with the extension
+// their files get. SVG is left out: it can carry scripts.
+var mediaExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+var (
+	// ErrMediaTooLarge is returned for an upload over the size limit.
+	ErrMediaTooLarge = errors.New("media file too large")
+	// ErrUnsupportedMediaType is returned for an upload whose content is not one of
+	// the accepted types.
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	// ErrInvalidMediaName is returned for a name that is not a path below the media root.
+	ErrInvalidMediaName = errors.New("invalid media name")
+)
+
+type IMediaService interface {
+	Upload(actor dto.UserModel, r io.Reader, altText string) (mediaDto dto.MediaModel, created bool, err error)
+	GetAll(actor dto.UserModel) (mediaDtos []dto.MediaModel, err error)
+	Open(name string, accepted []string) (content io.ReadCloser, mediaDto dto.MediaModel, err error)
+	Update(actor dto.UserModel, name string, mediaInputDto dto.MediaInputModel) (mediaDto dto.MediaModel, err error)
+	Delete(actor dto.UserModel, name string) (err error)
+	GenerateDerivatives(actor dto.UserModel, name string) (mediaDto dto.MediaModel, err error)
+	GetUsage(actor dto.UserModel) (usageDtos []dto.MediaUsageModel, err error)
+	GetUsageByName(actor dto.UserModel, name string) (usageDto dto.MediaUsageModel, err error)
+}
+
+type MediaService struct {
+	repository.IMediaStorage
+	repository.IMediaRepository
+	repository.IImageDerivativeRepository
+	maxSize int64
+}
+
+// NewMediaService accepts uploads of up to maxSize bytes; 0 means 10 MiB.
+func NewMediaService(mediaStorage repository.IMediaStorage, mediaRepository repository.IMediaRepository, imageDerivativeRepository repository.IImageDerivativeRepository, maxSize int64) (mediaService IMediaService) {
+	if maxSize <= 0 {
+		maxSize = defaultMaxMediaSize
+	}
+	mediaService = &MediaService{mediaStorage, mediaRepository, imageDerivativeRepository, maxSize}
+	return
+}
+
+// convertToDto describes the stored object; media is the zero value for files
+// without a record.
+func (s *MediaService) convertToDto(object entity.MediaObject, media entity.Media, derivatives []entity.ImageDerivative) (mediaDto dto.MediaModel) {
+	mediaDto = dto.MediaModel{
+		Name:        object.Name,
+		Url:         mediaUrlPath + object.Name,
+		Size:        object.Size,
+		ContentType: object.ContentType,
+		ModifiedAt:  object.ModifiedAt,
+		Images:      convertToImagesDto(derivatives),
+		Width:       media.Width,
+		Height:      media.Height,
+		AltText:     media.AltText,
+		UploadedBy:  media.UploadedBy,
+	}
+	if !media.CreatedAt.IsZero() {
+		createdAt := media.CreatedAt
+		mediaDto.CreatedAt = &createdAt
+	}
+	return
+}
+
+// Upload stores the file under the SHA-256 of its content and an extension for
+// the type sniffed from it; the name the client gave is ignored. Uploading a file
+// that is stored already returns it with created false. Images are stored without
+// their metadata and get derivatives in every size. The upload is recorded with the
+// alt text and the actor as uploader.
+func (s *MediaService) Upload(actor dto.UserModel, r io.Reader, altText string) (mediaDto dto.MediaModel, created bool, err error) {
+	err = authorize(actor, entity.PermissionUploadMedia)
+	if err != nil {
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return
+	}
+	if int64(len(data)) > s.maxSize {
+		err = fmt.Errorf("%w: the limit is %d bytes", ErrMediaTooLarge, s.maxSize)
+		return
+	}
+	contentType := http.DetectContentType(data)
+	extension, ok := mediaExtensions[contentType]
+	if !ok || len(data) == 0 {
+		err = fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType)
+		return
+	}
+	var img *image.RGBA
+	if imageTypes[contentType] {
+		data, img, err = prepareImage(data, contentType)
+		if err != nil {
+			return
+		}
+	}
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:]) + extension
+
+	object, err := s.IMediaStorage.Stat(name)
+	if err == nil {
+		mediaDto, err = s.withDerivatives(object)
+		return
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	err = s.IMediaStorage.Put(name, bytes.NewReader(data), int64(len(data)), contentType)
+	if err != nil {
+		return
+	}
+	created = true
+	object = entity.MediaObject{Name: name, Size: int64(len(data)), ContentType: contentType, ModifiedAt: time.Now().UTC()}
+	media := entity.Media{Name: name, ContentType: contentType, Size: object.Size, AltText: altText, UploadedBy: actor.Id, CreatedAt: object.ModifiedAt}
+	var derivatives []entity.ImageDerivative
+	if img != nil {
+		media.Width, media.Height = img.Bounds().Dx(), img.Bounds().Dy()
+		derivatives, err = s.storeDerivatives(name, contentType, img)
+		if err != nil {
+			return
+		}
+	}
+	err = s.IMediaRepository.Save(media)
+	if err != nil {
+		return
+	}
+	mediaDto = s.convertToDto(object, media, derivatives)
+	return
+}
+
+// storeDerivatives stores every size of the image and records them.
+func (s *MediaService) storeDerivatives(name string, contentType string, img *image.RGBA) (derivatives []entity.ImageDerivative, err error) {
+	derivatives, files, err := renderDerivatives(name, contentType, img)
+	if err != nil {
+		return
+	}
+	outputType := "image/png"
+	if contentType == "image/jpeg" {
+		outputType = "image/jpeg"
+	}
+	for i, derivative := range derivatives {
+		err = s.IMediaStorage.Put(derivative.Name, bytes.NewReader(files[i]), int64(len(files[i])), outputType)
+		if err != nil {
+			return
+		}
+	}
+	err = s.IImageDerivativeRepository.Save(name, derivatives)
+	return
+}
+
+// withDerivatives describes the stored object with its record and derivatives.
+func (s *MediaService) withDerivatives(object entity.MediaObject) (mediaDto dto.MediaModel, err error) {
+	media, err := s.record(object)
+	if err != nil {
+		return
+	}
+	derivatives, err := s.IImageDerivativeRepository.GetBySource(object.Name)
+	if err != nil {
+		return
+	}
+	mediaDto = s.convertToDto(object, media, derivatives)
+	return
+}
+
+// record returns the record of the stored object, or a new one describing it for
+// files stored without uploading them.
+func (s *MediaService) record(object entity.MediaObject) (media entity.Media, err error) {
+	media, err = s.IMediaRepository.GetByName(object.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		media = entity.Media{Name: object.Name, ContentType: object.ContentType, Size: object.Size, CreatedAt: object.ModifiedAt}
+		err = nil
+	}
+	return
+}
+
+// GenerateDerivatives makes the sizes of an image stored already, e.g. one copied
+// into the media directory by hand, replacing those it has. The image is left as it
+// is; its record is created if it has none.
+func (s *MediaService) GenerateDerivatives(actor dto.UserModel, name string) (mediaDto dto.MediaModel, err error) {
+	err = authorize(actor, entity.PermissionUploadMedia)
+	if err != nil {
+		return
+	}
+	if !validMediaName(name) {
+		err = ErrInvalidMediaName
+		return
+	}
+	content, object, err := s.IMediaStorage.Open(name)
+	if err != nil {
+		return
+	}
+	data, err := io.ReadAll(content)
+	content.Close()
+	if err != nil {
+		return
+	}
+	contentType := http.DetectContentType(data)
+	if !imageTypes[contentType] {
+		err = fmt.Errorf("%w: %s has no derivatives", ErrUnsupportedMediaType, contentType)
+		return
+	}
+	img, _, err := decodeUpright(data, contentType)
+	if err != nil {
+		return
+	}
+	derivatives, err := s.storeDerivatives(name, contentType, img)
+	if err != nil {
+		return
+	}
+	media, err := s.record(object)
+	if err != nil {
+		return
+	}
+	media.ContentType, media.Size = contentType, int64(len(data))
+	media.Width, media.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	err = s.IMediaRepository.Save(media)
+	if err != nil {
+		return
+	}
+	mediaDto = s.convertToDto(object, media, derivatives)
+	return
+}
+
+func (s *MediaService) GetAll(actor dto.UserModel) (mediaDtos []dto.MediaModel, err error) {
+	err = authorize(actor, entity.PermissionUploadMedia)
+	if err != nil {
+		return
+	}
+	objects, err := s.IMediaStorage.List()
+	if err != nil {
+		return
+	}
+	derivatives, err := s.IImageDerivativeRepository.GetAll()
+	if err != nil {
+		return
+	}
+	records, err := s.IMediaRepository.GetAll()
+	if err != nil {
+		return
+	}
+	byName := make(map[string]entity.Media)
+	for _, media := range records {
+		byName[media.Name] = media
+	}
+	sources := make(map[string]string)
+	bySource := make(map[string][]entity.ImageDerivative)
+	for _, derivative := range derivatives {
+		sources[derivative.Name] = derivative.Source
+		bySource[derivative.Source] = append(bySource[derivative.Source], derivative)
+	}
+	stored := make(map[string]bool)
+	for _, object := range objects {
+		stored[object.Name] = true
+	}
+	for _, object := range objects {
+		mediaDto := s.convertToDto(object, byName[object.Name], bySource[object.Name])
+		mediaDto.DerivativeOf = sources[object.Name]
+		mediaDto.VariantOf, _ = variantSource(object.Name, stored)
+		mediaDtos = append(mediaDtos, mediaDto)
+	}
+	return
+}
+
+// Open returns the content of a media file; media files are public. Only the
+// types that can be uploaded are served, files of other types put into the storage
+// by other means yield ErrUnsupportedMediaType. accepted lists the media types and
+// content codings the client accepts; a stored variant of the file in one of them
+// is returned instead of the file, with VariantOf set. The derivatives of the file
+// are not looked up.
+func (s *MediaService) Open(name string, accepted []string) (content io.ReadCloser, mediaDto dto.MediaModel, err error) {
+	if !validMediaName(name) {
+		err = ErrInvalidMediaName
+		return
+	}
+	content, object, err := s.IMediaStorage.Open(name)
+	if err != nil {
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(object.ContentType)
+	if _, ok := mediaExtensions[contentType]; !ok {
+		content.Close()
+		content = nil
+		err = fmt.Errorf("%w: %s is not served", ErrUnsupportedMediaType, object.ContentType)
+		return
+	}
+	object.ContentType = contentType
+	mediaDto = s.convertToDto(object, entity.Media{}, nil)
+	if len(accepted) == 0 {
+		return
+	}
+	variantContent, variantObject, variant, ok, err := s.openVariant(name, contentType, accepted)
+	if err != nil || !ok {
+		// a variant that fails to open leaves the file itself to serve
+		err = nil
+		return
+	}
+	content.Close()
+	content = variantContent
+	mediaDto.Name = variantObject.Name
+	mediaDto.Url = mediaUrlPath + variantObject.Name
+	mediaDto.Size = variantObject.Size
+	mediaDto.ModifiedAt = variantObject.ModifiedAt
+	mediaDto.VariantOf = name
+	mediaDto.ContentEncoding = variant.Encoding
+	if variant.ContentType != "" {
+		mediaDto.ContentType = variant.ContentType
+	}
+	return
+}
+
+// Update changes the alt text of a stored file, recording the file if it has no
+// record yet.
+func (s *MediaService) Update(actor dto.UserModel, name string, mediaInputDto dto.MediaInputModel) (mediaDto dto.MediaModel, err error) {
+	err = authorize(actor, entity.PermissionUploadMedia)
+	if err != nil {
+		return
+	}
+	if !validMediaName(name) {
+		err = ErrInvalidMediaName
+		return
+	}
+	object, err := s.IMediaStorage.Stat(name)
+	if err != nil {
+		return
+	}
+	media, err := s.record(object)
+	if err != nil {
+		return
+	}
+	media.AltText = mediaInputDto.AltText
+	err = s.IMediaRepository.Save(media)
+	if err != nil {
+		return
+	}
+	derivatives, err := s.IImageDerivativeRepository.GetBySource(name)
+	if err != nil {
+		return
+	}
+	mediaDto = s.convertToDto(object, media, derivatives)
+	return
+}
+
+// Delete removes the file together with its derivatives, the variants of both
+// and its record.
+func (s *MediaService) Delete(actor dto.UserModel, name string) (err error) {
+	err = authorize(actor, entity.PermissionDeleteMedia)
+	if err != nil {
+		return
+	}
+	if !validMediaName(name) {
+		err = ErrInvalidMediaName
+		return
+	}
+	err = s.IMediaStorage.Delete(name)
+	if err != nil {
+		return
+	}
+	err = s.IMediaRepository.Delete(name)
+	if err != nil {
+		return
+	}
+	err = s.deleteVariants(name)
+	if err != nil {
+		return
+	}
+	derivatives, err := s.IImageDerivativeRepository.Delete(name)
+	if err != nil {
+		return
+	}
+	for _, derivative := range derivatives {
+		err = s.IMediaStorage.Delete(derivative.Name)
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		if err != nil {
+			return
+		}
+		err = s.deleteVariants(derivative.Name)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// validMediaName accepts clean relative paths without hidden segments, which
+// also rules out "..".
+func validMediaName(name string) bool {
+	if name == "" || path.Clean(name) != name || strings.ContainsRune(name, '\\') {
+		return false
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || strings.HasPrefix(segment, ".") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	mocks "backend/mocks/repository"
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"image/png"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testPNG has no metadata, so it is stored as it is.
+var testPNG = func() string {
+	var buf bytes.Buffer
+	png.Encode(&buf, testImage(4, 2))
+	return buf.String()
+}()
+
+func testPNGName() string {
+	sum := sha256.Sum256([]byte(testPNG))
+	return hex.EncodeToString(sum[:]) + ".png"
+}
+
+func testPNGStem() string {
+	return strings.TrimSuffix(testPNGName(), ".png")
+}
+
+func TestMediaService_Upload(t *testing.T) {
+	t.Run(
+		"stores under the hash of the content",
+		func(t *testing.T) {
+			r := new(mocks.IMediaStorage)
+			r.On("Stat", testPNGName()).Return(entity.MediaObject{}, fs.ErrNotExist).Once()
+			r.On("Put", testPNGName(), mock.MatchedBy(func(content io.Reader) bool {
+				data, _ := io.ReadAll(content)
+				return string(data) == testPNG
+			}), int64(len(testPNG)), "image/png").Return(nil).Once()
+			for _, kind := range []string{"thumbnail", "medium", "og"} {
+				r.On("Put", testPNGStem()+"-"+kind+".png", mock.Anything, mock.Anything, "image/png").Return(nil).Once()
+			}
+			d := new(mocks.IImageDerivativeRepository)
+			d.On("Save", testPNGName(), mock.MatchedBy(func(derivatives []entity.ImageDerivative) bool {
+				return len(derivatives) == 3
+			})).Return(nil).Once()
+			m := new(mocks.IMediaRepository)
+			m.On("Save", mock.MatchedBy(func(media entity.Media) bool {
+				return media.Name == testPNGName() && media.ContentType == "image/png" && media.Size == int64(len(testPNG)) &&
+					media.Width == 4 && media.Height == 2 && media.AltText == "testAlt" && media.UploadedBy == author.Id && !media.CreatedAt.IsZero()
+			})).Return(nil).Once()
+
+			s := NewMediaService(r, m, d, 0)
+			ret, created, err := s.Upload(author, strings.NewReader(testPNG), "testAlt")
+
+			assert.NoError(t, err)
+			assert.True(t, created)
+			assert.Equal(t, testPNGName(), ret.Name)
+			assert.Equal(t, "/api/v1/media/"+testPNGName(), ret.Url)
+			assert.Equal(t, "image/png", ret.ContentType)
+			assert.Equal(t, &dto.ImageModel{Url: "/api/v1/media/" + testPNGStem() + "-thumbnail.png", Width: 2, Height: 2}, ret.Images.Thumbnail)
+			assert.Equal(t, &dto.ImageModel{Url: "/api/v1/media/" + testPNGStem() + "-medium.png", Width: 4, Height: 2}, ret.Images.Medium)
+			assert.Equal(t, 4, ret.Width)
+			assert.Equal(t, "testAlt", ret.AltText)
+			assert.Equal(t, author.Id, ret.UploadedBy)
+			r.AssertExpectations(t)
+			d.AssertExpectations(t)
+			m.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"keeps a file stored already",
+		func(t *testing.T) {
+			r := new(mocks.IMediaStorage)
+			r.On("Stat", testPNGName()).Return(entity.MediaObject{Name: testPNGName(), Size: int64(len(testPNG)), ContentType: "image/png"}, nil).Once()
+			d := new(mocks.IImageDerivativeRepository)
+			d.On("GetBySource", testPNGName()).Return([]entity.ImageDerivative{{Source: testPNGName(), Kind: "og", Name: "og.png", Width: 4, Height: 2}}, nil).Once()
+			m := new(mocks.IMediaRepository)
+			m.On("GetByName", testPNGName()).Return(entity.Media{Name: testPNGName(), AltText: "first"}, nil).Once()
+
+			s := NewMediaService(r, m, d, 0)
+			ret, created, err := s.Upload(author, strings.NewReader(testPNG), "second")
+
+			assert.NoError(t, err)
+			assert.False(t, created)
+			assert.Equal(t, testPNGName(), ret.Name)
+			assert.Equal(t, "/api/v1/media/og.png", ret.Images.Og.Url)
+			assert.Equal(t, "first", ret.AltText)
+			m.AssertNotCalled(t, "Save", mock.Anything)
+			r.AssertNotCalled(t, "Put", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		},
+	)
+
+	t.Run(
+		"rejects files over the limit",
+		func(t *testing.T) {
+			s := NewMediaService(new(mocks.IMediaStorage), new(mocks.IMediaRepository), new(mocks.IImageDerivativeRepository), int64(len(testPNG)-1))
+			_, _, err := s.Upload(author, strings.NewReader(testPNG), "")
+
+			assert.ErrorIs(t, err, ErrMediaTooLarge)
+		},
+	)
+
+	t.Run(
+		"rejects other types by their content",
+		func(t *testing.T) {
+			s := NewMediaService(new(mocks.IMediaStorage), new(mocks.IMediaRepository), new(mocks.IImageDerivativeRepository), 0)
+			for _, content := range []string{"\x89PNG\r\n\x1a\ntestImage", "<svg xmlns=\"http://www.w3.org/2000/svg\"><script/></svg>", "<html><script></script></html>", ""} {
+				_, _, err := s.Upload(author, strings.NewReader(content), "")
+
+				assert.ErrorIs(t, err, ErrUnsupportedMediaType)
+			}
+		},
+	)
+
+	t.Run(
+		"viewers cannot upload",
+		func(t *testing.T) {
+			s := NewMediaService(new(mocks.IMediaStorage), new(mocks.IMediaRepository), new(mocks.IImageDerivativeRepository), 0)
+			_, _, err := s.Upload(viewer, strings.NewReader(testPNG), "")
+
+			assert.ErrorIs(t, err, ErrForbidden)
+		},
+	)
+}
+
+func TestMediaService_GetAll(t *testing.T) {
+	r := new(mocks.IMediaStorage)
+	r.On("List").Return([]entity.MediaObject{
+		{Name: "2019/05/test-og.jpg", Size: 5, ContentType: "image/jpeg"},
+		{Name: "2019/05/test.jpeg", Size: 9, ContentType: "image/jpeg"},
+	}, nil)
+	d := new(mocks.IImageDerivativeRepository)
+	d.On("GetAll").Return([]entity.ImageDerivative{{Source: "2019/05/test.jpeg", Kind: "og", Name: "2019/05/test-og.jpg", Width: 1200, Height: 630}}, nil)
+	createdAt := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	m := new(mocks.IMediaRepository)
+	m.On("GetAll").Return([]entity.Media{{Name: "2019/05/test.jpeg", Width: 1600, Height: 840, AltText: "testAlt", UploadedBy: 3, CreatedAt: createdAt}}, nil)
+
+	s := NewMediaService(r, m, d, 0)
+	ret, err := s.GetAll(author)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.MediaModel{
+		{Name: "2019/05/test-og.jpg", Url: "/api/v1/media/2019/05/test-og.jpg", Size: 5, ContentType: "image/jpeg", DerivativeOf: "2019/05/test.jpeg"},
+		{
+			Name: "2019/05/test.jpeg", Url: "/api/v1/media/2019/05/test.jpeg", Size: 9, ContentType: "image/jpeg",
+			Images: &dto.ImagesModel{Og: &dto.ImageModel{Url: "/api/v1/media/2019/05/test-og.jpg", Width: 1200, Height: 630}},
+			Width:  1600, Height: 840, AltText: "testAlt", UploadedBy: 3, CreatedAt: &createdAt,
+		},
+	}, ret)
+
+	_, err = s.GetAll(anonymous)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestMediaService_Delete(t *testing.T) {
+	t.Run(
+		"editors delete",
+		func(t *testing.T) {
+			r := new(mocks.IMediaStorage)
+			r.On("Delete", "test.png").Return(nil).Once()
+			r.On("Delete", "test-og.png").Return(nil).Once()
+			r.On("Delete", "test-medium.png").Return(fs.ErrNotExist).Once()
+			r.On("Delete", "test.png.webp").Return(nil).Once()
+			r.On("Delete", mock.MatchedBy(func(name string) bool {
+				return strings.HasSuffix(name, ".webp") || strings.HasSuffix(name, ".br") || strings.HasSuffix(name, ".gz")
+			})).Return(fs.ErrNotExist).Times(8)
+			d := new(mocks.IImageDerivativeRepository)
+			d.On("Delete", "test.png").Return([]entity.ImageDerivative{{Name: "test-og.png"}, {Name: "test-medium.png"}}, nil).Once()
+			m := new(mocks.IMediaRepository)
+			m.On("Delete", "test.png").Return(nil).Once()
+
+			s := NewMediaService(r, m, d, 0)
+			err := s.Delete(editor, "test.png")
+
+			assert.NoError(t, err)
+			r.AssertExpectations(t)
+			d.AssertExpectations(t)
+			m.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"authors cannot delete",
+		func(t *testing.T) {
+			s := NewMediaService(new(mocks.IMediaStorage), new(mocks.IMediaRepository), new(mocks.IImageDerivativeRepository), 0)
+			err := s.Delete(author, "test.png")
+
+			assert.ErrorIs(t, err, ErrForbidden)
+		},
+	)
+
+	t.Run(
+		"names stay below the media root",
+		func(t *testing.T) {
+			s := NewMediaService(new(mocks.IMediaStorage), new(mocks.IMediaRepository), new(mocks.IImageDerivativeRepository), 0)
+			for _, name := range []string{"", "../test.png", "/test.png", "a//test.png", "a/./test.png", ".upload-1", "a\\..\\test.png"} {
+				err := s.Delete(editor, name)
+
+				assert.ErrorIs(t, err, ErrInvalidMediaName, name)
+			}
+		},
+	)
+}
+
+func TestMediaService_Update(t *testing.T) {
+	t.Run(
+		"records files stored without uploading",
+		func(t *testing.T) {
+			modifiedAt := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+			r := new(mocks.IMediaStorage)
+			r.On("Stat", "2019/05/test.pdf").Return(entity.MediaObject{Name: "2019/05/test.pdf", Size: 9, ContentType: "application/pdf", ModifiedAt: modifiedAt}, nil).Once()
+			m := new(mocks.IMediaRepository)
+			m.On("GetByName", "2019/05/test.pdf").Return(entity.Media{}, sql.ErrNoRows).Once()
+			m.On("Save", entity.Media{Name: "2019/05/test.pdf", ContentType: "application/pdf", Size: 9, AltText: "testAlt", CreatedAt: modifiedAt}).Return(nil).Once()
+			d := new(mocks.IImageDerivativeRepository)
+			d.On("GetBySource", "2019/05/test.pdf").Return(nil, nil).Once()
+
+			s := NewMediaService(r, m, d, 0)
+			ret, err := s.Update(author, "2019/05/test.pdf", dto.MediaInputModel{AltText: "testAlt"})
+
+			assert.NoError(t, err)
+			assert.Equal(t, "testAlt", ret.AltText)
+			m.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"missing files",
+		func(t *testing.T) {
+			r := new(mocks.IMediaStorage)
+			r.On("Stat", "test.png").Return(entity.MediaObject{}, fs.ErrNotExist).Once()
+
+			s := NewMediaService(r, new(mocks.IMediaRepository), new(mocks.IImageDerivativeRepository), 0)
+			_, err := s.Update(author, "test.png", dto.MediaInputModel{AltText: "testAlt"})
+
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+		},
+	)
+}
+
+func TestMediaService_Open(t *testing.T) {
+	modifiedAt := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run(
+		"serves the file",
+		func(t *testing.T) {
+			r := new(mocks.IMediaStorage)
+			r.On("Open", "abc.png").Return(io.NopCloser(strings.NewReader("testImage")), entity.MediaObject{Name: "abc.png", Size: 9, ContentType: "image/png", ModifiedAt: modifiedAt}, nil)
+
+			s := NewMediaService(r, new(mocks.IMediaRepository), new(mocks.IImageDerivativeRepository), 0)
+			content, ret, err := s.Open("abc.png", nil)
+
+			assert.NoError(t, err)
+			data, _ := io.ReadAll(content)
+			assert.Equal(t, "testImage", string(data))
+			assert.Equal(t, dto.MediaModel{Name: "abc.png", Url: "/api/v1/media/abc.png", Size: 9, ContentType: "image/png", ModifiedAt: modifiedAt}, ret)
+		},
+	)
+
+	t.Run(
+		"serves only the types that can be uploaded",
+		func(t *testing.T) {
+			r := new(mocks.IMediaStorage)
+			for name, contentType := range map[string]string{"page.html": "text/html; charset=utf-8", "logo.svg": "image/svg+xml", "notes": ""} {
+				r.On("Open", name).Return(io.NopCloser(strings.NewReader("test")), entity.MediaObject{Name: name, ContentType: contentType}, nil)
+			}
+
+			s := NewMediaService(r, new(mocks.IMediaRepository), new(mocks.IImageDerivativeRepository), 0)
+			for _, name := range []string{"page.html", "logo.svg", "notes"} {
+				_, _, err := s.Open(name, nil)
+
+				assert.ErrorIs(t, err, ErrUnsupportedMediaType, name)
+			}
+		},
+	)
+
+	t.Run(
+		"serves the first accepted variant stored",
+		func(t *testing.T) {
+			r := new(mocks.IMediaStorage)
+			r.On("Open", "abc.png").Return(io.NopCloser(strings.NewReader("testImage")), entity.MediaObject{Name: "abc.png", Size: 9, ContentType: "image/png"}, nil)
+			r.On("Open", "abc.png.webp").Return(nil, entity.MediaObject{}, fs.ErrNotExist).Once()
+			r.On("Open", "abc.png.gz").Return(io.NopCloser(strings.NewReader("gz")), entity.MediaObject{Name: "abc.png.gz", Size: 2, ContentType: "application/gzip", ModifiedAt: modifiedAt}, nil).Once()
+
+			s := NewMediaService(r, new(mocks.IMediaRepository), new(mocks.IImageDerivativeRepository), 0)
+			content, ret, err := s.Open("abc.png", []string{"gzip", "deflate", "image/webp"})
+
+			assert.NoError(t, err)
+			data, _ := io.ReadAll(content)
+			assert.Equal(t, "gz", string(data))
+			assert.Equal(t, dto.MediaModel{
+				Name: "abc.png.gz", Url: "/api/v1/media/abc.png.gz", Size: 2, ContentType: "image/png", ModifiedAt: modifiedAt,
+				VariantOf: "abc.png", ContentEncoding: "gzip",
+			}, ret)
+			r.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"WebP stands in for images only",
+		func(t *testing.T) {
+			r := new(mocks.IMediaStorage)
+			r.On("Open", "abc.pdf").Return(io.NopCloser(strings.NewReader("testDocument")), entity.MediaObject{Name: "abc.pdf", Size: 12, ContentType: "application/pdf"}, nil)
+
+			s := NewMediaService(r, new(mocks.IMediaRepository), new(mocks.IImageDerivativeRepository), 0)
+			_, ret, err := s.Open("abc.pdf", []string{"image/webp"})
+
+			assert.NoError(t, err)
+			assert.Equal(t, "abc.pdf", ret.Name)
+			r.AssertNotCalled(t, "Open", "abc.pdf.webp")
+		},
+	)
+}
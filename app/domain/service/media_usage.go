@@ -0,0 +1,199 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// mediaReferencePattern finds the media URLs in post content, relative or absolute.
+// The path ends at whitespace, quotes, brackets, a query or a fragment.
+var mediaReferencePattern = regexp.MustCompile(regexp.QuoteMeta(mediaUrlPath) + `([^\s"'()<>\[\]?#\\]+)`)
+
+// mediaReference is a reference of a post to the media file name.
+type mediaReference struct {
+	field string
+	name  string
+}
+
+// unescapeMediaName decodes the escapes of a name taken from a URL.
+func unescapeMediaName(name string) string {
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	return name
+}
+
+// imageReference returns the media file an image field refers to. The field holds
+// a media name or URL; ok is false for anything else, e.g. an image elsewhere.
+func imageReference(value string) (name string, ok bool) {
+	value = strings.TrimSpace(value)
+	if match := mediaReferencePattern.FindStringSubmatch(value); match != nil {
+		name = unescapeMediaName(match[1])
+	} else if value != "" && !strings.Contains(value, ":") && !strings.HasPrefix(value, "/") {
+		name = unescapeMediaName(value)
+	}
+	ok = validMediaName(name)
+	return
+}
+
+// mediaReferences returns the media files a post refers to, each once per field.
+// eye_catching_img holds a media name or URL, the content media URLs.
+func mediaReferences(post entity.Post) (references []mediaReference) {
+	seen := make(map[mediaReference]bool)
+	add := func(field string, name string) {
+		reference := mediaReference{field, name}
+		if validMediaName(name) && !seen[reference] {
+			seen[reference] = true
+			references = append(references, reference)
+		}
+	}
+	if name, ok := imageReference(post.EyeCatchingImg); ok {
+		add("eye_catching_img", name)
+	}
+	for _, match := range mediaReferencePattern.FindAllStringSubmatch(post.Content, -1) {
+		// punctuation ending a sentence is not part of the URL
+		add("content", unescapeMediaName(strings.TrimRight(match[1], ".,;:!")))
+	}
+	return
+}
+
+// GetUsage lists every stored file but the derivatives and variants with the posts,
+// categories and users using it.
+func (s *MediaService) GetUsage(actor dto.UserModel) (usageDtos []dto.MediaUsageModel, err error) {
+	err = authorize(actor, entity.PermissionUploadMedia)
+	if err != nil {
+		return
+	}
+	usageDtos, _, err = s.usage()
+	return
+}
+
+// usage lists the usage of the stored files but the derivatives and variants, and
+// returns the source of each derivative and variant.
+func (s *MediaService) usage() (usageDtos []dto.MediaUsageModel, sources map[string]string, err error) {
+	objects, err := s.IMediaStorage.List()
+	if err != nil {
+		return
+	}
+	derivatives, err := s.IImageDerivativeRepository.GetAll()
+	if err != nil {
+		return
+	}
+	posts, err := s.IMediaRepository.GetReferringPosts()
+	if err != nil {
+		return
+	}
+	categories, err := s.IMediaRepository.GetReferringCategories()
+	if err != nil {
+		return
+	}
+	users, err := s.IMediaRepository.GetReferringUsers()
+	if err != nil {
+		return
+	}
+	sources = make(map[string]string)
+	for _, derivative := range derivatives {
+		sources[derivative.Name] = derivative.Source
+	}
+	stored := make(map[string]bool)
+	for _, object := range objects {
+		stored[object.Name] = true
+	}
+	for _, object := range objects {
+		if source, ok := variantSource(object.Name, stored); ok {
+			if derivativeSource, ok := sources[source]; ok {
+				source = derivativeSource
+			}
+			sources[object.Name] = source
+		}
+	}
+	uses := make(map[string][]dto.MediaUseModel)
+	addUse := func(use dto.MediaUseModel) {
+		used := use.Name
+		if source, ok := sources[used]; ok {
+			used = source
+		}
+		uses[used] = append(uses[used], use)
+	}
+	for _, post := range posts {
+		for _, reference := range mediaReferences(post) {
+			addUse(dto.MediaUseModel{
+				PostId:    post.Id,
+				PostSlug:  post.Slug,
+				PostTitle: post.Title,
+				Field:     reference.field,
+				Name:      reference.name,
+			})
+		}
+	}
+	for _, category := range categories {
+		if name, ok := imageReference(category.CoverImage); ok {
+			addUse(dto.MediaUseModel{
+				CategoryId:   category.Id,
+				CategorySlug: category.Slug,
+				CategoryName: category.Name,
+				Field:        "cover_image",
+				Name:         name,
+			})
+		}
+	}
+	for _, user := range users {
+		if name, ok := imageReference(user.AvatarUrl); ok {
+			addUse(dto.MediaUseModel{
+				UserId:   user.Id,
+				Username: user.Name,
+				Field:    "avatar_url",
+				Name:     name,
+			})
+		}
+	}
+	for _, object := range objects {
+		if _, ok := sources[object.Name]; ok {
+			continue
+		}
+		usedBy := uses[object.Name]
+		if usedBy == nil {
+			usedBy = []dto.MediaUseModel{}
+		}
+		usageDtos = append(usageDtos, dto.MediaUsageModel{
+			Name:   object.Name,
+			Url:    mediaUrlPath + object.Name,
+			Size:   object.Size,
+			UsedBy: usedBy,
+		})
+	}
+	return
+}
+
+// GetUsageByName returns the usage of a stored file; for a derivative or variant
+// that of the file it was made of.
+func (s *MediaService) GetUsageByName(actor dto.UserModel, name string) (usageDto dto.MediaUsageModel, err error) {
+	err = authorize(actor, entity.PermissionUploadMedia)
+	if err != nil {
+		return
+	}
+	if !validMediaName(name) {
+		err = ErrInvalidMediaName
+		return
+	}
+	usageDtos, sources, err := s.usage()
+	if err != nil {
+		return
+	}
+	if source, ok := sources[name]; ok {
+		name = source
+	}
+	for _, usageDto = range usageDtos {
+		if usageDto.Name == name {
+			return
+		}
+	}
+	usageDto = dto.MediaUsageModel{}
+	err = fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	return
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	mocks "backend/mocks/repository"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaReferences(t *testing.T) {
+	post := entity.Post{
+		EyeCatchingImg: "https://blog.example.com/api/v1/media/abc.jpg?v=2",
+		Content: "![cat](/api/v1/media/2019/05/cat%20photo.jpg \"cat\") and <img src='/api/v1/media/abc.jpg'>.\n" +
+			"See /api/v1/media/def.pdf. Not /api/v1/media/../etc/passwd or https://example.com/cat.jpg.",
+	}
+
+	assert.Equal(t, []mediaReference{
+		{"eye_catching_img", "abc.jpg"},
+		{"content", "2019/05/cat photo.jpg"},
+		{"content", "abc.jpg"},
+		{"content", "def.pdf"},
+	}, mediaReferences(post))
+
+	assert.Equal(t, []mediaReference{{"eye_catching_img", "2019/05/abc.jpg"}}, mediaReferences(entity.Post{EyeCatchingImg: "2019/05/abc.jpg"}))
+	assert.Nil(t, mediaReferences(entity.Post{EyeCatchingImg: "https://example.com/abc.jpg"}))
+	assert.Nil(t, mediaReferences(entity.Post{EyeCatchingImg: "/images/abc.jpg"}))
+}
+
+func TestMediaService_GetUsage(t *testing.T) {
+	newService := func() IMediaService {
+		r := new(mocks.IMediaStorage)
+		r.On("List").Return([]entity.MediaObject{
+			{Name: "abc-thumbnail.jpg", Size: 1},
+			{Name: "abc-thumbnail.jpg.webp", Size: 1},
+			{Name: "abc.jpg", Size: 2},
+			{Name: "abc.jpg.webp", Size: 1},
+			{Name: "def.pdf", Size: 3},
+			{Name: "def.pdf.gz", Size: 1},
+			{Name: "cover.png", Size: 4},
+			{Name: "avatars/alice.jpg", Size: 5},
+		}, nil)
+		d := new(mocks.IImageDerivativeRepository)
+		d.On("GetAll").Return([]entity.ImageDerivative{{Source: "abc.jpg", Kind: "thumbnail", Name: "abc-thumbnail.jpg"}}, nil)
+		m := new(mocks.IMediaRepository)
+		m.On("GetReferringPosts").Return([]entity.Post{
+			{Id: 1, Slug: "first", Title: "First", EyeCatchingImg: "abc.jpg"},
+			{Id: 2, Slug: "second", Title: "Second", Content: "![](/api/v1/media/abc-thumbnail.jpg) ![](/api/v1/media/gone.png)"},
+		}, nil)
+		m.On("GetReferringCategories").Return([]entity.Category{
+			{Id: 3, Slug: "go", Name: "Go", CoverImage: "https://blog.example.com/api/v1/media/cover.png"},
+			{Id: 4, Slug: "rust", Name: "Rust", CoverImage: "https://example.com/rust.png"},
+		}, nil)
+		m.On("GetReferringUsers").Return([]entity.User{{Id: 5, Name: "alice", AvatarUrl: "avatars/alice.jpg"}}, nil)
+		return NewMediaService(r, m, d, 0)
+	}
+	uses := []dto.MediaUseModel{
+		{PostId: 1, PostSlug: "first", PostTitle: "First", Field: "eye_catching_img", Name: "abc.jpg"},
+		{PostId: 2, PostSlug: "second", PostTitle: "Second", Field: "content", Name: "abc-thumbnail.jpg"},
+	}
+
+	ret, err := newService().GetUsage(author)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []dto.MediaUsageModel{
+		{Name: "abc.jpg", Url: "/api/v1/media/abc.jpg", Size: 2, UsedBy: uses},
+		{Name: "def.pdf", Url: "/api/v1/media/def.pdf", Size: 3, UsedBy: []dto.MediaUseModel{}},
+		{Name: "cover.png", Url: "/api/v1/media/cover.png", Size: 4, UsedBy: []dto.MediaUseModel{
+			{CategoryId: 3, CategorySlug: "go", CategoryName: "Go", Field: "cover_image", Name: "cover.png"},
+		}},
+		{Name: "avatars/alice.jpg", Url: "/api/v1/media/avatars/alice.jpg", Size: 5, UsedBy: []dto.MediaUseModel{
+			{UserId: 5, Username: "alice", Field: "avatar_url", Name: "avatars/alice.jpg"},
+		}},
+	}, ret)
+
+	t.Run(
+		"by name",
+		func(t *testing.T) {
+			for _, name := range []string{"abc-thumbnail.jpg", "abc-thumbnail.jpg.webp", "abc.jpg.webp"} {
+				ret, err := newService().GetUsageByName(author, name)
+				assert.NoError(t, err)
+				assert.Equal(t, "abc.jpg", ret.Name)
+				assert.Equal(t, uses, ret.UsedBy)
+			}
+
+			_, err = newService().GetUsageByName(author, "gone.png")
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+		},
+	)
+
+	t.Run(
+		"viewers cannot see usage",
+		func(t *testing.T) {
+			s := NewMediaService(new(mocks.IMediaStorage), new(mocks.IMediaRepository), new(mocks.IImageDerivativeRepository), 0)
+
+			_, err := s.GetUsage(viewer)
+			assert.ErrorIs(t, err, ErrForbidden)
+
+			_, err = s.GetUsageByName(viewer, "abc.jpg")
+			assert.ErrorIs(t, err, ErrForbidden)
+		},
+	)
+}
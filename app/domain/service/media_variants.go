@@ -0,0 +1,83 @@
+package service
+
+import (
+	"backend/app/domain/entity"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// mediaVariant is another representation of a media file, stored next to it
+// under its name plus Suffix by other tools, e.g. cwebp or brotli. Accept is the
+// media type or content coding a client has to accept for it.
+type mediaVariant struct {
+	Accept      string
+	Suffix      string
+	ContentType string
+	Encoding    string
+}
+
+// mediaVariants are tried in this order, the first stored one the client accepts
+// is served. WebP only stands in for the image types it can replace.
+var mediaVariants = []mediaVariant{
+	{Accept: "image/webp", Suffix: ".webp", ContentType: "image/webp"},
+	{Accept: "br", Suffix: ".br", Encoding: "br"},
+	{Accept: "gzip", Suffix: ".gz", Encoding: "gzip"},
+}
+
+func (v mediaVariant) appliesTo(contentType string) bool {
+	if v.ContentType == "image/webp" {
+		return contentType == "image/jpeg" || contentType == "image/png" || contentType == "image/gif"
+	}
+	return true
+}
+
+// variantSource returns the file a variant was made of, if it is stored.
+func variantSource(name string, stored map[string]bool) (source string, ok bool) {
+	for _, variant := range mediaVariants {
+		source = strings.TrimSuffix(name, variant.Suffix)
+		if source != name && stored[source] {
+			ok = true
+			return
+		}
+	}
+	source = ""
+	return
+}
+
+// openVariant opens the first variant of the file that the client accepts and
+// that is stored; ok is false when there is none.
+func (s *MediaService) openVariant(name string, contentType string, accepted []string) (content io.ReadCloser, object entity.MediaObject, variant mediaVariant, ok bool, err error) {
+	isAccepted := make(map[string]bool)
+	for _, accept := range accepted {
+		isAccepted[accept] = true
+	}
+	for _, variant = range mediaVariants {
+		if !isAccepted[variant.Accept] || !variant.appliesTo(contentType) {
+			continue
+		}
+		content, object, err = s.IMediaStorage.Open(name + variant.Suffix)
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+			continue
+		}
+		ok = err == nil
+		return
+	}
+	return
+}
+
+// deleteVariants deletes the variants stored for the file.
+func (s *MediaService) deleteVariants(name string) (err error) {
+	for _, variant := range mediaVariants {
+		err = s.IMediaStorage.Delete(name + variant.Suffix)
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
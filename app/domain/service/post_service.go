@@ -6,12 +6,16 @@ import (
 	"backend/app/domain/repository"
 )
 
+// ErrConflict is returned by the updates of posts and categories that were given a
+// version the stored one has moved past.
+var ErrConflict = repository.ErrConflict
+
 type IPostService interface {
 	GetPosts(map[string][]string) ([]dto.PostModel, error)
 	GetPostBySlug(string) (dto.PostModel, error)
-	Create(dto.PostModel) error
-	Update(dto.PostModel) error
-	Delete(dto.PostModel) error
+	Create(dto.UserModel, dto.PostModel) error
+	Update(dto.UserModel, dto.PostModel) error
+	Delete(dto.UserModel, dto.PostModel) error
 }
 
 type PostService struct {
@@ -37,10 +41,14 @@ func (s *PostService) convertToDtoFromEntity(post entity.Post) (postDto dto.Post
 		CategoryId:      post.CategoryId,
 		CategoryName:    post.CategoryName,
 		CategorySlug:    post.CategorySlug,
-		SubCategoryId:   post.SubCategoryId,
-		SubCategoryName: post.SubCategoryName,
-		SubCategorySlug: post.SubCategorySlug,
+		AuthorId:        post.AuthorId,
+		Version:         post.Version,
+	}
+	if post.AuthorId != 0 {
+		author := dto.NewAuthorModel(post.AuthorName, post.AuthorDisplayName, "", post.AuthorAvatarUrl)
+		postDto.Author = &author
 	}
+	postDto.Images = convertToImagesDto(post.Images)
 	return
 }
 
@@ -66,9 +74,8 @@ func (s *PostService) convertToEntityFromDto(postDto dto.PostModel) (post entity
 		CategoryId:      postDto.CategoryId,
 		CategoryName:    postDto.CategoryName,
 		CategorySlug:    postDto.CategorySlug,
-		SubCategoryId:   postDto.SubCategoryId,
-		SubCategoryName: postDto.SubCategoryName,
-		SubCategorySlug: postDto.SubCategorySlug,
+		AuthorId:        postDto.AuthorId,
+		Version:         postDto.Version,
 	}
 	return
 }
@@ -99,20 +106,78 @@ func (s *PostService) GetPostBySlug(slug string) (postDto dto.PostModel, err err
 	return
 }
 
-func (s *PostService) Create(postDto dto.PostModel) (err error) {
+// Create files the post under the acting user. Only roles allowed to publish
+// may create a post that is already public.
+func (s *PostService) Create(actor dto.UserModel, postDto dto.PostModel) (err error) {
+	err = authorize(actor, entity.PermissionCreatePost)
+	if err != nil {
+		return
+	}
+	if postDto.IsPublic {
+		err = authorize(actor, entity.PermissionPublishPost)
+		if err != nil {
+			return
+		}
+	}
 	post := s.convertToEntityFromDto(postDto)
+	post.AuthorId = actor.Id
+	err = validatePost(post)
+	if err != nil {
+		return
+	}
 	err = s.IPostRepository.Create(post)
 	return
 }
 
-func (s *PostService) Update(postDto dto.PostModel) (err error) {
+// Update lets authors edit their own posts and editors edit any post.
+// Changing is_public requires the publish permission. A non-zero Version must be
+// the stored one, or the update fails with ErrConflict.
+func (s *PostService) Update(actor dto.UserModel, postDto dto.PostModel) (err error) {
+	stored, err := s.authorizeOwned(actor, postDto.Id, entity.PermissionEditOwnPost, entity.PermissionEditAnyPost)
+	if err != nil {
+		return
+	}
+	if postDto.IsPublic != stored.IsPublic {
+		err = authorize(actor, entity.PermissionPublishPost)
+		if err != nil {
+			return
+		}
+	}
 	post := s.convertToEntityFromDto(postDto)
+	err = validatePost(post)
+	if err != nil {
+		return
+	}
 	err = s.IPostRepository.Update(post)
 	return
 }
 
-func (s *PostService) Delete(postDto dto.PostModel) (err error) {
+func (s *PostService) Delete(actor dto.UserModel, postDto dto.PostModel) (err error) {
+	_, err = s.authorizeOwned(actor, postDto.Id, entity.PermissionDeleteOwnPost, entity.PermissionDeleteAnyPost)
+	if err != nil {
+		return
+	}
 	post := s.convertToEntityFromDto(postDto)
 	err = s.IPostRepository.Delete(post)
 	return
 }
+
+// authorizeOwned loads the stored post and checks ownPermission when the actor
+// wrote it, anyPermission otherwise. The stored author wins over whatever the
+// request body claims.
+func (s *PostService) authorizeOwned(actor dto.UserModel, postId int, ownPermission entity.Permission, anyPermission entity.Permission) (stored entity.Post, err error) {
+	if actor.Role == "" {
+		err = ErrUnauthenticated
+		return
+	}
+	stored, err = s.IPostRepository.GetPostById(postId)
+	if err != nil {
+		return
+	}
+	if stored.AuthorId != 0 && stored.AuthorId == actor.Id {
+		err = authorize(actor, ownPermission)
+	} else {
+		err = authorize(actor, anyPermission)
+	}
+	return
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"backend/app/common/dto"
 	"backend/app/domain/entity"
+	"backend/app/domain/repository"
 	mocks "backend/mocks/repository"
 	"testing"
 	"time"
@@ -14,7 +15,6 @@ func AssertPosts(t *testing.T, ret []dto.PostModel, posts []entity.Post) {
 	for i, r := range ret {
 		assert.Equal(t, r.Id, posts[i].Id)
 		assert.Equal(t, r.CategoryId, posts[i].CategoryId)
-		assert.Equal(t, r.SubCategoryId, posts[i].SubCategoryId)
 		assert.Equal(t, r.Title, posts[i].Title)
 		assert.Equal(t, r.Slug, posts[i].Slug)
 		assert.Equal(t, r.EyeCatchingImg, posts[i].EyeCatchingImg)
@@ -44,9 +44,6 @@ func TestPostService_GetPosts(t *testing.T) {
 			CategoryId:      1,
 			CategoryName:    "testCategory1",
 			CategorySlug:    "test-category-1",
-			SubCategoryId:   1,
-			SubCategoryName: "testSubCategory1",
-			SubCategorySlug: "test-sub-category-1",
 		},
 		{
 			Id:              2,
@@ -61,9 +58,6 @@ func TestPostService_GetPosts(t *testing.T) {
 			CategoryId:      1,
 			CategoryName:    "testCategory1",
 			CategorySlug:    "test-category-1",
-			SubCategoryId:   1,
-			SubCategoryName: "testSubCategory1",
-			SubCategorySlug: "test-sub-category-1",
 		},
 	}
 
@@ -150,9 +144,6 @@ func TestPostService_CRUD(t *testing.T) {
 		CategoryId:      1,
 		CategoryName:    "testCategory1",
 		CategorySlug:    "test-category-1",
-		SubCategoryId:   1,
-		SubCategoryName: "testSubCategory1",
-		SubCategorySlug: "test-sub-category-1",
 	}
 
 	postDto := dto.PostModel{
@@ -168,9 +159,6 @@ func TestPostService_CRUD(t *testing.T) {
 		CategoryId:      1,
 		CategoryName:    "testCategory1",
 		CategorySlug:    "test-category-1",
-		SubCategoryId:   1,
-		SubCategoryName: "testSubCategory1",
-		SubCategorySlug: "test-sub-category-1",
 	}
 
 	t.Run(
@@ -196,9 +184,6 @@ func TestPostService_CRUD(t *testing.T) {
 			assert.Equal(t, ret.CategoryId, post.CategoryId)
 			assert.Equal(t, ret.CategoryName, post.CategoryName)
 			assert.Equal(t, ret.CategorySlug, post.CategorySlug)
-			assert.Equal(t, ret.SubCategoryId, post.SubCategoryId)
-			assert.Equal(t, ret.SubCategoryName, post.SubCategoryName)
-			assert.Equal(t, ret.SubCategorySlug, post.SubCategorySlug)
 			r.AssertExpectations(t)
 		},
 	)
@@ -208,11 +193,13 @@ func TestPostService_CRUD(t *testing.T) {
 		func(t *testing.T) {
 			r := new(mocks.IPostRepository)
 
-			r.On("Create", post).Return(nil)
+			created := post
+			created.AuthorId = editor.Id
+			r.On("Create", created).Return(nil)
 
 			s := NewPostService(r)
 
-			err := s.Create(postDto)
+			err := s.Create(editor, postDto)
 
 			assert.NoError(t, err)
 			r.AssertExpectations(t)
@@ -224,29 +211,100 @@ func TestPostService_CRUD(t *testing.T) {
 		func(t *testing.T) {
 			r := new(mocks.IPostRepository)
 
+			r.On("GetPostById", post.Id).Return(post, nil)
 			r.On("Update", post).Return(nil)
 
 			s := NewPostService(r)
 
-			err := s.Update(postDto)
+			err := s.Update(editor, postDto)
 
 			assert.NoError(t, err)
 			r.AssertExpectations(t)
 		},
 	)
 
+	t.Run(
+		"Update of a changed version",
+		func(t *testing.T) {
+			r := new(mocks.IPostRepository)
+
+			versioned := post
+			versioned.Version = 2
+			r.On("GetPostById", post.Id).Return(post, nil)
+			r.On("Update", versioned).Return(repository.ErrConflict)
+
+			s := NewPostService(r)
+
+			versionedDto := postDto
+			versionedDto.Version = 2
+			err := s.Update(editor, versionedDto)
+
+			assert.ErrorIs(t, err, ErrConflict)
+			r.AssertExpectations(t)
+		},
+	)
+
 	t.Run(
 		"Delete",
 		func(t *testing.T) {
 			r := new(mocks.IPostRepository)
 
+			r.On("GetPostById", post.Id).Return(post, nil)
 			r.On("Delete", post).Return(nil)
 
 			s := NewPostService(r)
 
-			err := s.Delete(postDto)
+			err := s.Delete(editor, postDto)
+
+			assert.NoError(t, err)
+			r.AssertExpectations(t)
+		},
+	)
+}
+
+func TestPostService_Author(t *testing.T) {
+	t.Run(
+		"with author",
+		func(t *testing.T) {
+			post := entity.Post{
+				Id:                1,
+				Slug:              "test-post-1",
+				AuthorId:          3,
+				AuthorName:        "testauthor",
+				AuthorDisplayName: "Test Author",
+				AuthorAvatarUrl:   "test_author.png",
+			}
+
+			r := new(mocks.IPostRepository)
+
+			r.On("GetPostBySlug", post.Slug).Return(post, nil)
+
+			s := NewPostService(r)
+
+			ret, err := s.GetPostBySlug(post.Slug)
+
+			assert.NoError(t, err)
+			assert.Equal(t, ret.AuthorId, 3)
+			assert.Equal(t, *ret.Author, dto.NewAuthorModel("testauthor", "Test Author", "", "test_author.png"))
+			r.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"without author",
+		func(t *testing.T) {
+			post := entity.Post{Id: 1, Slug: "test-post-1"}
+
+			r := new(mocks.IPostRepository)
+
+			r.On("GetPostBySlug", post.Slug).Return(post, nil)
+
+			s := NewPostService(r)
+
+			ret, err := s.GetPostBySlug(post.Slug)
 
 			assert.NoError(t, err)
+			assert.Nil(t, ret.Author)
 			r.AssertExpectations(t)
 		},
 	)
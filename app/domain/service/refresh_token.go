@@ -0,0 +1,133 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	accessTokenLifetime  = 15 * time.Minute
+	refreshTokenLifetime = 30 * 24 * time.Hour
+)
+
+// ErrRefreshTokenReused is returned when a refresh token that was already rotated
+// or revoked is presented again. Every session of its user is revoked when it happens.
+var ErrRefreshTokenReused = fmt.Errorf("%w: refresh token reuse detected", ErrUnauthenticated)
+
+// newOpaqueToken returns 32 random bytes, URL-safe encoded.
+func newOpaqueToken() (token string, err error) {
+	b := make([]byte, 32)
+	_, err = rand.Read(b)
+	if err != nil {
+		return
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueTokenPair signs an access token and stores a new refresh token in the family.
+func (s *UserService) issueTokenPair(userId int, familyId string) (authTokenModel dto.AuthTokenModel, err error) {
+	accessToken, err := s.signAccessToken(userId)
+	if err != nil {
+		return
+	}
+	refreshToken, err := newOpaqueToken()
+	if err != nil {
+		return
+	}
+	err = s.IRefreshTokenRepository.Create(entity.NewRefreshToken(userId, familyId, hashRefreshToken(refreshToken), time.Now().Add(refreshTokenLifetime)))
+	if err != nil {
+		return
+	}
+	authTokenModel = dto.AuthTokenModel{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenLifetime.Seconds()),
+	}
+	return
+}
+
+// Refresh rotates the refresh token: the presented token is revoked and a new
+// pair is issued in the same family.
+func (s *UserService) Refresh(refreshTokenDto dto.RefreshTokenModel) (authTokenModel dto.AuthTokenModel, err error) {
+	stored, err := s.IRefreshTokenRepository.GetByHash(hashRefreshToken(refreshTokenDto.RefreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrUnauthenticated
+		return
+	}
+	if err != nil {
+		return
+	}
+	if stored.Revoked {
+		err = s.revokeReused(stored)
+		return
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		err = ErrUnauthenticated
+		return
+	}
+	revoked, err := s.IRefreshTokenRepository.Revoke(stored.Id)
+	if err != nil {
+		return
+	}
+	// another request rotated the token between the lookup and the revoke
+	if !revoked {
+		err = s.revokeReused(stored)
+		return
+	}
+	authTokenModel, err = s.issueTokenPair(stored.UserId, stored.FamilyId)
+	return
+}
+
+// revokeReused ends every session of the token's user, since a rotated token
+// showing up again means it has leaked.
+func (s *UserService) revokeReused(stored entity.RefreshToken) (err error) {
+	err = s.IRefreshTokenRepository.RevokeAllForUser(stored.UserId)
+	if err != nil {
+		return
+	}
+	err = ErrRefreshTokenReused
+	return
+}
+
+// Logout revokes the whole family of the presented refresh token.
+func (s *UserService) Logout(refreshTokenDto dto.RefreshTokenModel) (err error) {
+	stored, err := s.IRefreshTokenRepository.GetByHash(hashRefreshToken(refreshTokenDto.RefreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrUnauthenticated
+		return
+	}
+	if err != nil {
+		return
+	}
+	err = s.IRefreshTokenRepository.RevokeFamily(stored.FamilyId)
+	return
+}
+
+// RevokeAllTokens revokes every refresh token of the user. Access tokens already
+// issued stay valid until they expire, at most accessTokenLifetime later.
+func (s *UserService) RevokeAllTokens(actor dto.UserModel, username string) (err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
+	user, err := s.IUserRepository.GetByUsername(username)
+	if err != nil {
+		return
+	}
+	err = s.IRefreshTokenRepository.RevokeAllForUser(user.Id)
+	return
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	mocks "backend/mocks/repository"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserService_IssueToken(t *testing.T) {
+
+	r := new(mocks.IUserRepository)
+	tr := new(mocks.IRefreshTokenRepository)
+
+	tr.On("Create", mock.MatchedBy(func(refreshToken entity.RefreshToken) bool {
+		return refreshToken.UserId == 1 && refreshToken.FamilyId != "" && refreshToken.ExpiresAt.After(time.Now())
+	})).Return(nil)
+	r.On("GetById", 1).Return(entity.NewUser(1, "testuser1", "", entity.RoleAuthor), nil)
+
+	s := NewUserService(r, tr, new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+	ret, err := s.IssueToken(1)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret.RefreshToken)
+	assert.Equal(t, ret.ExpiresIn, int(accessTokenLifetime.Seconds()))
+	stored := tr.Calls[0].Arguments.Get(0).(entity.RefreshToken)
+	assert.Equal(t, stored.TokenHash, hashRefreshToken(ret.RefreshToken))
+	assert.NotEqual(t, stored.TokenHash, ret.RefreshToken)
+
+	userDto, err := s.ValidateToken(dto.NewAuthTokenModel(ret.Token))
+
+	assert.NoError(t, err)
+	assert.Equal(t, userDto.Id, 1)
+	tr.AssertExpectations(t)
+}
+
+func TestUserService_Refresh(t *testing.T) {
+
+	stored := entity.RefreshToken{
+		Id:        7,
+		UserId:    1,
+		FamilyId:  "family",
+		TokenHash: hashRefreshToken("refresh"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	t.Run(
+		"rotates the token",
+		func(t *testing.T) {
+			tr := new(mocks.IRefreshTokenRepository)
+
+			tr.On("GetByHash", hashRefreshToken("refresh")).Return(stored, nil)
+			tr.On("Revoke", 7).Return(true, nil)
+			tr.On("Create", mock.MatchedBy(func(refreshToken entity.RefreshToken) bool {
+				return refreshToken.UserId == 1 && refreshToken.FamilyId == "family"
+			})).Return(nil)
+
+			s := NewUserService(new(mocks.IUserRepository), tr, new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			ret, err := s.Refresh(dto.RefreshTokenModel{RefreshToken: "refresh"})
+
+			assert.NoError(t, err)
+			assert.NotEqual(t, ret.RefreshToken, "refresh")
+			tr.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"reused token revokes every session",
+		func(t *testing.T) {
+			reused := stored
+			reused.Revoked = true
+
+			tr := new(mocks.IRefreshTokenRepository)
+
+			tr.On("GetByHash", hashRefreshToken("refresh")).Return(reused, nil)
+			tr.On("RevokeAllForUser", 1).Return(nil)
+
+			s := NewUserService(new(mocks.IUserRepository), tr, new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			_, err := s.Refresh(dto.RefreshTokenModel{RefreshToken: "refresh"})
+
+			assert.ErrorIs(t, err, ErrRefreshTokenReused)
+			assert.ErrorIs(t, err, ErrUnauthenticated)
+			tr.AssertExpectations(t)
+			tr.AssertNotCalled(t, "Create", mock.Anything)
+		},
+	)
+
+	t.Run(
+		"concurrent rotation counts as reuse",
+		func(t *testing.T) {
+			tr := new(mocks.IRefreshTokenRepository)
+
+			tr.On("GetByHash", hashRefreshToken("refresh")).Return(stored, nil)
+			tr.On("Revoke", 7).Return(false, nil)
+			tr.On("RevokeAllForUser", 1).Return(nil)
+
+			s := NewUserService(new(mocks.IUserRepository), tr, new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			_, err := s.Refresh(dto.RefreshTokenModel{RefreshToken: "refresh"})
+
+			assert.ErrorIs(t, err, ErrRefreshTokenReused)
+			tr.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"expired token",
+		func(t *testing.T) {
+			expired := stored
+			expired.ExpiresAt = time.Now().Add(-time.Hour)
+
+			tr := new(mocks.IRefreshTokenRepository)
+
+			tr.On("GetByHash", hashRefreshToken("refresh")).Return(expired, nil)
+
+			s := NewUserService(new(mocks.IUserRepository), tr, new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			_, err := s.Refresh(dto.RefreshTokenModel{RefreshToken: "refresh"})
+
+			assert.ErrorIs(t, err, ErrUnauthenticated)
+			tr.AssertNotCalled(t, "Revoke", 7)
+		},
+	)
+
+	t.Run(
+		"unknown token",
+		func(t *testing.T) {
+			tr := new(mocks.IRefreshTokenRepository)
+
+			tr.On("GetByHash", hashRefreshToken("unknown")).Return(entity.RefreshToken{}, sql.ErrNoRows)
+
+			s := NewUserService(new(mocks.IUserRepository), tr, new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			_, err := s.Refresh(dto.RefreshTokenModel{RefreshToken: "unknown"})
+
+			assert.ErrorIs(t, err, ErrUnauthenticated)
+		},
+	)
+}
+
+func TestUserService_Logout(t *testing.T) {
+	tr := new(mocks.IRefreshTokenRepository)
+
+	tr.On("GetByHash", hashRefreshToken("refresh")).Return(entity.RefreshToken{Id: 7, FamilyId: "family"}, nil)
+	tr.On("RevokeFamily", "family").Return(nil)
+
+	s := NewUserService(new(mocks.IUserRepository), tr, new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+	assert.NoError(t, s.Logout(dto.RefreshTokenModel{RefreshToken: "refresh"}))
+	tr.AssertExpectations(t)
+}
+
+func TestUserService_RevokeAllTokens(t *testing.T) {
+	t.Run(
+		"as admin",
+		func(t *testing.T) {
+			r := new(mocks.IUserRepository)
+			tr := new(mocks.IRefreshTokenRepository)
+
+			r.On("GetByUsername", "testuser5").Return(entity.NewUser(5, "testuser5", "", entity.RoleAuthor), nil)
+			tr.On("RevokeAllForUser", 5).Return(nil)
+
+			s := NewUserService(r, tr, new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			assert.NoError(t, s.RevokeAllTokens(admin, "testuser5"))
+			r.AssertExpectations(t)
+			tr.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"as editor",
+		func(t *testing.T) {
+			tr := new(mocks.IRefreshTokenRepository)
+
+			s := NewUserService(new(mocks.IUserRepository), tr, new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			assert.ErrorIs(t, s.RevokeAllTokens(editor, "testuser5"), ErrForbidden)
+			tr.AssertNotCalled(t, "RevokeAllForUser", 5)
+		},
+	)
+}
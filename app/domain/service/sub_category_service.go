@@ -9,9 +9,10 @@ import (
 type ISubCategoryService interface {
 	GetSubCategories(map[string][]string) ([]dto.SubCategoryModel, error)
 	GetSubCategoryBySlug(string) (dto.SubCategoryModel, error)
-	Create(dto.SubCategoryModel) error
-	Update(dto.SubCategoryModel) error
-	Delete(dto.SubCategoryModel) error
+	GetSubCategoryDetailBySlug(string) (dto.SubCategoryModel, error)
+	Create(dto.UserModel, dto.SubCategoryModel) error
+	Update(dto.UserModel, dto.SubCategoryModel) error
+	Delete(dto.UserModel, dto.SubCategoryModel) error
 }
 
 type SubCategoryService struct {
@@ -31,6 +32,12 @@ func (s *SubCategoryService) convertToDtoFromEntity(subCategory entity.SubCatego
 		ParentCategoryId:   subCategory.ParentCategoryId,
 		ParentCategoryName: subCategory.ParentCategoryName,
 		ParentCategorySlug: subCategory.ParentCategorySlug,
+		Position:           subCategory.Position,
+		Description:        subCategory.Description,
+		CoverImage:         subCategory.CoverImage,
+		Children:           s.convertToDtosFromEntities(subCategory.Children),
+		Stats:              convertToStatsDtoFromEntity(subCategory.Stats),
+		Version:            subCategory.Version,
 	}
 	return
 }
@@ -51,6 +58,10 @@ func (s *SubCategoryService) convertToEntityFromDto(subCategoryDto dto.SubCatego
 		ParentCategoryId:   subCategoryDto.ParentCategoryId,
 		ParentCategoryName: subCategoryDto.ParentCategoryName,
 		ParentCategorySlug: subCategoryDto.ParentCategorySlug,
+		Position:           subCategoryDto.Position,
+		Description:        subCategoryDto.Description,
+		CoverImage:         subCategoryDto.CoverImage,
+		Version:            subCategoryDto.Version,
 	}
 	return
 }
@@ -63,11 +74,19 @@ func (s *SubCategoryService) convertToEntitiesFromDtos(subCategoryDtos []dto.Sub
 	return
 }
 
+// GetSubCategories lists sub-categories; with the "with-counts=true" query param
+// each one carries the public post statistics of its subtree.
 func (s *SubCategoryService) GetSubCategories(queryParams map[string][]string) (subCategoryDtos []dto.SubCategoryModel, err error) {
 	subCategories, err := s.ISubCategoryRepository.GetSubCategories(queryParams)
 	if err != nil {
 		return
 	}
+	if withCounts, ok := queryParams["with-counts"]; ok && withCounts[0] == "true" {
+		err = s.attachStats(subCategories)
+		if err != nil {
+			return
+		}
+	}
 	subCategoryDtos = s.convertToDtosFromEntities(subCategories)
 	return
 }
@@ -81,19 +100,100 @@ func (s *SubCategoryService) GetSubCategoryBySlug(slug string) (subCategoryDto d
 	return
 }
 
-func (s *SubCategoryService) Create(subCategoryDto dto.SubCategoryModel) (err error) {
+// GetSubCategoryDetailBySlug returns the sub-category with its public post statistics and direct children.
+func (s *SubCategoryService) GetSubCategoryDetailBySlug(slug string) (subCategoryDto dto.SubCategoryModel, err error) {
+	subCategory, err := s.ISubCategoryRepository.GetSubCategoryBySlug(slug)
+	if err != nil {
+		return
+	}
+	subCategories := []entity.SubCategory{subCategory}
+	err = s.attachStats(subCategories)
+	if err != nil {
+		return
+	}
+	subCategory = subCategories[0]
+	subCategory.Children, err = s.ISubCategoryRepository.GetSubCategories(map[string][]string{"category-name": {slug}})
+	if err != nil {
+		return
+	}
+	subCategoryDto = s.convertToDtoFromEntity(subCategory)
+	return
+}
+
+func (s *SubCategoryService) attachStats(subCategories []entity.SubCategory) (err error) {
+	var ids []int
+	for _, subCategory := range subCategories {
+		ids = append(ids, subCategory.Id)
+	}
+	stats, err := s.ISubCategoryRepository.GetStats(ids)
+	if err != nil {
+		return
+	}
+	for i := range subCategories {
+		stat := stats[subCategories[i].Id]
+		subCategories[i].Stats = &stat
+	}
+	return
+}
+
+func (s *SubCategoryService) Create(actor dto.UserModel, subCategoryDto dto.SubCategoryModel) (err error) {
+	err = authorize(actor, entity.PermissionManageCategories)
+	if err != nil {
+		return
+	}
 	subCategory := s.convertToEntityFromDto(subCategoryDto)
+	err = validateSubCategory(subCategory)
+	if err != nil {
+		return
+	}
 	err = s.ISubCategoryRepository.Create(subCategory)
 	return
 }
 
-func (s *SubCategoryService) Update(subCategoryDto dto.SubCategoryModel) (err error) {
+func (s *SubCategoryService) Update(actor dto.UserModel, subCategoryDto dto.SubCategoryModel) (err error) {
+	err = authorize(actor, entity.PermissionManageCategories)
+	if err != nil {
+		return
+	}
 	subCategory := s.convertToEntityFromDto(subCategoryDto)
+	err = validateSubCategory(subCategory)
+	if err != nil {
+		return
+	}
+	err = s.checkParent(subCategory)
+	if err != nil {
+		return
+	}
 	err = s.ISubCategoryRepository.Update(subCategory)
 	return
 }
 
-func (s *SubCategoryService) Delete(subCategoryDto dto.SubCategoryModel) (err error) {
+// checkParent rejects a reparenting that would make the sub-category its own
+// ancestor, as CategoryService.checkParent does.
+func (s *SubCategoryService) checkParent(subCategory entity.SubCategory) (err error) {
+	if subCategory.ParentCategoryId == 0 {
+		return
+	}
+	if subCategory.ParentCategoryId == subCategory.Id {
+		return ErrCategoryCycle
+	}
+	ancestorIds, err := s.ISubCategoryRepository.GetAncestorIds(subCategory.ParentCategoryId)
+	if err != nil {
+		return
+	}
+	for _, ancestorId := range ancestorIds {
+		if ancestorId == subCategory.Id {
+			return ErrCategoryCycle
+		}
+	}
+	return
+}
+
+func (s *SubCategoryService) Delete(actor dto.UserModel, subCategoryDto dto.SubCategoryModel) (err error) {
+	err = authorize(actor, entity.PermissionManageCategories)
+	if err != nil {
+		return
+	}
 	subCategory := s.convertToEntityFromDto(subCategoryDto)
 	err = s.ISubCategoryRepository.Delete(subCategory)
 	return
@@ -5,8 +5,10 @@ import (
 	"backend/app/domain/entity"
 	mocks "backend/mocks/repository"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func assertSubCategories(t *testing.T, ret []dto.SubCategoryModel, subCategories []entity.SubCategory) {
@@ -132,7 +134,7 @@ func TestSubCategoryService_CRUD(t *testing.T) {
 
 			s := NewSubCategoryService(r)
 
-			err := s.Create(subCategoryDto)
+			err := s.Create(editor, subCategoryDto)
 
 			assert.NoError(t, err)
 			r.AssertExpectations(t)
@@ -142,19 +144,55 @@ func TestSubCategoryService_CRUD(t *testing.T) {
 	t.Run(
 		"Update",
 		func(t *testing.T) {
+			updated := subCategory
+			updated.Id = 2
+			updatedDto := subCategoryDto
+			updatedDto.Id = 2
+
 			r := new(mocks.ISubCategoryRepository)
 
-			r.On("Update", subCategory).Return(nil)
+			r.On("GetAncestorIds", 1).Return([]int{1}, nil)
+			r.On("Update", updated).Return(nil)
 
 			s := NewSubCategoryService(r)
 
-			err := s.Update(subCategoryDto)
+			err := s.Update(editor, updatedDto)
 
 			assert.NoError(t, err)
 			r.AssertExpectations(t)
 		},
 	)
 
+	t.Run(
+		"Update under a descendant",
+		func(t *testing.T) {
+			moved := subCategoryDto
+			moved.Id = 2
+			moved.ParentCategoryId = 4
+
+			r := new(mocks.ISubCategoryRepository)
+
+			r.On("GetAncestorIds", 4).Return([]int{1, 2, 4}, nil)
+
+			s := NewSubCategoryService(r)
+
+			assert.ErrorIs(t, s.Update(editor, moved), ErrCategoryCycle)
+			r.AssertNotCalled(t, "Update", mock.Anything)
+		},
+	)
+
+	t.Run(
+		"Update under itself",
+		func(t *testing.T) {
+			r := new(mocks.ISubCategoryRepository)
+
+			s := NewSubCategoryService(r)
+
+			assert.ErrorIs(t, s.Update(editor, subCategoryDto), ErrCategoryCycle)
+			r.AssertNotCalled(t, "Update", mock.Anything)
+		},
+	)
+
 	t.Run(
 		"Delete",
 		func(t *testing.T) {
@@ -164,9 +202,97 @@ func TestSubCategoryService_CRUD(t *testing.T) {
 
 			s := NewSubCategoryService(r)
 
-			err := s.Delete(subCategoryDto)
+			err := s.Delete(editor, subCategoryDto)
+
+			assert.NoError(t, err)
+			r.AssertExpectations(t)
+		},
+	)
+}
+
+func TestSubCategoryService_Counts(t *testing.T) {
+	latestPostAt, _ := time.Parse("2006-01-02 15:04:05.999999-07", "2006-01-02 15:04:05.999999-07")
+
+	subCategories := []entity.SubCategory{
+		{
+			Id:                 2,
+			Name:               "testSubCategory1",
+			Slug:               "test-sub-category-1",
+			ParentCategoryId:   1,
+			ParentCategoryName: "testCategory1",
+			ParentCategorySlug: "test-category-1",
+		},
+		{
+			Id:                 3,
+			Name:               "testSubCategory2",
+			Slug:               "test-sub-category-2",
+			ParentCategoryId:   1,
+			ParentCategoryName: "testCategory1",
+			ParentCategorySlug: "test-category-1",
+		},
+	}
+
+	stats := map[int]entity.CategoryStats{
+		2: {PostCount: 2, LatestPostAt: latestPostAt},
+		3: {PostCount: 0},
+	}
+
+	t.Run(
+		"GetSubCategories with-counts",
+		func(t *testing.T) {
+			r := new(mocks.ISubCategoryRepository)
+
+			queryParams := map[string][]string{
+				"with-counts": {
+					"true",
+				},
+			}
+
+			r.On("GetSubCategories", queryParams).Return(subCategories, nil)
+			r.On("GetStats", []int{2, 3}).Return(stats, nil)
+
+			s := NewSubCategoryService(r)
+
+			ret, err := s.GetSubCategories(queryParams)
+
+			assert.NoError(t, err)
+			assertSubCategories(t, ret, subCategories)
+			assert.Equal(t, ret[0].Stats.PostCount, 2)
+			assert.Equal(t, *ret[0].Stats.LatestPostAt, latestPostAt)
+			assert.Equal(t, ret[1].Stats.PostCount, 0)
+			assert.Nil(t, ret[1].Stats.LatestPostAt)
+			r.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"GetSubCategoryDetailBySlug",
+		func(t *testing.T) {
+			r := new(mocks.ISubCategoryRepository)
+
+			children := []entity.SubCategory{
+				{
+					Id:                 4,
+					Name:               "testSubSubCategory1",
+					Slug:               "test-sub-sub-category-1",
+					ParentCategoryId:   2,
+					ParentCategoryName: "testSubCategory1",
+					ParentCategorySlug: "test-sub-category-1",
+				},
+			}
+
+			r.On("GetSubCategoryBySlug", subCategories[0].Slug).Return(subCategories[0], nil)
+			r.On("GetStats", []int{2}).Return(stats, nil)
+			r.On("GetSubCategories", map[string][]string{"category-name": {subCategories[0].Slug}}).Return(children, nil)
+
+			s := NewSubCategoryService(r)
+
+			ret, err := s.GetSubCategoryDetailBySlug(subCategories[0].Slug)
 
 			assert.NoError(t, err)
+			assert.Equal(t, ret.Id, subCategories[0].Id)
+			assert.Equal(t, ret.Stats.PostCount, 2)
+			assertSubCategories(t, ret.Children, children)
 			r.AssertExpectations(t)
 		},
 	)
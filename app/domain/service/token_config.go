@@ -0,0 +1,239 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/joho/godotenv"
+)
+
+// ErrInvalidToken is returned for an access token that is malformed, badly signed,
+// expired or issued for another issuer or audience.
+var ErrInvalidToken = fmt.Errorf("%w: invalid token", ErrUnauthenticated)
+
+const (
+	defaultIssuer   = "golang_blog_API"
+	defaultAudience = "golang_blog_API"
+	// clockSkew is the leeway granted when comparing exp, iat and nbf with the clock.
+	clockSkew = 30 * time.Second
+)
+
+// SigningKey is one key of the ring. Tokens name the key that signed them in
+// their kid header.
+type SigningKey struct {
+	Id        string
+	Method    jwt.SigningMethod
+	SignKey   interface{}
+	VerifyKey interface{}
+}
+
+func NewHMACKey(id string, secret []byte) (signingKey SigningKey) {
+	signingKey = SigningKey{
+		Id:        id,
+		Method:    jwt.SigningMethodHS256,
+		SignKey:   secret,
+		VerifyKey: secret,
+	}
+	return
+}
+
+func NewEd25519Key(id string, privateKey ed25519.PrivateKey) (signingKey SigningKey) {
+	signingKey = SigningKey{
+		Id:        id,
+		Method:    SigningMethodEdDSA,
+		SignKey:   privateKey,
+		VerifyKey: privateKey.Public(),
+	}
+	return
+}
+
+// TokenConfig describes how access tokens are signed and verified. New tokens are
+// signed with SigningKeyId; tokens signed with any key in Keys are accepted, so a
+// key can be rotated out by switching SigningKeyId first and removing it later.
+type TokenConfig struct {
+	Issuer       string
+	Audience     string
+	SigningKeyId string
+	Keys         map[string]SigningKey
+}
+
+func NewTokenConfig(issuer string, audience string, signingKeyId string, keys ...SigningKey) (tokenConfig TokenConfig) {
+	tokenConfig = TokenConfig{
+		Issuer:       issuer,
+		Audience:     audience,
+		SigningKeyId: signingKeyId,
+		Keys:         map[string]SigningKey{},
+	}
+	for _, key := range keys {
+		tokenConfig.Keys[key.Id] = key
+	}
+	return
+}
+
+// TokenConfigFromEnv reads the key ring from the environment, loading the .env file
+// first when present.
+//
+// JWT_KEYS lists keys as comma separated "kid:alg:base64 key" entries, where alg is
+// HS256 (the secret) or EdDSA (a 32 byte seed or 64 byte private key).
+// JWT_SIGNING_KEY picks the kid new tokens are signed with and defaults to the first
+// entry. Without JWT_KEYS, SECRET_KEY is used as a single HS256 key named "default".
+// JWT_ISSUER and JWT_AUDIENCE default to "golang_blog_API"; they are filled in
+// even when the keys are missing or malformed.
+func TokenConfigFromEnv() (tokenConfig TokenConfig, err error) {
+	godotenv.Load(fmt.Sprint(".env", os.Getenv("GO_ENV")))
+	tokenConfig = NewTokenConfig(envOr("JWT_ISSUER", defaultIssuer), envOr("JWT_AUDIENCE", defaultAudience), "")
+
+	var keys []SigningKey
+	if spec := os.Getenv("JWT_KEYS"); spec != "" {
+		keys, err = parseSigningKeys(spec)
+		if err != nil {
+			return
+		}
+	} else if secret := os.Getenv("SECRET_KEY"); secret != "" {
+		keys = []SigningKey{NewHMACKey("default", []byte(secret))}
+	} else {
+		err = errors.New("no signing keys configured: set JWT_KEYS or SECRET_KEY")
+		return
+	}
+
+	signingKeyId := os.Getenv("JWT_SIGNING_KEY")
+	if signingKeyId == "" {
+		signingKeyId = keys[0].Id
+	}
+	tokenConfig = NewTokenConfig(tokenConfig.Issuer, tokenConfig.Audience, signingKeyId, keys...)
+	if _, ok := tokenConfig.Keys[signingKeyId]; !ok {
+		err = fmt.Errorf("JWT_SIGNING_KEY %q is not in JWT_KEYS", signingKeyId)
+	}
+	return
+}
+
+func envOr(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func parseSigningKeys(spec string) (keys []SigningKey, err error) {
+	for _, entry := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(parts) != 3 {
+			err = fmt.Errorf("malformed JWT_KEYS entry %q, expected kid:alg:key", entry)
+			return
+		}
+		id, alg := parts[0], parts[1]
+		var material []byte
+		material, err = base64.StdEncoding.DecodeString(parts[2])
+		if err != nil {
+			err = fmt.Errorf("key %q: %w", id, err)
+			return
+		}
+		switch {
+		case alg == "HS256":
+			keys = append(keys, NewHMACKey(id, material))
+		case alg == "EdDSA" && len(material) == ed25519.SeedSize:
+			keys = append(keys, NewEd25519Key(id, ed25519.NewKeyFromSeed(material)))
+		case alg == "EdDSA" && len(material) == ed25519.PrivateKeySize:
+			keys = append(keys, NewEd25519Key(id, ed25519.PrivateKey(material)))
+		default:
+			err = fmt.Errorf("key %q: unsupported algorithm %q or key size", id, alg)
+			return
+		}
+	}
+	return
+}
+
+// Token purposes. Access tokens carry none; a token signed for one purpose is
+// rejected everywhere else.
+const (
+	purposeAccess    = ""
+	purposeTwoFactor = "2fa"
+)
+
+// accessClaims are the claims of a signed token.
+type accessClaims struct {
+	UserId  int    `json:"user_id"`
+	Purpose string `json:"purpose,omitempty"`
+	jwt.StandardClaims
+}
+
+func (c TokenConfig) sign(userId int, purpose string, lifetime time.Duration, now time.Time) (tokenString string, err error) {
+	key, ok := c.Keys[c.SigningKeyId]
+	if !ok {
+		err = fmt.Errorf("signing key %q is not configured", c.SigningKeyId)
+		return
+	}
+	claims := accessClaims{
+		UserId:  userId,
+		Purpose: purpose,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    c.Issuer,
+			Audience:  c.Audience,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(lifetime).Unix(),
+		},
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.Id
+	tokenString, err = token.SignedString(key.SignKey)
+	return
+}
+
+// verify checks the signature with the key named by kid and then every claim,
+// including that the token was signed for purpose. All failures wrap ErrInvalidToken.
+func (c TokenConfig) verify(tokenString string, purpose string, now time.Time) (claims accessClaims, err error) {
+	parser := jwt.Parser{SkipClaimsValidation: true}
+	_, err = parser.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := c.Keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		// the key decides the algorithm, never the token
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %q for key %q", token.Method.Alg(), kid)
+		}
+		return key.VerifyKey, nil
+	})
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrInvalidToken, err)
+		return
+	}
+	err = c.validateClaims(claims, purpose, now)
+	return
+}
+
+func (c TokenConfig) validateClaims(claims accessClaims, purpose string, now time.Time) (err error) {
+	var problem string
+	switch {
+	case claims.ExpiresAt == 0:
+		problem = "missing exp claim"
+	case now.Add(-clockSkew).Unix() > claims.ExpiresAt:
+		problem = "token is expired"
+	case claims.IssuedAt == 0:
+		problem = "missing iat claim"
+	case now.Add(clockSkew).Unix() < claims.IssuedAt:
+		problem = "token is issued in the future"
+	case claims.NotBefore != 0 && now.Add(clockSkew).Unix() < claims.NotBefore:
+		problem = "token is not valid yet"
+	case claims.Issuer != c.Issuer:
+		problem = "unexpected issuer"
+	case claims.Audience != c.Audience:
+		problem = "unexpected audience"
+	case claims.UserId == 0:
+		problem = "missing user_id claim"
+	case claims.Purpose != purpose:
+		problem = "token is not valid for this use"
+	}
+	if problem != "" {
+		err = fmt.Errorf("%w: %s", ErrInvalidToken, problem)
+	}
+	return
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+var testTokenConfig = NewTokenConfig("test-issuer", "test-audience", "k1", NewHMACKey("k1", []byte("testsecret")))
+
+func TestTokenConfig_SignAndVerify(t *testing.T) {
+	now := time.Now()
+	edKey := NewEd25519Key("ed1", ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)))
+
+	t.Run(
+		"hmac",
+		func(t *testing.T) {
+			token, err := testTokenConfig.sign(1, purposeAccess, accessTokenLifetime, now)
+			assert.NoError(t, err)
+
+			claims, err := testTokenConfig.verify(token, purposeAccess, now)
+
+			assert.NoError(t, err)
+			assert.Equal(t, claims.UserId, 1)
+		},
+	)
+
+	t.Run(
+		"ed25519",
+		func(t *testing.T) {
+			config := NewTokenConfig("test-issuer", "test-audience", "ed1", edKey)
+			token, err := config.sign(1, purposeAccess, accessTokenLifetime, now)
+			assert.NoError(t, err)
+
+			claims, err := config.verify(token, purposeAccess, now)
+
+			assert.NoError(t, err)
+			assert.Equal(t, claims.UserId, 1)
+		},
+	)
+
+	t.Run(
+		"rotated key is still accepted",
+		func(t *testing.T) {
+			token, err := testTokenConfig.sign(1, purposeAccess, accessTokenLifetime, now)
+			assert.NoError(t, err)
+			rotated := NewTokenConfig("test-issuer", "test-audience", "ed1", edKey, testTokenConfig.Keys["k1"])
+
+			_, err = rotated.verify(token, purposeAccess, now)
+
+			assert.NoError(t, err)
+		},
+	)
+
+	t.Run(
+		"removed key is rejected",
+		func(t *testing.T) {
+			token, err := testTokenConfig.sign(1, purposeAccess, accessTokenLifetime, now)
+			assert.NoError(t, err)
+			config := NewTokenConfig("test-issuer", "test-audience", "ed1", edKey)
+
+			_, err = config.verify(token, purposeAccess, now)
+
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		},
+	)
+
+	t.Run(
+		"algorithm must match the key",
+		func(t *testing.T) {
+			// an HS256 token claiming the kid of an Ed25519 key
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims(now))
+			token.Header["kid"] = "ed1"
+			tokenString, err := token.SignedString([]byte("testsecret"))
+			assert.NoError(t, err)
+			config := NewTokenConfig("test-issuer", "test-audience", "ed1", edKey)
+
+			_, err = config.verify(tokenString, purposeAccess, now)
+
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		},
+	)
+
+	t.Run(
+		"missing kid",
+		func(t *testing.T) {
+			tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims(now)).SignedString([]byte("testsecret"))
+			assert.NoError(t, err)
+
+			_, err = testTokenConfig.verify(tokenString, purposeAccess, now)
+
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		},
+	)
+}
+
+func validClaims(now time.Time) accessClaims {
+	return accessClaims{
+		UserId: 1,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    "test-issuer",
+			Audience:  "test-audience",
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(accessTokenLifetime).Unix(),
+		},
+	}
+}
+
+func TestTokenConfig_ValidateClaims(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		modify func(claims *accessClaims)
+		valid  bool
+	}{
+		{"valid", func(claims *accessClaims) {}, true},
+		{"within clock skew", func(claims *accessClaims) { claims.ExpiresAt = now.Add(-clockSkew / 2).Unix() }, true},
+		{"without nbf", func(claims *accessClaims) { claims.NotBefore = 0 }, true},
+		{"expired", func(claims *accessClaims) { claims.ExpiresAt = now.Add(-time.Hour).Unix() }, false},
+		{"missing exp", func(claims *accessClaims) { claims.ExpiresAt = 0 }, false},
+		{"missing iat", func(claims *accessClaims) { claims.IssuedAt = 0 }, false},
+		{"issued in the future", func(claims *accessClaims) { claims.IssuedAt = now.Add(time.Hour).Unix() }, false},
+		{"not valid yet", func(claims *accessClaims) { claims.NotBefore = now.Add(time.Hour).Unix() }, false},
+		{"wrong issuer", func(claims *accessClaims) { claims.Issuer = "other" }, false},
+		{"wrong audience", func(claims *accessClaims) { claims.Audience = "other" }, false},
+		{"missing user_id", func(claims *accessClaims) { claims.UserId = 0 }, false},
+		{"other purpose", func(claims *accessClaims) { claims.Purpose = purposeTwoFactor }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims(now)
+			tt.modify(&claims)
+
+			err := testTokenConfig.validateClaims(claims, purposeAccess, now)
+
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+				assert.ErrorIs(t, err, ErrUnauthenticated)
+			}
+		})
+	}
+}
+
+func TestTokenConfigFromEnv(t *testing.T) {
+	t.Run(
+		"key ring",
+		func(t *testing.T) {
+			seed := base64.StdEncoding.EncodeToString(make([]byte, ed25519.SeedSize))
+			t.Setenv("JWT_KEYS", "old:HS256:"+base64.StdEncoding.EncodeToString([]byte("oldsecret"))+",new:EdDSA:"+seed)
+			t.Setenv("JWT_SIGNING_KEY", "new")
+
+			config, err := TokenConfigFromEnv()
+
+			assert.NoError(t, err)
+			assert.Equal(t, config.SigningKeyId, "new")
+			assert.Len(t, config.Keys, 2)
+			assert.Equal(t, config.Keys["new"].Method, SigningMethodEdDSA)
+			assert.Equal(t, config.Issuer, defaultIssuer)
+		},
+	)
+
+	t.Run(
+		"falls back to SECRET_KEY",
+		func(t *testing.T) {
+			t.Setenv("JWT_KEYS", "")
+			t.Setenv("JWT_SIGNING_KEY", "")
+			t.Setenv("SECRET_KEY", "testsecret")
+
+			config, err := TokenConfigFromEnv()
+
+			assert.NoError(t, err)
+			assert.Equal(t, config.SigningKeyId, "default")
+		},
+	)
+
+	t.Run(
+		"unknown signing key",
+		func(t *testing.T) {
+			t.Setenv("JWT_KEYS", "k1:HS256:"+base64.StdEncoding.EncodeToString([]byte("secret")))
+			t.Setenv("JWT_SIGNING_KEY", "k2")
+
+			_, err := TokenConfigFromEnv()
+
+			assert.Error(t, err)
+		},
+	)
+
+	t.Run(
+		"malformed entry",
+		func(t *testing.T) {
+			t.Setenv("JWT_KEYS", "k1:HS256")
+
+			_, err := TokenConfigFromEnv()
+
+			assert.Error(t, err)
+		},
+	)
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	totpDigits = 6
+	totpPeriod = 30 * time.Second
+	// totpSkew is the number of steps a code may be early or late.
+	totpSkew          = 1
+	mfaTokenLifetime  = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+var (
+	// ErrInvalidTwoFactorCode is returned for a wrong, reused or expired TOTP or recovery code.
+	ErrInvalidTwoFactorCode = fmt.Errorf("%w: invalid two-factor code", ErrUnauthenticated)
+	// ErrTwoFactorNotEnabled is returned when resetting 2FA of a user without it.
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newTotpSecret returns 160 random bits, the key size RFC 4226 recommends.
+func newTotpSecret() (secret string, err error) {
+	b := make([]byte, 20)
+	_, err = rand.Read(b)
+	if err != nil {
+		return
+	}
+	secret = base32NoPadding.EncodeToString(b)
+	return
+}
+
+// totpCode computes the RFC 6238 code of a time step: HOTP (RFC 4226) with SHA-1.
+func totpCode(secret string, step int64) (code string, err error) {
+	key, err := base32NoPadding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	truncated := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	code = fmt.Sprintf("%0*d", totpDigits, truncated%1000000)
+	return
+}
+
+func totpStep(now time.Time) int64 {
+	return now.Unix() / int64(totpPeriod.Seconds())
+}
+
+// matchTotp returns the step the code belongs to, looking totpSkew steps around now.
+func matchTotp(secret string, code string, now time.Time) (step int64, ok bool) {
+	current := totpStep(now)
+	for candidate := current - totpSkew; candidate <= current+totpSkew; candidate++ {
+		expected, err := totpCode(secret, candidate)
+		if err != nil {
+			return
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			step, ok = candidate, true
+		}
+	}
+	return
+}
+
+// totpUri builds the otpauth URI authenticator apps read from a QR code.
+func totpUri(issuer string, account string, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// newRecoveryCode returns 50 random bits as two groups of five base32 characters.
+func newRecoveryCode() (code string, err error) {
+	b := make([]byte, 7)
+	_, err = rand.Read(b)
+	if err != nil {
+		return
+	}
+	encoded := strings.ToLower(base32NoPadding.EncodeToString(b))[:10]
+	code = encoded[:5] + "-" + encoded[5:]
+	return
+}
+
+// hashRecoveryCode ignores case, spaces and dashes, so codes can be typed loosely.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *UserService) twoFactorEnabled(userId int) (enabled bool, err error) {
+	_, err = s.ITwoFactorRepository.GetByUserId(userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+		return
+	}
+	enabled = err == nil
+	return
+}
+
+// NewTwoFactorSecret generates a secret for the user to add to an authenticator app.
+// Nothing is stored until EnableTwoFactor confirms a code of it.
+func (s *UserService) NewTwoFactorSecret(actor dto.UserModel, username string) (enrollmentDto dto.TwoFactorEnrollmentModel, err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
+	secret, err := newTotpSecret()
+	if err != nil {
+		return
+	}
+	enrollmentDto = dto.TwoFactorEnrollmentModel{
+		Secret: secret,
+		Uri:    totpUri(s.tokenConfig.Issuer, username, secret),
+	}
+	return
+}
+
+// EnableTwoFactor stores the secret once code proves the authenticator app holds it,
+// and returns the recovery codes. They are shown only this once; only hashes are stored.
+func (s *UserService) EnableTwoFactor(actor dto.UserModel, username string, secret string, code string) (recoveryCodes []string, err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
+	user, err := s.IUserRepository.GetByUsername(username)
+	if err != nil {
+		return
+	}
+	step, ok := matchTotp(secret, code, time.Now())
+	if !ok {
+		err = ErrInvalidTwoFactorCode
+		return
+	}
+	var hashes []string
+	for i := 0; i < recoveryCodeCount; i++ {
+		var recoveryCode string
+		recoveryCode, err = newRecoveryCode()
+		if err != nil {
+			return
+		}
+		recoveryCodes = append(recoveryCodes, recoveryCode)
+		hashes = append(hashes, hashRecoveryCode(recoveryCode))
+	}
+	twoFactor := entity.NewTwoFactor(user.Id, secret)
+	err = s.ITwoFactorRepository.Enable(twoFactor, hashes)
+	if err != nil {
+		recoveryCodes = nil
+		return
+	}
+	// the confirmation code must not work a second time
+	_, err = s.ITwoFactorRepository.UseStep(user.Id, step)
+	return
+}
+
+// ResetTwoFactor removes the secret and recovery codes of a user, e.g. after a lost phone.
+func (s *UserService) ResetTwoFactor(actor dto.UserModel, username string) (err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
+	user, err := s.IUserRepository.GetByUsername(username)
+	if err != nil {
+		return
+	}
+	enabled, err := s.twoFactorEnabled(user.Id)
+	if err != nil {
+		return
+	}
+	if !enabled {
+		err = ErrTwoFactorNotEnabled
+		return
+	}
+	err = s.ITwoFactorRepository.Disable(user.Id)
+	return
+}
+
+// TwoFactorChallenge is called after a correct password. For users with 2FA enabled
+// it returns a short-lived mfa_token for the second step; otherwise Required is false.
+func (s *UserService) TwoFactorChallenge(userDto dto.UserModel) (challengeDto dto.TwoFactorChallengeModel, err error) {
+	enabled, err := s.twoFactorEnabled(userDto.Id)
+	if err != nil || !enabled {
+		return
+	}
+	mfaToken, err := s.tokenConfig.sign(userDto.Id, purposeTwoFactor, mfaTokenLifetime, time.Now())
+	if err != nil {
+		return
+	}
+	challengeDto = dto.TwoFactorChallengeModel{
+		Required:  true,
+		MfaToken:  mfaToken,
+		ExpiresIn: int(mfaTokenLifetime.Seconds()),
+	}
+	return
+}
+
+// CompleteTwoFactor checks the second login step. Wrong codes count as failed logins
+// of the username and client IP, so codes cannot be guessed faster than passwords.
+func (s *UserService) CompleteTwoFactor(twoFactorDto dto.TwoFactorLoginModel) (userDto dto.UserModel, err error) {
+	now := time.Now()
+	claims, err := s.tokenConfig.verify(twoFactorDto.MfaToken, purposeTwoFactor, now)
+	if err != nil {
+		return
+	}
+	user, err := s.IUserRepository.GetById(claims.UserId)
+	if err != nil {
+		err = fmt.Errorf("%w: unknown user", ErrInvalidToken)
+		return
+	}
+	subjects := loginSubjects(dto.CredentialsModel{Username: user.Name, RemoteAddr: twoFactorDto.RemoteAddr})
+	err = s.checkThrottle(subjects, now)
+	if err != nil {
+		return
+	}
+	twoFactor, err := s.ITwoFactorRepository.GetByUserId(user.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrInvalidTwoFactorCode
+		}
+		return
+	}
+
+	var used bool
+	switch {
+	case twoFactorDto.Code != "":
+		if step, ok := matchTotp(twoFactor.Secret, twoFactorDto.Code, now); ok {
+			used, err = s.ITwoFactorRepository.UseStep(user.Id, step)
+		}
+	case twoFactorDto.RecoveryCode != "":
+		used, err = s.ITwoFactorRepository.UseRecoveryCode(user.Id, hashRecoveryCode(twoFactorDto.RecoveryCode))
+	}
+	if err != nil {
+		return
+	}
+	if !used {
+		err = s.recordFailure(subjects, now)
+		if err == nil {
+			err = ErrInvalidTwoFactorCode
+		}
+		return
+	}
+
+	err = s.ILoginAttemptRepository.Reset(usernameKey(user.Name))
+	if err != nil {
+		return
+	}
+	userDto = s.convertToDtoFromEntity(user)
+	return
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	mocks "backend/mocks/repository"
+	"database/sql"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890".
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTotpCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		code, err := totpCode(rfc6238Secret, totpStep(time.Unix(tt.unix, 0)))
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.code, code)
+	}
+}
+
+func TestMatchTotp(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+
+	step, ok := matchTotp(rfc6238Secret, "081804", now.Add(totpPeriod))
+	assert.True(t, ok)
+	assert.Equal(t, totpStep(now), step)
+
+	_, ok = matchTotp(rfc6238Secret, "081804", now.Add(3*totpPeriod))
+	assert.False(t, ok)
+
+	_, ok = matchTotp("not base32!", "081804", now)
+	assert.False(t, ok)
+}
+
+func TestTotpUri(t *testing.T) {
+	uri, err := url.Parse(totpUri("golang_blog_API", "test user", rfc6238Secret))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/golang_blog_API:test user", uri.Path)
+	assert.Equal(t, rfc6238Secret, uri.Query().Get("secret"))
+	assert.Equal(t, "golang_blog_API", uri.Query().Get("issuer"))
+}
+
+func TestRecoveryCode(t *testing.T) {
+	code, err := newRecoveryCode()
+
+	assert.NoError(t, err)
+	assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, code)
+	assert.Equal(t, hashRecoveryCode("abcde-fghij"), hashRecoveryCode(" ABCDE FGHIJ"))
+}
+
+func TestUserService_EnableTwoFactor(t *testing.T) {
+	user := entity.NewUser(1, "testadmin", "hashed", entity.RoleAdmin)
+	secret, _ := newTotpSecret()
+
+	t.Run(
+		"confirmed code",
+		func(t *testing.T) {
+			r := new(mocks.IUserRepository)
+			tfr := new(mocks.ITwoFactorRepository)
+
+			r.On("GetByUsername", "testadmin").Return(user, nil)
+			tfr.On("Enable", entity.NewTwoFactor(1, secret), mock.MatchedBy(func(hashes []string) bool {
+				return len(hashes) == recoveryCodeCount
+			})).Return(nil)
+			tfr.On("UseStep", 1, totpStep(time.Now())).Return(true, nil)
+
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), tfr, new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			code, _ := totpCode(secret, totpStep(time.Now()))
+			recoveryCodes, err := s.EnableTwoFactor(admin, "testadmin", secret, code)
+
+			assert.NoError(t, err)
+			assert.Len(t, recoveryCodes, recoveryCodeCount)
+			hashes := tfr.Calls[0].Arguments.Get(1).([]string)
+			assert.Equal(t, hashRecoveryCode(recoveryCodes[0]), hashes[0])
+			tfr.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"wrong code",
+		func(t *testing.T) {
+			r := new(mocks.IUserRepository)
+			tfr := new(mocks.ITwoFactorRepository)
+
+			r.On("GetByUsername", "testadmin").Return(user, nil)
+
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), tfr, new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			_, err := s.EnableTwoFactor(admin, "testadmin", secret, "000000x")
+
+			assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+			tfr.AssertNotCalled(t, "Enable", mock.Anything, mock.Anything)
+		},
+	)
+}
+
+func TestUserService_TwoFactorLogin(t *testing.T) {
+	user := entity.NewUser(1, "testadmin", "hashed", entity.RoleAdmin)
+	userDto := dto.NewUserModel(1, "testadmin", "admin")
+
+	newService := func(lr *mocks.ILoginAttemptRepository, tfr *mocks.ITwoFactorRepository) IUserService {
+		r := new(mocks.IUserRepository)
+		r.On("GetById", 1).Return(user, nil)
+		lr.On("Get", mock.Anything).Return(entity.LoginAttempt{}, sql.ErrNoRows)
+		tfr.On("GetByUserId", 1).Return(entity.NewTwoFactor(1, rfc6238Secret), nil)
+		return NewUserService(r, new(mocks.IRefreshTokenRepository), lr, tfr, new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+	}
+	challenge := func(s IUserService) string {
+		challengeDto, err := s.TwoFactorChallenge(userDto)
+		assert.NoError(t, err)
+		assert.True(t, challengeDto.Required)
+		return challengeDto.MfaToken
+	}
+
+	t.Run(
+		"totp code",
+		func(t *testing.T) {
+			lr := new(mocks.ILoginAttemptRepository)
+			tfr := new(mocks.ITwoFactorRepository)
+			s := newService(lr, tfr)
+
+			step := totpStep(time.Now())
+			code, _ := totpCode(rfc6238Secret, step)
+			tfr.On("UseStep", 1, step).Return(true, nil)
+			lr.On("Reset", "username:testadmin").Return(nil)
+
+			ret, err := s.CompleteTwoFactor(dto.TwoFactorLoginModel{MfaToken: challenge(s), Code: code})
+
+			assert.NoError(t, err)
+			assert.Equal(t, userDto, ret)
+			lr.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"replayed code",
+		func(t *testing.T) {
+			lr := new(mocks.ILoginAttemptRepository)
+			tfr := new(mocks.ITwoFactorRepository)
+			s := newService(lr, tfr)
+
+			step := totpStep(time.Now())
+			code, _ := totpCode(rfc6238Secret, step)
+			tfr.On("UseStep", 1, step).Return(false, nil)
+			lr.On("RecordFailure", "username:testadmin", mock.Anything).Return(1, nil)
+
+			_, err := s.CompleteTwoFactor(dto.TwoFactorLoginModel{MfaToken: challenge(s), Code: code})
+
+			assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+			lr.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"recovery code",
+		func(t *testing.T) {
+			lr := new(mocks.ILoginAttemptRepository)
+			tfr := new(mocks.ITwoFactorRepository)
+			s := newService(lr, tfr)
+
+			tfr.On("UseRecoveryCode", 1, hashRecoveryCode("abcde-fghij")).Return(true, nil)
+			lr.On("Reset", "username:testadmin").Return(nil)
+
+			_, err := s.CompleteTwoFactor(dto.TwoFactorLoginModel{MfaToken: challenge(s), RecoveryCode: "ABCDE-FGHIJ"})
+
+			assert.NoError(t, err)
+			tfr.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"wrong code",
+		func(t *testing.T) {
+			lr := new(mocks.ILoginAttemptRepository)
+			tfr := new(mocks.ITwoFactorRepository)
+			s := newService(lr, tfr)
+
+			lr.On("RecordFailure", "username:testadmin", mock.Anything).Return(1, nil)
+			lr.On("RecordFailure", "ip:192.0.2.1", mock.Anything).Return(1, nil)
+
+			_, err := s.CompleteTwoFactor(dto.TwoFactorLoginModel{MfaToken: challenge(s), Code: "abcdef", RemoteAddr: "192.0.2.1"})
+
+			assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+			lr.AssertExpectations(t)
+			tfr.AssertNotCalled(t, "UseStep", mock.Anything, mock.Anything)
+		},
+	)
+
+	t.Run(
+		"access token is not an mfa token",
+		func(t *testing.T) {
+			s := newService(new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository))
+
+			accessToken, _ := testTokenConfig.sign(1, purposeAccess, accessTokenLifetime, time.Now())
+
+			_, err := s.CompleteTwoFactor(dto.TwoFactorLoginModel{MfaToken: accessToken, Code: "287082"})
+
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		},
+	)
+
+	t.Run(
+		"mfa token is not an access token",
+		func(t *testing.T) {
+			s := newService(new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository))
+
+			_, err := s.ValidateToken(dto.NewAuthTokenModel(challenge(s)))
+
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		},
+	)
+}
+
+func TestUserService_TwoFactorChallenge_NotEnabled(t *testing.T) {
+	tfr := new(mocks.ITwoFactorRepository)
+
+	tfr.On("GetByUserId", 1).Return(entity.TwoFactor{}, sql.ErrNoRows)
+
+	s := NewUserService(new(mocks.IUserRepository), new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), tfr, new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+	challengeDto, err := s.TwoFactorChallenge(dto.NewUserModel(1, "testuser1", "author"))
+
+	assert.NoError(t, err)
+	assert.False(t, challengeDto.Required)
+}
+
+func TestUserService_ResetTwoFactor(t *testing.T) {
+	r := new(mocks.IUserRepository)
+	tfr := new(mocks.ITwoFactorRepository)
+
+	r.On("GetByUsername", "testadmin").Return(entity.NewUser(1, "testadmin", "hashed", entity.RoleAdmin), nil)
+	r.On("GetByUsername", "testuser2").Return(entity.NewUser(2, "testuser2", "hashed", entity.RoleAuthor), nil)
+	tfr.On("GetByUserId", 1).Return(entity.NewTwoFactor(1, rfc6238Secret), nil)
+	tfr.On("GetByUserId", 2).Return(entity.TwoFactor{}, sql.ErrNoRows)
+	tfr.On("Disable", 1).Return(nil)
+
+	s := NewUserService(r, new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), tfr, new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+	assert.NoError(t, s.ResetTwoFactor(admin, "testadmin"))
+	assert.ErrorIs(t, s.ResetTwoFactor(admin, "testuser2"), ErrTwoFactorNotEnabled)
+	assert.ErrorIs(t, s.ResetTwoFactor(editor, "testadmin"), ErrForbidden)
+	tfr.AssertExpectations(t)
+}
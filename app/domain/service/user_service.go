@@ -4,57 +4,123 @@ import (
 	"backend/app/common/dto"
 	"backend/app/domain/entity"
 	"backend/app/domain/repository"
+	"errors"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
-	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrEmptyPassword is returned when a user would be created without a password.
+var ErrEmptyPassword = errors.New("password must not be empty")
+
 type IUserService interface {
-	GetAll() ([]dto.UserModel, error)
+	GetAll(actor dto.UserModel) ([]dto.UserModel, error)
+	GetById(actor dto.UserModel, id int) (dto.UserModel, error)
 	ValidateUser(dto.CredentialsModel) (dto.UserModel, error)
-	Create(dto.UserModel) error
-	Update(dto.UserModel) error
-	Delete(dto.UserModel) error
+	Create(actor dto.UserModel, userInputDto dto.UserInputModel) error
+	Update(actor dto.UserModel, userInputDto dto.UserInputModel) error
+	ChangeRole(actor dto.UserModel, id int, role string) error
+	Delete(actor dto.UserModel, userDto dto.UserModel) error
 	IssueToken(int) (dto.AuthTokenModel, error)
-	ValidateToken(dto.AuthTokenModel) (bool, error)
+	Refresh(dto.RefreshTokenModel) (dto.AuthTokenModel, error)
+	Logout(dto.RefreshTokenModel) error
+	RevokeAllTokens(actor dto.UserModel, username string) error
+	UnlockUser(actor dto.UserModel, username string) error
+	UnlockAddress(actor dto.UserModel, ip string) error
+	NewTwoFactorSecret(actor dto.UserModel, username string) (dto.TwoFactorEnrollmentModel, error)
+	EnableTwoFactor(actor dto.UserModel, username string, secret string, code string) ([]string, error)
+	ResetTwoFactor(actor dto.UserModel, username string) error
+	TwoFactorChallenge(userDto dto.UserModel) (dto.TwoFactorChallengeModel, error)
+	CompleteTwoFactor(twoFactorDto dto.TwoFactorLoginModel) (dto.UserModel, error)
+	CreateApiKey(actor dto.UserModel, apiKeyInputDto dto.ApiKeyInputModel) (string, error)
+	GetApiKeys(actor dto.UserModel) ([]dto.ApiKeyModel, error)
+	RevokeApiKey(actor dto.UserModel, prefix string) error
+	ValidateToken(dto.AuthTokenModel) (dto.UserModel, error)
+	GetAuthor(username string) (dto.AuthorModel, error)
 }
 
 type UserService struct {
 	repository.IUserRepository
+	repository.IRefreshTokenRepository
+	repository.ILoginAttemptRepository
+	repository.ITwoFactorRepository
+	repository.IApiKeyRepository
+	bcryptCost  int
+	tokenConfig TokenConfig
 }
 
-func NewUserService(repo repository.IUserRepository) (userService IUserService) {
-	userService = &UserService{repo}
+// NewUserService hashes passwords with the given bcrypt cost and signs access tokens
+// as described by tokenConfig. Costs outside bcrypt's accepted range fall back to
+// bcrypt.DefaultCost.
+func NewUserService(repo repository.IUserRepository, refreshTokenRepo repository.IRefreshTokenRepository, loginAttemptRepo repository.ILoginAttemptRepository, twoFactorRepo repository.ITwoFactorRepository, apiKeyRepo repository.IApiKeyRepository, bcryptCost int, tokenConfig TokenConfig) (userService IUserService) {
+	if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+		bcryptCost = bcrypt.DefaultCost
+	}
+	userService = &UserService{repo, refreshTokenRepo, loginAttemptRepo, twoFactorRepo, apiKeyRepo, bcryptCost, tokenConfig}
 	return
 }
 
 func (s *UserService) convertToDtosFromEntities(users []entity.User) (userDtos []dto.UserModel) {
 	for _, user := range users {
-		userDto := dto.NewUserModel(user.Id, user.Name, user.Password)
+		userDto := s.convertToDtoFromEntity(user)
 		userDtos = append(userDtos, userDto)
 	}
 	return
 }
 
 func (s *UserService) convertToDtoFromEntity(user entity.User) (userDto dto.UserModel) {
-	userDto = dto.NewUserModel(user.Id, user.Name, user.Password)
+	userDto = dto.NewUserModel(user.Id, user.Name, string(user.Role))
+	userDto.DisplayName = user.DisplayName
+	userDto.Bio = user.Bio
+	userDto.AvatarUrl = user.AvatarUrl
 	return
 }
 
 func (s *UserService) convertToEntityFromDto(userDto dto.UserModel) (user entity.User) {
-	user = entity.NewUser(userDto.Id, userDto.Name, userDto.Password)
+	user = entity.NewUser(userDto.Id, userDto.Name, "", entity.Role(userDto.Role))
+	user.DisplayName = userDto.DisplayName
+	user.Bio = userDto.Bio
+	user.AvatarUrl = userDto.AvatarUrl
 	return
 }
 
-func (s *UserService) convertToEntityFromDtoCreds(credsDto dto.CredentialsModel) (creds entity.Credentials) {
-	creds = entity.NewCreds(credsDto.Username, credsDto.Password)
+// applyInput copies the input onto the user, hashing the password when one is given.
+func (s *UserService) applyInput(user *entity.User, userInputDto dto.UserInputModel) (err error) {
+	if userInputDto.Name != "" {
+		user.Name = userInputDto.Name
+	}
+	if userInputDto.Role != "" {
+		user.Role = entity.Role(userInputDto.Role)
+	}
+	if !user.Role.IsValid() {
+		err = ErrInvalidRole
+		return
+	}
+	user.DisplayName = userInputDto.DisplayName
+	user.Bio = userInputDto.Bio
+	user.AvatarUrl = userInputDto.AvatarUrl
+	if userInputDto.Password != "" {
+		user.Password, err = s.hashPassword(userInputDto.Password)
+	}
+	return
+}
+
+func (s *UserService) hashPassword(password string) (hash string, err error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), s.bcryptCost)
+	if err != nil {
+		return
+	}
+	hash = string(hashed)
 	return
 }
 
-func (s *UserService) GetAll() (userDtos []dto.UserModel, err error) {
+func (s *UserService) GetAll(actor dto.UserModel) (userDtos []dto.UserModel, err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
 	users, err := s.IUserRepository.GetAll()
 	if err != nil {
 		return
@@ -63,79 +129,133 @@ func (s *UserService) GetAll() (userDtos []dto.UserModel, err error) {
 	return
 }
 
-func (s *UserService) ValidateUser(credsDto dto.CredentialsModel) (userDto dto.UserModel, err error) {
-	creds := s.convertToEntityFromDtoCreds(credsDto)
-	user, err := s.IUserRepository.ValidateUser(creds)
+func (s *UserService) GetById(actor dto.UserModel, id int) (userDto dto.UserModel, err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
+	user, err := s.IUserRepository.GetById(id)
+	if err != nil {
+		return
+	}
 	userDto = s.convertToDtoFromEntity(user)
 	return
 }
 
-func (s *UserService) Create(userDto dto.UserModel) (err error) {
-	user := s.convertToEntityFromDto(userDto)
+// GetAuthor returns the public profile of a user. It is readable without a token.
+func (s *UserService) GetAuthor(username string) (authorDto dto.AuthorModel, err error) {
+	user, err := s.IUserRepository.GetByUsername(username)
+	if err != nil {
+		return
+	}
+	authorDto = dto.NewAuthorModel(user.Name, user.DisplayName, user.Bio, user.AvatarUrl)
+	return
+}
+
+func (s *UserService) Create(actor dto.UserModel, userInputDto dto.UserInputModel) (err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
+	if userInputDto.Password == "" {
+		err = ErrEmptyPassword
+		return
+	}
+	user := entity.User{Role: entity.RoleViewer}
+	err = s.applyInput(&user, userInputDto)
+	if err != nil {
+		return
+	}
 	err = s.IUserRepository.Create(user)
 	return
 }
 
-func (s *UserService) Update(userDto dto.UserModel) (err error) {
-	user := s.convertToEntityFromDto(userDto)
+// Update applies the input to the stored user. Empty name, role and password keep
+// their current values.
+func (s *UserService) Update(actor dto.UserModel, userInputDto dto.UserInputModel) (err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
+	user, err := s.IUserRepository.GetById(userInputDto.Id)
+	if err != nil {
+		return
+	}
+	err = s.applyInput(&user, userInputDto)
+	if err != nil {
+		return
+	}
 	err = s.IUserRepository.Update(user)
+	if err != nil {
+		return
+	}
+	// a new password ends every session opened with the old one
+	if userInputDto.Password != "" {
+		err = s.IRefreshTokenRepository.RevokeAllForUser(user.Id)
+	}
 	return
 }
 
-func (s *UserService) Delete(userDto dto.UserModel) (err error) {
+func (s *UserService) ChangeRole(actor dto.UserModel, id int, role string) (err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
+	if !entity.Role(role).IsValid() {
+		err = ErrInvalidRole
+		return
+	}
+	user, err := s.IUserRepository.GetById(id)
+	if err != nil {
+		return
+	}
+	user.Role = entity.Role(role)
+	err = s.IUserRepository.Update(user)
+	return
+}
+
+func (s *UserService) Delete(actor dto.UserModel, userDto dto.UserModel) (err error) {
+	err = authorize(actor, entity.PermissionManageUsers)
+	if err != nil {
+		return
+	}
 	user := s.convertToEntityFromDto(userDto)
 	err = s.IUserRepository.Delete(user)
 	return
 }
 
+// IssueToken starts a new session: a short-lived access token and the first
+// refresh token of a new family.
 func (s *UserService) IssueToken(userId int) (authTokenModel dto.AuthTokenModel, err error) {
-	claims := jwt.MapClaims{
-		"user_id": userId,
-		"iat":     time.Now().Unix(),
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	err = godotenv.Load(fmt.Sprint(".env", os.Getenv("GO_ENV")))
+	familyId, err := newOpaqueToken()
 	if err != nil {
-		panic(err)
+		return
 	}
+	authTokenModel, err = s.issueTokenPair(userId, familyId)
+	return
+}
 
-	secret := os.Getenv("SECRET_KEY")
-
-	tokenString, _ := token.SignedString([]byte(secret))
-
-	authTokenModel = dto.AuthTokenModel{
-		Token: tokenString,
-	}
+func (s *UserService) signAccessToken(userId int) (tokenString string, err error) {
+	tokenString, err = s.tokenConfig.sign(userId, purposeAccess, accessTokenLifetime, time.Now())
 	return
 }
 
-func (s *UserService) ValidateToken(authTokenDto dto.AuthTokenModel) (isAdmin bool, err error) {
-	err = godotenv.Load(fmt.Sprint(".env", os.Getenv("GO_ENV")))
+// ValidateToken resolves an access token, or an API key, to the user it was issued
+// for. Every failure wraps ErrInvalidToken.
+func (s *UserService) ValidateToken(authTokenDto dto.AuthTokenModel) (userDto dto.UserModel, err error) {
+	if strings.HasPrefix(authTokenDto.Token, apiKeyTag) {
+		userDto, err = s.validateApiKey(authTokenDto.Token)
+		return
+	}
+	claims, err := s.tokenConfig.verify(authTokenDto.Token, purposeAccess, time.Now())
 	if err != nil {
-		panic(err)
+		return
 	}
-
-	secret := os.Getenv("SECRET_KEY")
-
-	authToken, err := jwt.Parse(authTokenDto.Token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(secret), nil
-	})
-
-	if claims, ok := authToken.Claims.(jwt.MapClaims); ok && authToken.Valid {
-		userId := int(claims["user_id"].(float64))
-		isAdmin, err = s.IUserRepository.IsAdmin(userId)
-		if err != nil {
-			return
-		}
-	} else {
-		fmt.Println(err)
+	user, err := s.IUserRepository.GetById(claims.UserId)
+	if err != nil {
+		err = fmt.Errorf("%w: unknown user", ErrInvalidToken)
+		return
 	}
+	userDto = s.convertToDtoFromEntity(user)
 	return
-
 }
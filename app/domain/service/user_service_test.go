@@ -4,24 +4,34 @@ import (
 	"backend/app/common/dto"
 	"backend/app/domain/entity"
 	mocks "backend/mocks/repository"
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// hashes matches a user whose stored password is a bcrypt hash of password.
+func hashes(password string) interface{} {
+	return mock.MatchedBy(func(user entity.User) bool {
+		return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) == nil
+	})
+}
+
 func TestUserService_GetAll(t *testing.T) {
 	users := []entity.User{
 		{
 			Id:       1,
 			Name:     "testuser1",
 			Password: "testpass1",
-			IsAdmin:  true,
+			Role:     entity.RoleAdmin,
 		},
 		{
 			Id:       2,
 			Name:     "testuser2",
 			Password: "testpass2",
-			IsAdmin:  true,
+			Role:     entity.RoleAuthor,
 		},
 	}
 
@@ -29,86 +39,199 @@ func TestUserService_GetAll(t *testing.T) {
 
 	r.On("GetAll").Return(users, nil)
 
-	s := NewUserService(r)
+	s := NewUserService(r, new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
 
-	ret, err := s.GetAll()
+	ret, err := s.GetAll(admin)
 
 	assert.NoError(t, err)
 	for i, r := range ret {
 		assert.Equal(t, r.Id, users[i].Id)
 		assert.Equal(t, r.Name, users[i].Name)
-		assert.Equal(t, r.Password, users[i].Password)
-		assert.Equal(t, r.IsAdmin, users[i].IsAdmin)
+		assert.Equal(t, r.Role, string(users[i].Role))
 	}
 	r.AssertExpectations(t)
 }
 
-func TestUserService_ValidateUser(t *testing.T) {
-	user := entity.NewUser(1, "testuser1", "testpass1")
+func TestUserService_Create(t *testing.T) {
+	t.Run(
+		"hashes the password",
+		func(t *testing.T) {
+			userInputDto := dto.UserInputModel{Name: "testuser1", Password: "testpass1", Role: "author"}
 
-	creds := entity.NewCreds("testuser1", "testpass1")
-	credsDto := dto.NewCredsModel("testuser1", "testpass1")
+			r := new(mocks.IUserRepository)
 
-	r := new(mocks.IUserRepository)
+			r.On("Create", hashes("testpass1")).Return(nil)
 
-	r.On("ValidateUser", creds).Return(user, nil)
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
 
-	s := NewUserService(r)
+			err := s.Create(admin, userInputDto)
 
-	ret, err := s.ValidateUser(credsDto)
+			assert.NoError(t, err)
+			r.AssertExpectations(t)
+			created := r.Calls[0].Arguments.Get(0).(entity.User)
+			assert.Equal(t, created.Name, "testuser1")
+			assert.Equal(t, created.Role, entity.RoleAuthor)
+			cost, _ := bcrypt.Cost([]byte(created.Password))
+			assert.Equal(t, cost, bcrypt.MinCost)
+		},
+	)
 
-	assert.NoError(t, err)
-	assert.Equal(t, ret.Id, user.Id)
-	assert.Equal(t, ret.Name, user.Name)
-	assert.Equal(t, ret.Password, user.Password)
-	assert.Equal(t, ret.IsAdmin, user.IsAdmin)
-	r.AssertExpectations(t)
+	t.Run(
+		"defaults to viewer",
+		func(t *testing.T) {
+			userInputDto := dto.UserInputModel{Name: "testuser1", Password: "testpass1"}
+
+			r := new(mocks.IUserRepository)
+
+			r.On("Create", mock.MatchedBy(func(user entity.User) bool {
+				return user.Role == entity.RoleViewer
+			})).Return(nil)
+
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			assert.NoError(t, s.Create(admin, userInputDto))
+			r.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"empty password",
+		func(t *testing.T) {
+			r := new(mocks.IUserRepository)
+
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			assert.ErrorIs(t, s.Create(admin, dto.UserInputModel{Name: "testuser1"}), ErrEmptyPassword)
+			r.AssertNotCalled(t, "Create", mock.Anything)
+		},
+	)
 }
 
-func TestUserService_Create(t *testing.T) {
-	user := entity.NewUser(1, "testuser1", "testpass1")
-	userDto := dto.NewUserModel(1, "testuser1", "testpass1")
+func TestUserService_Update(t *testing.T) {
+	t.Run(
+		"keeps the password",
+		func(t *testing.T) {
+			stored := entity.NewUser(1, "testuser1", "hashed", entity.RoleAuthor)
+			updated := entity.NewUser(1, "testuser2", "hashed", entity.RoleAuthor)
+			updated.DisplayName = "Test User 2"
+			userInputDto := dto.UserInputModel{Id: 1, Name: "testuser2", DisplayName: "Test User 2"}
+
+			r := new(mocks.IUserRepository)
+
+			r.On("GetById", 1).Return(stored, nil)
+			r.On("Update", updated).Return(nil)
+
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			assert.NoError(t, s.Update(admin, userInputDto))
+			r.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"changes the password",
+		func(t *testing.T) {
+			stored := entity.NewUser(1, "testuser1", "hashed", entity.RoleAuthor)
+			userInputDto := dto.UserInputModel{Id: 1, Password: "newpass1"}
+
+			r := new(mocks.IUserRepository)
+
+			tr := new(mocks.IRefreshTokenRepository)
+
+			r.On("GetById", 1).Return(stored, nil)
+			r.On("Update", hashes("newpass1")).Return(nil)
+			tr.On("RevokeAllForUser", 1).Return(nil)
+
+			s := NewUserService(r, tr, new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			assert.NoError(t, s.Update(admin, userInputDto))
+			r.AssertExpectations(t)
+			tr.AssertExpectations(t)
+		},
+	)
+}
+
+func TestUserService_Delete(t *testing.T) {
+	user := entity.NewUser(1, "testuser1", "", entity.RoleAuthor)
+	userDto := dto.NewUserModel(1, "testuser1", "author")
 
 	r := new(mocks.IUserRepository)
 
-	r.On("Create", user).Return(nil)
+	r.On("Delete", user).Return(nil)
 
-	s := NewUserService(r)
+	s := NewUserService(r, new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
 
-	err := s.Create(userDto)
+	err := s.Delete(admin, userDto)
 
 	assert.NoError(t, err)
 	r.AssertExpectations(t)
 }
 
-func TestUserService_Update(t *testing.T) {
-	user := entity.NewUser(1, "testuser1", "testpass1")
-	userDto := dto.NewUserModel(1, "testuser1", "testpass1")
+func TestUserService_BcryptCost(t *testing.T) {
+	s := NewUserService(new(mocks.IUserRepository), new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), 0, testTokenConfig).(*UserService)
 
-	r := new(mocks.IUserRepository)
+	assert.Equal(t, s.bcryptCost, bcrypt.DefaultCost)
+}
 
-	r.On("Update", user).Return(nil)
+func TestUserModel_HasNoPassword(t *testing.T) {
+	user := entity.NewUser(1, "testuser1", "hashed", entity.RoleAdmin)
 
-	s := NewUserService(r)
+	s := NewUserService(new(mocks.IUserRepository), new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig).(*UserService)
 
-	err := s.Update(userDto)
+	output, err := json.Marshal(s.convertToDtoFromEntity(user))
 
 	assert.NoError(t, err)
-	r.AssertExpectations(t)
+	assert.NotContains(t, string(output), "password")
+	assert.NotContains(t, string(output), "hashed")
 }
 
-func TestUserService_Delete(t *testing.T) {
-	user := entity.NewUser(1, "testuser1", "testpass1")
-	userDto := dto.NewUserModel(1, "testuser1", "testpass1")
+func TestUserService_ChangeRole(t *testing.T) {
+	t.Run(
+		"valid role",
+		func(t *testing.T) {
+			user := entity.NewUser(5, "testuser5", "testpass5", entity.RoleViewer)
+			updated := entity.NewUser(5, "testuser5", "testpass5", entity.RoleEditor)
+
+			r := new(mocks.IUserRepository)
+
+			r.On("GetById", 5).Return(user, nil)
+			r.On("Update", updated).Return(nil)
+
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			assert.NoError(t, s.ChangeRole(admin, 5, "editor"))
+			r.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"unknown role",
+		func(t *testing.T) {
+			r := new(mocks.IUserRepository)
+
+			s := NewUserService(r, new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
+
+			assert.ErrorIs(t, s.ChangeRole(admin, 5, "owner"), ErrInvalidRole)
+			r.AssertNotCalled(t, "GetById", 5)
+		},
+	)
+}
+
+func TestUserService_GetAuthor(t *testing.T) {
+	user := entity.NewUser(3, "testauthor", "hashed", entity.RoleAuthor)
+	user.DisplayName = "Test Author"
+	user.Bio = "bio"
+	user.AvatarUrl = "test_author.png"
 
 	r := new(mocks.IUserRepository)
 
-	r.On("Delete", user).Return(nil)
+	r.On("GetByUsername", "testauthor").Return(user, nil)
 
-	s := NewUserService(r)
+	s := NewUserService(r, new(mocks.IRefreshTokenRepository), new(mocks.ILoginAttemptRepository), new(mocks.ITwoFactorRepository), new(mocks.IApiKeyRepository), bcrypt.MinCost, testTokenConfig)
 
-	err := s.Delete(userDto)
+	ret, err := s.GetAuthor("testauthor")
 
 	assert.NoError(t, err)
+	assert.Equal(t, dto.NewAuthorModel("testauthor", "Test Author", "bio", "test_author.png"), ret)
 	r.AssertExpectations(t)
 }
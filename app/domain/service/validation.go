@@ -0,0 +1,71 @@
+package service
+
+import (
+	"backend/app/domain/entity"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidInput is wrapped by the errors of writes that would store an
+// incomplete post or category; the message tells which field is wrong.
+var ErrInvalidInput = errors.New("invalid input")
+
+// reservedCategorySlugs are the last path segments the router takes for a
+// listing or an action rather than a category, such as /categories/tree.
+// Sub-categories are categories too, so they are kept from both routes.
+var reservedCategorySlugs = []string{"categories", "sub-categories", "tree", "breadcrumbs", "reorder"}
+
+// reservedPostSlugs are those the router takes for the post listing.
+var reservedPostSlugs = []string{"posts"}
+
+// validateSlug rejects slugs that cannot be a path segment of the API, or that
+// the router would read as one of the reserved segments.
+func validateSlug(slug string, reserved ...string) (err error) {
+	switch {
+	case slug == "":
+		err = fmt.Errorf("%w: slug is missing", ErrInvalidInput)
+	case strings.ContainsAny(slug, "/?#") || strings.IndexFunc(slug, isSpaceOrControl) >= 0:
+		err = fmt.Errorf("%w: slug %q cannot be part of a URL path", ErrInvalidInput, slug)
+	}
+	for _, segment := range reserved {
+		if err == nil && slug == segment {
+			err = fmt.Errorf("%w: slug %q is reserved", ErrInvalidInput, slug)
+		}
+	}
+	return
+}
+
+func isSpaceOrControl(c rune) bool {
+	return c <= ' ' || c == 0x7f
+}
+
+func validatePost(post entity.Post) (err error) {
+	switch {
+	case strings.TrimSpace(post.Title) == "":
+		err = fmt.Errorf("%w: title is missing", ErrInvalidInput)
+	case post.CategoryId == 0:
+		err = fmt.Errorf("%w: category_id is missing", ErrInvalidInput)
+	default:
+		err = validateSlug(post.Slug, reservedPostSlugs...)
+	}
+	return
+}
+
+func validateCategory(name string, slug string) (err error) {
+	if strings.TrimSpace(name) == "" {
+		err = fmt.Errorf("%w: name is missing", ErrInvalidInput)
+		return
+	}
+	err = validateSlug(slug, reservedCategorySlugs...)
+	return
+}
+
+func validateSubCategory(subCategory entity.SubCategory) (err error) {
+	if subCategory.ParentCategoryId == 0 {
+		err = fmt.Errorf("%w: parent_category_id is missing", ErrInvalidInput)
+		return
+	}
+	err = validateCategory(subCategory.Name, subCategory.Slug)
+	return
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	mocks "backend/mocks/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidation(t *testing.T) {
+	valid := entity.Post{Title: "testPost1", Slug: "test-post-1", CategoryId: 1}
+	assert.NoError(t, validatePost(valid))
+	for name, change := range map[string]func(post *entity.Post){
+		"no title":      func(post *entity.Post) { post.Title = " " },
+		"no slug":       func(post *entity.Post) { post.Slug = "" },
+		"slug with /":   func(post *entity.Post) { post.Slug = "test/post" },
+		"slug with tab": func(post *entity.Post) { post.Slug = "test\tpost" },
+		"reserved slug": func(post *entity.Post) { post.Slug = "posts" },
+		"no category":   func(post *entity.Post) { post.CategoryId = 0 },
+	} {
+		post := valid
+		change(&post)
+		assert.ErrorIs(t, validatePost(post), ErrInvalidInput, name)
+	}
+
+	assert.NoError(t, validateCategory("testCategory1", "test-category-1"))
+	assert.ErrorIs(t, validateCategory("", "test-category-1"), ErrInvalidInput)
+	assert.ErrorIs(t, validateCategory("testCategory1", "test category"), ErrInvalidInput)
+	for _, slug := range []string{"tree", "breadcrumbs", "reorder", "categories", "sub-categories"} {
+		assert.ErrorIs(t, validateCategory("testCategory1", slug), ErrInvalidInput, slug)
+		assert.ErrorIs(t, validateSubCategory(entity.SubCategory{Name: "testSubCategory1", Slug: slug, ParentCategoryId: 1}), ErrInvalidInput, slug)
+	}
+	assert.NoError(t, validatePost(entity.Post{Title: "testPost1", Slug: "tree", CategoryId: 1}))
+	assert.NoError(t, validateSubCategory(entity.SubCategory{Name: "testSubCategory1", Slug: "test-sub-category-1", ParentCategoryId: 1}))
+	assert.ErrorIs(t, validateSubCategory(entity.SubCategory{Name: "testSubCategory1", Slug: "test-sub-category-1"}), ErrInvalidInput)
+}
+
+func TestPostService_Update_invalid(t *testing.T) {
+	r := new(mocks.IPostRepository)
+	r.On("GetPostById", 1).Return(entity.Post{Id: 1, Title: "testPost1", Slug: "test-post-1", CategoryId: 1}, nil)
+	s := NewPostService(r)
+
+	err := s.Update(editor, dto.PostModel{Id: 1, Slug: "test-post-1", CategoryId: 1})
+
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	r.AssertNotCalled(t, "Update", entity.Post{Id: 1, Slug: "test-post-1", CategoryId: 1})
+}
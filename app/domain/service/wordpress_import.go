@@ -0,0 +1,334 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/entity"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidWXR is returned for a file that is not a WordPress export.
+var ErrInvalidWXR = errors.New("invalid WXR file")
+
+// wxrDateLayout is the layout of wp:post_date and wp:post_date_gmt.
+const wxrDateLayout = "2006-01-02 15:04:05"
+
+// uploadUrlPattern matches links into the uploads directory of a WordPress site;
+// the group is the path below it, e.g. 2019/05/photo.jpg.
+var uploadUrlPattern = regexp.MustCompile(`https?://[^\s"'<>()]+?/wp-content/uploads/([^\s"'<>()?#]+)`)
+
+// The parts of a WXR file the importer reads. WXR puts its own elements in a
+// namespace whose URL changes with the version, so they are matched by local name.
+type (
+	wxrDocument struct {
+		Channel wxrChannel `xml:"channel"`
+	}
+	wxrChannel struct {
+		Categories []wxrCategory `xml:"category"`
+		Items      []wxrItem     `xml:"item"`
+	}
+	wxrCategory struct {
+		Nicename    string `xml:"category_nicename"`
+		Parent      string `xml:"category_parent"`
+		Name        string `xml:"cat_name"`
+		Description string `xml:"category_description"`
+	}
+	wxrItem struct {
+		Title         string        `xml:"title"`
+		Creator       string        `xml:"creator"`
+		Encoded       []wxrEncoded  `xml:"encoded"`
+		PostId        int           `xml:"post_id"`
+		PostDate      string        `xml:"post_date"`
+		PostDateGmt   string        `xml:"post_date_gmt"`
+		PostName      string        `xml:"post_name"`
+		Status        string        `xml:"status"`
+		PostType      string        `xml:"post_type"`
+		AttachmentUrl string        `xml:"attachment_url"`
+		Terms         []wxrTerm     `xml:"category"`
+		Meta          []wxrPostMeta `xml:"postmeta"`
+		Comments      []struct{}    `xml:"comment"`
+	}
+	// wxrEncoded is content:encoded or excerpt:encoded, told apart by namespace.
+	wxrEncoded struct {
+		XMLName xml.Name
+		Text    string `xml:",chardata"`
+	}
+	wxrTerm struct {
+		Domain   string `xml:"domain,attr"`
+		Nicename string `xml:"nicename,attr"`
+	}
+	wxrPostMeta struct {
+		Key   string `xml:"meta_key"`
+		Value string `xml:"meta_value"`
+	}
+)
+
+func (item wxrItem) encoded(kind string) string {
+	for _, encoded := range item.Encoded {
+		if strings.Contains(encoded.XMLName.Space, kind) {
+			return encoded.Text
+		}
+	}
+	return ""
+}
+
+func (item wxrItem) meta(key string) string {
+	for _, meta := range item.Meta {
+		if meta.Key == key {
+			return meta.Value
+		}
+	}
+	return ""
+}
+
+// date is the publication date in UTC. Drafts have no GMT date, so their local
+// date is taken as UTC.
+func (item wxrItem) date() (date time.Time) {
+	for _, value := range []string{item.PostDateGmt, item.PostDate} {
+		parsed, err := time.Parse(wxrDateLayout, value)
+		if err == nil && parsed.Year() > 1 {
+			return parsed
+		}
+	}
+	return
+}
+
+// ImportWordPress imports a WXR export: every WordPress category becomes a category
+// under its parent, posts are upserted by slug like ImportPosts does, keeping their
+// status and date. Links to uploads are pointed at copies in mediaDir, taken from
+// uploadsDir, the wp-content/uploads directory of the site; nothing is downloaded.
+// Pages, tags, comments, authors and uploads not found are reported as unmapped.
+func (s *ImportService) ImportWordPress(actor dto.UserModel, r io.Reader, uploadsDir string, mediaDir string, options dto.ImportOptionsModel) (planDto dto.ImportPlanModel, err error) {
+	err = authorize(actor, entity.PermissionCreatePost)
+	if err == nil {
+		err = authorize(actor, entity.PermissionManageCategories)
+	}
+	if err != nil {
+		return
+	}
+	var document wxrDocument
+	err = xml.NewDecoder(r).Decode(&document)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", ErrInvalidWXR, err)
+		return
+	}
+
+	// the categories exist by the time the posts are imported, so nothing else is created
+	options.CreateCategories = false
+	run := newImportRun(s, actor, options)
+	run.uploadsDir, run.mediaDir, run.media = uploadsDir, mediaDir, make(map[string]bool)
+
+	for _, category := range sortWXRCategories(document.Channel.Categories) {
+		err = run.ensureCategory(category)
+		if err != nil {
+			run.add(dto.ImportActionError, "category", category.Nicename, "", err.Error())
+			err = nil
+		}
+	}
+
+	attachments := make(map[string]string)
+	for _, item := range document.Channel.Items {
+		if item.PostType == "attachment" {
+			attachments[fmt.Sprint(item.PostId)] = item.AttachmentUrl
+		}
+	}
+	authors := make(map[string]int)
+	comments := 0
+	for _, item := range document.Channel.Items {
+		source := fmt.Sprintf("%s %d", item.PostType, item.PostId)
+		switch {
+		case item.PostType == "attachment":
+			// imported with the posts that link to them
+		case item.PostType != "post":
+			run.add(dto.ImportActionUnmapped, item.PostType, item.PostName, source, item.Title)
+		case item.Status == "trash" || item.Status == "auto-draft":
+			run.add(dto.ImportActionUnmapped, "post", item.PostName, source, "status "+item.Status)
+		default:
+			authors[item.Creator]++
+			comments += len(item.Comments)
+			run.tryPost(run.wxrPost(item, source, attachments))
+		}
+	}
+
+	var names []string
+	for name := range authors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		run.add(dto.ImportActionUnmapped, "author", name, "", fmt.Sprintf("%d posts are imported without an author", authors[name]))
+	}
+	if comments > 0 {
+		run.add(dto.ImportActionUnmapped, "comments", "", "", fmt.Sprintf("%d comments are not imported", comments))
+	}
+	planDto, err = run.result()
+	return
+}
+
+// sortWXRCategories orders the categories so that parents come before their children.
+// Categories whose parent is not in the file are treated as roots.
+func sortWXRCategories(categories []wxrCategory) (sorted []wxrCategory) {
+	exists := make(map[string]bool)
+	for _, category := range categories {
+		exists[category.Nicename] = true
+	}
+	childrenOf := make(map[string][]wxrCategory)
+	for _, category := range categories {
+		parent := category.Parent
+		if !exists[parent] || parent == category.Nicename {
+			parent = ""
+		}
+		childrenOf[parent] = append(childrenOf[parent], category)
+	}
+	var visit func(parent string)
+	visit = func(parent string) {
+		for _, category := range childrenOf[parent] {
+			sorted = append(sorted, category)
+			visit(category.Nicename)
+		}
+	}
+	visit("")
+	return
+}
+
+// ensureCategory creates the category unless one with its slug exists; an existing
+// category is left as it is.
+func (r *importRun) ensureCategory(category wxrCategory) (err error) {
+	categoryDto, err := r.ICategoryService.GetBySlug(category.Nicename)
+	if err == nil {
+		r.categoryIds[category.Nicename] = categoryDto.Id
+		r.add(dto.ImportActionUnchanged, "category", category.Nicename, "", "")
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	parentId := 0
+	if category.Parent != "" {
+		var ok bool
+		parentId, ok = r.categoryIds[category.Parent]
+		if !ok {
+			return fmt.Errorf("parent category %s could not be imported", category.Parent)
+		}
+	}
+	_, err = r.createCategory("category", category.Nicename, "", func() error {
+		return r.ICategoryService.Create(r.actor, dto.CategoryModel{Name: category.Name, Slug: category.Nicename, ParentId: parentId, Description: category.Description})
+	})
+	return
+}
+
+// wxrPost maps a WordPress post. It is filed under its first category; further
+// categories and the tags are reported.
+func (r *importRun) wxrPost(item wxrItem, source string, attachments map[string]string) (postImportDto dto.PostImportModel) {
+	postImportDto = dto.PostImportModel{
+		Source:          source,
+		Title:           item.Title,
+		Slug:            item.PostName,
+		Content:         r.rewriteUploads(item.encoded("content"), source),
+		MetaDescription: strings.TrimSpace(item.encoded("excerpt")),
+		IsPublic:        item.Status == "publish",
+		Date:            item.date(),
+	}
+	for _, term := range item.Terms {
+		switch {
+		case term.Domain == "post_tag":
+			postImportDto.Tags = append(postImportDto.Tags, term.Nicename)
+		case term.Domain != "category":
+			r.add(dto.ImportActionUnmapped, term.Domain, term.Nicename, source, "")
+		case postImportDto.CategorySlug == "":
+			postImportDto.CategorySlug = term.Nicename
+		default:
+			r.add(dto.ImportActionUnmapped, "category", term.Nicename, source, "posts have one category, filed under "+postImportDto.CategorySlug)
+		}
+	}
+	if thumbnailUrl, ok := attachments[item.meta("_thumbnail_id")]; ok {
+		if match := uploadUrlPattern.FindStringSubmatch(thumbnailUrl); match != nil {
+			if name, ok := r.localMedia(match[1], source); ok {
+				postImportDto.EyeCatchingImg = name
+			}
+		}
+	}
+	return
+}
+
+// rewriteUploads points links to uploads at the copies in the media directory.
+// Links to uploads that are not available are kept.
+func (r *importRun) rewriteUploads(content string, source string) string {
+	return uploadUrlPattern.ReplaceAllStringFunc(content, func(url string) string {
+		name, ok := r.localMedia(uploadUrlPattern.FindStringSubmatch(url)[1], source)
+		if !ok {
+			return url
+		}
+		return mediaUrlPath + name
+	})
+}
+
+// localMedia copies the upload at the slash separated path below the uploads
+// directory to the same path below the media directory, unless a file of the same
+// size is there already, and returns that path.
+func (r *importRun) localMedia(upload string, source string) (name string, ok bool) {
+	name = path.Clean(upload)
+	if ok, seen := r.media[name]; seen {
+		return name, ok
+	}
+	if name == ".." || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+		r.add(dto.ImportActionUnmapped, "media", upload, source, "path leaves the uploads directory")
+		return
+	}
+	defer func() { r.media[name] = ok }()
+	if r.uploadsDir == "" {
+		r.add(dto.ImportActionUnmapped, "media", name, source, "no uploads directory given")
+		return
+	}
+	from := filepath.Join(r.uploadsDir, filepath.FromSlash(name))
+	info, err := os.Stat(from)
+	if err != nil || !info.Mode().IsRegular() {
+		r.add(dto.ImportActionUnmapped, "media", name, source, "not in the uploads directory")
+		return
+	}
+	to := filepath.Join(r.mediaDir, filepath.FromSlash(name))
+	if existing, err := os.Stat(to); err == nil && existing.Size() == info.Size() {
+		r.add(dto.ImportActionUnchanged, "media", name, source, "")
+		return name, true
+	}
+	if !r.options.DryRun {
+		err = copyFile(from, to)
+		if err != nil {
+			r.add(dto.ImportActionError, "media", name, source, err.Error())
+			return
+		}
+	}
+	r.add(dto.ImportActionCreate, "media", name, source, "")
+	return name, true
+}
+
+func copyFile(from string, to string) (err error) {
+	in, err := os.Open(from)
+	if err != nil {
+		return
+	}
+	defer in.Close()
+	err = os.MkdirAll(filepath.Dir(to), 0o755)
+	if err != nil {
+		return
+	}
+	out, err := os.Create(to)
+	if err != nil {
+		return
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return
+}
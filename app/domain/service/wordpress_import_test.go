@@ -0,0 +1,174 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	mocks "backend/mocks/service"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testWXR = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"
+	xmlns:excerpt="http://wordpress.org/export/1.2/excerpt/"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/"
+	xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+	<wp:category><wp:category_nicename>test-sub-category-1</wp:category_nicename><wp:category_parent>test-category-1</wp:category_parent><wp:cat_name><![CDATA[testSubCategory1]]></wp:cat_name></wp:category>
+	<wp:category><wp:category_nicename>test-category-1</wp:category_nicename><wp:category_parent></wp:category_parent><wp:cat_name><![CDATA[testCategory1]]></wp:cat_name></wp:category>
+	<item>
+		<title>testTitle1</title>
+		<dc:creator><![CDATA[testuser1]]></dc:creator>
+		<content:encoded><![CDATA[<img src="https://example.com/wp-content/uploads/2019/05/test.jpeg"> <a href="http://example.com/wp-content/uploads/missing.pdf">pdf</a>]]></content:encoded>
+		<excerpt:encoded><![CDATA[testDescription1]]></excerpt:encoded>
+		<wp:post_id>1</wp:post_id>
+		<wp:post_date>2019-05-01 12:00:00</wp:post_date>
+		<wp:post_date_gmt>2019-05-01 10:00:00</wp:post_date_gmt>
+		<wp:post_name>test-slug-1</wp:post_name>
+		<wp:status>publish</wp:status>
+		<wp:post_type>post</wp:post_type>
+		<category domain="category" nicename="test-sub-category-1"><![CDATA[testSubCategory1]]></category>
+		<category domain="category" nicename="test-category-1"><![CDATA[testCategory1]]></category>
+		<category domain="post_tag" nicename="go"><![CDATA[go]]></category>
+		<wp:postmeta><wp:meta_key>_thumbnail_id</wp:meta_key><wp:meta_value>3</wp:meta_value></wp:postmeta>
+		<wp:comment><wp:comment_id>1</wp:comment_id></wp:comment>
+	</item>
+	<item>
+		<title>testTitle2</title>
+		<dc:creator><![CDATA[testuser1]]></dc:creator>
+		<content:encoded><![CDATA[testContent2]]></content:encoded>
+		<wp:post_id>2</wp:post_id>
+		<wp:post_date>2019-06-01 12:00:00</wp:post_date>
+		<wp:post_date_gmt>0000-00-00 00:00:00</wp:post_date_gmt>
+		<wp:post_name>test-slug-2</wp:post_name>
+		<wp:status>draft</wp:status>
+		<wp:post_type>post</wp:post_type>
+		<category domain="category" nicename="test-category-1"><![CDATA[testCategory1]]></category>
+	</item>
+	<item>
+		<title>test</title>
+		<wp:post_id>3</wp:post_id>
+		<wp:post_name>test</wp:post_name>
+		<wp:post_type>attachment</wp:post_type>
+		<wp:attachment_url>https://example.com/wp-content/uploads/2019/05/test.jpeg</wp:attachment_url>
+	</item>
+	<item>
+		<title>testPage1</title>
+		<wp:post_id>4</wp:post_id>
+		<wp:post_name>about</wp:post_name>
+		<wp:status>publish</wp:status>
+		<wp:post_type>page</wp:post_type>
+	</item>
+</channel>
+</rss>`
+
+func TestImportService_ImportWordPress(t *testing.T) {
+	newDirs := func(t *testing.T) (uploadsDir string, mediaDir string) {
+		uploadsDir, mediaDir = t.TempDir(), t.TempDir()
+		assert.NoError(t, os.MkdirAll(filepath.Join(uploadsDir, "2019", "05"), 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(uploadsDir, "2019", "05", "test.jpeg"), []byte("testImage"), 0o644))
+		return
+	}
+
+	t.Run(
+		"imports categories, posts and uploads",
+		func(t *testing.T) {
+			uploadsDir, mediaDir := newDirs(t)
+			ps, cs, ss := new(mocks.IPostService), new(mocks.ICategoryService), new(mocks.ISubCategoryService)
+			cs.On("GetBySlug", "test-category-1").Return(dto.NewCategoryModel(1, "testCategory1", "test-category-1", 0), nil).Once()
+			cs.On("GetBySlug", "test-sub-category-1").Return(dto.CategoryModel{}, sql.ErrNoRows).Once()
+			cs.On("Create", admin, dto.CategoryModel{Name: "testSubCategory1", Slug: "test-sub-category-1", ParentId: 1}).Return(nil).Once()
+			cs.On("GetBySlug", "test-sub-category-1").Return(dto.NewCategoryModel(2, "testSubCategory1", "test-sub-category-1", 1), nil).Once()
+			ps.On("GetPostBySlug", mock.Anything).Return(dto.PostModel{}, sql.ErrNoRows)
+			ps.On("Create", admin, mock.MatchedBy(func(postDto dto.PostModel) bool {
+				return postDto.Slug == "test-slug-1"
+			})).Return(nil).Once()
+			ps.On("Create", admin, mock.MatchedBy(func(postDto dto.PostModel) bool {
+				return postDto.Slug == "test-slug-2"
+			})).Return(nil).Once()
+
+			s := NewImportService(ps, cs, ss)
+			ret, err := s.ImportWordPress(admin, strings.NewReader(testWXR), uploadsDir, mediaDir, dto.ImportOptionsModel{})
+
+			assert.NoError(t, err)
+			assert.Equal(t, []dto.ImportActionModel{
+				{Action: dto.ImportActionUnchanged, Kind: "category", Slug: "test-category-1"},
+				{Action: dto.ImportActionCreate, Kind: "category", Slug: "test-sub-category-1"},
+				{Action: dto.ImportActionCreate, Kind: "media", Slug: "2019/05/test.jpeg", Source: "post 1"},
+				{Action: dto.ImportActionUnmapped, Kind: "media", Slug: "missing.pdf", Source: "post 1", Detail: "not in the uploads directory"},
+				{Action: dto.ImportActionUnmapped, Kind: "category", Slug: "test-category-1", Source: "post 1", Detail: "posts have one category, filed under test-sub-category-1"},
+				{Action: dto.ImportActionCreate, Kind: "post", Slug: "test-slug-1", Source: "post 1", Detail: "tags are not stored"},
+				{Action: dto.ImportActionCreate, Kind: "post", Slug: "test-slug-2", Source: "post 2"},
+				{Action: dto.ImportActionUnmapped, Kind: "page", Slug: "about", Source: "page 4", Detail: "testPage1"},
+				{Action: dto.ImportActionUnmapped, Kind: "author", Slug: "testuser1", Detail: "2 posts are imported without an author"},
+				{Action: dto.ImportActionUnmapped, Kind: "comments", Detail: "1 comments are not imported"},
+			}, ret.Actions)
+
+			post1 := ps.Calls[1].Arguments.Get(1).(dto.PostModel)
+			assert.Equal(t, `<img src="/api/v1/media/2019/05/test.jpeg"> <a href="http://example.com/wp-content/uploads/missing.pdf">pdf</a>`, post1.Content)
+			assert.Equal(t, "testDescription1", post1.MetaDescription)
+			assert.Equal(t, "2019/05/test.jpeg", post1.EyeCatchingImg)
+			assert.Equal(t, 2, post1.CategoryId)
+			assert.True(t, post1.IsPublic)
+			assert.Equal(t, time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC), post1.CreatedAt)
+			post2 := ps.Calls[3].Arguments.Get(1).(dto.PostModel)
+			assert.False(t, post2.IsPublic)
+			assert.Equal(t, time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC), post2.CreatedAt)
+
+			copied, err := os.ReadFile(filepath.Join(mediaDir, "2019", "05", "test.jpeg"))
+			assert.NoError(t, err)
+			assert.Equal(t, "testImage", string(copied))
+			ps.AssertExpectations(t)
+			cs.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"dry run copies and writes nothing",
+		func(t *testing.T) {
+			uploadsDir, mediaDir := newDirs(t)
+			ps, cs, ss := new(mocks.IPostService), new(mocks.ICategoryService), new(mocks.ISubCategoryService)
+			cs.On("GetBySlug", mock.Anything).Return(dto.CategoryModel{}, sql.ErrNoRows)
+			ps.On("GetPostBySlug", mock.Anything).Return(dto.PostModel{}, sql.ErrNoRows)
+
+			s := NewImportService(ps, cs, ss)
+			ret, err := s.ImportWordPress(admin, strings.NewReader(testWXR), uploadsDir, mediaDir, dto.ImportOptionsModel{DryRun: true})
+
+			assert.NoError(t, err)
+			assert.True(t, ret.DryRun)
+			assert.Equal(t, dto.ImportActionCreate, ret.Actions[0].Action)
+			assert.Equal(t, "test-category-1", ret.Actions[0].Slug)
+			cs.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			ps.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			_, err = os.Stat(filepath.Join(mediaDir, "2019"))
+			assert.True(t, os.IsNotExist(err))
+		},
+	)
+
+	t.Run(
+		"rejects a file that is not WXR",
+		func(t *testing.T) {
+			s := NewImportService(new(mocks.IPostService), new(mocks.ICategoryService), new(mocks.ISubCategoryService))
+			_, err := s.ImportWordPress(admin, strings.NewReader("not xml"), "", "", dto.ImportOptionsModel{})
+
+			assert.ErrorIs(t, err, ErrInvalidWXR)
+		},
+	)
+
+	t.Run(
+		"needs permission to manage categories",
+		func(t *testing.T) {
+			s := NewImportService(new(mocks.IPostService), new(mocks.ICategoryService), new(mocks.ISubCategoryService))
+			_, err := s.ImportWordPress(author, strings.NewReader(testWXR), "", "", dto.ImportOptionsModel{})
+
+			assert.ErrorIs(t, err, ErrForbidden)
+		},
+	)
+}
@@ -0,0 +1,25 @@
+package cache
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+)
+
+// ArchiveRepository invalidates the whole query cache when an archive is restored,
+// which rewrites posts and categories behind the back of their repositories.
+type ArchiveRepository struct {
+	repository.IArchiveRepository
+	cache *ReadThrough
+}
+
+func NewArchiveRepository(archiveRepository repository.IArchiveRepository, cache *ReadThrough) repository.IArchiveRepository {
+	return &ArchiveRepository{archiveRepository, cache}
+}
+
+func (r *ArchiveRepository) Restore(archive entity.Archive, replace bool) (err error) {
+	err = r.IArchiveRepository.Restore(archive, replace)
+	if err == nil {
+		r.cache.invalidate(categoryWrites)
+	}
+	return
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+	"strconv"
+)
+
+// CategoryRepository reads categories through the query cache, and invalidates
+// it when the tree changes.
+type CategoryRepository struct {
+	repository.ICategoryRepository
+	cache *ReadThrough
+}
+
+func NewCategoryRepository(categoryRepository repository.ICategoryRepository, cache *ReadThrough) repository.ICategoryRepository {
+	return &CategoryRepository{categoryRepository, cache}
+}
+
+func (r *CategoryRepository) GetAll() (categories []entity.Category, err error) {
+	err = r.cache.load(categoriesPrefix+"all", &categories, func() (interface{}, error) {
+		return r.ICategoryRepository.GetAll()
+	})
+	return
+}
+
+func (r *CategoryRepository) GetTree() (categories []entity.Category, err error) {
+	err = r.cache.load(categoriesPrefix+"tree", &categories, func() (interface{}, error) {
+		return r.ICategoryRepository.GetTree()
+	})
+	return
+}
+
+func (r *CategoryRepository) GetChildren(parentId int) (categories []entity.Category, err error) {
+	err = r.cache.load(categoriesPrefix+"children:"+strconv.Itoa(parentId), &categories, func() (interface{}, error) {
+		return r.ICategoryRepository.GetChildren(parentId)
+	})
+	return
+}
+
+func (r *CategoryRepository) GetBySlug(slug string) (category entity.Category, err error) {
+	err = r.cache.load(categoriesPrefix+"slug:"+slug, &category, func() (interface{}, error) {
+		return r.ICategoryRepository.GetBySlug(slug)
+	})
+	return
+}
+
+func (r *CategoryRepository) GetStats(ids []int) (stats map[int]entity.CategoryStats, err error) {
+	err = r.cache.load(categoriesPrefix+"stats:"+idsKey(ids), &stats, func() (interface{}, error) {
+		return r.ICategoryRepository.GetStats(ids)
+	})
+	return
+}
+
+func (r *CategoryRepository) GetAncestors(id int) (categories []entity.Category, err error) {
+	err = r.cache.load(categoriesPrefix+"ancestors:"+strconv.Itoa(id), &categories, func() (interface{}, error) {
+		return r.ICategoryRepository.GetAncestors(id)
+	})
+	return
+}
+
+func (r *CategoryRepository) Create(category entity.Category) (err error) {
+	err = r.ICategoryRepository.Create(category)
+	if err == nil {
+		r.cache.invalidate(categoryWrites)
+	}
+	return
+}
+
+func (r *CategoryRepository) Update(category entity.Category) (err error) {
+	err = r.ICategoryRepository.Update(category)
+	if err == nil {
+		r.cache.invalidate(categoryWrites)
+	}
+	return
+}
+
+func (r *CategoryRepository) Delete(category entity.Category) (err error) {
+	err = r.ICategoryRepository.Delete(category)
+	if err == nil {
+		r.cache.invalidate(categoryWrites)
+	}
+	return
+}
+
+func (r *CategoryRepository) Reorder(ids []int) (err error) {
+	err = r.ICategoryRepository.Reorder(ids)
+	if err == nil {
+		r.cache.invalidate(categoryWrites)
+	}
+	return
+}
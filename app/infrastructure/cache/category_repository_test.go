@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"backend/app/domain/entity"
+	"testing"
+	"time"
+
+	mocks "backend/mocks/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryRepository(t *testing.T) {
+	stats := map[int]entity.CategoryStats{1: {PostCount: 3}}
+
+	t.Run("read through", func(t *testing.T) {
+		categoryRepository := new(mocks.ICategoryRepository)
+		categoryRepository.On("GetStats", []int{1}).Return(stats, nil).Once()
+		r := NewCategoryRepository(categoryRepository, NewReadThrough(NewLRUQueryCache(10), time.Minute))
+
+		for i := 0; i < 2; i++ {
+			cached, err := r.GetStats([]int{1})
+
+			assert.NoError(t, err)
+			assert.Equal(t, stats, cached)
+		}
+		categoryRepository.AssertNumberOfCalls(t, "GetStats", 1)
+	})
+
+	t.Run("writes invalidate every query", func(t *testing.T) {
+		category := entity.Category{Id: 1, Name: "Go", Slug: "go"}
+		categoryRepository := new(mocks.ICategoryRepository)
+		categoryRepository.On("Update", category).Return(nil)
+		queryCache := NewLRUQueryCache(10)
+		for _, key := range []string{"categories:tree", "sub-categories:slug:go", "posts:list:"} {
+			queryCache.Set(key, []byte("{}"), time.Minute)
+		}
+		r := NewCategoryRepository(categoryRepository, NewReadThrough(queryCache, time.Minute))
+
+		assert.NoError(t, r.Update(category))
+
+		for _, key := range []string{"categories:tree", "sub-categories:slug:go", "posts:list:"} {
+			_, found, _ := queryCache.Get(key)
+			assert.False(t, found, key)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package cache
+
+import "sync"
+
+// flightGroup runs a function once for concurrent callers asking for the same
+// key, so that when a hot entry expires a single query goes to the database
+// while the others wait for its result.
+type flightGroup struct {
+	mutex   sync.Mutex
+	flights map[string]*flight
+}
+
+type flight struct {
+	done  sync.WaitGroup
+	value []byte
+	err   error
+}
+
+// do returns the result of fn, run by this call or by a call for key already
+// in progress. shared tells whether the result came from another call.
+func (g *flightGroup) do(key string, fn func() ([]byte, error)) (value []byte, shared bool, err error) {
+	g.mutex.Lock()
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	if f, ok := g.flights[key]; ok {
+		g.mutex.Unlock()
+		f.done.Wait()
+		return f.value, true, f.err
+	}
+	f := &flight{}
+	f.done.Add(1)
+	g.flights[key] = f
+	g.mutex.Unlock()
+
+	defer func() {
+		g.mutex.Lock()
+		delete(g.flights, key)
+		g.mutex.Unlock()
+		f.done.Done()
+	}()
+	f.value, f.err = fn()
+	value, err = f.value, f.err
+	return
+}
@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+)
+
+// ImageDerivativeRepository invalidates the cached posts when the derivatives of
+// an image change: on upload, generation and deletion of media. Posts carry the
+// derivatives of their eye-catching image.
+type ImageDerivativeRepository struct {
+	repository.IImageDerivativeRepository
+	cache *ReadThrough
+}
+
+func NewImageDerivativeRepository(imageDerivativeRepository repository.IImageDerivativeRepository, cache *ReadThrough) repository.IImageDerivativeRepository {
+	return &ImageDerivativeRepository{imageDerivativeRepository, cache}
+}
+
+func (r *ImageDerivativeRepository) Save(source string, derivatives []entity.ImageDerivative) (err error) {
+	err = r.IImageDerivativeRepository.Save(source, derivatives)
+	if err == nil {
+		r.cache.invalidate(embeddedWrites)
+	}
+	return
+}
+
+func (r *ImageDerivativeRepository) Delete(source string) (derivatives []entity.ImageDerivative, err error) {
+	derivatives, err = r.IImageDerivativeRepository.Delete(source)
+	if err == nil {
+		r.cache.invalidate(embeddedWrites)
+	}
+	return
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"backend/app/domain/repository"
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LRUQueryCache keeps up to capacity entries in the memory of the process. When it
+// is full, setting a new key evicts the least recently used one.
+type LRUQueryCache struct {
+	mutex    sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	// order holds the *lruEntry values, the most recently used first.
+	order *list.List
+	now   func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func NewLRUQueryCache(capacity int) repository.IQueryCache {
+	if capacity < 1 {
+		capacity = 1000
+	}
+	return &LRUQueryCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+func (c *LRUQueryCache) Get(key string) (value []byte, found bool, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	entry := element.Value.(*lruEntry)
+	if !c.now().Before(entry.expires) {
+		c.remove(element)
+		return
+	}
+	c.order.MoveToFront(element)
+	value, found = entry.value, true
+	return
+}
+
+func (c *LRUQueryCache) Set(key string, value []byte, ttl time.Duration) (err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	expires := c.now().Add(ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key, value, expires})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+	return
+}
+
+func (c *LRUQueryCache) DeletePrefix(prefix string) (err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, element := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(element)
+		}
+	}
+	return
+}
+
+func (c *LRUQueryCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*lruEntry).key)
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUQueryCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewLRUQueryCache(2).(*LRUQueryCache)
+	c.now = func() time.Time { return now }
+
+	t.Run("get what was set", func(t *testing.T) {
+		assert.NoError(t, c.Set("posts:slug:a", []byte("a"), time.Minute))
+
+		value, found, err := c.Get("posts:slug:a")
+
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []byte("a"), value)
+
+		_, found, _ = c.Get("posts:slug:b")
+		assert.False(t, found)
+	})
+
+	t.Run("evict the least recently used", func(t *testing.T) {
+		c.Set("posts:slug:b", []byte("b"), time.Minute)
+		c.Get("posts:slug:a")
+		c.Set("posts:slug:c", []byte("c"), time.Minute)
+
+		_, foundA, _ := c.Get("posts:slug:a")
+		_, foundB, _ := c.Get("posts:slug:b")
+		_, foundC, _ := c.Get("posts:slug:c")
+
+		assert.True(t, foundA)
+		assert.False(t, foundB)
+		assert.True(t, foundC)
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		c.Set("posts:slug:a", []byte("a"), time.Second)
+		now = now.Add(time.Second)
+
+		_, found, _ := c.Get("posts:slug:a")
+
+		assert.False(t, found)
+		assert.NotContains(t, c.entries, "posts:slug:a")
+	})
+
+	t.Run("delete by prefix", func(t *testing.T) {
+		c.Set("posts:list:", []byte("[]"), time.Minute)
+		c.Set("categories:tree", []byte("[]"), time.Minute)
+
+		assert.NoError(t, c.DeletePrefix("posts:"))
+
+		_, foundPosts, _ := c.Get("posts:list:")
+		_, foundCategories, _ := c.Get("categories:tree")
+		assert.False(t, foundPosts)
+		assert.True(t, foundCategories)
+	})
+}
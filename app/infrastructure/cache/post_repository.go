@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+)
+
+// PostRepository reads posts through the query cache, and invalidates it when
+// they change. GetPostById is left uncached: the services use it to check the
+// post they are about to write, which must be current.
+type PostRepository struct {
+	repository.IPostRepository
+	cache *ReadThrough
+}
+
+func NewPostRepository(postRepository repository.IPostRepository, cache *ReadThrough) repository.IPostRepository {
+	return &PostRepository{postRepository, cache}
+}
+
+func (r *PostRepository) GetPosts(query map[string][]string) (posts []entity.Post, err error) {
+	err = r.cache.load(postsPrefix+"list:"+postsQueryKey(query), &posts, func() (interface{}, error) {
+		return r.IPostRepository.GetPosts(query)
+	})
+	return
+}
+
+// postsQueryKey keys a list of posts by the filters postgresql.PostRepository.GetPosts
+// applies, the first value of each, so that other parameters do not multiply the
+// entries of one list. A filter given empty still filters, so it is kept.
+func postsQueryKey(query map[string][]string) string {
+	filters := make(map[string][]string)
+	category, ok := query["category-name"]
+	if !ok {
+		category, ok = query["sub-category-name"]
+	}
+	if ok && len(category) > 0 {
+		filters["category"] = category[:1]
+	}
+	if author, ok := query["author"]; ok && len(author) > 0 {
+		filters["author"] = author[:1]
+	}
+	return queryKey(filters)
+}
+
+func (r *PostRepository) GetPostBySlug(slug string) (post entity.Post, err error) {
+	err = r.cache.load(postsPrefix+"slug:"+slug, &post, func() (interface{}, error) {
+		return r.IPostRepository.GetPostBySlug(slug)
+	})
+	return
+}
+
+func (r *PostRepository) Create(post entity.Post) (err error) {
+	err = r.IPostRepository.Create(post)
+	if err == nil {
+		r.cache.invalidate(postWrites)
+	}
+	return
+}
+
+func (r *PostRepository) Update(post entity.Post) (err error) {
+	err = r.IPostRepository.Update(post)
+	if err == nil {
+		r.cache.invalidate(postWrites)
+	}
+	return
+}
+
+func (r *PostRepository) Delete(post entity.Post) (err error) {
+	err = r.IPostRepository.Delete(post)
+	if err == nil {
+		r.cache.invalidate(postWrites)
+	}
+	return
+}
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"backend/app/domain/entity"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	mocks "backend/mocks/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPostRepository(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	post := entity.Post{Id: 1, Title: "Hello", Slug: "hello", CreatedAt: createdAt, CategoryName: "Go", Version: 2}
+
+	t.Run("read through", func(t *testing.T) {
+		postRepository := new(mocks.IPostRepository)
+		postRepository.On("GetPostBySlug", "hello").Return(post, nil).Once()
+		r := NewPostRepository(postRepository, NewReadThrough(NewLRUQueryCache(10), time.Minute))
+
+		for i := 0; i < 2; i++ {
+			cached, err := r.GetPostBySlug("hello")
+
+			assert.NoError(t, err)
+			assert.Equal(t, post, cached)
+		}
+		postRepository.AssertNumberOfCalls(t, "GetPostBySlug", 1)
+	})
+
+	t.Run("key lists by their filters", func(t *testing.T) {
+		postRepository := new(mocks.IPostRepository)
+		postRepository.On("GetPosts", mock.Anything).Return([]entity.Post{post}, nil)
+		r := NewPostRepository(postRepository, NewReadThrough(NewLRUQueryCache(10), time.Minute))
+
+		r.GetPosts(map[string][]string{"category-name": {"go"}, "author": {"alice"}})
+		// parameters the repository ignores, and values after the first, share the entry
+		r.GetPosts(map[string][]string{"author": {"alice", "bob"}, "category-name": {"go"}, "utm_source": {"feed"}})
+		r.GetPosts(map[string][]string{"sub-category-name": {"go"}, "author": {"alice"}})
+		r.GetPosts(map[string][]string{"category-name": {"go"}})
+		r.GetPosts(map[string][]string{"category-name": {""}})
+		r.GetPosts(nil)
+		r.GetPosts(map[string][]string{"page": {"2"}})
+
+		postRepository.AssertNumberOfCalls(t, "GetPosts", 4)
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		postRepository := new(mocks.IPostRepository)
+		postRepository.On("GetPostBySlug", "missing").Return(entity.Post{}, sql.ErrNoRows)
+		r := NewPostRepository(postRepository, NewReadThrough(NewLRUQueryCache(10), time.Minute))
+
+		for i := 0; i < 2; i++ {
+			_, err := r.GetPostBySlug("missing")
+
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+		}
+		postRepository.AssertNumberOfCalls(t, "GetPostBySlug", 2)
+	})
+
+	t.Run("GetPostById is not cached", func(t *testing.T) {
+		postRepository := new(mocks.IPostRepository)
+		postRepository.On("GetPostById", 1).Return(post, nil)
+		r := NewPostRepository(postRepository, NewReadThrough(NewLRUQueryCache(10), time.Minute))
+
+		r.GetPostById(1)
+		r.GetPostById(1)
+
+		postRepository.AssertNumberOfCalls(t, "GetPostById", 2)
+	})
+
+	t.Run("writes invalidate posts and category stats", func(t *testing.T) {
+		postRepository := new(mocks.IPostRepository)
+		postRepository.On("Update", post).Return(nil)
+		queryCache := NewLRUQueryCache(10)
+		for _, key := range []string{"posts:slug:hello", "categories:stats:1", "sub-categories:stats:1", "categories:tree"} {
+			queryCache.Set(key, []byte("{}"), time.Minute)
+		}
+		r := NewPostRepository(postRepository, NewReadThrough(queryCache, time.Minute))
+
+		assert.NoError(t, r.Update(post))
+
+		for key, cached := range map[string]bool{"posts:slug:hello": false, "categories:stats:1": false, "sub-categories:stats:1": false, "categories:tree": true} {
+			_, found, _ := queryCache.Get(key)
+			assert.Equal(t, cached, found, key)
+		}
+	})
+
+	t.Run("failed writes do not invalidate", func(t *testing.T) {
+		postRepository := new(mocks.IPostRepository)
+		postRepository.On("Delete", post).Return(sql.ErrConnDone)
+		queryCache := NewLRUQueryCache(10)
+		queryCache.Set("posts:slug:hello", []byte("{}"), time.Minute)
+		r := NewPostRepository(postRepository, NewReadThrough(queryCache, time.Minute))
+
+		assert.ErrorIs(t, r.Delete(post), sql.ErrConnDone)
+
+		_, found, _ := queryCache.Get("posts:slug:hello")
+		assert.True(t, found)
+	})
+
+	t.Run("concurrent misses share one query", func(t *testing.T) {
+		release := make(chan struct{})
+		postRepository := new(mocks.IPostRepository)
+		postRepository.On("GetPostBySlug", "hello").Run(func(mock.Arguments) { <-release }).Return(post, nil)
+		r := NewPostRepository(postRepository, NewReadThrough(NewLRUQueryCache(10), time.Minute))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cached, err := r.GetPostBySlug("hello")
+				assert.NoError(t, err)
+				assert.Equal(t, post, cached)
+			}()
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		postRepository.AssertNumberOfCalls(t, "GetPostBySlug", 1)
+	})
+
+	t.Run("a query overtaken by a write is not cached", func(t *testing.T) {
+		release := make(chan struct{})
+		postRepository := new(mocks.IPostRepository)
+		postRepository.On("GetPostBySlug", "hello").Run(func(mock.Arguments) { <-release }).Return(post, nil).Once()
+		postRepository.On("Update", post).Return(nil)
+		queryCache := NewLRUQueryCache(10)
+		r := NewPostRepository(postRepository, NewReadThrough(queryCache, time.Minute))
+
+		done := make(chan struct{})
+		go func() {
+			r.GetPostBySlug("hello")
+			close(done)
+		}()
+		time.Sleep(20 * time.Millisecond)
+		r.Update(post)
+		close(release)
+		<-done
+
+		_, found, _ := queryCache.Get("posts:slug:hello")
+		assert.False(t, found)
+	})
+}
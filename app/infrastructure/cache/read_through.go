@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"backend/app/domain/repository"
+	"encoding/json"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Key prefixes of the cached queries, one per repository.
+const (
+	categoriesPrefix    = "categories:"
+	subCategoriesPrefix = "sub-categories:"
+	postsPrefix         = "posts:"
+)
+
+var (
+	// A write to the category tree changes every cached query: posts carry the
+	// name and slug of their category, and are filtered by its subtree.
+	categoryWrites = []string{categoriesPrefix, subCategoriesPrefix, postsPrefix}
+	// A write to the posts changes the post queries and the post counts of the
+	// categories, but nothing else about them.
+	postWrites = []string{postsPrefix, categoriesPrefix + "stats:", subCategoriesPrefix + "stats:"}
+	// Posts carry the profile of their author and the derivatives of their image,
+	// so writes to users and image derivatives change the post queries too.
+	embeddedWrites = []string{postsPrefix}
+)
+
+// ReadThrough reads query results through an IQueryCache on behalf of the caching
+// repositories, which share one per process. Results are cached as JSON for ttl.
+// The cache only ever speeds things up: when it fails, the error is logged and
+// the query goes to the database.
+type ReadThrough struct {
+	cache  repository.IQueryCache
+	ttl    time.Duration
+	flight flightGroup
+	// generation counts the invalidations, so that a query which started before
+	// one does not put its now stale result in the cache after it.
+	generation uint64
+}
+
+func NewReadThrough(cache repository.IQueryCache, ttl time.Duration) *ReadThrough {
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	return &ReadThrough{cache: cache, ttl: ttl}
+}
+
+// load decodes the cached result for key into v. On a miss it runs query, caches
+// its result and decodes that; concurrent misses for key share a single query.
+// Errors of query are returned as they are and not cached.
+func (c *ReadThrough) load(key string, v interface{}, query func() (interface{}, error)) (err error) {
+	data, found, err := c.cache.Get(key)
+	if err != nil {
+		log.Printf("query cache: get %s: %v", key, err)
+	}
+	if found && json.Unmarshal(data, v) == nil {
+		err = nil
+		return
+	}
+	generation := atomic.LoadUint64(&c.generation)
+	data, _, err = c.flight.do(strconv.FormatUint(generation, 10)+"/"+key, func() (data []byte, err error) {
+		result, err := query()
+		if err != nil {
+			return
+		}
+		data, err = json.Marshal(result)
+		if err != nil {
+			return
+		}
+		if atomic.LoadUint64(&c.generation) != generation {
+			return
+		}
+		if err := c.cache.Set(key, data, c.ttl); err != nil {
+			log.Printf("query cache: set %s: %v", key, err)
+		}
+		return
+	})
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, v)
+	return
+}
+
+// invalidate drops the cached queries under prefixes after a write.
+func (c *ReadThrough) invalidate(prefixes []string) {
+	atomic.AddUint64(&c.generation, 1)
+	for _, prefix := range prefixes {
+		if err := c.cache.DeletePrefix(prefix); err != nil {
+			log.Printf("query cache: invalidate %s: %v", prefix, err)
+		}
+	}
+}
+
+// queryKey encodes the query parameters of a list in a canonical order.
+func queryKey(query map[string][]string) string {
+	return url.Values(query).Encode()
+}
+
+// idsKey encodes a list of ids.
+func idsKey(ids []int) string {
+	texts := make([]string, len(ids))
+	for i, id := range ids {
+		texts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(texts, ",")
+}
@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"backend/app/domain/repository"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedisConfig locates a Redis server, or another server speaking its protocol.
+// Keys are stored with KeyPrefix prepended, so that several applications can
+// share a database.
+type RedisConfig struct {
+	Address   string
+	Username  string
+	Password  string
+	DB        int
+	KeyPrefix string
+	// Timeout bounds connecting and each command; a slow server is treated as
+	// unavailable rather than holding up the requests.
+	Timeout time.Duration
+}
+
+// RedisQueryCache keeps the entries in Redis, so that they are shared by every
+// server process and survive restarts. It talks RESP over a small pool of
+// connections.
+//
+// Each prefix of a key that ends in a colon, such as "categories:" and
+// "categories:stats:" for "categories:stats:1,2", has a version, kept under
+// "version/<prefix>", and the key is stored under the versions of all of them.
+// DeletePrefix increments the version of the prefix, which orphans its keys in
+// a single command; they expire with their TTL.
+type RedisQueryCache struct {
+	config RedisConfig
+	idle   chan *redisConn
+}
+
+// redisError is an error reply of the server. Unlike network errors, it leaves
+// the connection usable.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// maxIdleConns is the number of connections kept open between commands.
+const maxIdleConns = 8
+
+func NewRedisQueryCache(config RedisConfig) (queryCache repository.IQueryCache, err error) {
+	if config.Address == "" {
+		config.Address = "localhost:6379"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = time.Second
+	}
+	if _, _, err = net.SplitHostPort(config.Address); err != nil {
+		err = fmt.Errorf("redis address %q is not host:port", config.Address)
+		return
+	}
+	queryCache = &RedisQueryCache{config, make(chan *redisConn, maxIdleConns)}
+	return
+}
+
+func (c *RedisQueryCache) Get(key string) (value []byte, found bool, err error) {
+	stored, err := c.storedKey(key)
+	if err != nil {
+		return
+	}
+	reply, err := c.do("GET", stored)
+	if err != nil || reply == nil {
+		return
+	}
+	value, found = reply.([]byte)
+	if !found {
+		err = fmt.Errorf("redis: unexpected reply %v to GET", reply)
+	}
+	return
+}
+
+func (c *RedisQueryCache) Set(key string, value []byte, ttl time.Duration) (err error) {
+	milliseconds := ttl.Milliseconds()
+	if milliseconds < 1 {
+		milliseconds = 1
+	}
+	stored, err := c.storedKey(key)
+	if err != nil {
+		return
+	}
+	_, err = c.do("SET", stored, string(value), "PX", strconv.FormatInt(milliseconds, 10))
+	return
+}
+
+// DeletePrefix increments the version of prefix, which must end in a colon, so
+// that a write waits on one command whatever the number of keys it drops.
+func (c *RedisQueryCache) DeletePrefix(prefix string) (err error) {
+	if !strings.HasSuffix(prefix, ":") {
+		err = fmt.Errorf("redis: cannot delete the keys under %q, which does not end in a colon", prefix)
+		return
+	}
+	_, err = c.do("INCR", c.versionKey(prefix))
+	return
+}
+
+// versionKey is the key of the version of prefix.
+func (c *RedisQueryCache) versionKey(prefix string) string {
+	return c.config.KeyPrefix + "version/" + prefix
+}
+
+// storedKey returns the key under which key is stored: the current versions of
+// its prefixes, then key, e.g. "blog:3.0/posts:slug:hello".
+func (c *RedisQueryCache) storedKey(key string) (stored string, err error) {
+	args := []string{"MGET"}
+	for i := range key {
+		if key[i] == ':' {
+			args = append(args, c.versionKey(key[:i+1]))
+		}
+	}
+	if len(args) == 1 {
+		stored = c.config.KeyPrefix + "/" + key
+		return
+	}
+	reply, err := c.do(args...)
+	if err != nil {
+		return
+	}
+	versions, ok := reply.([]interface{})
+	if !ok || len(versions) != len(args)-1 {
+		err = fmt.Errorf("redis: unexpected reply %v to MGET", reply)
+		return
+	}
+	texts := make([]string, len(versions))
+	for i, version := range versions {
+		texts[i] = "0"
+		if version, ok := version.([]byte); ok {
+			texts[i] = string(version)
+		}
+	}
+	stored = c.config.KeyPrefix + strings.Join(texts, ".") + "/" + key
+	return
+}
+
+// do sends a command and reads its reply. Bulk strings are returned as []byte,
+// integers as int64, arrays as []interface{} and nil replies as nil.
+func (c *RedisQueryCache) do(args ...string) (reply interface{}, err error) {
+	conn, err := c.conn()
+	if err != nil {
+		return
+	}
+	reply, err = conn.do(c.config.Timeout, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		conn.Close()
+		return
+	}
+	select {
+	case c.idle <- conn:
+	default:
+		conn.Close()
+	}
+	return
+}
+
+// conn takes an idle connection, or opens and authenticates a new one.
+func (c *RedisQueryCache) conn() (conn *redisConn, err error) {
+	select {
+	case conn = <-c.idle:
+		return
+	default:
+	}
+	netConn, err := net.DialTimeout("tcp", c.config.Address, c.config.Timeout)
+	if err != nil {
+		return
+	}
+	conn = &redisConn{netConn, bufio.NewReader(netConn)}
+	if c.config.Password != "" {
+		args := []string{"AUTH", c.config.Password}
+		if c.config.Username != "" {
+			args = []string{"AUTH", c.config.Username, c.config.Password}
+		}
+		_, err = conn.do(c.config.Timeout, args...)
+	}
+	if err == nil && c.config.DB != 0 {
+		_, err = conn.do(c.config.Timeout, "SELECT", strconv.Itoa(c.config.DB))
+	}
+	if err != nil {
+		conn.Close()
+		conn = nil
+	}
+	return
+}
+
+func (conn *redisConn) do(timeout time.Duration, args ...string) (reply interface{}, err error) {
+	if err = conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return
+	}
+	var command strings.Builder
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err = io.WriteString(conn, command.String()); err != nil {
+		return
+	}
+	reply, err = readReply(conn.reader)
+	return
+}
+
+func readReply(reader *bufio.Reader) (reply interface{}, err error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return
+	}
+	if !strings.HasSuffix(line, "\r\n") || len(line) < 3 {
+		err = fmt.Errorf("redis: malformed reply %q", line)
+		return
+	}
+	kind, text := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		reply = text
+	case '-':
+		err = redisError(text)
+	case ':':
+		reply, err = strconv.ParseInt(text, 10, 64)
+	case '$':
+		var size int
+		size, err = strconv.Atoi(text)
+		if err != nil || size < 0 {
+			return
+		}
+		data := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, data); err != nil {
+			return
+		}
+		reply = data[:size]
+	case '*':
+		var count int
+		count, err = strconv.Atoi(text)
+		if err != nil || count < 0 {
+			return
+		}
+		elements := make([]interface{}, count)
+		for i := range elements {
+			if elements[i], err = readReply(reader); err != nil {
+				return
+			}
+		}
+		reply = elements
+	default:
+		err = fmt.Errorf("redis: malformed reply %q", line)
+	}
+	return
+}
@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// redisStandIn is an in-memory server speaking enough RESP for the cache: AUTH,
+// SELECT, GET, MGET, SET with PX and INCR.
+type redisStandIn struct {
+	listener net.Listener
+	password string
+	mu       sync.Mutex
+	values   map[string]string
+	expires  map[string]time.Time
+	commands []string
+}
+
+func newRedisStandIn(t *testing.T, password string) (s *redisStandIn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = &redisStandIn{listener: listener, password: password, values: map[string]string{}, expires: map[string]time.Time{}}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return
+}
+
+func (s *redisStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := s.password == ""
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range reply.([]interface{}) {
+			args = append(args, string(arg.([]byte)))
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, args[0])
+		switch {
+		case args[0] == "AUTH":
+			authenticated = args[len(args)-1] == s.password
+			if authenticated {
+				fmt.Fprint(conn, "+OK\r\n")
+			} else {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+			}
+		case !authenticated:
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+		case args[0] == "SELECT":
+			fmt.Fprint(conn, "+OK\r\n")
+		case args[0] == "GET":
+			s.get(conn, args[1])
+		case args[0] == "MGET":
+			fmt.Fprintf(conn, "*%d\r\n", len(args)-1)
+			for _, key := range args[1:] {
+				s.get(conn, key)
+			}
+		case args[0] == "SET":
+			milliseconds, _ := strconv.Atoi(args[4])
+			s.values[args[1]] = args[2]
+			s.expires[args[1]] = time.Now().Add(time.Duration(milliseconds) * time.Millisecond)
+			fmt.Fprint(conn, "+OK\r\n")
+		case args[0] == "INCR":
+			value, _ := strconv.Atoi(s.values[args[1]])
+			s.values[args[1]] = strconv.Itoa(value + 1)
+			delete(s.expires, args[1])
+			fmt.Fprintf(conn, ":%d\r\n", value+1)
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		s.mu.Unlock()
+	}
+}
+
+// get replies with the value of key, which does not expire unless SET gave it a TTL.
+func (s *redisStandIn) get(conn net.Conn, key string) {
+	value, found := s.values[key]
+	if expires, ok := s.expires[key]; ok && !time.Now().Before(expires) {
+		found = false
+	}
+	if !found {
+		fmt.Fprint(conn, "$-1\r\n")
+		return
+	}
+	fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+}
+
+func TestRedisQueryCache(t *testing.T) {
+	s := newRedisStandIn(t, "secret")
+	queryCache, err := NewRedisQueryCache(RedisConfig{Address: s.listener.Addr().String(), Password: "secret", DB: 2, KeyPrefix: "blog:"})
+	assert.NoError(t, err)
+
+	t.Run("get what was set", func(t *testing.T) {
+		assert.NoError(t, queryCache.Set("posts:slug:a", []byte("line\r\nbreak"), time.Minute))
+
+		value, found, err := queryCache.Get("posts:slug:a")
+
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []byte("line\r\nbreak"), value)
+		s.mu.Lock()
+		assert.Contains(t, s.values, "blog:0.0/posts:slug:a")
+		assert.Equal(t, []string{"AUTH", "SELECT", "MGET", "SET", "MGET", "GET"}, s.commands, "the connection is reused")
+		s.mu.Unlock()
+
+		_, found, err = queryCache.Get("posts:slug:b")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		assert.NoError(t, queryCache.Set("posts:slug:c", []byte("c"), time.Millisecond))
+		time.Sleep(5 * time.Millisecond)
+
+		_, found, err := queryCache.Get("posts:slug:c")
+
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("delete by prefix", func(t *testing.T) {
+		keys := []string{"posts:list:", "posts:list:page=2", "posts:slug:d", "posts*:x", "categories:tree", "categories:stats:1,2"}
+		for _, key := range keys {
+			assert.NoError(t, queryCache.Set(key, []byte("[]"), time.Minute))
+		}
+		s.mu.Lock()
+		s.commands = nil
+		s.mu.Unlock()
+
+		assert.NoError(t, queryCache.DeletePrefix("posts:"))
+		assert.NoError(t, queryCache.DeletePrefix("categories:stats:"))
+
+		s.mu.Lock()
+		assert.Equal(t, []string{"INCR", "INCR"}, s.commands, "one command per prefix, whatever the number of keys")
+		s.mu.Unlock()
+		for key, kept := range map[string]bool{"posts:list:": false, "posts:list:page=2": false, "posts:slug:d": false, "posts*:x": true, "categories:tree": true, "categories:stats:1,2": false} {
+			_, found, err := queryCache.Get(key)
+			assert.NoError(t, err)
+			assert.Equal(t, kept, found, key)
+		}
+
+		assert.NoError(t, queryCache.Set("posts:slug:d", []byte("{}"), time.Minute))
+		value, found, err := queryCache.Get("posts:slug:d")
+		assert.NoError(t, err)
+		assert.True(t, found, "set again after the delete")
+		assert.Equal(t, []byte("{}"), value)
+	})
+
+	t.Run("prefix not ending in a colon", func(t *testing.T) {
+		assert.Error(t, queryCache.DeletePrefix("posts"))
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		queryCache, _ := NewRedisQueryCache(RedisConfig{Address: s.listener.Addr().String(), Password: "wrong"})
+
+		_, _, err := queryCache.Get("posts:slug:a")
+
+		assert.EqualError(t, err, "redis: WRONGPASS invalid password")
+	})
+
+	t.Run("server down", func(t *testing.T) {
+		listener, _ := net.Listen("tcp", "127.0.0.1:0")
+		listener.Close()
+		queryCache, _ := NewRedisQueryCache(RedisConfig{Address: listener.Addr().String()})
+
+		_, _, err := queryCache.Get("posts:slug:a")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("malformed address", func(t *testing.T) {
+		_, err := NewRedisQueryCache(RedisConfig{Address: "localhost"})
+
+		assert.Error(t, err)
+	})
+}
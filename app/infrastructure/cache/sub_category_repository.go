@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+)
+
+// SubCategoryRepository reads sub-categories through the query cache, and
+// invalidates it when they change. Sub-categories are nodes of the category
+// tree, so their writes invalidate as much as those of categories.
+type SubCategoryRepository struct {
+	repository.ISubCategoryRepository
+	cache *ReadThrough
+}
+
+func NewSubCategoryRepository(subCategoryRepository repository.ISubCategoryRepository, cache *ReadThrough) repository.ISubCategoryRepository {
+	return &SubCategoryRepository{subCategoryRepository, cache}
+}
+
+func (r *SubCategoryRepository) GetSubCategories(query map[string][]string) (subCategories []entity.SubCategory, err error) {
+	err = r.cache.load(subCategoriesPrefix+"list:"+queryKey(query), &subCategories, func() (interface{}, error) {
+		return r.ISubCategoryRepository.GetSubCategories(query)
+	})
+	return
+}
+
+func (r *SubCategoryRepository) GetSubCategoryBySlug(slug string) (subCategory entity.SubCategory, err error) {
+	err = r.cache.load(subCategoriesPrefix+"slug:"+slug, &subCategory, func() (interface{}, error) {
+		return r.ISubCategoryRepository.GetSubCategoryBySlug(slug)
+	})
+	return
+}
+
+func (r *SubCategoryRepository) GetStats(ids []int) (stats map[int]entity.CategoryStats, err error) {
+	err = r.cache.load(subCategoriesPrefix+"stats:"+idsKey(ids), &stats, func() (interface{}, error) {
+		return r.ISubCategoryRepository.GetStats(ids)
+	})
+	return
+}
+
+func (r *SubCategoryRepository) Create(subCategory entity.SubCategory) (err error) {
+	err = r.ISubCategoryRepository.Create(subCategory)
+	if err == nil {
+		r.cache.invalidate(categoryWrites)
+	}
+	return
+}
+
+func (r *SubCategoryRepository) Update(subCategory entity.SubCategory) (err error) {
+	err = r.ISubCategoryRepository.Update(subCategory)
+	if err == nil {
+		r.cache.invalidate(categoryWrites)
+	}
+	return
+}
+
+func (r *SubCategoryRepository) Delete(subCategory entity.SubCategory) (err error) {
+	err = r.ISubCategoryRepository.Delete(subCategory)
+	if err == nil {
+		r.cache.invalidate(categoryWrites)
+	}
+	return
+}
@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+)
+
+// UserRepository invalidates the cached posts when a user changes, as posts carry
+// the profile of their author. Users themselves are not cached.
+type UserRepository struct {
+	repository.IUserRepository
+	cache *ReadThrough
+}
+
+func NewUserRepository(userRepository repository.IUserRepository, cache *ReadThrough) repository.IUserRepository {
+	return &UserRepository{userRepository, cache}
+}
+
+func (r *UserRepository) Update(user entity.User) (err error) {
+	err = r.IUserRepository.Update(user)
+	if err == nil {
+		r.cache.invalidate(embeddedWrites)
+	}
+	return
+}
+
+func (r *UserRepository) Delete(user entity.User) (err error) {
+	err = r.IUserRepository.Delete(user)
+	if err == nil {
+		r.cache.invalidate(embeddedWrites)
+	}
+	return
+}
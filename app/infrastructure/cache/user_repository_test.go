@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+	"database/sql"
+	"testing"
+	"time"
+
+	mocks "backend/mocks/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRepository(t *testing.T) {
+	user := entity.User{Id: 3, Name: "alice", AvatarUrl: "/api/v1/media/alice.png"}
+	newCache := func() repository.IQueryCache {
+		queryCache := NewLRUQueryCache(10)
+		for _, key := range []string{"posts:slug:hello", "posts:list:", "categories:tree"} {
+			queryCache.Set(key, []byte("{}"), time.Minute)
+		}
+		return queryCache
+	}
+
+	t.Run("profile updates invalidate posts", func(t *testing.T) {
+		userRepository := new(mocks.IUserRepository)
+		userRepository.On("Update", user).Return(nil)
+		userRepository.On("Delete", user).Return(nil)
+		for _, write := range []func(r *UserRepository) error{
+			func(r *UserRepository) error { return r.Update(user) },
+			func(r *UserRepository) error { return r.Delete(user) },
+		} {
+			queryCache := newCache()
+
+			assert.NoError(t, write(NewUserRepository(userRepository, NewReadThrough(queryCache, time.Minute)).(*UserRepository)))
+
+			for key, cached := range map[string]bool{"posts:slug:hello": false, "posts:list:": false, "categories:tree": true} {
+				_, found, _ := queryCache.Get(key)
+				assert.Equal(t, cached, found, key)
+			}
+		}
+	})
+
+	t.Run("failed writes do not invalidate", func(t *testing.T) {
+		userRepository := new(mocks.IUserRepository)
+		userRepository.On("Update", user).Return(sql.ErrConnDone)
+		queryCache := newCache()
+		r := NewUserRepository(userRepository, NewReadThrough(queryCache, time.Minute))
+
+		assert.ErrorIs(t, r.Update(user), sql.ErrConnDone)
+
+		_, found, _ := queryCache.Get("posts:slug:hello")
+		assert.True(t, found)
+	})
+}
+
+func TestImageDerivativeRepository(t *testing.T) {
+	derivatives := []entity.ImageDerivative{{Source: "abc.jpg", Kind: "thumbnail", Name: "abc-thumbnail.jpg"}}
+	derivativeRepository := new(mocks.IImageDerivativeRepository)
+	derivativeRepository.On("Save", "abc.jpg", derivatives).Return(nil)
+	derivativeRepository.On("Delete", "abc.jpg").Return(derivatives, nil)
+	derivativeRepository.On("GetBySource", "abc.jpg").Return(derivatives, nil)
+
+	for name, write := range map[string]func(r *ImageDerivativeRepository) error{
+		"upload and generation": func(r *ImageDerivativeRepository) error { return r.Save("abc.jpg", derivatives) },
+		"deletion": func(r *ImageDerivativeRepository) (err error) {
+			deleted, err := r.Delete("abc.jpg")
+			assert.Equal(t, derivatives, deleted)
+			return
+		},
+	} {
+		queryCache := NewLRUQueryCache(10)
+		queryCache.Set("posts:slug:hello", []byte("{}"), time.Minute)
+		queryCache.Set("categories:tree", []byte("{}"), time.Minute)
+		r := NewImageDerivativeRepository(derivativeRepository, NewReadThrough(queryCache, time.Minute)).(*ImageDerivativeRepository)
+
+		assert.NoError(t, write(r), name)
+
+		_, found, _ := queryCache.Get("posts:slug:hello")
+		assert.False(t, found, name)
+		_, found, _ = queryCache.Get("categories:tree")
+		assert.True(t, found, name)
+		// reads go to the database
+		ret, err := r.GetBySource("abc.jpg")
+		assert.NoError(t, err)
+		assert.Equal(t, derivatives, ret)
+	}
+}
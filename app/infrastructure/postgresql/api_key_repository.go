@@ -0,0 +1,96 @@
+package postgresql
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type ApiKeyRepository struct {
+	*sql.DB
+}
+
+func NewApiKeyRepository(db *sql.DB) (apiKeyRepository repository.IApiKeyRepository) {
+	apiKeyRepository = &ApiKeyRepository{db}
+	return
+}
+
+const selectApiKeys = `
+	select api_keys.id, api_keys.user_id, users.username, api_keys.name, api_keys.prefix, api_keys.key_hash,
+		api_keys.scopes, api_keys.created_at, api_keys.last_used_at, api_keys.revoked_at is not null
+	from api_keys join users on api_keys.user_id = users.id
+`
+
+func scanApiKey(row rowScanner) (apiKey entity.ApiKey, err error) {
+	var lastUsedAt sql.NullTime
+	err = row.Scan(
+		&apiKey.Id,
+		&apiKey.UserId,
+		&apiKey.Username,
+		&apiKey.Name,
+		&apiKey.Prefix,
+		&apiKey.KeyHash,
+		pq.Array(&apiKey.Scopes),
+		&apiKey.CreatedAt,
+		&lastUsedAt,
+		&apiKey.Revoked,
+	)
+	apiKey.LastUsedAt = lastUsedAt.Time
+	return
+}
+
+func (r *ApiKeyRepository) GetAll() (apiKeys []entity.ApiKey, err error) {
+	rows, err := r.Query(selectApiKeys + " order by api_keys.created_at")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var apiKey entity.ApiKey
+		apiKey, err = scanApiKey(rows)
+		if err != nil {
+			return
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+	err = rows.Err()
+	return
+}
+
+func (r *ApiKeyRepository) GetByPrefix(prefix string) (apiKey entity.ApiKey, err error) {
+	apiKey, err = scanApiKey(r.QueryRow(selectApiKeys+" where api_keys.prefix = $1", prefix))
+	return
+}
+
+func (r *ApiKeyRepository) Create(apiKey entity.ApiKey) (err error) {
+	_, err = r.Exec("insert into api_keys (user_id, name, prefix, key_hash, scopes) values ($1, $2, $3, $4, $5)",
+		apiKey.UserId, apiKey.Name, apiKey.Prefix, apiKey.KeyHash, pq.Array(apiKey.Scopes))
+	return
+}
+
+func (r *ApiKeyRepository) Revoke(prefix string) (err error) {
+	result, err := r.Exec("update api_keys set revoked_at = current_timestamp where prefix = $1 and revoked_at is null", prefix)
+	if err != nil {
+		return
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affected == 0 {
+		err = sql.ErrNoRows
+	}
+	return
+}
+
+// Touch writes at most once a minute per key, so busy clients do not turn every
+// request into a write.
+func (r *ApiKeyRepository) Touch(id int) (err error) {
+	_, err = r.Exec(`
+		update api_keys set last_used_at = current_timestamp
+		where id = $1 and (last_used_at is null or last_used_at < current_timestamp - interval '1 minute')
+	`, id)
+	return
+}
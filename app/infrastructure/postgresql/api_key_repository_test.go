@@ -0,0 +1,116 @@
+package postgresql
+
+import (
+	"backend/app/domain/entity"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApiKeyRepository(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt, _ := time.Parse("2006-01-02 15:04:05.999999-07", "2006-01-02 15:04:05.999999-07")
+	lastUsedAt := createdAt.Add(time.Hour)
+	columns := []string{"id", "user_id", "username", "name", "prefix", "key_hash", "scopes", "created_at", "last_used_at", "revoked"}
+
+	t.Run(
+		"GetByPrefix",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta("from api_keys join users on api_keys.user_id = users.id where api_keys.prefix = $1")).
+				WithArgs("0123abcd").
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(3, 6, "testci", "ci", "0123abcd", "hash", "{posts:write,posts:publish}", createdAt, lastUsedAt, false))
+
+			r := NewApiKeyRepository(db)
+
+			ret, err := r.GetByPrefix("0123abcd")
+
+			assert.NoError(t, err)
+			assert.Equal(t, ret, entity.ApiKey{
+				Id:         3,
+				UserId:     6,
+				Username:   "testci",
+				Name:       "ci",
+				Prefix:     "0123abcd",
+				KeyHash:    "hash",
+				Scopes:     []string{"posts:write", "posts:publish"},
+				CreatedAt:  createdAt,
+				LastUsedAt: lastUsedAt,
+			})
+		},
+	)
+
+	t.Run(
+		"GetAll",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta("from api_keys join users on api_keys.user_id = users.id order by api_keys.created_at")).
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(3, 6, "testci", "ci", "0123abcd", "hash", "{posts:write}", createdAt, nil, false).
+					AddRow(4, 6, "testci", "old", "89abcdef", "hash", "{posts:write}", createdAt, nil, true))
+
+			r := NewApiKeyRepository(db)
+
+			ret, err := r.GetAll()
+
+			assert.NoError(t, err)
+			assert.Len(t, ret, 2)
+			assert.True(t, ret[0].LastUsedAt.IsZero())
+			assert.True(t, ret[1].Revoked)
+		},
+	)
+
+	t.Run(
+		"Create",
+		func(t *testing.T) {
+			mock.ExpectExec(regexp.QuoteMeta("insert into api_keys (user_id, name, prefix, key_hash, scopes) values ($1, $2, $3, $4, $5)")).
+				WithArgs(6, "ci", "0123abcd", "hash", pq.Array([]string{"posts:write"})).
+				WillReturnResult(sqlmock.NewResult(3, 1))
+
+			r := NewApiKeyRepository(db)
+
+			assert.NoError(t, r.Create(entity.NewApiKey(6, "ci", "0123abcd", "hash", []string{"posts:write"})))
+		},
+	)
+
+	t.Run(
+		"Revoke",
+		func(t *testing.T) {
+			mock.ExpectExec(regexp.QuoteMeta("update api_keys set revoked_at = current_timestamp where prefix = $1 and revoked_at is null")).
+				WithArgs("0123abcd").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta("update api_keys set revoked_at = current_timestamp where prefix = $1 and revoked_at is null")).
+				WithArgs("0123abcd").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			r := NewApiKeyRepository(db)
+
+			assert.NoError(t, r.Revoke("0123abcd"))
+			assert.ErrorIs(t, r.Revoke("0123abcd"), sql.ErrNoRows)
+		},
+	)
+
+	t.Run(
+		"Touch",
+		func(t *testing.T) {
+			mock.ExpectExec(regexp.QuoteMeta("update api_keys set last_used_at = current_timestamp where id = $1")).
+				WithArgs(3).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			r := NewApiKeyRepository(db)
+
+			assert.NoError(t, r.Touch(3))
+		},
+	)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
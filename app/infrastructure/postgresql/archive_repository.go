@@ -0,0 +1,172 @@
+package postgresql
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type ArchiveRepository struct {
+	*sql.DB
+}
+
+func NewArchiveRepository(db *sql.DB) (archiveRepository repository.IArchiveRepository) {
+	archiveRepository = &ArchiveRepository{db}
+	return
+}
+
+// archiveTables are the tables an archive covers, in the order rows are inserted.
+var archiveTables = []string{"categories", "users", "posts"}
+
+// Dump reads in a read-only repeatable read transaction, so the three tables
+// match even while the blog is being written to.
+func (r *ArchiveRepository) Dump() (archive entity.Archive, err error) {
+	tx, err := r.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		with recursive tree as (
+			select id, 0 as depth from categories where parent_id is null
+			union all
+			select categories.id, tree.depth + 1 from categories inner join tree on categories.parent_id = tree.id
+		)
+		select categories.id, name, slug, coalesce(parent_id, 0), position, description, cover_image, version
+		from categories inner join tree on categories.id = tree.id
+		order by tree.depth, categories.id
+	`)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var category entity.Category
+		err = rows.Scan(&category.Id, &category.Name, &category.Slug, &category.ParentId, &category.Position, &category.Description, &category.CoverImage, &category.Version)
+		if err != nil {
+			rows.Close()
+			return
+		}
+		archive.Categories = append(archive.Categories, category)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	rows, err = tx.Query("select id, username, coalesce(password, ''), role, display_name, bio, avatar_url from users order by id")
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var user entity.User
+		err = rows.Scan(&user.Id, &user.Name, &user.Password, &user.Role, &user.DisplayName, &user.Bio, &user.AvatarUrl)
+		if err != nil {
+			rows.Close()
+			return
+		}
+		archive.Users = append(archive.Users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	rows, err = tx.Query(`
+		select id, title, slug, coalesce(eye_catching_img, ''), coalesce(content, ''), coalesce(meta_description, ''), coalesce(is_public, false),
+		created_at, updated_at, coalesce(category_id, 0), coalesce(author_id, 0), version
+		from posts order by id
+	`)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var post entity.Post
+		err = rows.Scan(&post.Id, &post.Title, &post.Slug, &post.EyeCatchingImg, &post.Content, &post.MetaDescription, &post.IsPublic,
+			&post.CreatedAt, &post.UpdatedAt, &post.CategoryId, &post.AuthorId, &post.Version)
+		if err != nil {
+			rows.Close()
+			return
+		}
+		archive.Posts = append(archive.Posts, post)
+	}
+	err = rows.Err()
+	return
+}
+
+func (r *ArchiveRepository) Restore(archive entity.Archive, replace bool) (err error) {
+	tx, err := r.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	// tells the update_timestamp trigger to keep the restored updated_at
+	_, err = tx.Exec("set local blog.restoring = 'on'")
+	if err != nil {
+		return
+	}
+	if replace {
+		// users last: deleting them cascades to their tokens, 2FA and API keys
+		for _, table := range []string{"posts", "categories", "users"} {
+			_, err = tx.Exec("delete from " + table)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	// rows keep the archived version, 1 for archives without one; a row the archive
+	// overwrites gets a version above both, so that a client holding either version
+	// does not overwrite the restored row in turn
+	for _, category := range archive.Categories {
+		_, err = tx.Exec(`
+			insert into categories (id, name, slug, parent_id, position, description, cover_image, version)
+			values ($1, $2, $3, nullif($4, 0), $5, $6, $7, greatest($8, 1))
+			on conflict (id) do update set name = excluded.name, slug = excluded.slug, parent_id = excluded.parent_id,
+			position = excluded.position, description = excluded.description, cover_image = excluded.cover_image,
+			version = greatest(categories.version, excluded.version) + 1
+		`, category.Id, category.Name, category.Slug, category.ParentId, category.Position, category.Description, category.CoverImage, category.Version)
+		if err != nil {
+			return
+		}
+	}
+	for _, user := range archive.Users {
+		_, err = tx.Exec(`
+			insert into users (id, username, password, role, display_name, bio, avatar_url) values ($1, $2, $3, $4, $5, $6, $7)
+			on conflict (id) do update set username = excluded.username, password = excluded.password, role = excluded.role,
+			display_name = excluded.display_name, bio = excluded.bio, avatar_url = excluded.avatar_url
+		`, user.Id, user.Name, user.Password, user.Role, user.DisplayName, user.Bio, user.AvatarUrl)
+		if err != nil {
+			return
+		}
+	}
+	for _, post := range archive.Posts {
+		_, err = tx.Exec(`
+			insert into posts (id, title, slug, eye_catching_img, content, meta_description, is_public, created_at, updated_at, category_id, author_id, version)
+			values ($1, $2, $3, $4, $5, $6, $7, $8, $9, nullif($10, 0), nullif($11, 0), greatest($12, 1))
+			on conflict (id) do update set title = excluded.title, slug = excluded.slug, eye_catching_img = excluded.eye_catching_img,
+			content = excluded.content, meta_description = excluded.meta_description, is_public = excluded.is_public,
+			created_at = excluded.created_at, updated_at = excluded.updated_at, category_id = excluded.category_id, author_id = excluded.author_id,
+			version = greatest(posts.version, excluded.version) + 1
+		`, post.Id, post.Title, post.Slug, post.EyeCatchingImg, post.Content, post.MetaDescription, post.IsPublic,
+			post.CreatedAt, post.UpdatedAt, post.CategoryId, post.AuthorId, post.Version)
+		if err != nil {
+			return
+		}
+	}
+
+	// the rows came with their ids, so move the sequences past them
+	for _, table := range archiveTables {
+		_, err = tx.Exec(fmt.Sprintf("select setval(pg_get_serial_sequence('%s', 'id'), coalesce(max(id), 0) + 1, false) from %s", table, table))
+		if err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,110 @@
+package postgresql
+
+import (
+	"backend/app/domain/entity"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveRepository(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt, _ := time.Parse("2006-01-02 15:04:05.999999-07", "2006-01-01 15:04:05.999999-07")
+	updatedAt, _ := time.Parse("2006-01-02 15:04:05.999999-07", "2006-01-02 15:04:05.999999-07")
+
+	archive := entity.Archive{
+		Categories: []entity.Category{
+			{Id: 1, Name: "testCategory1", Slug: "test-category-1", Version: 1},
+			{Id: 2, Name: "testSubCategory1", Slug: "test-sub-category-1", ParentId: 1, Position: 1, Description: "testDescription1", Version: 3},
+		},
+		Users: []entity.User{
+			{Id: 3, Name: "testuser1", Password: "$2a$10$hash", Role: entity.RoleAuthor, DisplayName: "Test User 1"},
+		},
+		Posts: []entity.Post{
+			{Id: 4, Title: "testTitle1", Slug: "test-slug-1", Content: "testContent1", IsPublic: true, CreatedAt: createdAt, UpdatedAt: updatedAt, CategoryId: 2, AuthorId: 3, Version: 2},
+		},
+	}
+
+	t.Run(
+		"Dump",
+		func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta("order by tree.depth, categories.id")).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug", "parent_id", "position", "description", "cover_image", "version"}).
+					AddRow(1, "testCategory1", "test-category-1", 0, 0, "", "", 1).
+					AddRow(2, "testSubCategory1", "test-sub-category-1", 1, 1, "testDescription1", "", 3))
+			mock.ExpectQuery(regexp.QuoteMeta("select id, username, coalesce(password, ''), role, display_name, bio, avatar_url from users order by id")).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "role", "display_name", "bio", "avatar_url"}).
+					AddRow(3, "testuser1", "$2a$10$hash", "author", "Test User 1", "", ""))
+			mock.ExpectQuery(regexp.QuoteMeta("from posts order by id")).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "title", "slug", "eye_catching_img", "content", "meta_description", "is_public", "created_at", "updated_at", "category_id", "author_id", "version"}).
+					AddRow(4, "testTitle1", "test-slug-1", "", "testContent1", "", true, createdAt, updatedAt, 2, 3, 2))
+			mock.ExpectRollback()
+
+			r := NewArchiveRepository(db)
+
+			ret, err := r.Dump()
+
+			assert.NoError(t, err)
+			assert.Equal(t, archive, ret)
+		},
+	)
+
+	t.Run(
+		"Restore",
+		func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("set local blog.restoring = 'on'")).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta("delete from posts")).WillReturnResult(sqlmock.NewResult(0, 5))
+			mock.ExpectExec(regexp.QuoteMeta("delete from categories")).WillReturnResult(sqlmock.NewResult(0, 5))
+			mock.ExpectExec(regexp.QuoteMeta("delete from users")).WillReturnResult(sqlmock.NewResult(0, 5))
+			mock.ExpectExec(regexp.QuoteMeta("values ($1, $2, $3, nullif($4, 0), $5, $6, $7, greatest($8, 1))")).
+				WithArgs(1, "testCategory1", "test-category-1", 0, 0, "", "", 1).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectExec(regexp.QuoteMeta("version = greatest(categories.version, excluded.version) + 1")).
+				WithArgs(2, "testSubCategory1", "test-sub-category-1", 1, 1, "testDescription1", "", 3).
+				WillReturnResult(sqlmock.NewResult(2, 1))
+			mock.ExpectExec(regexp.QuoteMeta("insert into users (id, username, password, role, display_name, bio, avatar_url) values ($1, $2, $3, $4, $5, $6, $7) on conflict (id) do update")).
+				WithArgs(3, "testuser1", "$2a$10$hash", entity.RoleAuthor, "Test User 1", "", "").
+				WillReturnResult(sqlmock.NewResult(3, 1))
+			mock.ExpectExec(regexp.QuoteMeta("version = greatest(posts.version, excluded.version) + 1")).
+				WithArgs(4, "testTitle1", "test-slug-1", "", "testContent1", "", true, createdAt, updatedAt, 2, 3, 2).
+				WillReturnResult(sqlmock.NewResult(4, 1))
+			for _, table := range []string{"categories", "users", "posts"} {
+				mock.ExpectExec(regexp.QuoteMeta("select setval(pg_get_serial_sequence('" + table + "', 'id'), coalesce(max(id), 0) + 1, false) from " + table)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			mock.ExpectCommit()
+
+			r := NewArchiveRepository(db)
+
+			assert.NoError(t, r.Restore(archive, true))
+		},
+	)
+
+	t.Run(
+		"Restore rolls back",
+		func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("set local blog.restoring = 'on'")).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta("insert into categories")).
+				WithArgs(1, "testCategory1", "test-category-1", 0, 0, "", "", 1).
+				WillReturnError(assert.AnError)
+			mock.ExpectRollback()
+
+			r := NewArchiveRepository(db)
+
+			assert.ErrorIs(t, r.Restore(archive, false), assert.AnError)
+		},
+	)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
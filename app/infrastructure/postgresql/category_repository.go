@@ -4,6 +4,7 @@ import (
 	"backend/app/domain/entity"
 	"backend/app/domain/repository"
 	"database/sql"
+	"fmt"
 )
 
 type CategoryRepository struct {
@@ -15,32 +16,144 @@ func NewCategoryRepository(db *sql.DB) (categoryRepository repository.ICategoryR
 	return
 }
 
+func (r *CategoryRepository) scanCategories(rows *sql.Rows) (categories []entity.Category, err error) {
+	defer rows.Close()
+	for rows.Next() {
+		var category entity.Category
+		err = rows.Scan(&category.Id, &category.Name, &category.Slug, &category.ParentId, &category.Position, &category.Description, &category.CoverImage, &category.Version)
+		if err != nil {
+			return
+		}
+		categories = append(categories, category)
+	}
+	err = rows.Err()
+	return
+}
+
+// GetAll returns the root categories only.
 func (r *CategoryRepository) GetAll() (categories []entity.Category, err error) {
-	rows, err := r.Query("select * from categories")
+	rows, err := r.Query("select id, name, slug, coalesce(parent_id, 0), position, description, cover_image, version from categories where parent_id is null order by position, id")
 	if err != nil {
 		return
 	}
-	for rows.Next() {
-		var category entity.Category
-		rows.Scan(&category.Id, &category.Name, &category.Slug)
-		categories = append(categories, category)
+	categories, err = r.scanCategories(rows)
+	return
+}
+
+// GetTree returns every node of the category tree as a flat list, siblings ordered by position.
+func (r *CategoryRepository) GetTree() (categories []entity.Category, err error) {
+	rows, err := r.Query("select id, name, slug, coalesce(parent_id, 0), position, description, cover_image, version from categories order by position, id")
+	if err != nil {
+		return
 	}
+	categories, err = r.scanCategories(rows)
+	return
+}
+
+// GetChildren returns the direct children of the category, ordered by position.
+func (r *CategoryRepository) GetChildren(parentId int) (categories []entity.Category, err error) {
+	rows, err := r.Query("select id, name, slug, coalesce(parent_id, 0), position, description, cover_image, version from categories where parent_id = $1 order by position, id", parentId)
+	if err != nil {
+		return
+	}
+	categories, err = r.scanCategories(rows)
+	return
+}
+
+func (r *CategoryRepository) GetStats(ids []int) (stats map[int]entity.CategoryStats, err error) {
+	stats, err = queryCategoryStats(r.DB, ids)
 	return
 }
 
 func (r *CategoryRepository) GetBySlug(slug string) (category entity.Category, err error) {
-	err = r.QueryRow("select id, name, slug from categories where slug = $1", slug).
-		Scan(&category.Id, &category.Name, &category.Slug)
+	err = r.QueryRow("select id, name, slug, coalesce(parent_id, 0), position, description, cover_image, version from categories where slug = $1", slug).
+		Scan(&category.Id, &category.Name, &category.Slug, &category.ParentId, &category.Position, &category.Description, &category.CoverImage, &category.Version)
+	return
+}
+
+// GetAncestors returns the path from the root down to the category with the given id,
+// the category itself included.
+func (r *CategoryRepository) GetAncestors(id int) (categories []entity.Category, err error) {
+	rows, err := r.Query(`
+		with recursive ancestors as (
+			select id, name, slug, parent_id, position, description, cover_image, version, 0 as depth from categories where id = $1
+			union all
+			select categories.id, categories.name, categories.slug, categories.parent_id,
+			categories.position, categories.description, categories.cover_image, categories.version, ancestors.depth + 1
+			from categories inner join ancestors on categories.id = ancestors.parent_id
+		)
+		select id, name, slug, coalesce(parent_id, 0), position, description, cover_image, version from ancestors order by depth desc
+	`, id)
+	if err != nil {
+		return
+	}
+	categories, err = r.scanCategories(rows)
 	return
 }
 
 func (r *CategoryRepository) Create(category entity.Category) (err error) {
-	_, err = r.Exec("insert into categories (name, slug) values ($1, $2)", category.Name, category.Slug)
+	_, err = r.Exec("insert into categories (name, slug, parent_id, position, description, cover_image) values ($1, $2, nullif($3, 0), $4, $5, $6)",
+		category.Name, category.Slug, category.ParentId, category.Position, category.Description, category.CoverImage)
 	return
 }
 
+// Update overwrites the category and increments its version, checking a non-zero
+// Version as PostRepository.Update does.
 func (r *CategoryRepository) Update(category entity.Category) (err error) {
-	_, err = r.Exec("update categories set name = $2, slug = $3 where id = $1", category.Id, category.Name, category.Slug)
+	result, err := r.Exec("update categories set name = $2, slug = $3, parent_id = nullif($4, 0), position = $5, description = $6, cover_image = $7, version = version + 1 where id = $1 and ($8 = 0 or version = $8)",
+		category.Id, category.Name, category.Slug, category.ParentId, category.Position, category.Description, category.CoverImage, category.Version)
+	if err != nil {
+		return
+	}
+	err = checkVersion(result, category.Version)
+	return
+}
+
+// Reorder sets the position of each category to its index in ids, which counts
+// as an update of its version. The categories must be all the children of one
+// parent, or the reorder fails with repository.ErrMixedParents or
+// repository.ErrIncompleteOrder, and an unknown id with sql.ErrNoRows. Either
+// every position is updated or none is.
+func (r *CategoryRepository) Reorder(ids []int) (err error) {
+	tx, err := r.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	firstParentId := 0
+	for position, id := range ids {
+		var parentId int
+		err = tx.QueryRow("update categories set position = $2, version = version + 1 where id = $1 returning coalesce(parent_id, 0)", id, position).
+			Scan(&parentId)
+		if err == sql.ErrNoRows {
+			err = fmt.Errorf("category %d: %w", id, err)
+			return
+		}
+		if err != nil {
+			return
+		}
+		if position == 0 {
+			firstParentId = parentId
+		} else if parentId != firstParentId {
+			err = fmt.Errorf("categories %d and %d: %w", ids[0], id, repository.ErrMixedParents)
+			return
+		}
+	}
+	var siblings int
+	err = tx.QueryRow("select count(*) from categories where parent_id is not distinct from nullif($1, 0)", firstParentId).
+		Scan(&siblings)
+	if err != nil {
+		return
+	}
+	if siblings != len(ids) {
+		err = fmt.Errorf("%d of %d categories: %w", len(ids), siblings, repository.ErrIncompleteOrder)
+	}
 	return
 }
 
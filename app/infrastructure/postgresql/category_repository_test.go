@@ -2,11 +2,16 @@ package postgresql
 
 import (
 	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+	"database/sql"
+	"errors"
 	"reflect"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 )
 
 func TestCategoryRepositoryGetAll(t *testing.T) {
@@ -16,11 +21,11 @@ func TestCategoryRepositoryGetAll(t *testing.T) {
 	}
 	defer db.Close()
 
-	rows := sqlmock.NewRows([]string{"id", "name", "slug"}).
-		AddRow(1, "testCategory1", "test-category-1").
-		AddRow(2, "testCategory2", "test-category-2")
+	rows := sqlmock.NewRows([]string{"id", "name", "slug", "parent_id", "position", "description", "cover_image", "version"}).
+		AddRow(1, "testCategory1", "test-category-1", 0, 0, "", "", 1).
+		AddRow(2, "testCategory2", "test-category-2", 0, 0, "", "", 1)
 
-	mock.ExpectQuery(regexp.QuoteMeta("select * from categories")).
+	mock.ExpectQuery(regexp.QuoteMeta("select id, name, slug, coalesce(parent_id, 0), position, description, cover_image, version from categories where parent_id is null order by position, id")).
 		WillReturnRows(rows)
 
 	r := NewCategoryRepository(db)
@@ -32,14 +37,16 @@ func TestCategoryRepositoryGetAll(t *testing.T) {
 
 	expectedCategories := []entity.Category{
 		{
-			Id:   1,
-			Name: "testCategory1",
-			Slug: "test-category-1",
+			Id:      1,
+			Name:    "testCategory1",
+			Slug:    "test-category-1",
+			Version: 1,
 		},
 		{
-			Id:   2,
-			Name: "testCategory2",
-			Slug: "test-category-2",
+			Id:      2,
+			Name:    "testCategory2",
+			Slug:    "test-category-2",
+			Version: 1,
 		},
 	}
 
@@ -55,8 +62,8 @@ func TestCategoryRepositoryCreate(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectExec(regexp.QuoteMeta("insert into categories (name, slug) values ($1, $2)")).
-		WithArgs("testCategory1", "test-category-1").
+	mock.ExpectExec(regexp.QuoteMeta("insert into categories (name, slug, parent_id, position, description, cover_image) values ($1, $2, nullif($3, 0), $4, $5, $6)")).
+		WithArgs("testCategory1", "test-category-1", 0, 0, "", "").
 		WillReturnResult(sqlmock.NewResult(1, 3))
 
 	r := NewCategoryRepository(db)
@@ -78,21 +85,32 @@ func TestCategoryRepositoryUpdate(t *testing.T) {
 	}
 	defer db.Close()
 
-	mock.ExpectExec(regexp.QuoteMeta("update categories set name = $2, slug = $3 where id = $1")).
-		WithArgs(1, "testCategory1", "test-category-1").
-		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectExec(regexp.QuoteMeta("update categories set name = $2, slug = $3, parent_id = nullif($4, 0), position = $5, description = $6, cover_image = $7, version = version + 1 where id = $1 and ($8 = 0 or version = $8)")).
+		WithArgs(1, "testCategory1", "test-category-1", 0, 2, "about testCategory1", "test_category_1.png", 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("update categories")).
+		WithArgs(1, "testCategory1", "test-category-1", 0, 2, "about testCategory1", "test_category_1.png", 3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
 
 	r := NewCategoryRepository(db)
 
 	category := entity.Category{
-		Id:   1,
-		Name: "testCategory1",
-		Slug: "test-category-1",
+		Id:          1,
+		Name:        "testCategory1",
+		Slug:        "test-category-1",
+		Position:    2,
+		Description: "about testCategory1",
+		CoverImage:  "test_category_1.png",
+		Version:     3,
 	}
 
 	if err := r.Update(category); err != nil {
 		t.Fatal(err)
 	}
+	// the second update finds the version gone
+	if err := r.Update(category); !errors.Is(err, repository.ErrConflict) {
+		t.Fatalf("Wrong error, was expecting %v, but got %v\n", repository.ErrConflict, err)
+	}
 }
 
 func TestCategoryRepositoryDelete(t *testing.T) {
@@ -126,10 +144,10 @@ func TestCategoryRepositoryGetBySlug(t *testing.T) {
 	}
 	defer db.Close()
 
-	rows := sqlmock.NewRows([]string{"id", "name", "slug"}).
-		AddRow(1, "testCategory1", "test-category-1")
+	rows := sqlmock.NewRows([]string{"id", "name", "slug", "parent_id", "position", "description", "cover_image", "version"}).
+		AddRow(1, "testCategory1", "test-category-1", 0, 0, "", "", 1)
 
-	mock.ExpectQuery(regexp.QuoteMeta("select id, name, slug from categories where slug = $1")).
+	mock.ExpectQuery(regexp.QuoteMeta("select id, name, slug, coalesce(parent_id, 0), position, description, cover_image, version from categories where slug = $1")).
 		WithArgs("test-category-1").
 		WillReturnRows(rows)
 
@@ -141,12 +159,310 @@ func TestCategoryRepositoryGetBySlug(t *testing.T) {
 	}
 
 	expectedCategory := entity.Category{
-		Id:   1,
-		Name: "testCategory1",
-		Slug: "test-category-1",
+		Id:      1,
+		Name:    "testCategory1",
+		Slug:    "test-category-1",
+		Version: 1,
 	}
 
 	if !(reflect.DeepEqual(category, expectedCategory)) {
 		t.Fatalf("Wrong content, was expecting %v, but got %v\n", expectedCategory, category)
 	}
 }
+
+func TestCategoryRepositoryGetTree(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "slug", "parent_id", "position", "description", "cover_image", "version"}).
+		AddRow(1, "testCategory1", "test-category-1", 0, 0, "", "", 1).
+		AddRow(2, "testSubCategory1", "test-sub-category-1", 1, 0, "", "", 1)
+
+	mock.ExpectQuery(regexp.QuoteMeta("select id, name, slug, coalesce(parent_id, 0), position, description, cover_image, version from categories order by position, id")).
+		WillReturnRows(rows)
+
+	r := NewCategoryRepository(db)
+
+	categories, err := r.GetTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCategories := []entity.Category{
+		{
+			Id:      1,
+			Name:    "testCategory1",
+			Slug:    "test-category-1",
+			Version: 1,
+		},
+		{
+			Id:       2,
+			Name:     "testSubCategory1",
+			Slug:     "test-sub-category-1",
+			Version:  1,
+			ParentId: 1,
+		},
+	}
+
+	if !(reflect.DeepEqual(categories, expectedCategories)) {
+		t.Fatalf("Wrong content, was expecting %v, but got %v\n", expectedCategories, categories)
+	}
+}
+
+func TestCategoryRepositoryGetAncestors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "slug", "parent_id", "position", "description", "cover_image", "version"}).
+		AddRow(1, "testCategory1", "test-category-1", 0, 0, "", "", 1).
+		AddRow(2, "testSubCategory1", "test-sub-category-1", 1, 0, "", "", 1)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		with recursive ancestors as (
+			select id, name, slug, parent_id, position, description, cover_image, version, 0 as depth from categories where id = $1
+			union all
+			select categories.id, categories.name, categories.slug, categories.parent_id,
+			categories.position, categories.description, categories.cover_image, categories.version, ancestors.depth + 1
+			from categories inner join ancestors on categories.id = ancestors.parent_id
+		)
+		select id, name, slug, coalesce(parent_id, 0), position, description, cover_image, version from ancestors order by depth desc
+	`)).
+		WithArgs(2).
+		WillReturnRows(rows)
+
+	r := NewCategoryRepository(db)
+
+	categories, err := r.GetAncestors(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCategories := []entity.Category{
+		{
+			Id:      1,
+			Name:    "testCategory1",
+			Slug:    "test-category-1",
+			Version: 1,
+		},
+		{
+			Id:       2,
+			Name:     "testSubCategory1",
+			Slug:     "test-sub-category-1",
+			Version:  1,
+			ParentId: 1,
+		},
+	}
+
+	if !(reflect.DeepEqual(categories, expectedCategories)) {
+		t.Fatalf("Wrong content, was expecting %v, but got %v\n", expectedCategories, categories)
+	}
+}
+
+func TestCategoryRepositoryReorder(t *testing.T) {
+	t.Run(
+		"all categories exist",
+		func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta("update categories set position = $2, version = version + 1 where id = $1 returning coalesce(parent_id, 0)")).
+				WithArgs(3, 0).
+				WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(1))
+			mock.ExpectQuery(regexp.QuoteMeta("update categories set position = $2, version = version + 1 where id = $1 returning coalesce(parent_id, 0)")).
+				WithArgs(1, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(1))
+			mock.ExpectQuery(regexp.QuoteMeta("select count(*) from categories where parent_id is not distinct from nullif($1, 0)")).
+				WithArgs(1).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+			mock.ExpectCommit()
+
+			r := NewCategoryRepository(db)
+
+			if err := r.Reorder([]int{3, 1}); err != nil {
+				t.Fatal(err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		},
+	)
+
+	t.Run(
+		"unknown category",
+		func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta("update categories set position = $2, version = version + 1 where id = $1 returning coalesce(parent_id, 0)")).
+				WithArgs(3, 0).
+				WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(1))
+			mock.ExpectQuery(regexp.QuoteMeta("update categories set position = $2, version = version + 1 where id = $1 returning coalesce(parent_id, 0)")).
+				WithArgs(99, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"parent_id"}))
+			mock.ExpectRollback()
+
+			r := NewCategoryRepository(db)
+
+			if err := r.Reorder([]int{3, 99}); !errors.Is(err, sql.ErrNoRows) {
+				t.Fatalf("expected sql.ErrNoRows, got %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		},
+	)
+
+	t.Run(
+		"categories of different parents",
+		func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta("update categories set position = $2, version = version + 1 where id = $1 returning coalesce(parent_id, 0)")).
+				WithArgs(3, 0).
+				WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(1))
+			mock.ExpectQuery(regexp.QuoteMeta("update categories set position = $2, version = version + 1 where id = $1 returning coalesce(parent_id, 0)")).
+				WithArgs(5, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(0))
+			mock.ExpectRollback()
+
+			r := NewCategoryRepository(db)
+
+			if err := r.Reorder([]int{3, 5}); !errors.Is(err, repository.ErrMixedParents) {
+				t.Fatalf("expected ErrMixedParents, got %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		},
+	)
+
+	t.Run(
+		"some children left out",
+		func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta("update categories set position = $2, version = version + 1 where id = $1 returning coalesce(parent_id, 0)")).
+				WithArgs(3, 0).
+				WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(0))
+			mock.ExpectQuery(regexp.QuoteMeta("update categories set position = $2, version = version + 1 where id = $1 returning coalesce(parent_id, 0)")).
+				WithArgs(1, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(0))
+			mock.ExpectQuery(regexp.QuoteMeta("select count(*) from categories where parent_id is not distinct from nullif($1, 0)")).
+				WithArgs(0).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+			mock.ExpectRollback()
+
+			r := NewCategoryRepository(db)
+
+			if err := r.Reorder([]int{3, 1}); !errors.Is(err, repository.ErrIncompleteOrder) {
+				t.Fatalf("expected ErrIncompleteOrder, got %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		},
+	)
+}
+
+func TestCategoryRepositoryGetChildren(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "slug", "parent_id", "position", "description", "cover_image", "version"}).
+		AddRow(2, "testSubCategory1", "test-sub-category-1", 1, 0, "", "", 1)
+
+	mock.ExpectQuery(regexp.QuoteMeta("select id, name, slug, coalesce(parent_id, 0), position, description, cover_image, version from categories where parent_id = $1 order by position, id")).
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	r := NewCategoryRepository(db)
+
+	categories, err := r.GetChildren(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedCategories := []entity.Category{
+		{
+			Id:       2,
+			Name:     "testSubCategory1",
+			Slug:     "test-sub-category-1",
+			Version:  1,
+			ParentId: 1,
+		},
+	}
+
+	if !(reflect.DeepEqual(categories, expectedCategories)) {
+		t.Fatalf("Wrong content, was expecting %v, but got %v\n", expectedCategories, categories)
+	}
+}
+
+func TestCategoryRepositoryGetStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	latestPostAt, _ := time.Parse("2006-01-02 15:04:05.999999-07", "2006-01-02 15:04:05.999999-07")
+
+	rows := sqlmock.NewRows([]string{"root_id", "count", "max"}).
+		AddRow(1, 3, latestPostAt).
+		AddRow(2, 0, nil)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		with recursive subtree as (
+			select id as root_id, id from categories where id = any($1)
+			union all
+			select subtree.root_id, categories.id from categories inner join subtree on categories.parent_id = subtree.id
+		)
+		select subtree.root_id, count(posts.id), max(posts.created_at)
+		from subtree left join posts on posts.category_id = subtree.id and posts.is_public
+		group by subtree.root_id
+	`)).
+		WithArgs(pq.Array([]int{1, 2})).
+		WillReturnRows(rows)
+
+	r := NewCategoryRepository(db)
+
+	stats, err := r.GetStats([]int{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedStats := map[int]entity.CategoryStats{
+		1: {PostCount: 3, LatestPostAt: latestPostAt},
+		2: {PostCount: 0},
+	}
+
+	if !(reflect.DeepEqual(stats, expectedStats)) {
+		t.Fatalf("Wrong content, was expecting %v, but got %v\n", expectedStats, stats)
+	}
+}
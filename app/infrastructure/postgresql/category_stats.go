@@ -0,0 +1,44 @@
+package postgresql
+
+import (
+	"backend/app/domain/entity"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// queryCategoryStats counts the public posts under each of the given categories,
+// descendants included, in one aggregate query. Categories without posts are
+// present in the result with a zero count.
+func queryCategoryStats(db *sql.DB, ids []int) (stats map[int]entity.CategoryStats, err error) {
+	rows, err := db.Query(`
+		with recursive subtree as (
+			select id as root_id, id from categories where id = any($1)
+			union all
+			select subtree.root_id, categories.id from categories inner join subtree on categories.parent_id = subtree.id
+		)
+		select subtree.root_id, count(posts.id), max(posts.created_at)
+		from subtree left join posts on posts.category_id = subtree.id and posts.is_public
+		group by subtree.root_id
+	`, pq.Array(ids))
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	stats = make(map[int]entity.CategoryStats)
+	for rows.Next() {
+		var id int
+		var stat entity.CategoryStats
+		var latestPostAt sql.NullTime
+		err = rows.Scan(&id, &stat.PostCount, &latestPostAt)
+		if err != nil {
+			return
+		}
+		if latestPostAt.Valid {
+			stat.LatestPostAt = latestPostAt.Time
+		}
+		stats[id] = stat
+	}
+	err = rows.Err()
+	return
+}
@@ -0,0 +1,83 @@
+package postgresql
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+	"database/sql"
+)
+
+type ImageDerivativeRepository struct {
+	*sql.DB
+}
+
+func NewImageDerivativeRepository(db *sql.DB) (imageDerivativeRepository repository.IImageDerivativeRepository) {
+	imageDerivativeRepository = &ImageDerivativeRepository{db}
+	return
+}
+
+func scanImageDerivatives(rows *sql.Rows) (derivatives []entity.ImageDerivative, err error) {
+	defer rows.Close()
+	for rows.Next() {
+		var derivative entity.ImageDerivative
+		err = rows.Scan(&derivative.Source, &derivative.Kind, &derivative.Name, &derivative.Width, &derivative.Height)
+		if err != nil {
+			return
+		}
+		derivatives = append(derivatives, derivative)
+	}
+	err = rows.Err()
+	return
+}
+
+func (r *ImageDerivativeRepository) GetAll() (derivatives []entity.ImageDerivative, err error) {
+	rows, err := r.Query("select source, kind, name, width, height from image_derivatives order by source, kind")
+	if err != nil {
+		return
+	}
+	derivatives, err = scanImageDerivatives(rows)
+	return
+}
+
+func (r *ImageDerivativeRepository) GetBySource(source string) (derivatives []entity.ImageDerivative, err error) {
+	rows, err := r.Query("select source, kind, name, width, height from image_derivatives where source = $1 order by kind", source)
+	if err != nil {
+		return
+	}
+	derivatives, err = scanImageDerivatives(rows)
+	return
+}
+
+func (r *ImageDerivativeRepository) Save(source string, derivatives []entity.ImageDerivative) (err error) {
+	tx, err := r.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	_, err = tx.Exec("delete from image_derivatives where source = $1", source)
+	if err != nil {
+		return
+	}
+	for _, derivative := range derivatives {
+		_, err = tx.Exec("insert into image_derivatives (source, kind, name, width, height) values ($1, $2, $3, $4, $5)",
+			source, derivative.Kind, derivative.Name, derivative.Width, derivative.Height)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (r *ImageDerivativeRepository) Delete(source string) (derivatives []entity.ImageDerivative, err error) {
+	rows, err := r.Query("delete from image_derivatives where source = $1 returning source, kind, name, width, height", source)
+	if err != nil {
+		return
+	}
+	derivatives, err = scanImageDerivatives(rows)
+	return
+}
@@ -0,0 +1,97 @@
+package postgresql
+
+import (
+	"backend/app/domain/entity"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageDerivativeRepository(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"source", "kind", "name", "width", "height"}
+	derivatives := []entity.ImageDerivative{
+		{Source: "abc.jpg", Kind: "medium", Name: "abc-medium.jpg", Width: 800, Height: 600},
+		{Source: "abc.jpg", Kind: "thumbnail", Name: "abc-thumbnail.jpg", Width: 150, Height: 150},
+	}
+
+	t.Run(
+		"GetAll",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta("select source, kind, name, width, height from image_derivatives order by source, kind")).
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow("abc.jpg", "medium", "abc-medium.jpg", 800, 600).
+					AddRow("abc.jpg", "thumbnail", "abc-thumbnail.jpg", 150, 150))
+
+			r := NewImageDerivativeRepository(db)
+
+			ret, err := r.GetAll()
+
+			assert.NoError(t, err)
+			assert.Equal(t, derivatives, ret)
+		},
+	)
+
+	t.Run(
+		"GetBySource",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta("select source, kind, name, width, height from image_derivatives where source = $1 order by kind")).
+				WithArgs("abc.jpg").
+				WillReturnRows(sqlmock.NewRows(columns).AddRow("abc.jpg", "medium", "abc-medium.jpg", 800, 600))
+
+			r := NewImageDerivativeRepository(db)
+
+			ret, err := r.GetBySource("abc.jpg")
+
+			assert.NoError(t, err)
+			assert.Equal(t, derivatives[:1], ret)
+		},
+	)
+
+	t.Run(
+		"Save",
+		func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("delete from image_derivatives where source = $1")).
+				WithArgs("abc.jpg").
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			for _, derivative := range derivatives {
+				mock.ExpectExec(regexp.QuoteMeta("insert into image_derivatives (source, kind, name, width, height) values ($1, $2, $3, $4, $5)")).
+					WithArgs("abc.jpg", derivative.Kind, derivative.Name, derivative.Width, derivative.Height).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			mock.ExpectCommit()
+
+			r := NewImageDerivativeRepository(db)
+
+			err := r.Save("abc.jpg", derivatives)
+
+			assert.NoError(t, err)
+		},
+	)
+
+	t.Run(
+		"Delete",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta("delete from image_derivatives where source = $1 returning source, kind, name, width, height")).
+				WithArgs("abc.jpg").
+				WillReturnRows(sqlmock.NewRows(columns).AddRow("abc.jpg", "medium", "abc-medium.jpg", 800, 600))
+
+			r := NewImageDerivativeRepository(db)
+
+			ret, err := r.Delete("abc.jpg")
+
+			assert.NoError(t, err)
+			assert.Equal(t, derivatives[:1], ret)
+		},
+	)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,52 @@
+package postgresql
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+	"database/sql"
+	"time"
+)
+
+type LoginAttemptRepository struct {
+	*sql.DB
+}
+
+func NewLoginAttemptRepository(db *sql.DB) (loginAttemptRepository repository.ILoginAttemptRepository) {
+	loginAttemptRepository = &LoginAttemptRepository{db}
+	return
+}
+
+func (r *LoginAttemptRepository) Get(key string) (loginAttempt entity.LoginAttempt, err error) {
+	var lockedUntil sql.NullTime
+	err = r.QueryRow("select key, failures, last_failure_at, locked_until from login_attempts where key = $1", key).Scan(
+		&loginAttempt.Key,
+		&loginAttempt.Failures,
+		&loginAttempt.LastFailureAt,
+		&lockedUntil,
+	)
+	loginAttempt.LockedUntil = lockedUntil.Time
+	return
+}
+
+// RecordFailure increments the counter in a single statement so that concurrent
+// failures are all counted.
+func (r *LoginAttemptRepository) RecordFailure(key string, windowStart time.Time) (failures int, err error) {
+	err = r.QueryRow(`
+		insert into login_attempts (key, failures, last_failure_at) values ($1, 1, current_timestamp)
+		on conflict (key) do update set
+			failures = case when login_attempts.last_failure_at < $2 then 1 else login_attempts.failures + 1 end,
+			last_failure_at = current_timestamp
+		returning failures
+	`, key, windowStart).Scan(&failures)
+	return
+}
+
+func (r *LoginAttemptRepository) Lock(key string, until time.Time) (err error) {
+	_, err = r.Exec("update login_attempts set locked_until = $2 where key = $1", key, until)
+	return
+}
+
+func (r *LoginAttemptRepository) Reset(key string) (err error) {
+	_, err = r.Exec("delete from login_attempts where key = $1", key)
+	return
+}
@@ -0,0 +1,101 @@
+package postgresql
+
+import (
+	"backend/app/domain/entity"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginAttemptRepository(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	lastFailureAt, _ := time.Parse("2006-01-02 15:04:05.999999-07", "2006-01-02 15:04:05.999999-07")
+	lockedUntil := lastFailureAt.Add(time.Minute)
+
+	t.Run(
+		"Get",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta("select key, failures, last_failure_at, locked_until from login_attempts where key = $1")).
+				WithArgs("username:testuser1").
+				WillReturnRows(sqlmock.NewRows([]string{"key", "failures", "last_failure_at", "locked_until"}).
+					AddRow("username:testuser1", 4, lastFailureAt, lockedUntil))
+
+			r := NewLoginAttemptRepository(db)
+
+			ret, err := r.Get("username:testuser1")
+
+			assert.NoError(t, err)
+			assert.Equal(t, ret, entity.LoginAttempt{Key: "username:testuser1", Failures: 4, LastFailureAt: lastFailureAt, LockedUntil: lockedUntil})
+		},
+	)
+
+	t.Run(
+		"Get without lock",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta("select key, failures, last_failure_at, locked_until from login_attempts where key = $1")).
+				WithArgs("ip:192.0.2.1").
+				WillReturnRows(sqlmock.NewRows([]string{"key", "failures", "last_failure_at", "locked_until"}).
+					AddRow("ip:192.0.2.1", 1, lastFailureAt, nil))
+
+			r := NewLoginAttemptRepository(db)
+
+			ret, err := r.Get("ip:192.0.2.1")
+
+			assert.NoError(t, err)
+			assert.True(t, ret.LockedUntil.IsZero())
+		},
+	)
+
+	t.Run(
+		"RecordFailure",
+		func(t *testing.T) {
+			windowStart := lastFailureAt.Add(-24 * time.Hour)
+			mock.ExpectQuery(regexp.QuoteMeta("insert into login_attempts (key, failures, last_failure_at) values ($1, 1, current_timestamp) on conflict (key) do update set")).
+				WithArgs("username:testuser1", windowStart).
+				WillReturnRows(sqlmock.NewRows([]string{"failures"}).AddRow(5))
+
+			r := NewLoginAttemptRepository(db)
+
+			failures, err := r.RecordFailure("username:testuser1", windowStart)
+
+			assert.NoError(t, err)
+			assert.Equal(t, failures, 5)
+		},
+	)
+
+	t.Run(
+		"Lock",
+		func(t *testing.T) {
+			mock.ExpectExec(regexp.QuoteMeta("update login_attempts set locked_until = $2 where key = $1")).
+				WithArgs("username:testuser1", lockedUntil).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			r := NewLoginAttemptRepository(db)
+
+			assert.NoError(t, r.Lock("username:testuser1", lockedUntil))
+		},
+	)
+
+	t.Run(
+		"Reset",
+		func(t *testing.T) {
+			mock.ExpectExec(regexp.QuoteMeta("delete from login_attempts where key = $1")).
+				WithArgs("username:testuser1").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			r := NewLoginAttemptRepository(db)
+
+			assert.NoError(t, r.Reset("username:testuser1"))
+		},
+	)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,116 @@
+package postgresql
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+	"database/sql"
+)
+
+type MediaRepository struct {
+	*sql.DB
+}
+
+func NewMediaRepository(db *sql.DB) (mediaRepository repository.IMediaRepository) {
+	mediaRepository = &MediaRepository{db}
+	return
+}
+
+const selectMedia = `select name, content_type, size, coalesce(width, 0), coalesce(height, 0), alt_text,
+	coalesce(uploaded_by, 0), created_at from media`
+
+func scanMedia(row rowScanner) (media entity.Media, err error) {
+	err = row.Scan(&media.Name, &media.ContentType, &media.Size, &media.Width, &media.Height, &media.AltText, &media.UploadedBy, &media.CreatedAt)
+	return
+}
+
+func (r *MediaRepository) GetAll() (media []entity.Media, err error) {
+	rows, err := r.Query(selectMedia + " order by name")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var medium entity.Media
+		medium, err = scanMedia(rows)
+		if err != nil {
+			return
+		}
+		media = append(media, medium)
+	}
+	err = rows.Err()
+	return
+}
+
+func (r *MediaRepository) GetByName(name string) (media entity.Media, err error) {
+	media, err = scanMedia(r.QueryRow(selectMedia+" where name = $1", name))
+	return
+}
+
+func (r *MediaRepository) Save(media entity.Media) (err error) {
+	createdAt := sql.NullTime{Time: media.CreatedAt, Valid: !media.CreatedAt.IsZero()}
+	_, err = r.Exec(`insert into media (name, content_type, size, width, height, alt_text, uploaded_by, created_at)
+		values ($1, $2, $3, nullif($4, 0), nullif($5, 0), $6, nullif($7, 0), coalesce($8, current_timestamp))
+		on conflict (name) do update set content_type = excluded.content_type, size = excluded.size,
+		width = excluded.width, height = excluded.height, alt_text = excluded.alt_text`,
+		media.Name, media.ContentType, media.Size, media.Width, media.Height, media.AltText, media.UploadedBy, createdAt)
+	return
+}
+
+func (r *MediaRepository) Delete(name string) (err error) {
+	_, err = r.Exec("delete from media where name = $1", name)
+	return
+}
+
+func (r *MediaRepository) GetReferringPosts() (posts []entity.Post, err error) {
+	rows, err := r.Query("select id, slug, title, coalesce(eye_catching_img, ''), coalesce(content, '') from posts order by id")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var post entity.Post
+		err = rows.Scan(&post.Id, &post.Slug, &post.Title, &post.EyeCatchingImg, &post.Content)
+		if err != nil {
+			return
+		}
+		posts = append(posts, post)
+	}
+	err = rows.Err()
+	return
+}
+
+func (r *MediaRepository) GetReferringCategories() (categories []entity.Category, err error) {
+	rows, err := r.Query("select id, slug, name, cover_image from categories where cover_image <> '' order by id")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var category entity.Category
+		err = rows.Scan(&category.Id, &category.Slug, &category.Name, &category.CoverImage)
+		if err != nil {
+			return
+		}
+		categories = append(categories, category)
+	}
+	err = rows.Err()
+	return
+}
+
+func (r *MediaRepository) GetReferringUsers() (users []entity.User, err error) {
+	rows, err := r.Query("select id, username, avatar_url from users where avatar_url <> '' order by id")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var user entity.User
+		err = rows.Scan(&user.Id, &user.Name, &user.AvatarUrl)
+		if err != nil {
+			return
+		}
+		users = append(users, user)
+	}
+	err = rows.Err()
+	return
+}
@@ -0,0 +1,151 @@
+package postgresql
+
+import (
+	"backend/app/domain/entity"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaRepository(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"name", "content_type", "size", "width", "height", "alt_text", "uploaded_by", "created_at"}
+	media := []entity.Media{
+		{Name: "abc.jpg", ContentType: "image/jpeg", Size: 1024, Width: 800, Height: 600, AltText: "a cat", UploadedBy: 3, CreatedAt: createdAt},
+		{Name: "def.pdf", ContentType: "application/pdf", Size: 2048, CreatedAt: createdAt},
+	}
+	query := "select name, content_type, size, coalesce(width, 0), coalesce(height, 0), alt_text, coalesce(uploaded_by, 0), created_at from media"
+
+	t.Run(
+		"GetAll",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta(query + " order by name")).
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow("abc.jpg", "image/jpeg", 1024, 800, 600, "a cat", 3, createdAt).
+					AddRow("def.pdf", "application/pdf", 2048, 0, 0, "", 0, createdAt))
+
+			r := NewMediaRepository(db)
+
+			ret, err := r.GetAll()
+
+			assert.NoError(t, err)
+			assert.Equal(t, media, ret)
+		},
+	)
+
+	t.Run(
+		"GetByName",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta(query + " where name = $1")).
+				WithArgs("abc.jpg").
+				WillReturnRows(sqlmock.NewRows(columns).AddRow("abc.jpg", "image/jpeg", 1024, 800, 600, "a cat", 3, createdAt))
+			mock.ExpectQuery(regexp.QuoteMeta(query + " where name = $1")).
+				WithArgs("missing.jpg").
+				WillReturnRows(sqlmock.NewRows(columns))
+
+			r := NewMediaRepository(db)
+
+			ret, err := r.GetByName("abc.jpg")
+			assert.NoError(t, err)
+			assert.Equal(t, media[0], ret)
+
+			_, err = r.GetByName("missing.jpg")
+			assert.ErrorIs(t, err, sql.ErrNoRows)
+		},
+	)
+
+	t.Run(
+		"Save",
+		func(t *testing.T) {
+			mock.ExpectExec(regexp.QuoteMeta(`insert into media (name, content_type, size, width, height, alt_text, uploaded_by, created_at)
+				values ($1, $2, $3, nullif($4, 0), nullif($5, 0), $6, nullif($7, 0), coalesce($8, current_timestamp))
+				on conflict (name) do update set content_type = excluded.content_type, size = excluded.size,
+				width = excluded.width, height = excluded.height, alt_text = excluded.alt_text`)).
+				WithArgs("abc.jpg", "image/jpeg", int64(1024), 800, 600, "a cat", 3, sql.NullTime{Time: createdAt, Valid: true}).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			r := NewMediaRepository(db)
+
+			err := r.Save(media[0])
+
+			assert.NoError(t, err)
+		},
+	)
+
+	t.Run(
+		"Delete",
+		func(t *testing.T) {
+			mock.ExpectExec(regexp.QuoteMeta("delete from media where name = $1")).
+				WithArgs("abc.jpg").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			r := NewMediaRepository(db)
+
+			err := r.Delete("abc.jpg")
+
+			assert.NoError(t, err)
+		},
+	)
+
+	t.Run(
+		"GetReferringPosts",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta("select id, slug, title, coalesce(eye_catching_img, ''), coalesce(content, '') from posts order by id")).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "slug", "title", "eye_catching_img", "content"}).
+					AddRow(1, "hello", "Hello", "abc.jpg", "![](/api/v1/media/def.pdf)"))
+
+			r := NewMediaRepository(db)
+
+			ret, err := r.GetReferringPosts()
+
+			assert.NoError(t, err)
+			assert.Equal(t, []entity.Post{{Id: 1, Slug: "hello", Title: "Hello", EyeCatchingImg: "abc.jpg", Content: "![](/api/v1/media/def.pdf)"}}, ret)
+		},
+	)
+
+	t.Run(
+		"GetReferringCategories",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta("select id, slug, name, cover_image from categories where cover_image <> '' order by id")).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "slug", "name", "cover_image"}).
+					AddRow(2, "go", "Go", "/api/v1/media/gopher.png"))
+
+			r := NewMediaRepository(db)
+
+			ret, err := r.GetReferringCategories()
+
+			assert.NoError(t, err)
+			assert.Equal(t, []entity.Category{{Id: 2, Slug: "go", Name: "Go", CoverImage: "/api/v1/media/gopher.png"}}, ret)
+		},
+	)
+
+	t.Run(
+		"GetReferringUsers",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta("select id, username, avatar_url from users where avatar_url <> '' order by id")).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "username", "avatar_url"}).
+					AddRow(3, "alice", "alice.jpg"))
+
+			r := NewMediaRepository(db)
+
+			ret, err := r.GetReferringUsers()
+
+			assert.NoError(t, err)
+			assert.Equal(t, []entity.User{{Id: 3, Name: "alice", AvatarUrl: "alice.jpg"}}, ret)
+		},
+	)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
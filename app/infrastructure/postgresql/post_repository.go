@@ -4,8 +4,9 @@ import (
 	"backend/app/domain/entity"
 	"backend/app/domain/repository"
 	"database/sql"
+	"encoding/json"
 	"fmt"
-	"log"
+	"strings"
 )
 
 type PostRepository struct {
@@ -17,116 +18,136 @@ func NewPostRepository(db *sql.DB) (postRepository repository.IPostRepository) {
 	return
 }
 
+// selectPosts joins posts with the category node each post is filed under and its author,
+// and collects the derivatives of the eye-catching image as a JSON array.
+const selectPosts = `
+	select
+	posts.id as id, title, posts.slug, eye_catching_img, content, meta_description, is_public, created_at, updated_at, posts.version,
+	categories.id as category_id, categories.name as category_name, categories.slug as category_slug,
+	coalesce(posts.author_id, 0) as author_id, coalesce(users.username, '') as author_name,
+	coalesce(users.display_name, '') as author_display_name, coalesce(users.avatar_url, '') as author_avatar_url,
+	coalesce((
+		select json_agg(json_build_object('kind', kind, 'name', name, 'width', width, 'height', height) order by kind)
+		from image_derivatives where image_derivatives.source = posts.eye_catching_img
+	), '[]') as images
+	from posts inner join categories on posts.category_id = categories.id
+	left join users on posts.author_id = users.id
+`
+
+// withCategoryTree collects the category given as $1 and all its descendants.
+const withCategoryTree = `
+	with recursive category_tree as (
+		select id from categories where slug = $1
+		union all
+		select categories.id from categories inner join category_tree on categories.parent_id = category_tree.id
+	)
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPost(row rowScanner) (post entity.Post, err error) {
+	var images []byte
+	err = row.Scan(
+		&post.Id,
+		&post.Title,
+		&post.Slug,
+		&post.EyeCatchingImg,
+		&post.Content,
+		&post.MetaDescription,
+		&post.IsPublic,
+		&post.CreatedAt,
+		&post.UpdatedAt,
+		&post.Version,
+		&post.CategoryId,
+		&post.CategoryName,
+		&post.CategorySlug,
+		&post.AuthorId,
+		&post.AuthorName,
+		&post.AuthorDisplayName,
+		&post.AuthorAvatarUrl,
+		&images,
+	)
+	if err != nil {
+		return
+	}
+	// the keys of the JSON objects match the field names of ImageDerivative
+	err = json.Unmarshal(images, &post.Images)
+	return
+}
+
+// GetPosts lists posts, optionally filtered by "category-name" (the whole subtree of the
+// category) and "author" (username). Filters combine with "and".
 func (r *PostRepository) GetPosts(queryParams map[string][]string) (posts []entity.Post, err error) {
-	var rows *sql.Rows
-	// join 3 tables (posts, categories, sub_categories)
-	// on posts.sub_category_id = sub_categories.id
-	// on sub_categories.parent_category_id = categories.id
-	if categorySlugs, ok := queryParams["category-name"]; ok { // given category-name as query-params
-		categorySlug := categorySlugs[0]
-		rows, err = r.Query(`
-			select
-			posts.id as id, title, posts.slug, eye_catching_img, content, meta_description, is_public, created_at, updated_at,
-			categories.id as category_id, categories.name as category_name, categories.slug as category_slug,
-			sub_categories.id as sub_category_id, sub_categories.name as sub_category_name, sub_categories.slug as sub_category_slug
-			from (
-			(posts inner join sub_categories on posts.sub_category_id = sub_categories.id)
-			inner join categories on sub_categories.parent_category_id = categories.id)
-			where categories.slug = $1
-	`, categorySlug)
-	} else if subCategorySlugs, ok := queryParams["sub-category-name"]; ok { // given sub-category-name as query-params
-		subCategorySlug := subCategorySlugs[0]
-		rows, err = r.Query(`
-			select
-			posts.id as id, title, posts.slug, eye_catching_img, content, meta_description, is_public, created_at, updated_at,
-			categories.id as category_id, categories.name as category_name, categories.slug as category_slug,
-			sub_categories.id as sub_category_id, sub_categories.name as sub_category_name, sub_categories.slug as sub_category_slug
-			from (
-			(posts inner join sub_categories on posts.sub_category_id = sub_categories.id)
-			inner join categories on sub_categories.parent_category_id = categories.id)
-			where sub_categories.slug = $1
-		`, subCategorySlug)
-	} else { // no query-params; return all posts
-		rows, err = r.Query(`
-			select
-			posts.id as id, title, posts.slug, eye_catching_img, content, meta_description, is_public, created_at, updated_at,
-			categories.id as category_id, categories.name as category_name, categories.slug as category_slug,
-			sub_categories.id as sub_category_id, sub_categories.name as sub_category_name, sub_categories.slug as sub_category_slug
-			from (
-			(posts inner join sub_categories on posts.sub_category_id = sub_categories.id)
-			inner join categories on sub_categories.parent_category_id = categories.id)
-		`)
+	query := selectPosts
+	var conditions []string
+	var args []interface{}
+	// "sub-category-name" is kept for clients written against the two-level model;
+	// both parameters list the posts of the whole subtree.
+	categorySlugs, ok := queryParams["category-name"]
+	if !ok {
+		categorySlugs, ok = queryParams["sub-category-name"]
+	}
+	if ok {
+		query = withCategoryTree + query
+		args = append(args, categorySlugs[0])
+		conditions = append(conditions, "posts.category_id in (select id from category_tree)")
+	}
+	if authors, ok := queryParams["author"]; ok {
+		args = append(args, authors[0])
+		conditions = append(conditions, fmt.Sprintf("users.username = $%d", len(args)))
+	}
+	if len(conditions) > 0 {
+		query += "where " + strings.Join(conditions, " and ")
 	}
+	rows, err := r.Query(query, args...)
 	if err != nil {
 		return
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var post entity.Post
-		rows.Scan(
-			&post.Id,
-			&post.Title,
-			&post.Slug,
-			&post.EyeCatchingImg,
-			&post.Content,
-			&post.MetaDescription,
-			&post.IsPublic,
-			&post.CreatedAt,
-			&post.UpdatedAt,
-			&post.CategoryId,
-			&post.CategoryName,
-			&post.CategorySlug,
-			&post.SubCategoryId,
-			&post.SubCategoryName,
-			&post.SubCategorySlug,
-		)
+		post, err = scanPost(rows)
+		if err != nil {
+			return
+		}
 		posts = append(posts, post)
 	}
+	err = rows.Err()
 	return
 }
 
 func (r *PostRepository) GetPostBySlug(slug string) (post entity.Post, err error) {
-	err = r.QueryRow(`
-		select
-		posts.id as id, title, posts.slug, eye_catching_img, content, meta_description, is_public, created_at, updated_at,
-		categories.id as category_id, categories.name as category_name, categories.slug as category_slug,
-		sub_categories.id as sub_category_id, sub_categories.name as sub_category_name, sub_categories.slug as sub_category_slug
-		from (
-		(posts inner join sub_categories on posts.sub_category_id = sub_categories.id)
-		inner join categories on sub_categories.parent_category_id = categories.id)
-		where posts.slug = $1
-	`, slug).
-		Scan(
-			&post.Id,
-			&post.Title,
-			&post.Slug,
-			&post.EyeCatchingImg,
-			&post.Content,
-			&post.MetaDescription,
-			&post.IsPublic,
-			&post.CreatedAt,
-			&post.UpdatedAt,
-			&post.CategoryId,
-			&post.CategoryName,
-			&post.CategorySlug,
-			&post.SubCategoryId,
-			&post.SubCategoryName,
-			&post.SubCategorySlug,
-		)
+	post, err = scanPost(r.QueryRow(selectPosts+"where posts.slug = $1", slug))
 	return
 }
 
+func (r *PostRepository) GetPostById(id int) (post entity.Post, err error) {
+	post, err = scanPost(r.QueryRow(selectPosts+"where posts.id = $1", id))
+	return
+}
+
+// Create inserts the post. A zero CreatedAt means now; imports set it to keep the
+// original date.
 func (r *PostRepository) Create(post entity.Post) (err error) {
-	_, err = r.Exec("insert into posts (title, slug, eye_catching_img, content, meta_description, is_public, sub_category_id) values ($1, $2, $3, $4, $5, $6, $7)",
-		post.Title, post.Slug, post.EyeCatchingImg, post.Content, post.MetaDescription, post.IsPublic, post.SubCategoryId)
+	createdAt := sql.NullTime{Time: post.CreatedAt, Valid: !post.CreatedAt.IsZero()}
+	_, err = r.Exec("insert into posts (title, slug, eye_catching_img, content, meta_description, is_public, category_id, author_id, created_at) values ($1, $2, $3, $4, $5, $6, $7, nullif($8, 0), coalesce($9, current_timestamp))",
+		post.Title, post.Slug, post.EyeCatchingImg, post.Content, post.MetaDescription, post.IsPublic, post.CategoryId, post.AuthorId, createdAt)
 	return
 }
 
+// Update overwrites the post and increments its version. With a non-zero Version
+// the post is only changed if it still has that version; otherwise the update
+// fails with repository.ErrConflict.
 func (r *PostRepository) Update(post entity.Post) (err error) {
-	_, err = r.Exec("update posts set title = $2, slug = $3, eye_catching_img = $4, content = $5, meta_description = $6, is_public = $7, sub_category_id = $8 where id = $1",
-		post.Id, post.Title, post.Slug, post.EyeCatchingImg, post.Content, post.MetaDescription, post.IsPublic, post.SubCategoryId)
+	result, err := r.Exec("update posts set title = $2, slug = $3, eye_catching_img = $4, content = $5, meta_description = $6, is_public = $7, category_id = $8, version = version + 1 where id = $1 and ($9 = 0 or version = $9)",
+		post.Id, post.Title, post.Slug, post.EyeCatchingImg, post.Content, post.MetaDescription, post.IsPublic, post.CategoryId, post.Version)
 	if err != nil {
 		fmt.Println(err)
+		return
 	}
+	err = checkVersion(result, post.Version)
 	return
 }
 
@@ -2,6 +2,7 @@ package postgresql
 
 import (
 	"backend/app/domain/entity"
+	"backend/app/domain/repository"
 	"regexp"
 	"testing"
 	"time"
@@ -14,7 +15,6 @@ func AssertPosts(t *testing.T, ret []entity.Post, posts []entity.Post) {
 	for i, r := range ret {
 		assert.Equal(t, r.Id, posts[i].Id)
 		assert.Equal(t, r.CategoryId, posts[i].CategoryId)
-		assert.Equal(t, r.SubCategoryId, posts[i].SubCategoryId)
 		assert.Equal(t, r.Title, posts[i].Title)
 		assert.Equal(t, r.Slug, posts[i].Slug)
 		assert.Equal(t, r.EyeCatchingImg, posts[i].EyeCatchingImg)
@@ -23,6 +23,9 @@ func AssertPosts(t *testing.T, ret []entity.Post, posts []entity.Post) {
 		assert.Equal(t, r.IsPublic, posts[i].IsPublic)
 		assert.Equal(t, r.CreatedAt, posts[i].CreatedAt)
 		assert.Equal(t, r.UpdatedAt, posts[i].UpdatedAt)
+		assert.Equal(t, r.Version, posts[i].Version)
+		assert.Equal(t, r.AuthorId, posts[i].AuthorId)
+		assert.Equal(t, r.AuthorName, posts[i].AuthorName)
 	}
 }
 
@@ -47,12 +50,10 @@ func TestPostRepository_GetPosts(t *testing.T) {
 			IsPublic:        false,
 			CreatedAt:       postCreatedAt,
 			UpdatedAt:       postUpdatedAt,
+			Version:         1,
 			CategoryId:      1,
 			CategoryName:    "testCategory1",
 			CategorySlug:    "test-category-1",
-			SubCategoryId:   1,
-			SubCategoryName: "testSubCategory1",
-			SubCategorySlug: "test-sub-category-1",
 		},
 		{
 			Id:              2,
@@ -64,12 +65,10 @@ func TestPostRepository_GetPosts(t *testing.T) {
 			IsPublic:        false,
 			CreatedAt:       postCreatedAt,
 			UpdatedAt:       postUpdatedAt,
+			Version:         1,
 			CategoryId:      1,
 			CategoryName:    "testCategory1",
 			CategorySlug:    "test-category-1",
-			SubCategoryId:   1,
-			SubCategoryName: "testSubCategory1",
-			SubCategorySlug: "test-sub-category-1",
 		},
 	}
 
@@ -83,63 +82,84 @@ func TestPostRepository_GetPosts(t *testing.T) {
 		"is_public",
 		"created_at",
 		"updated_at",
+		"version",
 		"category_id",
 		"category_name",
 		"category_slug",
-		"sub_category_id",
-		"sub_category_name",
-		"sub_category_slug",
+		"author_id",
+		"author_name",
+		"author_display_name",
+		"author_avatar_url",
+		"images",
 	}
 
-	rows := sqlmock.NewRows(fields).
-		AddRow(
-			posts[0].Id,
-			posts[0].Title,
-			posts[0].Slug,
-			posts[0].EyeCatchingImg,
-			posts[0].Content,
-			posts[0].MetaDescription,
-			posts[0].IsPublic,
-			posts[0].CreatedAt,
-			posts[0].UpdatedAt,
-			posts[0].CategoryId,
-			posts[0].CategoryName,
-			posts[0].CategorySlug,
-			posts[0].SubCategoryId,
-			posts[0].SubCategoryName,
-			posts[0].SubCategorySlug,
-		).
-		AddRow(
-			posts[1].Id,
-			posts[1].Title,
-			posts[1].Slug,
-			posts[1].EyeCatchingImg,
-			posts[1].Content,
-			posts[1].MetaDescription,
-			posts[1].IsPublic,
-			posts[1].CreatedAt,
-			posts[1].UpdatedAt,
-			posts[1].CategoryId,
-			posts[1].CategoryName,
-			posts[1].CategorySlug,
-			posts[1].SubCategoryId,
-			posts[1].SubCategoryName,
-			posts[1].SubCategorySlug,
-		)
+	// every query consumes its rows, so each subtest needs a fresh set
+	newRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(fields).
+			AddRow(
+				posts[0].Id,
+				posts[0].Title,
+				posts[0].Slug,
+				posts[0].EyeCatchingImg,
+				posts[0].Content,
+				posts[0].MetaDescription,
+				posts[0].IsPublic,
+				posts[0].CreatedAt,
+				posts[0].UpdatedAt,
+				posts[0].Version,
+				posts[0].CategoryId,
+				posts[0].CategoryName,
+				posts[0].CategorySlug,
+				posts[0].AuthorId,
+				posts[0].AuthorName,
+				posts[0].AuthorDisplayName,
+				posts[0].AuthorAvatarUrl,
+				[]byte("[]"),
+			).
+			AddRow(
+				posts[1].Id,
+				posts[1].Title,
+				posts[1].Slug,
+				posts[1].EyeCatchingImg,
+				posts[1].Content,
+				posts[1].MetaDescription,
+				posts[1].IsPublic,
+				posts[1].CreatedAt,
+				posts[1].UpdatedAt,
+				posts[1].Version,
+				posts[1].CategoryId,
+				posts[1].CategoryName,
+				posts[1].CategorySlug,
+				posts[1].AuthorId,
+				posts[1].AuthorName,
+				posts[1].AuthorDisplayName,
+				posts[1].AuthorAvatarUrl,
+				[]byte("[]"),
+			)
+	}
 
 	t.Run(
 		"with query-params: category-name",
 		func(t *testing.T) {
 			mock.ExpectQuery(regexp.QuoteMeta(`
+				with recursive category_tree as (
+					select id from categories where slug = $1
+					union all
+					select categories.id from categories inner join category_tree on categories.parent_id = category_tree.id
+				)
 				select
-				posts.id as id, title, posts.slug, eye_catching_img, content, meta_description, is_public, created_at, updated_at,
+				posts.id as id, title, posts.slug, eye_catching_img, content, meta_description, is_public, created_at, updated_at, posts.version,
 				categories.id as category_id, categories.name as category_name, categories.slug as category_slug,
-				sub_categories.id as sub_category_id, sub_categories.name as sub_category_name, sub_categories.slug as sub_category_slug
-				from (
-				(posts inner join sub_categories on posts.sub_category_id = sub_categories.id)
-				inner join categories on sub_categories.parent_category_id = categories.id)
-				where categories.slug = $1
-			`)).WithArgs(posts[0].CategorySlug).WillReturnRows(rows)
+				coalesce(posts.author_id, 0) as author_id, coalesce(users.username, '') as author_name,
+				coalesce(users.display_name, '') as author_display_name, coalesce(users.avatar_url, '') as author_avatar_url,
+				coalesce((
+					select json_agg(json_build_object('kind', kind, 'name', name, 'width', width, 'height', height) order by kind)
+					from image_derivatives where image_derivatives.source = posts.eye_catching_img
+				), '[]') as images
+				from posts inner join categories on posts.category_id = categories.id
+				left join users on posts.author_id = users.id
+				where posts.category_id in (select id from category_tree)
+			`)).WithArgs(posts[0].CategorySlug).WillReturnRows(newRows())
 
 			r := NewPostRepository(db)
 
@@ -152,6 +172,7 @@ func TestPostRepository_GetPosts(t *testing.T) {
 			ret, err := r.GetPosts(queryParams)
 
 			assert.NoError(t, err)
+			assert.Len(t, ret, 2)
 			AssertPosts(t, ret, posts)
 		},
 	)
@@ -160,27 +181,37 @@ func TestPostRepository_GetPosts(t *testing.T) {
 		"with query-params: sub-category-name",
 		func(t *testing.T) {
 			mock.ExpectQuery(regexp.QuoteMeta(`
+				with recursive category_tree as (
+					select id from categories where slug = $1
+					union all
+					select categories.id from categories inner join category_tree on categories.parent_id = category_tree.id
+				)
 				select
-				posts.id as id, title, posts.slug, eye_catching_img, content, meta_description, is_public, created_at, updated_at,
+				posts.id as id, title, posts.slug, eye_catching_img, content, meta_description, is_public, created_at, updated_at, posts.version,
 				categories.id as category_id, categories.name as category_name, categories.slug as category_slug,
-				sub_categories.id as sub_category_id, sub_categories.name as sub_category_name, sub_categories.slug as sub_category_slug
-				from (
-				(posts inner join sub_categories on posts.sub_category_id = sub_categories.id)
-				inner join categories on sub_categories.parent_category_id = categories.id)
-				where sub_categories.slug = $1
-			`)).WithArgs(posts[0].SubCategorySlug).WillReturnRows(rows)
+				coalesce(posts.author_id, 0) as author_id, coalesce(users.username, '') as author_name,
+				coalesce(users.display_name, '') as author_display_name, coalesce(users.avatar_url, '') as author_avatar_url,
+				coalesce((
+					select json_agg(json_build_object('kind', kind, 'name', name, 'width', width, 'height', height) order by kind)
+					from image_derivatives where image_derivatives.source = posts.eye_catching_img
+				), '[]') as images
+				from posts inner join categories on posts.category_id = categories.id
+				left join users on posts.author_id = users.id
+				where posts.category_id in (select id from category_tree)
+			`)).WithArgs(posts[0].CategorySlug).WillReturnRows(newRows())
 
 			r := NewPostRepository(db)
 
 			queryParams := map[string][]string{
 				"sub-category-name": {
-					posts[0].SubCategorySlug,
+					posts[0].CategorySlug,
 				},
 			}
 
 			ret, err := r.GetPosts(queryParams)
 
 			assert.NoError(t, err)
+			assert.Len(t, ret, 2)
 			AssertPosts(t, ret, posts)
 		},
 	)
@@ -190,13 +221,17 @@ func TestPostRepository_GetPosts(t *testing.T) {
 		func(t *testing.T) {
 			mock.ExpectQuery(regexp.QuoteMeta(`
 				select
-				posts.id as id, title, posts.slug, eye_catching_img, content, meta_description, is_public, created_at, updated_at,
+				posts.id as id, title, posts.slug, eye_catching_img, content, meta_description, is_public, created_at, updated_at, posts.version,
 				categories.id as category_id, categories.name as category_name, categories.slug as category_slug,
-				sub_categories.id as sub_category_id, sub_categories.name as sub_category_name, sub_categories.slug as sub_category_slug
-				from (
-				(posts inner join sub_categories on posts.sub_category_id = sub_categories.id)
-				inner join categories on sub_categories.parent_category_id = categories.id)
-			`)).WillReturnRows(rows)
+				coalesce(posts.author_id, 0) as author_id, coalesce(users.username, '') as author_name,
+				coalesce(users.display_name, '') as author_display_name, coalesce(users.avatar_url, '') as author_avatar_url,
+				coalesce((
+					select json_agg(json_build_object('kind', kind, 'name', name, 'width', width, 'height', height) order by kind)
+					from image_derivatives where image_derivatives.source = posts.eye_catching_img
+				), '[]') as images
+				from posts inner join categories on posts.category_id = categories.id
+				left join users on posts.author_id = users.id
+			`)).WillReturnRows(newRows())
 
 			r := NewPostRepository(db)
 
@@ -205,6 +240,59 @@ func TestPostRepository_GetPosts(t *testing.T) {
 			ret, err := r.GetPosts(queryParams)
 
 			assert.NoError(t, err)
+			assert.Len(t, ret, 2)
+			AssertPosts(t, ret, posts)
+		},
+	)
+
+	t.Run(
+		"with query-params: author",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta(`
+				from posts inner join categories on posts.category_id = categories.id
+				left join users on posts.author_id = users.id
+				where users.username = $1
+			`)).WithArgs("testauthor").WillReturnRows(newRows())
+
+			r := NewPostRepository(db)
+
+			queryParams := map[string][]string{
+				"author": {
+					"testauthor",
+				},
+			}
+
+			ret, err := r.GetPosts(queryParams)
+
+			assert.NoError(t, err)
+			assert.Len(t, ret, 2)
+			AssertPosts(t, ret, posts)
+		},
+	)
+
+	t.Run(
+		"with query-params: category-name and author",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta(`
+				left join users on posts.author_id = users.id
+				where posts.category_id in (select id from category_tree) and users.username = $2
+			`)).WithArgs(posts[0].CategorySlug, "testauthor").WillReturnRows(newRows())
+
+			r := NewPostRepository(db)
+
+			queryParams := map[string][]string{
+				"category-name": {
+					posts[0].CategorySlug,
+				},
+				"author": {
+					"testauthor",
+				},
+			}
+
+			ret, err := r.GetPosts(queryParams)
+
+			assert.NoError(t, err)
+			assert.Len(t, ret, 2)
 			AssertPosts(t, ret, posts)
 		},
 	)
@@ -221,21 +309,23 @@ func TestPostRepository_CRUD(t *testing.T) {
 	defer db.Close()
 
 	post := entity.Post{
-		Id:              1,
-		Title:           "testPost1",
-		Slug:            "test-post-1",
-		EyeCatchingImg:  "test_post_1.png",
-		Content:         "This is 1st post",
-		MetaDescription: "This is 1st post",
-		IsPublic:        false,
-		CreatedAt:       postCreatedAt,
-		UpdatedAt:       postUpdatedAt,
-		CategoryId:      1,
-		CategoryName:    "testCategory1",
-		CategorySlug:    "test-category-1",
-		SubCategoryId:   1,
-		SubCategoryName: "testSubCategory1",
-		SubCategorySlug: "test-sub-category-1",
+		Id:                1,
+		Title:             "testPost1",
+		Slug:              "test-post-1",
+		EyeCatchingImg:    "test_post_1.png",
+		Content:           "This is 1st post",
+		MetaDescription:   "This is 1st post",
+		IsPublic:          false,
+		CreatedAt:         postCreatedAt,
+		UpdatedAt:         postUpdatedAt,
+		Version:           2,
+		CategoryId:        1,
+		CategoryName:      "testCategory1",
+		CategorySlug:      "test-category-1",
+		AuthorId:          3,
+		AuthorName:        "testauthor",
+		AuthorDisplayName: "Test Author",
+		AuthorAvatarUrl:   "test_author.png",
 	}
 
 	fields := []string{
@@ -248,12 +338,15 @@ func TestPostRepository_CRUD(t *testing.T) {
 		"is_public",
 		"created_at",
 		"updated_at",
+		"version",
 		"category_id",
 		"category_name",
 		"category_slug",
-		"sub_category_id",
-		"sub_category_name",
-		"sub_category_slug",
+		"author_id",
+		"author_name",
+		"author_display_name",
+		"author_avatar_url",
+		"images",
 	}
 
 	rows := sqlmock.NewRows(fields).
@@ -267,12 +360,15 @@ func TestPostRepository_CRUD(t *testing.T) {
 			post.IsPublic,
 			post.CreatedAt,
 			post.UpdatedAt,
+			post.Version,
 			post.CategoryId,
 			post.CategoryName,
 			post.CategorySlug,
-			post.SubCategoryId,
-			post.SubCategoryName,
-			post.SubCategorySlug,
+			post.AuthorId,
+			post.AuthorName,
+			post.AuthorDisplayName,
+			post.AuthorAvatarUrl,
+			[]byte(`[{"kind": "thumbnail", "name": "test-thumbnail.jpg", "width": 150, "height": 150}]`),
 		)
 
 	t.Run(
@@ -280,12 +376,16 @@ func TestPostRepository_CRUD(t *testing.T) {
 		func(t *testing.T) {
 			mock.ExpectQuery(regexp.QuoteMeta(`
 				select
-				posts.id as id, title, posts.slug, eye_catching_img, content, meta_description, is_public, created_at, updated_at,
+				posts.id as id, title, posts.slug, eye_catching_img, content, meta_description, is_public, created_at, updated_at, posts.version,
 				categories.id as category_id, categories.name as category_name, categories.slug as category_slug,
-				sub_categories.id as sub_category_id, sub_categories.name as sub_category_name, sub_categories.slug as sub_category_slug
-				from (
-				(posts inner join sub_categories on posts.sub_category_id = sub_categories.id)
-				inner join categories on sub_categories.parent_category_id = categories.id)
+				coalesce(posts.author_id, 0) as author_id, coalesce(users.username, '') as author_name,
+				coalesce(users.display_name, '') as author_display_name, coalesce(users.avatar_url, '') as author_avatar_url,
+				coalesce((
+					select json_agg(json_build_object('kind', kind, 'name', name, 'width', width, 'height', height) order by kind)
+					from image_derivatives where image_derivatives.source = posts.eye_catching_img
+				), '[]') as images
+				from posts inner join categories on posts.category_id = categories.id
+				left join users on posts.author_id = users.id
 				where posts.slug = $1
 			`)).WithArgs(post.Slug).WillReturnRows(rows)
 
@@ -296,7 +396,6 @@ func TestPostRepository_CRUD(t *testing.T) {
 			assert.NoError(t, err)
 			assert.Equal(t, ret.Id, post.Id)
 			assert.Equal(t, ret.CategoryId, post.CategoryId)
-			assert.Equal(t, ret.SubCategoryId, post.SubCategoryId)
 			assert.Equal(t, ret.Title, post.Title)
 			assert.Equal(t, ret.Slug, post.Slug)
 			assert.Equal(t, ret.EyeCatchingImg, post.EyeCatchingImg)
@@ -305,20 +404,80 @@ func TestPostRepository_CRUD(t *testing.T) {
 			assert.Equal(t, ret.IsPublic, post.IsPublic)
 			assert.Equal(t, ret.CreatedAt, post.CreatedAt)
 			assert.Equal(t, ret.UpdatedAt, post.UpdatedAt)
+			assert.Equal(t, ret.Version, post.Version)
+			assert.Equal(t, ret.AuthorId, post.AuthorId)
+			assert.Equal(t, ret.AuthorName, post.AuthorName)
+			assert.Equal(t, ret.AuthorDisplayName, post.AuthorDisplayName)
+			assert.Equal(t, ret.AuthorAvatarUrl, post.AuthorAvatarUrl)
+			assert.Equal(t, []entity.ImageDerivative{{Kind: "thumbnail", Name: "test-thumbnail.jpg", Width: 150, Height: 150}}, ret.Images)
+		},
+	)
+
+	t.Run(
+		"GetPostById",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta(`
+				select
+				posts.id as id, title, posts.slug, eye_catching_img, content, meta_description, is_public, created_at, updated_at, posts.version,
+				categories.id as category_id, categories.name as category_name, categories.slug as category_slug,
+				coalesce(posts.author_id, 0) as author_id, coalesce(users.username, '') as author_name,
+				coalesce(users.display_name, '') as author_display_name, coalesce(users.avatar_url, '') as author_avatar_url,
+				coalesce((
+					select json_agg(json_build_object('kind', kind, 'name', name, 'width', width, 'height', height) order by kind)
+					from image_derivatives where image_derivatives.source = posts.eye_catching_img
+				), '[]') as images
+				from posts inner join categories on posts.category_id = categories.id
+				left join users on posts.author_id = users.id
+				where posts.id = $1
+			`)).WithArgs(post.Id).WillReturnRows(sqlmock.NewRows(fields).AddRow(
+				post.Id,
+				post.Title,
+				post.Slug,
+				post.EyeCatchingImg,
+				post.Content,
+				post.MetaDescription,
+				post.IsPublic,
+				post.CreatedAt,
+				post.UpdatedAt,
+				post.Version,
+				post.CategoryId,
+				post.CategoryName,
+				post.CategorySlug,
+				post.AuthorId,
+				post.AuthorName,
+				post.AuthorDisplayName,
+				post.AuthorAvatarUrl,
+				[]byte("[]"),
+			))
+
+			r := NewPostRepository(db)
+
+			ret, err := r.GetPostById(post.Id)
+
+			assert.NoError(t, err)
+			assert.Equal(t, ret.Id, post.Id)
+			assert.Equal(t, ret.AuthorId, post.AuthorId)
 		},
 	)
 
 	t.Run(
 		"Create",
 		func(t *testing.T) {
-			mock.ExpectExec(regexp.QuoteMeta("insert into posts (title, slug, eye_catching_img, content, meta_description, is_public, sub_category_id) values ($1, $2, $3, $4, $5, $6, $7)")).
-				WithArgs(post.Title, post.Slug, post.EyeCatchingImg, post.Content, post.MetaDescription, post.IsPublic, post.SubCategoryId).
+			mock.ExpectExec(regexp.QuoteMeta("insert into posts (title, slug, eye_catching_img, content, meta_description, is_public, category_id, author_id, created_at) values ($1, $2, $3, $4, $5, $6, $7, nullif($8, 0), coalesce($9, current_timestamp))")).
+				WithArgs(post.Title, post.Slug, post.EyeCatchingImg, post.Content, post.MetaDescription, post.IsPublic, post.CategoryId, post.AuthorId, post.CreatedAt).
 				WillReturnResult(sqlmock.NewResult(1, 8))
+			mock.ExpectExec(regexp.QuoteMeta("insert into posts")).
+				WithArgs(post.Title, post.Slug, post.EyeCatchingImg, post.Content, post.MetaDescription, post.IsPublic, post.CategoryId, post.AuthorId, nil).
+				WillReturnResult(sqlmock.NewResult(2, 1))
 
 			r := NewPostRepository(db)
 
 			err := r.Create(post)
+			assert.NoError(t, err)
 
+			undated := post
+			undated.CreatedAt = time.Time{}
+			err = r.Create(undated)
 			assert.NoError(t, err)
 		},
 	)
@@ -326,14 +485,37 @@ func TestPostRepository_CRUD(t *testing.T) {
 	t.Run(
 		"Update",
 		func(t *testing.T) {
-			mock.ExpectExec(regexp.QuoteMeta("update posts set title = $2, slug = $3, eye_catching_img = $4, content = $5, meta_description = $6, is_public = $7, sub_category_id = $8 where id = $1")).
-				WithArgs(post.Id, post.Title, post.Slug, post.EyeCatchingImg, post.Content, post.MetaDescription, post.IsPublic, post.SubCategoryId).
-				WillReturnResult(sqlmock.NewResult(1, 7))
+			mock.ExpectExec(regexp.QuoteMeta("update posts set title = $2, slug = $3, eye_catching_img = $4, content = $5, meta_description = $6, is_public = $7, category_id = $8, version = version + 1 where id = $1 and ($9 = 0 or version = $9)")).
+				WithArgs(post.Id, post.Title, post.Slug, post.EyeCatchingImg, post.Content, post.MetaDescription, post.IsPublic, post.CategoryId, post.Version).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			r := NewPostRepository(db)
+
+			err := r.Update(post)
+
+			assert.NoError(t, err)
+		},
+	)
+
+	t.Run(
+		"Update of a changed version",
+		func(t *testing.T) {
+			mock.ExpectExec(regexp.QuoteMeta("update posts")).
+				WithArgs(post.Id, post.Title, post.Slug, post.EyeCatchingImg, post.Content, post.MetaDescription, post.IsPublic, post.CategoryId, post.Version).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			unversioned := post
+			unversioned.Version = 0
+			mock.ExpectExec(regexp.QuoteMeta("update posts")).
+				WithArgs(post.Id, post.Title, post.Slug, post.EyeCatchingImg, post.Content, post.MetaDescription, post.IsPublic, post.CategoryId, 0).
+				WillReturnResult(sqlmock.NewResult(0, 0))
 
 			r := NewPostRepository(db)
 
 			err := r.Update(post)
+			assert.ErrorIs(t, err, repository.ErrConflict)
 
+			// without a version nothing is checked
+			err = r.Update(unversioned)
 			assert.NoError(t, err)
 		},
 	)
@@ -0,0 +1,61 @@
+package postgresql
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+	"database/sql"
+)
+
+type RefreshTokenRepository struct {
+	*sql.DB
+}
+
+func NewRefreshTokenRepository(db *sql.DB) (refreshTokenRepository repository.IRefreshTokenRepository) {
+	refreshTokenRepository = &RefreshTokenRepository{db}
+	return
+}
+
+func (r *RefreshTokenRepository) GetByHash(tokenHash string) (refreshToken entity.RefreshToken, err error) {
+	err = r.QueryRow(`
+		select id, user_id, family_id, token_hash, expires_at, created_at, revoked_at is not null
+		from refresh_tokens where token_hash = $1
+	`, tokenHash).Scan(
+		&refreshToken.Id,
+		&refreshToken.UserId,
+		&refreshToken.FamilyId,
+		&refreshToken.TokenHash,
+		&refreshToken.ExpiresAt,
+		&refreshToken.CreatedAt,
+		&refreshToken.Revoked,
+	)
+	return
+}
+
+func (r *RefreshTokenRepository) Create(refreshToken entity.RefreshToken) (err error) {
+	_, err = r.Exec("insert into refresh_tokens (user_id, family_id, token_hash, expires_at) values ($1, $2, $3, $4)",
+		refreshToken.UserId, refreshToken.FamilyId, refreshToken.TokenHash, refreshToken.ExpiresAt)
+	return
+}
+
+func (r *RefreshTokenRepository) Revoke(id int) (revoked bool, err error) {
+	result, err := r.Exec("update refresh_tokens set revoked_at = current_timestamp where id = $1 and revoked_at is null", id)
+	if err != nil {
+		return
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	revoked = affected == 1
+	return
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(familyId string) (err error) {
+	_, err = r.Exec("update refresh_tokens set revoked_at = current_timestamp where family_id = $1 and revoked_at is null", familyId)
+	return
+}
+
+func (r *RefreshTokenRepository) RevokeAllForUser(userId int) (err error) {
+	_, err = r.Exec("update refresh_tokens set revoked_at = current_timestamp where user_id = $1 and revoked_at is null", userId)
+	return
+}
@@ -0,0 +1,113 @@
+package postgresql
+
+import (
+	"backend/app/domain/entity"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshTokenRepository(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expiresAt, _ := time.Parse("2006-01-02 15:04:05.999999-07", "2006-01-02 15:04:05.999999-07")
+	createdAt, _ := time.Parse("2006-01-02 15:04:05.999999-07", "2006-01-01 15:04:05.999999-07")
+
+	refreshToken := entity.RefreshToken{
+		Id:        1,
+		UserId:    2,
+		FamilyId:  "family",
+		TokenHash: "hash",
+		ExpiresAt: expiresAt,
+		CreatedAt: createdAt,
+	}
+
+	t.Run(
+		"GetByHash",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta(`
+				select id, user_id, family_id, token_hash, expires_at, created_at, revoked_at is not null
+				from refresh_tokens where token_hash = $1
+			`)).WithArgs("hash").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id", "token_hash", "expires_at", "created_at", "revoked"}).
+					AddRow(1, 2, "family", "hash", expiresAt, createdAt, false))
+
+			r := NewRefreshTokenRepository(db)
+
+			ret, err := r.GetByHash("hash")
+
+			assert.NoError(t, err)
+			assert.Equal(t, ret, refreshToken)
+		},
+	)
+
+	t.Run(
+		"Create",
+		func(t *testing.T) {
+			mock.ExpectExec(regexp.QuoteMeta("insert into refresh_tokens (user_id, family_id, token_hash, expires_at) values ($1, $2, $3, $4)")).
+				WithArgs(2, "family", "hash", expiresAt).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			r := NewRefreshTokenRepository(db)
+
+			assert.NoError(t, r.Create(entity.NewRefreshToken(2, "family", "hash", expiresAt)))
+		},
+	)
+
+	t.Run(
+		"Revoke",
+		func(t *testing.T) {
+			mock.ExpectExec(regexp.QuoteMeta("update refresh_tokens set revoked_at = current_timestamp where id = $1 and revoked_at is null")).
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta("update refresh_tokens set revoked_at = current_timestamp where id = $1 and revoked_at is null")).
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			r := NewRefreshTokenRepository(db)
+
+			revoked, err := r.Revoke(1)
+			assert.NoError(t, err)
+			assert.True(t, revoked)
+
+			revoked, err = r.Revoke(1)
+			assert.NoError(t, err)
+			assert.False(t, revoked)
+		},
+	)
+
+	t.Run(
+		"RevokeFamily",
+		func(t *testing.T) {
+			mock.ExpectExec(regexp.QuoteMeta("update refresh_tokens set revoked_at = current_timestamp where family_id = $1 and revoked_at is null")).
+				WithArgs("family").
+				WillReturnResult(sqlmock.NewResult(0, 2))
+
+			r := NewRefreshTokenRepository(db)
+
+			assert.NoError(t, r.RevokeFamily("family"))
+		},
+	)
+
+	t.Run(
+		"RevokeAllForUser",
+		func(t *testing.T) {
+			mock.ExpectExec(regexp.QuoteMeta("update refresh_tokens set revoked_at = current_timestamp where user_id = $1 and revoked_at is null")).
+				WithArgs(2).
+				WillReturnResult(sqlmock.NewResult(0, 3))
+
+			r := NewRefreshTokenRepository(db)
+
+			assert.NoError(t, r.RevokeAllForUser(2))
+		},
+	)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
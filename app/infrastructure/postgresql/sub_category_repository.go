@@ -7,6 +7,8 @@ import (
 	"log"
 )
 
+// SubCategoryRepository exposes the non-root nodes of the category tree
+// together with their direct parent.
 type SubCategoryRepository struct {
 	*sql.DB
 }
@@ -18,35 +20,41 @@ func NewSubcategoryRepository(db *sql.DB) (subCategoryRepository repository.ISub
 
 func (r *SubCategoryRepository) GetSubCategories(queryParams map[string][]string) (subCategories []entity.SubCategory, err error) {
 	var rows *sql.Rows
-	// join 2 tables (sub_categories, categories)
-	// on sub_categories.parent_category_id = categories.id
+	// self-join categories
+	// on sub_categories.parent_id = parent_categories.id
 	if categorySlugs, ok := queryParams["category-name"]; ok { // given category-name as query-params
 		categorySlug := categorySlugs[0]
 		rows, err = r.Query(`
-			select 
+			select
 			sub_categories.id as id, sub_categories.name, sub_categories.slug,
-			categories.id as parent_category_id, categories.name as parent_category_name, categories.slug as parent_category_slug 
-			from sub_categories 
-			inner join categories 
-			on sub_categories.parent_category_id = categories.id
-			where categories.slug = $1
+			parent_categories.id as parent_category_id, parent_categories.name as parent_category_name, parent_categories.slug as parent_category_slug,
+			sub_categories.position, sub_categories.description, sub_categories.cover_image, sub_categories.version
+			from categories as sub_categories
+			inner join categories as parent_categories
+			on sub_categories.parent_id = parent_categories.id
+			where parent_categories.slug = $1
+			order by sub_categories.position, sub_categories.id
 	`, categorySlug)
 	} else { // no query-params; return all sub-categories
 		rows, err = r.Query(`
-			select 
-			sub_categories.id as id, sub_categories.name, sub_categories.slug, 
-			categories.id as parent_category_id, categories.name as parent_category_name, categories.slug as parent_category_slug 
-			from sub_categories 
-			inner join categories
-			on sub_categories.parent_category_id = categories.id
+			select
+			sub_categories.id as id, sub_categories.name, sub_categories.slug,
+			parent_categories.id as parent_category_id, parent_categories.name as parent_category_name, parent_categories.slug as parent_category_slug,
+			sub_categories.position, sub_categories.description, sub_categories.cover_image, sub_categories.version
+			from categories as sub_categories
+			inner join categories as parent_categories
+			on sub_categories.parent_id = parent_categories.id
+			order by parent_categories.position, parent_categories.id, sub_categories.position, sub_categories.id
 	`)
 	}
 	if err != nil {
 		return
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var subCategory entity.SubCategory
-		rows.Scan(&subCategory.Id, &subCategory.Name, &subCategory.Slug, &subCategory.ParentCategoryId, &subCategory.ParentCategoryName, &subCategory.ParentCategorySlug)
+		rows.Scan(&subCategory.Id, &subCategory.Name, &subCategory.Slug, &subCategory.ParentCategoryId, &subCategory.ParentCategoryName, &subCategory.ParentCategorySlug,
+			&subCategory.Position, &subCategory.Description, &subCategory.CoverImage, &subCategory.Version)
 		subCategories = append(subCategories, subCategory)
 	}
 	return
@@ -56,38 +64,75 @@ func (r *SubCategoryRepository) GetSubCategoryBySlug(slug string) (subCategory e
 	err = r.QueryRow(`
 		select
 		sub_categories.id as id, sub_categories.name, sub_categories.slug,
-		categories.name as parent_category_name, categories.slug as parent_category_slug 
-		from sub_categories
-		inner join categories
-		on sub_categories.parent_category_id = categories.id
+		parent_categories.id as parent_category_id, parent_categories.name as parent_category_name, parent_categories.slug as parent_category_slug,
+		sub_categories.position, sub_categories.description, sub_categories.cover_image, sub_categories.version
+		from categories as sub_categories
+		inner join categories as parent_categories
+		on sub_categories.parent_id = parent_categories.id
 		where sub_categories.slug = $1
 	`, slug).
-		Scan(&subCategory.Id, &subCategory.Name, &subCategory.Slug, &subCategory.ParentCategoryId, &subCategory.ParentCategoryName, &subCategory.ParentCategorySlug)
+		Scan(&subCategory.Id, &subCategory.Name, &subCategory.Slug, &subCategory.ParentCategoryId, &subCategory.ParentCategoryName, &subCategory.ParentCategorySlug,
+			&subCategory.Position, &subCategory.Description, &subCategory.CoverImage, &subCategory.Version)
 	if err != nil {
 		return
 	}
 	return
 }
 
+func (r *SubCategoryRepository) GetStats(ids []int) (stats map[int]entity.CategoryStats, err error) {
+	stats, err = queryCategoryStats(r.DB, ids)
+	return
+}
+
 func (r *SubCategoryRepository) Create(subCategory entity.SubCategory) (err error) {
-	_, err = r.Exec("insert into sub_categories (name, slug, parent_category_id) values ($1, $2, $3)", subCategory.Name, subCategory.Slug, subCategory.ParentCategoryId)
+	_, err = r.Exec("insert into categories (name, slug, parent_id, position, description, cover_image) values ($1, $2, $3, $4, $5, $6)",
+		subCategory.Name, subCategory.Slug, subCategory.ParentCategoryId, subCategory.Position, subCategory.Description, subCategory.CoverImage)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func (r *SubCategoryRepository) GetAncestorIds(id int) (ids []int, err error) {
+	rows, err := r.Query(`
+		with recursive ancestors as (
+			select id, parent_id, 0 as depth from categories where id = $1
+			union all
+			select categories.id, categories.parent_id, ancestors.depth + 1
+			from categories inner join ancestors on categories.id = ancestors.parent_id
+		)
+		select id from ancestors order by depth desc
+	`, id)
 	if err != nil {
 		return
 	}
+	defer rows.Close()
+	for rows.Next() {
+		var ancestorId int
+		err = rows.Scan(&ancestorId)
+		if err != nil {
+			return
+		}
+		ids = append(ids, ancestorId)
+	}
+	err = rows.Err()
 	return
 }
 
+// Update overwrites the sub-category and increments its version, checking a
+// non-zero Version as PostRepository.Update does.
 func (r *SubCategoryRepository) Update(subCategory entity.SubCategory) (err error) {
-	_, err = r.Exec("update sub_categories set name = $2, slug = $3, parent_category_id = $4 where id = $1",
-		subCategory.Id, subCategory.Name, subCategory.Slug, subCategory.ParentCategoryId)
+	result, err := r.Exec("update categories set name = $2, slug = $3, parent_id = $4, position = $5, description = $6, cover_image = $7, version = version + 1 where id = $1 and ($8 = 0 or version = $8)",
+		subCategory.Id, subCategory.Name, subCategory.Slug, subCategory.ParentCategoryId, subCategory.Position, subCategory.Description, subCategory.CoverImage, subCategory.Version)
 	if err != nil {
 		return
 	}
+	err = checkVersion(result, subCategory.Version)
 	return
 }
 
 func (r *SubCategoryRepository) Delete(subCategory entity.SubCategory) (err error) {
-	_, err = r.Exec("delete from sub_categories where id = $1", subCategory.Id)
+	_, err = r.Exec("delete from categories where id = $1", subCategory.Id)
 	if err != nil {
 		return
 	}
@@ -2,6 +2,7 @@ package postgresql
 
 import (
 	"backend/app/domain/entity"
+	"backend/app/domain/repository"
 	"regexp"
 	"testing"
 
@@ -17,6 +18,7 @@ func assertSubCategories(t *testing.T, ret []entity.SubCategory, subCategories [
 		assert.Equal(t, r.ParentCategoryId, subCategories[i].ParentCategoryId)
 		assert.Equal(t, r.ParentCategoryName, subCategories[i].ParentCategoryName)
 		assert.Equal(t, r.ParentCategorySlug, subCategories[i].ParentCategorySlug)
+		assert.Equal(t, r.Version, subCategories[i].Version)
 	}
 }
 
@@ -35,6 +37,7 @@ func TestSubCategoryRepository_GetSubCategories(t *testing.T) {
 			ParentCategoryId:   1,
 			ParentCategoryName: "testCategory1",
 			ParentCategorySlug: "test-category-1",
+			Version:            1,
 		},
 		{
 			Id:                 2,
@@ -43,6 +46,7 @@ func TestSubCategoryRepository_GetSubCategories(t *testing.T) {
 			ParentCategoryId:   1,
 			ParentCategoryName: "testCategory1",
 			ParentCategorySlug: "test-category-1",
+			Version:            1,
 		},
 	}
 
@@ -53,6 +57,10 @@ func TestSubCategoryRepository_GetSubCategories(t *testing.T) {
 		"parent_category_id",
 		"parent_category_name",
 		"parent_category_slug",
+		"position",
+		"description",
+		"cover_image",
+		"version",
 	}
 
 	rows := sqlmock.NewRows(fields).
@@ -63,6 +71,10 @@ func TestSubCategoryRepository_GetSubCategories(t *testing.T) {
 			subCategories[0].ParentCategoryId,
 			subCategories[0].ParentCategoryName,
 			subCategories[0].ParentCategorySlug,
+			subCategories[0].Position,
+			subCategories[0].Description,
+			subCategories[0].CoverImage,
+			subCategories[0].Version,
 		).
 		AddRow(
 			subCategories[1].Id,
@@ -71,19 +83,25 @@ func TestSubCategoryRepository_GetSubCategories(t *testing.T) {
 			subCategories[1].ParentCategoryId,
 			subCategories[1].ParentCategoryName,
 			subCategories[1].ParentCategorySlug,
+			subCategories[1].Position,
+			subCategories[1].Description,
+			subCategories[1].CoverImage,
+			subCategories[1].Version,
 		)
 
 	t.Run(
 		"with query params: category-name",
 		func(t *testing.T) {
 			mock.ExpectQuery(regexp.QuoteMeta(`
-				select 
+				select
 				sub_categories.id as id, sub_categories.name, sub_categories.slug,
-				categories.id as parent_category_id, categories.name as parent_category_name, categories.slug as parent_category_slug 
-				from sub_categories 
-				inner join categories 
-				on sub_categories.parent_category_id = categories.id
-				where categories.slug = $1
+				parent_categories.id as parent_category_id, parent_categories.name as parent_category_name, parent_categories.slug as parent_category_slug,
+				sub_categories.position, sub_categories.description, sub_categories.cover_image, sub_categories.version
+				from categories as sub_categories
+				inner join categories as parent_categories
+				on sub_categories.parent_id = parent_categories.id
+				where parent_categories.slug = $1
+				order by sub_categories.position, sub_categories.id
 			`)).WithArgs(subCategories[0].ParentCategorySlug).WillReturnRows(rows)
 
 			r := NewSubcategoryRepository(db)
@@ -105,12 +123,14 @@ func TestSubCategoryRepository_GetSubCategories(t *testing.T) {
 		"without query params",
 		func(t *testing.T) {
 			mock.ExpectQuery(regexp.QuoteMeta(`
-				select 
-				sub_categories.id as id, sub_categories.name, sub_categories.slug, 
-				categories.id as parent_category_id, categories.name as parent_category_name, categories.slug as parent_category_slug 
-				from sub_categories 
-				inner join categories
-				on sub_categories.parent_category_id = categories.id
+				select
+				sub_categories.id as id, sub_categories.name, sub_categories.slug,
+				parent_categories.id as parent_category_id, parent_categories.name as parent_category_name, parent_categories.slug as parent_category_slug,
+				sub_categories.position, sub_categories.description, sub_categories.cover_image, sub_categories.version
+				from categories as sub_categories
+				inner join categories as parent_categories
+				on sub_categories.parent_id = parent_categories.id
+				order by parent_categories.position, parent_categories.id, sub_categories.position, sub_categories.id
 			`)).WillReturnRows(rows)
 
 			r := NewSubcategoryRepository(db)
@@ -125,6 +145,29 @@ func TestSubCategoryRepository_GetSubCategories(t *testing.T) {
 	)
 }
 
+func TestSubCategoryRepository_GetAncestorIds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("select id from ancestors order by depth desc")).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3))
+
+	r := NewSubcategoryRepository(db)
+
+	ids, err := r.GetAncestorIds(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []int{1, 2, 3}, ids)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestSubCategoryRepository_CRUD(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -139,6 +182,7 @@ func TestSubCategoryRepository_CRUD(t *testing.T) {
 		ParentCategoryId:   1,
 		ParentCategoryName: "testCategory1",
 		ParentCategorySlug: "test-category-1",
+		Version:            2,
 	}
 
 	fields := []string{
@@ -148,6 +192,10 @@ func TestSubCategoryRepository_CRUD(t *testing.T) {
 		"parent_category_id",
 		"parent_category_name",
 		"parent_category_slug",
+		"position",
+		"description",
+		"cover_image",
+		"version",
 	}
 
 	rows := sqlmock.NewRows(fields).
@@ -158,6 +206,10 @@ func TestSubCategoryRepository_CRUD(t *testing.T) {
 			subCategory.ParentCategoryId,
 			subCategory.ParentCategoryName,
 			subCategory.ParentCategorySlug,
+			subCategory.Position,
+			subCategory.Description,
+			subCategory.CoverImage,
+			subCategory.Version,
 		)
 
 	t.Run(
@@ -166,10 +218,11 @@ func TestSubCategoryRepository_CRUD(t *testing.T) {
 			mock.ExpectQuery(regexp.QuoteMeta(`
 				select
 				sub_categories.id as id, sub_categories.name, sub_categories.slug,
-				categories.name as parent_category_name, categories.slug as parent_category_slug 
-				from sub_categories
-				inner join categories
-				on sub_categories.parent_category_id = categories.id
+				parent_categories.id as parent_category_id, parent_categories.name as parent_category_name, parent_categories.slug as parent_category_slug,
+				sub_categories.position, sub_categories.description, sub_categories.cover_image, sub_categories.version
+				from categories as sub_categories
+				inner join categories as parent_categories
+				on sub_categories.parent_id = parent_categories.id
 				where sub_categories.slug = $1
 			`)).WithArgs(subCategory.ParentCategorySlug).WillReturnRows(rows)
 
@@ -184,14 +237,15 @@ func TestSubCategoryRepository_CRUD(t *testing.T) {
 			assert.Equal(t, ret.ParentCategoryId, subCategory.ParentCategoryId)
 			assert.Equal(t, ret.ParentCategoryName, subCategory.ParentCategoryName)
 			assert.Equal(t, ret.ParentCategorySlug, subCategory.ParentCategorySlug)
+			assert.Equal(t, ret.Version, subCategory.Version)
 		},
 	)
 
 	t.Run(
 		"Create",
 		func(t *testing.T) {
-			mock.ExpectExec(regexp.QuoteMeta("insert into sub_categories (name, slug, parent_category_id) values ($1, $2, $3)")).
-				WithArgs(subCategory.Name, subCategory.Slug, subCategory.ParentCategoryId).
+			mock.ExpectExec(regexp.QuoteMeta("insert into categories (name, slug, parent_id, position, description, cover_image) values ($1, $2, $3, $4, $5, $6)")).
+				WithArgs(subCategory.Name, subCategory.Slug, subCategory.ParentCategoryId, subCategory.Position, subCategory.Description, subCategory.CoverImage).
 				WillReturnResult(sqlmock.NewResult(1, 4))
 
 			r := NewSubcategoryRepository(db)
@@ -205,9 +259,9 @@ func TestSubCategoryRepository_CRUD(t *testing.T) {
 	t.Run(
 		"Update",
 		func(t *testing.T) {
-			mock.ExpectExec(regexp.QuoteMeta("update sub_categories set name = $2, slug = $3, parent_category_id = $4 where id = $1")).
-				WithArgs(subCategory.Id, subCategory.Name, subCategory.Slug, subCategory.ParentCategoryId).
-				WillReturnResult(sqlmock.NewResult(1, 3))
+			mock.ExpectExec(regexp.QuoteMeta("update categories set name = $2, slug = $3, parent_id = $4, position = $5, description = $6, cover_image = $7, version = version + 1 where id = $1 and ($8 = 0 or version = $8)")).
+				WithArgs(subCategory.Id, subCategory.Name, subCategory.Slug, subCategory.ParentCategoryId, subCategory.Position, subCategory.Description, subCategory.CoverImage, subCategory.Version).
+				WillReturnResult(sqlmock.NewResult(0, 1))
 
 			r := NewSubcategoryRepository(db)
 
@@ -217,10 +271,25 @@ func TestSubCategoryRepository_CRUD(t *testing.T) {
 		},
 	)
 
+	t.Run(
+		"Update of a changed version",
+		func(t *testing.T) {
+			mock.ExpectExec(regexp.QuoteMeta("update categories")).
+				WithArgs(subCategory.Id, subCategory.Name, subCategory.Slug, subCategory.ParentCategoryId, subCategory.Position, subCategory.Description, subCategory.CoverImage, subCategory.Version).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			r := NewSubcategoryRepository(db)
+
+			err := r.Update(subCategory)
+
+			assert.ErrorIs(t, err, repository.ErrConflict)
+		},
+	)
+
 	t.Run(
 		"Delete",
 		func(t *testing.T) {
-			mock.ExpectExec(regexp.QuoteMeta("delete from sub_categories where id = $1")).
+			mock.ExpectExec(regexp.QuoteMeta("delete from categories where id = $1")).
 				WithArgs(subCategory.Id).
 				WillReturnResult(sqlmock.NewResult(1, 6))
 
@@ -0,0 +1,104 @@
+package postgresql
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+	"database/sql"
+)
+
+type TwoFactorRepository struct {
+	*sql.DB
+}
+
+func NewTwoFactorRepository(db *sql.DB) (twoFactorRepository repository.ITwoFactorRepository) {
+	twoFactorRepository = &TwoFactorRepository{db}
+	return
+}
+
+func (r *TwoFactorRepository) GetByUserId(userId int) (twoFactor entity.TwoFactor, err error) {
+	err = r.QueryRow("select user_id, secret, last_used_step from user_totp where user_id = $1", userId).Scan(
+		&twoFactor.UserId,
+		&twoFactor.Secret,
+		&twoFactor.LastUsedStep,
+	)
+	return
+}
+
+// Enable stores the enrollment and its recovery codes in one transaction.
+func (r *TwoFactorRepository) Enable(twoFactor entity.TwoFactor, recoveryCodeHashes []string) (err error) {
+	tx, err := r.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	_, err = tx.Exec(`
+		insert into user_totp (user_id, secret, last_used_step) values ($1, $2, 0)
+		on conflict (user_id) do update set secret = excluded.secret, last_used_step = 0
+	`, twoFactor.UserId, twoFactor.Secret)
+	if err != nil {
+		return
+	}
+	_, err = tx.Exec("delete from recovery_codes where user_id = $1", twoFactor.UserId)
+	if err != nil {
+		return
+	}
+	for _, codeHash := range recoveryCodeHashes {
+		_, err = tx.Exec("insert into recovery_codes (user_id, code_hash) values ($1, $2)", twoFactor.UserId, codeHash)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (r *TwoFactorRepository) Disable(userId int) (err error) {
+	tx, err := r.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	_, err = tx.Exec("delete from recovery_codes where user_id = $1", userId)
+	if err != nil {
+		return
+	}
+	_, err = tx.Exec("delete from user_totp where user_id = $1", userId)
+	return
+}
+
+func (r *TwoFactorRepository) UseStep(userId int, step int64) (used bool, err error) {
+	result, err := r.Exec("update user_totp set last_used_step = $2 where user_id = $1 and last_used_step < $2", userId, step)
+	if err != nil {
+		return
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	used = affected == 1
+	return
+}
+
+func (r *TwoFactorRepository) UseRecoveryCode(userId int, codeHash string) (used bool, err error) {
+	result, err := r.Exec("update recovery_codes set used_at = current_timestamp where user_id = $1 and code_hash = $2 and used_at is null", userId, codeHash)
+	if err != nil {
+		return
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	used = affected == 1
+	return
+}
@@ -0,0 +1,116 @@
+package postgresql
+
+import (
+	"backend/app/domain/entity"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactorRepository(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	t.Run(
+		"GetByUserId",
+		func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta("select user_id, secret, last_used_step from user_totp where user_id = $1")).
+				WithArgs(1).
+				WillReturnRows(sqlmock.NewRows([]string{"user_id", "secret", "last_used_step"}).AddRow(1, "SECRET", 42))
+
+			r := NewTwoFactorRepository(db)
+
+			ret, err := r.GetByUserId(1)
+
+			assert.NoError(t, err)
+			assert.Equal(t, ret, entity.TwoFactor{UserId: 1, Secret: "SECRET", LastUsedStep: 42})
+		},
+	)
+
+	t.Run(
+		"Enable",
+		func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("insert into user_totp (user_id, secret, last_used_step) values ($1, $2, 0)")).
+				WithArgs(1, "SECRET").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta("delete from recovery_codes where user_id = $1")).
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 3))
+			mock.ExpectExec(regexp.QuoteMeta("insert into recovery_codes (user_id, code_hash) values ($1, $2)")).
+				WithArgs(1, "hash1").
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectExec(regexp.QuoteMeta("insert into recovery_codes (user_id, code_hash) values ($1, $2)")).
+				WithArgs(1, "hash2").
+				WillReturnResult(sqlmock.NewResult(2, 1))
+			mock.ExpectCommit()
+
+			r := NewTwoFactorRepository(db)
+
+			assert.NoError(t, r.Enable(entity.NewTwoFactor(1, "SECRET"), []string{"hash1", "hash2"}))
+		},
+	)
+
+	t.Run(
+		"Disable",
+		func(t *testing.T) {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("delete from recovery_codes where user_id = $1")).
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			mock.ExpectExec(regexp.QuoteMeta("delete from user_totp where user_id = $1")).
+				WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			r := NewTwoFactorRepository(db)
+
+			assert.NoError(t, r.Disable(1))
+		},
+	)
+
+	t.Run(
+		"UseStep",
+		func(t *testing.T) {
+			mock.ExpectExec(regexp.QuoteMeta("update user_totp set last_used_step = $2 where user_id = $1 and last_used_step < $2")).
+				WithArgs(1, int64(43)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta("update user_totp set last_used_step = $2 where user_id = $1 and last_used_step < $2")).
+				WithArgs(1, int64(43)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+
+			r := NewTwoFactorRepository(db)
+
+			used, err := r.UseStep(1, 43)
+			assert.NoError(t, err)
+			assert.True(t, used)
+
+			used, err = r.UseStep(1, 43)
+			assert.NoError(t, err)
+			assert.False(t, used)
+		},
+	)
+
+	t.Run(
+		"UseRecoveryCode",
+		func(t *testing.T) {
+			mock.ExpectExec(regexp.QuoteMeta("update recovery_codes set used_at = current_timestamp where user_id = $1 and code_hash = $2 and used_at is null")).
+				WithArgs(1, "hash1").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			r := NewTwoFactorRepository(db)
+
+			used, err := r.UseRecoveryCode(1, "hash1")
+
+			assert.NoError(t, err)
+			assert.True(t, used)
+		},
+	)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -4,8 +4,6 @@ import (
 	"backend/app/domain/entity"
 	"backend/app/domain/repository"
 	"database/sql"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 type UserRepository struct {
@@ -17,37 +15,50 @@ func NewUserRepository(db *sql.DB) (userRepository repository.IUserRepository) {
 	return
 }
 
+const userColumns = "id, username, password, role, display_name, bio, avatar_url"
+
+func scanUser(row rowScanner) (user entity.User, err error) {
+	err = row.Scan(&user.Id, &user.Name, &user.Password, &user.Role, &user.DisplayName, &user.Bio, &user.AvatarUrl)
+	return
+}
+
 func (r *UserRepository) GetAll() (users []entity.User, err error) {
-	rows, err := r.Query("select * from users")
+	rows, err := r.Query("select " + userColumns + " from users")
 	if err != nil {
 		return
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var user entity.User
-		rows.Scan(&user.Id, &user.Name, &user.Password, &user.IsAdmin)
+		user, err = scanUser(rows)
+		if err != nil {
+			return
+		}
 		users = append(users, user)
 	}
+	err = rows.Err()
 	return
 }
 
-func (r *UserRepository) ValidateUser(creds entity.Credentials) (user entity.User, err error) {
-	err = r.QueryRow("select * from users where username = $1", creds.Username).
-		Scan(&user.Id, &user.Name, &user.Password, &user.IsAdmin)
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(creds.Password))
-	if err != nil {
-		panic(err)
-	}
+func (r *UserRepository) GetById(id int) (user entity.User, err error) {
+	user, err = scanUser(r.QueryRow("select "+userColumns+" from users where id = $1", id))
+	return
+}
+
+func (r *UserRepository) GetByUsername(username string) (user entity.User, err error) {
+	user, err = scanUser(r.QueryRow("select "+userColumns+" from users where username = $1", username))
 	return
 }
 
 func (r *UserRepository) Create(user entity.User) (err error) {
-	_, err = r.Exec("insert into users (username, password) values ($1, $2)", user.Name, user.Password)
+	_, err = r.Exec("insert into users (username, password, role, display_name, bio, avatar_url) values ($1, $2, $3, $4, $5, $6)",
+		user.Name, user.Password, user.Role, user.DisplayName, user.Bio, user.AvatarUrl)
 	return
 }
 
 func (r *UserRepository) Update(user entity.User) (err error) {
-	_, err = r.Exec("update users set username = $2, password = $3 where id = $1",
-		user.Id, user.Name, user.Password)
+	_, err = r.Exec("update users set username = $2, password = $3, role = $4, display_name = $5, bio = $6, avatar_url = $7 where id = $1",
+		user.Id, user.Name, user.Password, user.Role, user.DisplayName, user.Bio, user.AvatarUrl)
 	return
 }
 
@@ -55,8 +66,3 @@ func (r *UserRepository) Delete(user entity.User) (err error) {
 	_, err = r.Exec("delete from users where id = $1", user.Id)
 	return
 }
-
-func (r *UserRepository) IsAdmin(id int) (isAdmin bool, err error) {
-	err = r.QueryRow("select is_admin from users where id = $1", id).Scan(&isAdmin)
-	return
-}
@@ -7,7 +7,6 @@ import (
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func TestUserRepositoryGetAll(t *testing.T) {
@@ -17,11 +16,11 @@ func TestUserRepositoryGetAll(t *testing.T) {
 	}
 	defer db.Close()
 
-	rows := sqlmock.NewRows([]string{"id", "username", "password", "is_admin"}).
-		AddRow(1, "testuser1", "testpass1", true).
-		AddRow(2, "testuser2", "testpass2", true)
+	rows := sqlmock.NewRows([]string{"id", "username", "password", "role", "display_name", "bio", "avatar_url"}).
+		AddRow(1, "testuser1", "testpass1", "admin", "Test User 1", "bio", "testuser1.png").
+		AddRow(2, "testuser2", "testpass2", "author", "", "", "")
 
-	mock.ExpectQuery(regexp.QuoteMeta("select * from users")).
+	mock.ExpectQuery(regexp.QuoteMeta("select id, username, password, role, display_name, bio, avatar_url from users")).
 		WillReturnRows(rows)
 
 	r := NewUserRepository(db)
@@ -30,16 +29,19 @@ func TestUserRepositoryGetAll(t *testing.T) {
 
 	expectedUsers := []entity.User{
 		{
-			Id:       1,
-			Name:     "testuser1",
-			Password: "testpass1",
-			IsAdmin:  true,
+			Id:          1,
+			Name:        "testuser1",
+			Password:    "testpass1",
+			Role:        entity.RoleAdmin,
+			DisplayName: "Test User 1",
+			Bio:         "bio",
+			AvatarUrl:   "testuser1.png",
 		},
 		{
 			Id:       2,
 			Name:     "testuser2",
 			Password: "testpass2",
-			IsAdmin:  true,
+			Role:     entity.RoleAuthor,
 		},
 	}
 
@@ -48,80 +50,66 @@ func TestUserRepositoryGetAll(t *testing.T) {
 	}
 }
 
-func TestUserRepositoryValidateUser(t *testing.T) {
+func TestUserRepositoryCreate(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 	}
 	defer db.Close()
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("testpass1"), 10)
-	stringedHashedPassword := string(hashedPassword)
-
-	rows := sqlmock.NewRows([]string{"id", "username", "password", "is_admin"}).
-		AddRow(1, "testuser1", stringedHashedPassword, true)
-
-	mock.ExpectQuery(regexp.QuoteMeta("select * from users where username = $1")).
-		WithArgs("testuser1").
-		WillReturnRows(rows)
+	mock.ExpectExec(regexp.QuoteMeta("insert into users (username, password, role, display_name, bio, avatar_url) values ($1, $2, $3, $4, $5, $6)")).
+		WithArgs("testuser1", "testpass1", "author", "Test User 1", "", "").
+		WillReturnResult(sqlmock.NewResult(1, 3))
 
 	r := NewUserRepository(db)
 
-	creds := entity.Credentials{
-		Username: "testuser1",
-		Password: "testpass1",
+	user := entity.User{
+		Name:        "testuser1",
+		Password:    "testpass1",
+		Role:        entity.RoleAuthor,
+		DisplayName: "Test User 1",
 	}
 
-	user, err := r.ValidateUser(creds)
-	if err != nil {
+	if err := r.Create(user); err != nil {
 		t.Fatal(err)
 	}
-
-	expectedUser := entity.User{
-		Id:       1,
-		Name:     "testuser1",
-		Password: stringedHashedPassword,
-		IsAdmin:  true,
-	}
-
-	if !(reflect.DeepEqual(user, expectedUser)) {
-		t.Fatalf("Wrong content, was expecting %v, but got %v\n", expectedUser, user)
-	}
 }
 
-func TestUserRepositoryCreate(t *testing.T) {
+func TestUserRepositoryUpdate(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 	}
 	defer db.Close()
 
-	mock.ExpectExec(regexp.QuoteMeta("insert into users (username, password) values ($1, $2)")).
-		WithArgs("testuser1", "testpass1").
+	mock.ExpectExec(regexp.QuoteMeta("update users set username = $2, password = $3, role = $4, display_name = $5, bio = $6, avatar_url = $7 where id = $1")).
+		WithArgs(1, "testuser1", "testpass1", "editor", "", "", "").
 		WillReturnResult(sqlmock.NewResult(1, 3))
 
 	r := NewUserRepository(db)
 
 	user := entity.User{
+		Id:       1,
 		Name:     "testuser1",
 		Password: "testpass1",
+		Role:     entity.RoleEditor,
 	}
 
-	if err := r.Create(user); err != nil {
+	if err := r.Update(user); err != nil {
 		t.Fatal(err)
 	}
 }
 
-func TestUserRepositoryUpdate(t *testing.T) {
+func TestUserRepositoryDelete(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 	}
 	defer db.Close()
 
-	mock.ExpectExec(regexp.QuoteMeta("update users set username = $2, password = $3 where id = $1")).
-		WithArgs(1, "testuser1", "testpass1").
-		WillReturnResult(sqlmock.NewResult(1, 3))
+	mock.ExpectExec(regexp.QuoteMeta("delete from users where id = $1")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(1, 4))
 
 	r := NewUserRepository(db)
 
@@ -131,50 +119,67 @@ func TestUserRepositoryUpdate(t *testing.T) {
 		Password: "testpass1",
 	}
 
-	if err := r.Update(user); err != nil {
+	if err := r.Delete(user); err != nil {
 		t.Fatal(err)
 	}
 }
 
-func TestUserRepositoryDelete(t *testing.T) {
+func TestUserRepositoryGetById(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 	}
 	defer db.Close()
 
-	mock.ExpectExec(regexp.QuoteMeta("delete from users where id = $1")).
+	mock.ExpectQuery(regexp.QuoteMeta("select id, username, password, role, display_name, bio, avatar_url from users where id = $1")).
 		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(1, 4))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "role", "display_name", "bio", "avatar_url"}).
+			AddRow(1, "testuser1", "testpass1", "editor", "", "", ""))
 
 	r := NewUserRepository(db)
 
-	user := entity.User{
-		Id:       1,
-		Name:     "testuser1",
-		Password: "testpass1",
+	user, err := r.GetById(1)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	if err := r.Delete(user); err != nil {
-		t.Fatal(err)
+	expectedUser := entity.NewUser(1, "testuser1", "testpass1", entity.RoleEditor)
+
+	if !(reflect.DeepEqual(user, expectedUser)) {
+		t.Fatalf("Wrong content, was expecting %v, but got %v\n", expectedUser, user)
 	}
 }
 
-func TestUserRepositoryIsAdmin(t *testing.T) {
+func TestUserRepositoryGetByUsername(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 	}
 	defer db.Close()
 
-	mock.ExpectQuery(regexp.QuoteMeta("select is_admin from users where id = $1")).
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"is_admin"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta("select id, username, password, role, display_name, bio, avatar_url from users where username = $1")).
+		WithArgs("testuser1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "role", "display_name", "bio", "avatar_url"}).
+			AddRow(1, "testuser1", "testpass1", "author", "Test User 1", "bio", "testuser1.png"))
 
 	r := NewUserRepository(db)
 
-	_, err = r.IsAdmin(1)
+	user, err := r.GetByUsername("testuser1")
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	expectedUser := entity.User{
+		Id:          1,
+		Name:        "testuser1",
+		Password:    "testpass1",
+		Role:        entity.RoleAuthor,
+		DisplayName: "Test User 1",
+		Bio:         "bio",
+		AvatarUrl:   "testuser1.png",
+	}
+
+	if !(reflect.DeepEqual(user, expectedUser)) {
+		t.Fatalf("Wrong content, was expecting %v, but got %v\n", expectedUser, user)
+	}
 }
@@ -0,0 +1,23 @@
+package postgresql
+
+import (
+	"backend/app/domain/repository"
+	"database/sql"
+)
+
+// checkVersion turns an update that was given a version and changed no row into
+// repository.ErrConflict: the row has been updated, or deleted, since that version
+// was read.
+func checkVersion(result sql.Result, version int) (err error) {
+	if version == 0 {
+		return
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	if affected == 0 {
+		err = repository.ErrConflict
+	}
+	return
+}
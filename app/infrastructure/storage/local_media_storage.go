@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalMediaStorage keeps the media files in a directory of the server.
+type LocalMediaStorage struct {
+	dir string
+}
+
+func NewLocalMediaStorage(dir string) (mediaStorage repository.IMediaStorage) {
+	mediaStorage = &LocalMediaStorage{dir}
+	return
+}
+
+// path maps a name to a file below the directory; anchoring the name at the root
+// before cleaning it keeps ".." from leaving the directory.
+func (s *LocalMediaStorage) path(name string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(path.Clean("/"+name)))
+}
+
+// Put writes to a temporary file next to the target and renames it, so readers
+// never see a partial file.
+func (s *LocalMediaStorage) Put(name string, r io.Reader, size int64, contentType string) (err error) {
+	target := s.path(name)
+	err = os.MkdirAll(filepath.Dir(target), 0o755)
+	if err != nil {
+		return
+	}
+	file, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(file.Name())
+		}
+	}()
+	written, err := io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+	if written != size {
+		err = fmt.Errorf("wrote %d of %d bytes of %s", written, size, name)
+		return
+	}
+	err = os.Chmod(file.Name(), 0o644)
+	if err != nil {
+		return
+	}
+	err = os.Rename(file.Name(), target)
+	return
+}
+
+func (s *LocalMediaStorage) Open(name string) (content io.ReadCloser, object entity.MediaObject, err error) {
+	file, err := os.Open(s.path(name))
+	if err != nil {
+		err = hidePath("open", name, err)
+		return
+	}
+	info, err := file.Stat()
+	if err == nil && !info.Mode().IsRegular() {
+		err = &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if err != nil {
+		file.Close()
+		return
+	}
+	content, object = file, s.object(name, info)
+	return
+}
+
+func (s *LocalMediaStorage) Stat(name string) (object entity.MediaObject, err error) {
+	info, err := os.Stat(s.path(name))
+	if err != nil {
+		err = hidePath("stat", name, err)
+	} else if !info.Mode().IsRegular() {
+		err = &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	if err != nil {
+		return
+	}
+	object = s.object(name, info)
+	return
+}
+
+// List walks the directory; hidden files such as unfinished uploads are left out.
+func (s *LocalMediaStorage) List() (objects []entity.MediaObject, err error) {
+	err = filepath.WalkDir(s.dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if file == s.dir && os.IsNotExist(err) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), ".") && file != s.dir {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(s.dir, file)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, s.object(filepath.ToSlash(name), info))
+		return nil
+	})
+	return
+}
+
+func (s *LocalMediaStorage) Delete(name string) (err error) {
+	_, err = s.Stat(name)
+	if err != nil {
+		return
+	}
+	err = os.Remove(s.path(name))
+	return
+}
+
+// hidePath reports a missing file by its name rather than its path on the server,
+// as the error may end up in a response.
+func hidePath(op string, name string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return err
+}
+
+func (s *LocalMediaStorage) object(name string, info fs.FileInfo) entity.MediaObject {
+	return entity.MediaObject{
+		Name:        name,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(name)),
+		ModifiedAt:  info.ModTime(),
+	}
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalMediaStorage(t *testing.T) {
+	dir := t.TempDir()
+	s := NewLocalMediaStorage(dir)
+
+	err := s.Put("2019/05/test.jpeg", strings.NewReader("testImage"), 9, "image/jpeg")
+	assert.NoError(t, err)
+	err = s.Put("test.png", strings.NewReader("testImage2"), 10, "image/png")
+	assert.NoError(t, err)
+
+	object, err := s.Stat("2019/05/test.jpeg")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), object.Size)
+	assert.Equal(t, "image/jpeg", object.ContentType)
+
+	content, _, err := s.Open("2019/05/test.jpeg")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(content)
+	content.Close()
+	assert.Equal(t, "testImage", string(data))
+	_, ok := content.(io.ReadSeeker)
+	assert.True(t, ok)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".upload-1"), []byte("partial"), 0o644))
+	objects, err := s.List()
+	assert.NoError(t, err)
+	var names []string
+	for _, object := range objects {
+		names = append(names, object.Name)
+	}
+	assert.Equal(t, []string{"2019/05/test.jpeg", "test.png"}, names)
+
+	t.Run(
+		"missing files do not exist",
+		func(t *testing.T) {
+			_, err := s.Stat("missing.png")
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+			_, _, err = s.Open("2019")
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+			assert.ErrorIs(t, s.Delete("missing.png"), fs.ErrNotExist)
+		},
+	)
+
+	t.Run(
+		"names stay inside the directory",
+		func(t *testing.T) {
+			err := s.Put("../../escaped.png", strings.NewReader("x"), 1, "image/png")
+			assert.NoError(t, err)
+			_, err = os.Stat(filepath.Join(dir, "escaped.png"))
+			assert.NoError(t, err)
+		},
+	)
+
+	t.Run(
+		"a short write keeps the old file",
+		func(t *testing.T) {
+			err := s.Put("test.png", strings.NewReader("short"), 10, "image/png")
+			assert.Error(t, err)
+			data, _ := os.ReadFile(filepath.Join(dir, "test.png"))
+			assert.Equal(t, "testImage2", string(data))
+		},
+	)
+
+	assert.NoError(t, s.Delete("test.png"))
+	_, err = s.Stat("test.png")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	t.Run(
+		"a missing directory is empty",
+		func(t *testing.T) {
+			objects, err := NewLocalMediaStorage(filepath.Join(dir, "missing")).List()
+			assert.NoError(t, err)
+			assert.Empty(t, objects)
+		},
+	)
+}
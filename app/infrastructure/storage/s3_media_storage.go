@@ -0,0 +1,407 @@
+package storage
+
+import (
+	"backend/app/domain/entity"
+	"backend/app/domain/repository"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// emptyPayloadHash is the SHA-256 of an empty body, sent with every request
+	// but uploads, which are sent with unsignedPayload instead of hashing them twice.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+	amzDateLayout    = "20060102T150405Z"
+)
+
+// S3Config locates a bucket of an S3-compatible service. Endpoint is the base URL
+// of the service, e.g. https://s3.eu-central-1.amazonaws.com or http://127.0.0.1:9000
+// for MinIO; objects are addressed path-style below it.
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyId     string
+	SecretAccessKey string
+}
+
+// S3MediaStorage keeps the media files as objects of a bucket, named like the
+// files. Requests are signed with AWS Signature Version 4.
+type S3MediaStorage struct {
+	config S3Config
+	client *http.Client
+}
+
+func NewS3MediaStorage(config S3Config) (mediaStorage repository.IMediaStorage, err error) {
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	switch {
+	case err != nil:
+		return
+	case endpoint.Scheme != "http" && endpoint.Scheme != "https" || endpoint.Host == "":
+		err = fmt.Errorf("S3 endpoint %q is not an http(s) URL", config.Endpoint)
+	case config.Bucket == "":
+		err = errors.New("S3 bucket is missing")
+	case config.AccessKeyId == "" || config.SecretAccessKey == "":
+		err = errors.New("S3 credentials are missing")
+	}
+	if err != nil {
+		return
+	}
+	mediaStorage = &S3MediaStorage{config, http.DefaultClient}
+	return
+}
+
+func (s *S3MediaStorage) Put(name string, r io.Reader, size int64, contentType string) (err error) {
+	req, err := s.newRequest("PUT", name, nil, io.NopCloser(r))
+	if err != nil {
+		return
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := s.do(req, name, unsignedPayload)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+	return
+}
+
+func (s *S3MediaStorage) Open(name string) (content io.ReadCloser, object entity.MediaObject, err error) {
+	req, err := s.newRequest("GET", name, nil, nil)
+	if err != nil {
+		return
+	}
+	resp, err := s.do(req, name, emptyPayloadHash)
+	if err != nil {
+		return
+	}
+	object = s.object(name, resp)
+	content = resp.Body
+	if resp.Header.Get("Content-Length") != "" {
+		content = &s3Object{storage: s, name: name, size: object.Size, body: resp.Body}
+	}
+	return
+}
+
+// s3Object reads an object of known size. After a seek it fetches the rest of the
+// object from the new offset with a ranged GET, so http.ServeContent can answer
+// range requests without downloading the whole object.
+type s3Object struct {
+	storage *S3MediaStorage
+	name    string
+	size    int64
+	offset  int64
+	// body reads from bodyOffset on; it is replaced when a seek moved the offset.
+	body       io.ReadCloser
+	bodyOffset int64
+}
+
+func (o *s3Object) Read(p []byte) (n int, err error) {
+	if o.body != nil && o.bodyOffset != o.offset {
+		o.body.Close()
+		o.body = nil
+	}
+	if o.offset >= o.size {
+		err = io.EOF
+		return
+	}
+	if o.body == nil {
+		var req *http.Request
+		req, err = o.storage.newRequest("GET", o.name, nil, nil)
+		if err != nil {
+			return
+		}
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", o.offset))
+		var resp *http.Response
+		resp, err = o.storage.do(req, o.name, emptyPayloadHash)
+		if err != nil {
+			return
+		}
+		if resp.StatusCode != http.StatusPartialContent {
+			resp.Body.Close()
+			err = fmt.Errorf("S3 GET %s: no range support, %s", req.URL.Path, resp.Status)
+			return
+		}
+		o.body, o.bodyOffset = resp.Body, o.offset
+	}
+	n, err = o.body.Read(p)
+	o.offset += int64(n)
+	o.bodyOffset = o.offset
+	return
+}
+
+func (o *s3Object) Seek(offset int64, whence int) (position int64, err error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += o.offset
+	case io.SeekEnd:
+		offset += o.size
+	default:
+		err = fmt.Errorf("seek %s: invalid whence %d", o.name, whence)
+		return
+	}
+	if offset < 0 {
+		err = fmt.Errorf("seek %s: negative position", o.name)
+		return
+	}
+	o.offset, position = offset, offset
+	return
+}
+
+func (o *s3Object) Close() (err error) {
+	if o.body != nil {
+		err = o.body.Close()
+		o.body = nil
+	}
+	return
+}
+
+func (s *S3MediaStorage) Stat(name string) (object entity.MediaObject, err error) {
+	req, err := s.newRequest("HEAD", name, nil, nil)
+	if err != nil {
+		return
+	}
+	resp, err := s.do(req, name, emptyPayloadHash)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+	object = s.object(name, resp)
+	return
+}
+
+// listBucketResult is the part of a ListObjectsV2 response the storage reads.
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// List pages through ListObjectsV2, which returns the keys in UTF-8 binary order.
+func (s *S3MediaStorage) List() (objects []entity.MediaObject, err error) {
+	query := url.Values{"list-type": {"2"}}
+	for {
+		var req *http.Request
+		req, err = s.newRequest("GET", "", query, nil)
+		if err != nil {
+			return
+		}
+		var resp *http.Response
+		resp, err = s.do(req, "", emptyPayloadHash)
+		if err != nil {
+			return
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return
+		}
+		for _, content := range result.Contents {
+			objects = append(objects, entity.MediaObject{
+				Name:        content.Key,
+				Size:        content.Size,
+				ContentType: mime.TypeByExtension(path.Ext(content.Key)),
+				ModifiedAt:  content.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// Delete checks that the object exists first: S3 answers a delete of a missing
+// key with success.
+func (s *S3MediaStorage) Delete(name string) (err error) {
+	_, err = s.Stat(name)
+	if err != nil {
+		return
+	}
+	req, err := s.newRequest("DELETE", name, nil, nil)
+	if err != nil {
+		return
+	}
+	resp, err := s.do(req, name, emptyPayloadHash)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+	return
+}
+
+// newRequest addresses the object name, or the bucket for an empty name.
+func (s *S3MediaStorage) newRequest(method string, name string, query url.Values, body io.ReadCloser) (req *http.Request, err error) {
+	target := s.config.Endpoint + "/" + uriEncode(s.config.Bucket, false)
+	if name != "" {
+		target += "/" + uriEncode(name, true)
+	}
+	if query != nil {
+		target += "?" + canonicalQuery(query)
+	}
+	req, err = http.NewRequest(method, target, body)
+	return
+}
+
+// s3Error is the body of a failed request.
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// do signs and sends the request. A missing object yields fs.ErrNotExist, other
+// failures the error the service reports.
+func (s *S3MediaStorage) do(req *http.Request, name string, payloadHash string) (resp *http.Response, err error) {
+	s.sign(req, payloadHash, time.Now())
+	resp, err = s.client.Do(req)
+	if err != nil {
+		return
+	}
+	if resp.StatusCode < 300 {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && name != "" {
+		err = &fs.PathError{Op: strings.ToLower(req.Method), Path: name, Err: fs.ErrNotExist}
+		return
+	}
+	var body s3Error
+	xml.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body)
+	if body.Code == "" {
+		body.Code = resp.Status
+	}
+	err = fmt.Errorf("S3 %s %s: %s %s", req.Method, req.URL.Path, body.Code, body.Message)
+	return
+}
+
+func (s *S3MediaStorage) object(name string, resp *http.Response) (object entity.MediaObject) {
+	object = entity.MediaObject{Name: name, ContentType: resp.Header.Get("Content-Type")}
+	object.Size, _ = strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	object.ModifiedAt, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	return
+}
+
+// sign adds the headers of AWS Signature Version 4. It signs the host, the
+// content type, a range and every x-amz- header.
+func (s *S3MediaStorage) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format(amzDateLayout)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for key, values := range req.Header {
+		key = strings.ToLower(key)
+		if key == "content-type" || key == "range" || strings.HasPrefix(key, "x-amz-") {
+			headers[key] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	var names []string
+	for key := range headers {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, key := range names {
+		canonicalHeaders.WriteString(key + ":" + headers[key] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := amzDate[:8] + "/" + s.config.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex(canonicalRequest)
+
+	key := []byte("AWS4" + s.config.SecretAccessKey)
+	for _, part := range []string{amzDate[:8], s.config.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.config.AccessKeyId, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes the query sorted by key, then value.
+func canonicalQuery(query url.Values) string {
+	var keys []string
+	encodedValues := make(map[string][]string)
+	for key, values := range query {
+		encodedKey := uriEncode(key, false)
+		keys = append(keys, encodedKey)
+		for _, value := range values {
+			encodedValues[encodedKey] = append(encodedValues[encodedKey], uriEncode(value, false))
+		}
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		values := encodedValues[key]
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything but the unreserved characters of RFC 3986,
+// and slashes when keepSlash is set, as Signature Version 4 requires.
+func uriEncode(value string, keepSlash bool) string {
+	var encoded strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '.', b == '_', b == '~', b == '/' && keepSlash:
+			encoded.WriteByte(b)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return encoded.String()
+}
+
+func sha256Hex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,104 @@
+package CLI
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/service"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+type IArchiveCLI interface {
+	Export(path string, mediaDir string) error
+	Import(path string, mediaDir string, replace bool, yes bool) error
+}
+
+type ArchiveCLI struct {
+	service.IArchiveService
+	*Console
+}
+
+func NewArchiveCLI(srv service.IArchiveService, console *Console) (iArchiveCLI IArchiveCLI) {
+	iArchiveCLI = &ArchiveCLI{srv, console}
+	return
+}
+
+// Export writes the archive to path, or to stdout when path is "-". A file is
+// written under a temporary name first, so a failed export leaves no partial archive.
+func (c *ArchiveCLI) Export(path string, mediaDir string) (err error) {
+	if path == "-" {
+		var manifestDto dto.ArchiveManifestModel
+		manifestDto, err = c.IArchiveService.Export(operator, c.Stdout, mediaDir)
+		if err != nil {
+			return
+		}
+		printCounts(c.Stderr, "exported", manifestDto)
+		return
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), ".export-")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+	manifestDto, err := c.IArchiveService.Export(operator, file, mediaDir)
+	if err != nil {
+		return
+	}
+	err = file.Close()
+	if err != nil {
+		return
+	}
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return
+	}
+	printCounts(c.Stdout, "exported", manifestDto)
+	return
+}
+
+// Import restores the archive at path, or on stdin when path is "-". Replacing
+// the whole blog is confirmed first unless yes.
+func (c *ArchiveCLI) Import(path string, mediaDir string, replace bool, yes bool) (err error) {
+	if replace && !yes {
+		ok, err := c.confirm("replace all categories, users and posts?")
+		if err != nil || !ok {
+			return err
+		}
+	}
+	var r io.Reader = c.Stdin
+	if path != "-" {
+		var file *os.File
+		file, err = os.Open(path)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+		r = file
+	}
+	manifestDto, err := c.IArchiveService.Import(operator, r, mediaDir, replace)
+	if err != nil {
+		return
+	}
+	printCounts(c.Stdout, "imported", manifestDto)
+	return
+}
+
+func printCounts(w io.Writer, verb string, manifestDto dto.ArchiveManifestModel) {
+	var kinds []string
+	for kind := range manifestDto.Counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	fmt.Fprintf(w, "%s archive version %d:", verb, manifestDto.Version)
+	for _, kind := range kinds {
+		fmt.Fprintf(w, " %d %s", manifestDto.Counts[kind], kind)
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,144 @@
+package CLI
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/service"
+	"fmt"
+	"strings"
+)
+
+type ICategoryCLI interface {
+	List(format string) error
+	Show(slug string, format string) error
+	Create(path string) error
+	Update(slug string, path string) error
+	Delete(slug string, yes bool) error
+}
+
+type CategoryCLI struct {
+	service.ICategoryService
+	*Console
+}
+
+func NewCategoryCLI(srv service.ICategoryService, console *Console) (iCategoryCLI ICategoryCLI) {
+	iCategoryCLI = &CategoryCLI{srv, console}
+	return
+}
+
+// List prints the whole category tree; the table indents children under their parents.
+func (c *CategoryCLI) List(format string) (err error) {
+	err = checkFormat(format)
+	if err != nil {
+		return
+	}
+	categoryDtos, err := c.ICategoryService.GetTree()
+	if err != nil {
+		return
+	}
+	if format == "json" {
+		if categoryDtos == nil {
+			categoryDtos = []dto.CategoryModel{}
+		}
+		err = c.printJSON(categoryDtos)
+		return
+	}
+	table := c.newTable()
+	fmt.Fprintf(table, "ID\tSLUG\tNAME\tPOSITION\n")
+	var printNodes func(categoryDtos []dto.CategoryModel, depth int)
+	printNodes = func(categoryDtos []dto.CategoryModel, depth int) {
+		for _, categoryDto := range categoryDtos {
+			fmt.Fprintf(table, "%d\t%s%s\t%s\t%d\n", categoryDto.Id, strings.Repeat("  ", depth), categoryDto.Slug, categoryDto.Name, categoryDto.Position)
+			printNodes(categoryDto.Children, depth+1)
+		}
+	}
+	printNodes(categoryDtos, 0)
+	err = table.Flush()
+	return
+}
+
+func (c *CategoryCLI) Show(slug string, format string) (err error) {
+	err = checkFormat(format)
+	if err != nil {
+		return
+	}
+	categoryDto, err := c.ICategoryService.GetDetailBySlug(slug)
+	if err != nil {
+		return
+	}
+	if format == "json" {
+		err = c.printJSON(categoryDto)
+		return
+	}
+	table := c.newTable()
+	fmt.Fprintf(table, "id\t%d\n", categoryDto.Id)
+	fmt.Fprintf(table, "name\t%s\n", categoryDto.Name)
+	fmt.Fprintf(table, "slug\t%s\n", categoryDto.Slug)
+	fmt.Fprintf(table, "parent id\t%d\n", categoryDto.ParentId)
+	fmt.Fprintf(table, "position\t%d\n", categoryDto.Position)
+	fmt.Fprintf(table, "description\t%s\n", categoryDto.Description)
+	fmt.Fprintf(table, "cover image\t%s\n", categoryDto.CoverImage)
+	if categoryDto.Stats != nil {
+		fmt.Fprintf(table, "public posts\t%d\n", categoryDto.Stats.PostCount)
+	}
+	var children []string
+	for _, child := range categoryDto.Children {
+		children = append(children, child.Slug)
+	}
+	fmt.Fprintf(table, "children\t%s\n", strings.Join(children, ", "))
+	err = table.Flush()
+	return
+}
+
+func (c *CategoryCLI) Create(path string) (err error) {
+	var categoryDto dto.CategoryModel
+	err = c.readJSONFile(path, &categoryDto)
+	if err != nil {
+		return
+	}
+	err = c.ICategoryService.Create(operator, categoryDto)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "category %s has been created\n", categoryDto.Slug)
+	return
+}
+
+// Update applies the fields of the JSON file to the stored category; fields the
+// file leaves out are kept.
+func (c *CategoryCLI) Update(slug string, path string) (err error) {
+	categoryDto, err := c.ICategoryService.GetBySlug(slug)
+	if err != nil {
+		return
+	}
+	id := categoryDto.Id
+	err = c.readJSONFile(path, &categoryDto)
+	if err != nil {
+		return
+	}
+	categoryDto.Id = id
+	err = c.ICategoryService.Update(operator, categoryDto)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "category %s has been updated\n", categoryDto.Slug)
+	return
+}
+
+func (c *CategoryCLI) Delete(slug string, yes bool) (err error) {
+	categoryDto, err := c.ICategoryService.GetBySlug(slug)
+	if err != nil {
+		return
+	}
+	if !yes {
+		ok, err := c.confirm(fmt.Sprintf("delete %s?", slug))
+		if err != nil || !ok {
+			return err
+		}
+	}
+	err = c.ICategoryService.Delete(operator, categoryDto)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "category %s has been deleted\n", slug)
+	return
+}
@@ -0,0 +1,122 @@
+package CLI
+
+import (
+	"backend/app/common/dto"
+	"testing"
+
+	mocks "backend/mocks/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCategoryCLI(t *testing.T) {
+	categoryDto := dto.CategoryModel{Id: 1, Name: "Go", Slug: "go", Position: 1, Version: 2}
+
+	t.Run("list indents the children", func(t *testing.T) {
+		tree := categoryDto
+		tree.Children = []dto.CategoryModel{{Id: 2, Name: "Testing", Slug: "testing", ParentId: 1}}
+		s := new(mocks.ICategoryService)
+		s.On("GetTree").Return([]dto.CategoryModel{tree}, nil)
+		console, stdout, _ := newTestConsole("", false)
+
+		err := NewCategoryCLI(s, console).List("table")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ID  SLUG       NAME     POSITION\n1   go         Go       1\n2     testing  Testing  0\n", stdout.String())
+	})
+
+	t.Run("list as JSON", func(t *testing.T) {
+		s := new(mocks.ICategoryService)
+		s.On("GetTree").Return(nil, nil)
+		console, stdout, _ := newTestConsole("", false)
+
+		err := NewCategoryCLI(s, console).List("json")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "[]\n", stdout.String())
+	})
+
+	t.Run("show", func(t *testing.T) {
+		detail := categoryDto
+		detail.Stats = &dto.CategoryStatsModel{PostCount: 4}
+		detail.Children = []dto.CategoryModel{{Slug: "testing"}, {Slug: "tools"}}
+		s := new(mocks.ICategoryService)
+		s.On("GetDetailBySlug", "go").Return(detail, nil)
+		console, stdout, _ := newTestConsole("", false)
+
+		err := NewCategoryCLI(s, console).Show("go", "table")
+
+		assert.NoError(t, err)
+		assert.Contains(t, stdout.String(), "public posts  4\n")
+		assert.Contains(t, stdout.String(), "children      testing, tools\n")
+	})
+
+	t.Run("create from stdin", func(t *testing.T) {
+		s := new(mocks.ICategoryService)
+		s.On("Create", operator, dto.CategoryModel{Name: "Go", Slug: "go"}).Return(nil)
+		console, stdout, _ := newTestConsole(`{"name": "Go", "slug": "go"}`, false)
+
+		err := NewCategoryCLI(s, console).Create("-")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "category go has been created\n", stdout.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("create with malformed JSON", func(t *testing.T) {
+		s := new(mocks.ICategoryService)
+		console, _, _ := newTestConsole(`{"name": `, false)
+
+		err := NewCategoryCLI(s, console).Create("-")
+
+		assert.Error(t, err)
+		s.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("update keeps the id and the fields the file leaves out", func(t *testing.T) {
+		updated := categoryDto
+		updated.Name = "Golang"
+		s := new(mocks.ICategoryService)
+		s.On("GetBySlug", "go").Return(categoryDto, nil)
+		s.On("Update", operator, updated).Return(nil)
+		console, stdout, _ := newTestConsole(`{"id": 7, "name": "Golang"}`, false)
+
+		err := NewCategoryCLI(s, console).Update("go", "-")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "category go has been updated\n", stdout.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		for _, c := range []struct {
+			name     string
+			stdin    string
+			terminal bool
+			yes      bool
+			deleted  bool
+			err      error
+		}{
+			{"with --yes", "", false, true, true, nil},
+			{"confirmed", "y\n", true, false, true, nil},
+			{"declined", "\n", true, false, false, nil},
+			{"without a terminal", "y\n", false, false, false, ErrUsage},
+		} {
+			s := new(mocks.ICategoryService)
+			s.On("GetBySlug", "go").Return(categoryDto, nil)
+			s.On("Delete", operator, categoryDto).Return(nil)
+			console, stdout, _ := newTestConsole(c.stdin, c.terminal)
+
+			err := NewCategoryCLI(s, console).Delete("go", c.yes)
+
+			assert.ErrorIs(t, err, c.err, c.name)
+			if c.deleted {
+				s.AssertCalled(t, "Delete", operator, categoryDto)
+				assert.Contains(t, stdout.String(), "category go has been deleted\n", c.name)
+			} else {
+				s.AssertNotCalled(t, "Delete", operator, categoryDto)
+			}
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package CLI
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Exit codes of Execute.
+const (
+	ExitOK      = 0
+	ExitFailure = 1
+	ExitUsage   = 2
+)
+
+// ErrUsage marks errors caused by how a command was called rather than by the
+// command failing. Execute answers them with the usage and ExitUsage.
+var ErrUsage = errors.New("invalid usage")
+
+func usageErrorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrUsage, fmt.Sprintf(format, args...))
+}
+
+// Command is a node of the command tree: either a group of Subcommands or a leaf
+// whose Setup registers its flags and returns the function that runs it.
+type Command struct {
+	Name    string
+	Summary string
+	// Args describes the positional arguments in the usage line, if any.
+	Args        string
+	Setup       func(fs *flag.FlagSet) func(args []string) error
+	Subcommands []*Command
+	// Hidden commands are accepted but not listed, e.g. the old command names.
+	Hidden bool
+}
+
+// noArgs adapts a command that takes no positional arguments.
+func noArgs(run func() error) func(args []string) error {
+	return func(args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %v", args)
+		}
+		return run()
+	}
+}
+
+// noFlags adapts a command without flags or arguments.
+func noFlags(run func() error) func(fs *flag.FlagSet) func(args []string) error {
+	return func(fs *flag.FlagSet) func(args []string) error {
+		return noArgs(run)
+	}
+}
+
+// requireFlag fails with a usage error when the flag called name was not set.
+func requireFlag(name string, value string) (err error) {
+	if value == "" {
+		err = usageErrorf("--%s is required", name)
+	}
+	return
+}
+
+func (c *Command) find(name string) *Command {
+	for _, sub := range c.Subcommands {
+		if sub.Name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+func isHelp(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help" || arg == "help"
+}
+
+// wantsHelp reports whether the flags ask for help; "--" ends the flags.
+func wantsHelp(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		if isHelp(arg) && arg != "help" {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Command) printGroupHelp(w io.Writer, path string) {
+	fmt.Fprintf(w, "usage: %s <command> [flags]\n", path)
+	if c.Summary != "" {
+		fmt.Fprintf(w, "\n%s\n", c.Summary)
+	}
+	fmt.Fprintf(w, "\ncommands:\n")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, sub := range c.Subcommands {
+		if !sub.Hidden {
+			fmt.Fprintf(tw, "  %s\t%s\n", sub.Name, sub.Summary)
+		}
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nRun '%s <command> --help' for the flags of a command.\n", path)
+}
+
+func (c *Command) printLeafHelp(fs *flag.FlagSet, path string) {
+	w := fs.Output()
+	usage := path + " [flags]"
+	if c.Args != "" {
+		usage += " " + c.Args
+	}
+	fmt.Fprintf(w, "usage: %s\n", usage)
+	if c.Summary != "" {
+		fmt.Fprintf(w, "\n%s\n", c.Summary)
+	}
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintf(w, "\nflags:\n")
+		fs.PrintDefaults()
+	}
+}
+
+// Execute runs the command that args name below root and returns the exit code:
+// ExitOK on success, ExitFailure when the command failed and ExitUsage when it was
+// called wrongly. Help goes to stdout, errors and usage after an error to stderr.
+func Execute(root *Command, args []string, stdout io.Writer, stderr io.Writer) int {
+	cmd, path := root, root.Name
+	for len(cmd.Subcommands) > 0 {
+		if len(args) == 0 {
+			cmd.printGroupHelp(stderr, path)
+			return ExitUsage
+		}
+		if isHelp(args[0]) {
+			cmd.printGroupHelp(stdout, path)
+			return ExitOK
+		}
+		sub := cmd.find(args[0])
+		if sub == nil {
+			fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+			cmd.printGroupHelp(stderr, path)
+			return ExitUsage
+		}
+		cmd, path, args = sub, path+" "+sub.Name, args[1:]
+	}
+
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	run := cmd.Setup(fs)
+	fs.Usage = func() { cmd.printLeafHelp(fs, path) }
+	if wantsHelp(args) {
+		fs.SetOutput(stdout)
+		fs.Usage()
+		return ExitOK
+	}
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	err := run(fs.Args())
+	switch {
+	case errors.Is(err, ErrUsage):
+		fmt.Fprintf(stderr, "error: %s\n\n", err)
+		fs.Usage()
+		return ExitUsage
+	case err != nil:
+		fmt.Fprintf(stderr, "error: %s\n", err)
+		return ExitFailure
+	default:
+		return ExitOK
+	}
+}
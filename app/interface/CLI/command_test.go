@@ -0,0 +1,68 @@
+package CLI
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecute(t *testing.T) {
+	var got string
+	root := &Command{
+		Name: "blog",
+		Subcommands: []*Command{
+			{
+				Name: "users",
+				Subcommands: []*Command{
+					{
+						Name:    "create",
+						Summary: "Create a user",
+						Setup: func(fs *flag.FlagSet) func(args []string) error {
+							username := fs.String("username", "", "name of the new user")
+							return noArgs(func() error {
+								switch *username {
+								case "":
+									return usageErrorf("--username is required")
+								case "taken":
+									return errors.New("already exists")
+								}
+								got = *username
+								return nil
+							})
+						},
+					},
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{name: "runs", args: []string{"users", "create", "--username", "testuser1"}, code: ExitOK},
+		{name: "group help", args: []string{"--help"}, code: ExitOK, stdout: "usage: blog <command>"},
+		{name: "leaf help", args: []string{"users", "create", "-h"}, code: ExitOK, stdout: "-username"},
+		{name: "missing command", args: []string{}, code: ExitUsage, stderr: "usage: blog <command>"},
+		{name: "unknown command", args: []string{"posts"}, code: ExitUsage, stderr: `unknown command "posts"`},
+		{name: "unknown flag", args: []string{"users", "create", "--role", "admin"}, code: ExitUsage, stderr: "flag provided but not defined"},
+		{name: "extra arguments", args: []string{"users", "create", "--username", "testuser1", "x"}, code: ExitUsage, stderr: "unexpected arguments"},
+		{name: "usage error", args: []string{"users", "create"}, code: ExitUsage, stderr: "--username is required"},
+		{name: "failure", args: []string{"users", "create", "--username", "taken"}, code: ExitFailure, stderr: "error: already exists"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, c.code, Execute(root, c.args, &stdout, &stderr))
+			assert.Contains(t, stdout.String(), c.stdout)
+			assert.Contains(t, stderr.String(), c.stderr)
+		})
+	}
+	assert.Equal(t, "testuser1", got)
+}
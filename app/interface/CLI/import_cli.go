@@ -0,0 +1,139 @@
+package CLI
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/service"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type IImportCLI interface {
+	Markdown(dir string, format string, options dto.ImportOptionsModel) error
+	WordPress(path string, uploadsDir string, mediaDir string, format string, options dto.ImportOptionsModel) error
+}
+
+type ImportCLI struct {
+	service.IImportService
+	*Console
+}
+
+func NewImportCLI(srv service.IImportService, console *Console) (iImportCLI IImportCLI) {
+	iImportCLI = &ImportCLI{srv, console}
+	return
+}
+
+// Markdown imports every .md file below dir. Files that cannot be parsed are
+// reported with the plan and fail the command, but do not stop the others.
+func (c *ImportCLI) Markdown(dir string, format string, options dto.ImportOptionsModel) (err error) {
+	err = checkFormat(format)
+	if err != nil {
+		return
+	}
+	var postImportDtos []dto.PostImportModel
+	var parseErrors []dto.ImportActionModel
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !isMarkdown(path) {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		source, _ := filepath.Rel(dir, path)
+		postImportDto, err := service.ParseMarkdown(source, data)
+		if err != nil {
+			parseErrors = append(parseErrors, dto.ImportActionModel{Action: dto.ImportActionError, Kind: "post", Source: source, Detail: err.Error()})
+			return nil
+		}
+		postImportDtos = append(postImportDtos, postImportDto)
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	planDto, err := c.IImportService.ImportPosts(operator, postImportDtos, options)
+	if err != nil && len(planDto.Actions) == 0 {
+		return
+	}
+	planDto.Actions = append(parseErrors, planDto.Actions...)
+	printErr := c.printPlan(format, planDto)
+	if printErr != nil {
+		return printErr
+	}
+	if err == nil && len(parseErrors) > 0 {
+		err = fmt.Errorf("%w: %d files could not be parsed", service.ErrImportIncomplete, len(parseErrors))
+	}
+	return
+}
+
+// WordPress imports the WXR file at path.
+func (c *ImportCLI) WordPress(path string, uploadsDir string, mediaDir string, format string, options dto.ImportOptionsModel) (err error) {
+	err = checkFormat(format)
+	if err != nil {
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	planDto, err := c.IImportService.ImportWordPress(operator, file, uploadsDir, mediaDir, options)
+	if err != nil && len(planDto.Actions) == 0 {
+		return
+	}
+	printErr := c.printPlan(format, planDto)
+	if printErr != nil {
+		return printErr
+	}
+	return
+}
+
+func isMarkdown(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".md" || ext == ".markdown"
+}
+
+func (c *ImportCLI) printPlan(format string, planDto dto.ImportPlanModel) (err error) {
+	if format == "json" {
+		if planDto.Actions == nil {
+			planDto.Actions = []dto.ImportActionModel{}
+		}
+		err = c.printJSON(planDto)
+		return
+	}
+	counts := make(map[string]int)
+	table := c.newTable()
+	fmt.Fprintf(table, "ACTION\tKIND\tSLUG\tSOURCE\tDETAIL\n")
+	for _, action := range planDto.Actions {
+		if action.Kind == "post" {
+			counts[action.Action]++
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", action.Action, action.Kind, action.Slug, action.Source, action.Detail)
+	}
+	err = table.Flush()
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "\nposts: %d to create, %d to update, %d unchanged, %d failed\n",
+		counts[dto.ImportActionCreate], counts[dto.ImportActionUpdate], counts[dto.ImportActionUnchanged], counts[dto.ImportActionError])
+	if unmapped := countActions(planDto, dto.ImportActionUnmapped); unmapped > 0 {
+		fmt.Fprintf(c.Stdout, "%d items could not be mapped and were left out\n", unmapped)
+	}
+	if planDto.DryRun {
+		fmt.Fprintln(c.Stdout, "dry run: nothing has been changed")
+	}
+	return
+}
+
+func countActions(planDto dto.ImportPlanModel, action string) (count int) {
+	for _, actionDto := range planDto.Actions {
+		if actionDto.Action == action {
+			count++
+		}
+	}
+	return
+}
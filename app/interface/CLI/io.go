@@ -0,0 +1,124 @@
+package CLI
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Console is what the commands talk to: results go to Stdout, warnings and
+// messages meant for the operator rather than for a pipe to Stderr, and answers
+// are read from Stdin. Prompts are only shown when Terminal is set.
+type Console struct {
+	Stdin    io.Reader
+	Stdout   io.Writer
+	Stderr   io.Writer
+	Terminal bool
+	// scanner is shared by every prompt, so that answers piped in are not lost
+	// in the buffer of a scanner that is thrown away.
+	scanner *bufio.Scanner
+}
+
+// NewConsole returns the console of the process, on stdin, stdout and stderr.
+func NewConsole(stdin *os.File, stdout io.Writer, stderr io.Writer) *Console {
+	return &Console{Stdin: stdin, Stdout: stdout, Stderr: stderr, Terminal: terminal.IsTerminal(int(stdin.Fd()))}
+}
+
+func (c *Console) lines() *bufio.Scanner {
+	if c.scanner == nil {
+		c.scanner = bufio.NewScanner(c.Stdin)
+	}
+	return c.scanner
+}
+
+func (c *Console) prompt(question string) string {
+	fmt.Fprintf(c.Stdout, "%s", question)
+	c.lines().Scan()
+	return c.lines().Text()
+}
+
+// readPassword reads the first line of stdin when fromStdin is set, as for
+// --password-stdin, and otherwise asks on the terminal without echo.
+func (c *Console) readPassword(fromStdin bool, question string) (password string, err error) {
+	if fromStdin {
+		if !c.lines().Scan() {
+			err = c.lines().Err()
+			if err == nil {
+				err = usageErrorf("no password on stdin")
+			}
+			return
+		}
+		password = strings.TrimRight(c.lines().Text(), "\r")
+		return
+	}
+	file, ok := c.Stdin.(*os.File)
+	if !c.Terminal || !ok {
+		err = usageErrorf("stdin is not a terminal, pass the password with --password-stdin")
+		return
+	}
+	fmt.Fprintf(c.Stdout, "%s", question)
+	b, err := terminal.ReadPassword(int(file.Fd()))
+	fmt.Fprintf(c.Stdout, "\n")
+	password = string(b)
+	return
+}
+
+// confirm asks a yes/no question on the terminal. Without a terminal it refuses,
+// so scripts have to pass --yes explicitly.
+func (c *Console) confirm(question string) (ok bool, err error) {
+	if !c.Terminal {
+		err = usageErrorf("stdin is not a terminal, pass --yes to confirm")
+		return
+	}
+	ok = c.prompt(question+" (y/n): ") == "y"
+	return
+}
+
+func checkFormat(format string) (err error) {
+	if format != "table" && format != "json" {
+		err = usageErrorf("unknown format %q, expected table or json", format)
+	}
+	return
+}
+
+func (c *Console) printJSON(v interface{}) (err error) {
+	output, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(c.Stdout, string(output))
+	return
+}
+
+func (c *Console) newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(c.Stdout, 0, 4, 2, ' ', 0)
+}
+
+// readJSONFile decodes the JSON document in path, or on stdin when path is "-",
+// into v. Fields v does not have are rejected so typos do not pass silently;
+// fields the document leaves out keep the value v already had.
+func (c *Console) readJSONFile(path string, v interface{}) (err error) {
+	r := c.Stdin
+	if path != "-" {
+		var file *os.File
+		file, err = os.Open(path)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+		r = file
+	}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(v)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", path, err)
+	}
+	return
+}
@@ -0,0 +1,69 @@
+package CLI
+
+import (
+	"backend/app/common/dto"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadJSONFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("keeps fields the file leaves out", func(t *testing.T) {
+		postDto := dto.PostModel{Id: 1, Title: "testTitle1", Slug: "test-slug-1", Content: "testContent1"}
+		err := new(Console).readJSONFile(write("partial.json", `{"title": "testTitle2", "is_public": true}`), &postDto)
+
+		assert.NoError(t, err)
+		assert.Equal(t, dto.PostModel{Id: 1, Title: "testTitle2", Slug: "test-slug-1", Content: "testContent1", IsPublic: true}, postDto)
+	})
+
+	t.Run("rejects unknown fields", func(t *testing.T) {
+		var postDto dto.PostModel
+		err := new(Console).readJSONFile(write("typo.json", `{"titel": "testTitle1"}`), &postDto)
+
+		assert.ErrorContains(t, err, `unknown field "titel"`)
+	})
+}
+
+// newTestConsole returns a console reading stdin, and the buffers its output goes to.
+func newTestConsole(stdin string, terminal bool) (console *Console, stdout *bytes.Buffer, stderr *bytes.Buffer) {
+	stdout, stderr = new(bytes.Buffer), new(bytes.Buffer)
+	console = &Console{Stdin: strings.NewReader(stdin), Stdout: stdout, Stderr: stderr, Terminal: terminal}
+	return
+}
+
+func TestConsole_confirm(t *testing.T) {
+	for _, c := range []struct {
+		stdin    string
+		terminal bool
+		ok       bool
+		err      error
+	}{
+		{"y\n", true, true, nil},
+		{"n\n", true, false, nil},
+		{"", true, false, nil},
+		{"y\n", false, false, ErrUsage},
+	} {
+		console, stdout, _ := newTestConsole(c.stdin, c.terminal)
+
+		ok, err := console.confirm("delete it?")
+
+		assert.Equal(t, c.ok, ok)
+		assert.ErrorIs(t, err, c.err)
+		if c.terminal {
+			assert.Equal(t, "delete it? (y/n): ", stdout.String())
+		}
+	}
+}
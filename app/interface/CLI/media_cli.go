@@ -0,0 +1,159 @@
+package CLI
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/service"
+	"fmt"
+	"time"
+)
+
+type IMediaCLI interface {
+	List(format string) error
+	Derivatives(force bool) error
+	Orphans(format string) error
+	DeleteOrphans(yes bool) error
+}
+
+type MediaCLI struct {
+	service.IMediaService
+	*Console
+}
+
+func NewMediaCLI(srv service.IMediaService, console *Console) (iMediaCLI IMediaCLI) {
+	iMediaCLI = &MediaCLI{srv, console}
+	return
+}
+
+func (c *MediaCLI) List(format string) (err error) {
+	err = checkFormat(format)
+	if err != nil {
+		return
+	}
+	mediaDtos, err := c.IMediaService.GetAll(operator)
+	if err != nil {
+		return
+	}
+	if format == "json" {
+		if mediaDtos == nil {
+			mediaDtos = []dto.MediaModel{}
+		}
+		err = c.printJSON(mediaDtos)
+		return
+	}
+	table := c.newTable()
+	fmt.Fprintf(table, "NAME\tTYPE\tSIZE\tMODIFIED\tDERIVATIVE OF\n")
+	for _, mediaDto := range mediaDtos {
+		fmt.Fprintf(table, "%s\t%s\t%d\t%s\t%s\n", mediaDto.Name, mediaDto.ContentType, mediaDto.Size, mediaDto.ModifiedAt.Format(time.RFC3339), mediaDto.DerivativeOf)
+	}
+	err = table.Flush()
+	return
+}
+
+// Derivatives generates the sizes of the stored images that have none, e.g. images
+// stored before derivatives existed or restored from an archive; with force, of
+// every image. A failing image is reported and does not stop the others.
+func (c *MediaCLI) Derivatives(force bool) (err error) {
+	mediaDtos, err := c.IMediaService.GetAll(operator)
+	if err != nil {
+		return
+	}
+	generated, failed := 0, 0
+	for _, mediaDto := range mediaDtos {
+		if mediaDto.DerivativeOf != "" || mediaDto.VariantOf != "" || !isDerivableType(mediaDto.ContentType) || mediaDto.Images != nil && !force {
+			continue
+		}
+		_, err = c.IMediaService.GenerateDerivatives(operator, mediaDto.Name)
+		if err != nil {
+			fmt.Fprintf(c.Stderr, "%s: %s\n", mediaDto.Name, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(c.Stdout, "%s\n", mediaDto.Name)
+		generated++
+	}
+	fmt.Fprintf(c.Stdout, "derivatives generated for %d images\n", generated)
+	err = nil
+	if failed > 0 {
+		err = fmt.Errorf("%d images failed", failed)
+	}
+	return
+}
+
+// orphans returns the stored files no post, category or user uses, derivatives
+// left aside.
+func (c *MediaCLI) orphans() (orphans []dto.MediaUsageModel, err error) {
+	usageDtos, err := c.IMediaService.GetUsage(operator)
+	if err != nil {
+		return
+	}
+	for _, usageDto := range usageDtos {
+		if len(usageDto.UsedBy) == 0 {
+			orphans = append(orphans, usageDto)
+		}
+	}
+	return
+}
+
+// Orphans lists the stored files nothing uses.
+func (c *MediaCLI) Orphans(format string) (err error) {
+	err = checkFormat(format)
+	if err != nil {
+		return
+	}
+	orphans, err := c.orphans()
+	if err != nil {
+		return
+	}
+	if format == "json" {
+		if orphans == nil {
+			orphans = []dto.MediaUsageModel{}
+		}
+		err = c.printJSON(orphans)
+		return
+	}
+	table := c.newTable()
+	fmt.Fprintf(table, "NAME\tSIZE\n")
+	for _, orphan := range orphans {
+		fmt.Fprintf(table, "%s\t%d\n", orphan.Name, orphan.Size)
+	}
+	err = table.Flush()
+	return
+}
+
+// DeleteOrphans deletes the stored files nothing uses, with their derivatives,
+// after listing them and asking unless yes.
+func (c *MediaCLI) DeleteOrphans(yes bool) (err error) {
+	orphans, err := c.orphans()
+	if err != nil {
+		return
+	}
+	if len(orphans) == 0 {
+		fmt.Fprintf(c.Stdout, "no orphaned files\n")
+		return
+	}
+	var size int64
+	for _, orphan := range orphans {
+		fmt.Fprintf(c.Stdout, "%s\n", orphan.Name)
+		size += orphan.Size
+	}
+	if !yes {
+		ok, err := c.confirm(fmt.Sprintf("delete %d files of %d bytes?", len(orphans), size))
+		if err != nil || !ok {
+			return err
+		}
+	}
+	for _, orphan := range orphans {
+		err = c.IMediaService.Delete(operator, orphan.Name)
+		if err != nil {
+			return
+		}
+	}
+	fmt.Fprintf(c.Stdout, "deleted %d files\n", len(orphans))
+	return
+}
+
+// isDerivableType tells by the content type guessed from the file name which files
+// to try; GenerateDerivatives checks the content.
+func isDerivableType(contentType string) bool {
+	return contentType == "image/jpeg" || contentType == "image/png" || contentType == "image/gif"
+}
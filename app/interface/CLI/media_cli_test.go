@@ -0,0 +1,113 @@
+package CLI
+
+import (
+	"backend/app/common/dto"
+	"errors"
+	"testing"
+
+	mocks "backend/mocks/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMediaCLI(t *testing.T) {
+	usageDtos := []dto.MediaUsageModel{
+		{Name: "abc.jpg", Size: 2, UsedBy: []dto.MediaUseModel{{PostId: 1, PostSlug: "hello", Field: "content", Name: "abc.jpg"}}},
+		{Name: "cover.png", Size: 4, UsedBy: []dto.MediaUseModel{{CategoryId: 3, CategorySlug: "go", Field: "cover_image", Name: "cover.png"}}},
+		{Name: "alice.jpg", Size: 5, UsedBy: []dto.MediaUseModel{{UserId: 5, Username: "alice", Field: "avatar_url", Name: "alice.jpg"}}},
+		{Name: "def.pdf", Size: 3, UsedBy: []dto.MediaUseModel{}},
+		{Name: "old.gif", Size: 7, UsedBy: []dto.MediaUseModel{}},
+	}
+
+	t.Run("orphans", func(t *testing.T) {
+		s := new(mocks.IMediaService)
+		s.On("GetUsage", operator).Return(usageDtos, nil)
+		console, stdout, _ := newTestConsole("", false)
+
+		err := NewMediaCLI(s, console).Orphans("table")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "NAME     SIZE\ndef.pdf  3\nold.gif  7\n", stdout.String())
+	})
+
+	t.Run("no orphans as JSON", func(t *testing.T) {
+		s := new(mocks.IMediaService)
+		s.On("GetUsage", operator).Return(usageDtos[:3], nil)
+		console, stdout, _ := newTestConsole("", false)
+
+		err := NewMediaCLI(s, console).Orphans("json")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "[]\n", stdout.String())
+	})
+
+	t.Run("delete orphans", func(t *testing.T) {
+		for _, c := range []struct {
+			name     string
+			stdin    string
+			terminal bool
+			yes      bool
+			deleted  bool
+			err      error
+		}{
+			{"with --yes", "", false, true, true, nil},
+			{"confirmed", "y\n", true, false, true, nil},
+			{"declined", "n\n", true, false, false, nil},
+			{"without a terminal", "", false, false, false, ErrUsage},
+		} {
+			s := new(mocks.IMediaService)
+			s.On("GetUsage", operator).Return(usageDtos, nil)
+			s.On("Delete", operator, mock.Anything).Return(nil)
+			console, stdout, _ := newTestConsole(c.stdin, c.terminal)
+
+			err := NewMediaCLI(s, console).DeleteOrphans(c.yes)
+
+			assert.ErrorIs(t, err, c.err, c.name)
+			assert.Contains(t, stdout.String(), "def.pdf\nold.gif\n", c.name)
+			if c.deleted {
+				s.AssertCalled(t, "Delete", operator, "def.pdf")
+				s.AssertCalled(t, "Delete", operator, "old.gif")
+				s.AssertNumberOfCalls(t, "Delete", 2)
+				assert.Contains(t, stdout.String(), "deleted 2 files\n", c.name)
+			} else {
+				s.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+			}
+		}
+	})
+
+	t.Run("delete orphans asks with the total size", func(t *testing.T) {
+		s := new(mocks.IMediaService)
+		s.On("GetUsage", operator).Return(usageDtos, nil)
+		console, stdout, _ := newTestConsole("n\n", true)
+
+		err := NewMediaCLI(s, console).DeleteOrphans(false)
+
+		assert.NoError(t, err)
+		assert.Contains(t, stdout.String(), "delete 2 files of 10 bytes? (y/n): ")
+	})
+
+	t.Run("delete without orphans", func(t *testing.T) {
+		s := new(mocks.IMediaService)
+		s.On("GetUsage", operator).Return(usageDtos[:3], nil)
+		console, stdout, _ := newTestConsole("", false)
+
+		err := NewMediaCLI(s, console).DeleteOrphans(false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "no orphaned files\n", stdout.String())
+		s.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("delete stops at the first failure", func(t *testing.T) {
+		s := new(mocks.IMediaService)
+		s.On("GetUsage", operator).Return(usageDtos, nil)
+		s.On("Delete", operator, "def.pdf").Return(errors.New("read-only storage"))
+		console, _, _ := newTestConsole("", false)
+
+		err := NewMediaCLI(s, console).DeleteOrphans(true)
+
+		assert.EqualError(t, err, "read-only storage")
+		s.AssertNotCalled(t, "Delete", operator, "old.gif")
+	})
+}
@@ -0,0 +1,169 @@
+package CLI
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/service"
+	"fmt"
+	"time"
+)
+
+type IPostCLI interface {
+	List(format string, queryParams map[string][]string) error
+	Show(slug string, format string) error
+	Create(path string) error
+	Update(slug string, path string) error
+	Delete(slug string, yes bool) error
+	SetPublic(slug string, isPublic bool) error
+}
+
+type PostCLI struct {
+	service.IPostService
+	*Console
+}
+
+func NewPostCLI(srv service.IPostService, console *Console) (iPostCLI IPostCLI) {
+	iPostCLI = &PostCLI{srv, console}
+	return
+}
+
+func (c *PostCLI) List(format string, queryParams map[string][]string) (err error) {
+	err = checkFormat(format)
+	if err != nil {
+		return
+	}
+	postDtos, err := c.IPostService.GetPosts(queryParams)
+	if err != nil {
+		return
+	}
+	if format == "json" {
+		if postDtos == nil {
+			postDtos = []dto.PostModel{}
+		}
+		err = c.printJSON(postDtos)
+		return
+	}
+	table := c.newTable()
+	fmt.Fprintf(table, "ID\tSLUG\tTITLE\tCATEGORY\tAUTHOR\tPUBLIC\tUPDATED\n")
+	for _, postDto := range postDtos {
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%s\t%t\t%s\n", postDto.Id, postDto.Slug, postDto.Title, postDto.CategorySlug, authorName(postDto), postDto.IsPublic, postDto.UpdatedAt.Format(time.RFC3339))
+	}
+	err = table.Flush()
+	return
+}
+
+func (c *PostCLI) Show(slug string, format string) (err error) {
+	err = checkFormat(format)
+	if err != nil {
+		return
+	}
+	postDto, err := c.IPostService.GetPostBySlug(slug)
+	if err != nil {
+		return
+	}
+	if format == "json" {
+		err = c.printJSON(postDto)
+		return
+	}
+	table := c.newTable()
+	fmt.Fprintf(table, "id\t%d\n", postDto.Id)
+	fmt.Fprintf(table, "title\t%s\n", postDto.Title)
+	fmt.Fprintf(table, "slug\t%s\n", postDto.Slug)
+	fmt.Fprintf(table, "category\t%s\n", postDto.CategorySlug)
+	fmt.Fprintf(table, "author\t%s\n", authorName(postDto))
+	fmt.Fprintf(table, "public\t%t\n", postDto.IsPublic)
+	fmt.Fprintf(table, "eye catching img\t%s\n", postDto.EyeCatchingImg)
+	fmt.Fprintf(table, "meta description\t%s\n", postDto.MetaDescription)
+	fmt.Fprintf(table, "created\t%s\n", postDto.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(table, "updated\t%s\n", postDto.UpdatedAt.Format(time.RFC3339))
+	err = table.Flush()
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "\n%s\n", postDto.Content)
+	return
+}
+
+func authorName(postDto dto.PostModel) string {
+	if postDto.Author == nil {
+		return "-"
+	}
+	return postDto.Author.Username
+}
+
+// Create adds the post described by the JSON file. Posts created from the CLI
+// have no author.
+func (c *PostCLI) Create(path string) (err error) {
+	var postDto dto.PostModel
+	err = c.readJSONFile(path, &postDto)
+	if err != nil {
+		return
+	}
+	err = c.IPostService.Create(operator, postDto)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "post %s has been created\n", postDto.Slug)
+	return
+}
+
+// Update applies the fields of the JSON file to the stored post; fields the file
+// leaves out are kept. The output of "show --format=json" is a valid file.
+func (c *PostCLI) Update(slug string, path string) (err error) {
+	postDto, err := c.IPostService.GetPostBySlug(slug)
+	if err != nil {
+		return
+	}
+	id := postDto.Id
+	err = c.readJSONFile(path, &postDto)
+	if err != nil {
+		return
+	}
+	postDto.Id = id
+	err = c.IPostService.Update(operator, postDto)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "post %s has been updated\n", postDto.Slug)
+	return
+}
+
+func (c *PostCLI) Delete(slug string, yes bool) (err error) {
+	postDto, err := c.IPostService.GetPostBySlug(slug)
+	if err != nil {
+		return
+	}
+	if !yes {
+		ok, err := c.confirm(fmt.Sprintf("delete %s?", slug))
+		if err != nil || !ok {
+			return err
+		}
+	}
+	err = c.IPostService.Delete(operator, postDto)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "post %s has been deleted\n", slug)
+	return
+}
+
+func (c *PostCLI) SetPublic(slug string, isPublic bool) (err error) {
+	postDto, err := c.IPostService.GetPostBySlug(slug)
+	if err != nil {
+		return
+	}
+	state := "unpublished"
+	if isPublic {
+		state = "published"
+	}
+	if postDto.IsPublic == isPublic {
+		fmt.Fprintf(c.Stderr, "post %s is already %s\n", slug, state)
+		return
+	}
+	postDto.IsPublic = isPublic
+	err = c.IPostService.Update(operator, postDto)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "post %s has been %s\n", slug, state)
+	return
+}
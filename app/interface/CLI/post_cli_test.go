@@ -0,0 +1,178 @@
+package CLI
+
+import (
+	"backend/app/common/dto"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mocks "backend/mocks/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPostCLI(t *testing.T) {
+	updatedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	postDto := dto.PostModel{Id: 1, Title: "testTitle1", Slug: "test-slug-1", Content: "testContent1", CategorySlug: "go", UpdatedAt: updatedAt, Version: 3}
+
+	t.Run("list", func(t *testing.T) {
+		s := new(mocks.IPostService)
+		queryParams := map[string][]string{"author": {"testauthor"}}
+		s.On("GetPosts", queryParams).Return([]dto.PostModel{postDto}, nil)
+		console, stdout, _ := newTestConsole("", false)
+
+		err := NewPostCLI(s, console).List("table", queryParams)
+
+		assert.NoError(t, err)
+		assert.Contains(t, stdout.String(), "ID  SLUG         TITLE       CATEGORY  AUTHOR  PUBLIC  UPDATED\n")
+		assert.Contains(t, stdout.String(), "1   test-slug-1  testTitle1  go        -       false   2024-01-02T03:04:05Z\n")
+	})
+
+	t.Run("list as JSON", func(t *testing.T) {
+		s := new(mocks.IPostService)
+		s.On("GetPosts", mock.Anything).Return(nil, nil)
+		console, stdout, _ := newTestConsole("", false)
+
+		err := NewPostCLI(s, console).List("json", nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "[]\n", stdout.String())
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		console, _, _ := newTestConsole("", false)
+
+		err := NewPostCLI(new(mocks.IPostService), console).List("yaml", nil)
+
+		assert.ErrorIs(t, err, ErrUsage)
+	})
+
+	t.Run("show", func(t *testing.T) {
+		s := new(mocks.IPostService)
+		s.On("GetPostBySlug", "test-slug-1").Return(postDto, nil)
+		console, stdout, _ := newTestConsole("", false)
+
+		err := NewPostCLI(s, console).Show("test-slug-1", "table")
+
+		assert.NoError(t, err)
+		assert.Contains(t, stdout.String(), "title             testTitle1\n")
+		assert.Contains(t, stdout.String(), "\ntestContent1\n")
+	})
+
+	t.Run("show a missing post", func(t *testing.T) {
+		s := new(mocks.IPostService)
+		s.On("GetPostBySlug", "missing").Return(dto.PostModel{}, sql.ErrNoRows)
+		console, stdout, _ := newTestConsole("", false)
+
+		err := NewPostCLI(s, console).Show("missing", "json")
+
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assert.Empty(t, stdout.String())
+	})
+
+	t.Run("create from stdin", func(t *testing.T) {
+		s := new(mocks.IPostService)
+		s.On("Create", operator, dto.PostModel{Title: "testTitle1", Slug: "test-slug-1", CategoryId: 2}).Return(nil)
+		console, stdout, _ := newTestConsole(`{"title": "testTitle1", "slug": "test-slug-1", "category_id": 2}`, false)
+
+		err := NewPostCLI(s, console).Create("-")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "post test-slug-1 has been created\n", stdout.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("update keeps the fields the file leaves out", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "post.json")
+		os.WriteFile(path, []byte(`{"id": 9, "title": "testTitle2"}`), 0o600)
+		updated := postDto
+		updated.Title = "testTitle2"
+		s := new(mocks.IPostService)
+		s.On("GetPostBySlug", "test-slug-1").Return(postDto, nil)
+		s.On("Update", operator, updated).Return(nil)
+		console, stdout, _ := newTestConsole("", false)
+
+		err := NewPostCLI(s, console).Update("test-slug-1", path)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "post test-slug-1 has been updated\n", stdout.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		for _, c := range []struct {
+			name     string
+			stdin    string
+			terminal bool
+			yes      bool
+			deleted  bool
+			err      error
+		}{
+			{"with --yes", "", false, true, true, nil},
+			{"confirmed", "y\n", true, false, true, nil},
+			{"declined", "n\n", true, false, false, nil},
+			{"without a terminal", "y\n", false, false, false, ErrUsage},
+		} {
+			s := new(mocks.IPostService)
+			s.On("GetPostBySlug", "test-slug-1").Return(postDto, nil)
+			s.On("Delete", operator, postDto).Return(nil)
+			console, stdout, _ := newTestConsole(c.stdin, c.terminal)
+
+			err := NewPostCLI(s, console).Delete("test-slug-1", c.yes)
+
+			assert.ErrorIs(t, err, c.err, c.name)
+			if c.deleted {
+				s.AssertCalled(t, "Delete", operator, postDto)
+				assert.Contains(t, stdout.String(), "post test-slug-1 has been deleted\n", c.name)
+			} else {
+				s.AssertNotCalled(t, "Delete", operator, postDto)
+			}
+		}
+	})
+
+	t.Run("set public", func(t *testing.T) {
+		published := postDto
+		published.IsPublic = true
+		s := new(mocks.IPostService)
+		s.On("GetPostBySlug", "test-slug-1").Return(postDto, nil)
+		s.On("Update", operator, published).Return(nil)
+		console, stdout, _ := newTestConsole("", false)
+
+		err := NewPostCLI(s, console).SetPublic("test-slug-1", true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "post test-slug-1 has been published\n", stdout.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("set public again", func(t *testing.T) {
+		s := new(mocks.IPostService)
+		s.On("GetPostBySlug", "test-slug-1").Return(postDto, nil)
+		console, stdout, stderr := newTestConsole("", false)
+
+		err := NewPostCLI(s, console).SetPublic("test-slug-1", false)
+
+		assert.NoError(t, err)
+		assert.Empty(t, stdout.String())
+		assert.Equal(t, "post test-slug-1 is already unpublished\n", stderr.String())
+		s.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("through the command line", func(t *testing.T) {
+		s := new(mocks.IPostService)
+		s.On("GetPostBySlug", "test-slug-1").Return(postDto, nil)
+		s.On("Delete", operator, postDto).Return(nil)
+		console, stdout, stderr := newTestConsole("", false)
+		root := NewRootCommand("blog", NewUserCLI(new(mocks.IUserService), console), NewPostCLI(s, console), NewCategoryCLI(new(mocks.ICategoryService), console), NewSubCategoryCLI(new(mocks.ISubCategoryService), console), NewImportCLI(nil, console), NewArchiveCLI(nil, console), NewMediaCLI(new(mocks.IMediaService), console))
+
+		assert.Equal(t, ExitUsage, Execute(root, []string{"posts", "delete", "--slug", "test-slug-1"}, stdout, stderr))
+		assert.Contains(t, stderr.String(), "pass --yes to confirm")
+		s.AssertNotCalled(t, "Delete", operator, postDto)
+
+		assert.Equal(t, ExitOK, Execute(root, []string{"posts", "delete", "--slug", "test-slug-1", "--yes"}, stdout, stderr))
+		assert.Contains(t, stdout.String(), "post test-slug-1 has been deleted\n")
+	})
+}
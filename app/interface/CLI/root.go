@@ -0,0 +1,496 @@
+package CLI
+
+import (
+	"backend/app/common/dto"
+	"flag"
+	"strings"
+)
+
+// NewRootCommand builds the command tree of the administration CLI.
+func NewRootCommand(name string, user IUserCLI, post IPostCLI, category ICategoryCLI, subCategory ISubCategoryCLI, importer IImportCLI, archive IArchiveCLI, media IMediaCLI) (root *Command) {
+	root = &Command{
+		Name:    name,
+		Summary: "Administer the blog. Without arguments the binary starts the API server.",
+		Subcommands: []*Command{
+			newUsersCommand(user),
+			newApiKeysCommand(user),
+			newPostsCommand(post),
+			newCategoriesCommand(category),
+			newSubCategoriesCommand(subCategory),
+			newMediaCommand(media),
+			newExportCommand(archive),
+			newImportCommand(importer, archive),
+			// the command names used before the subcommands
+			{Name: "showusers", Hidden: true, Setup: noFlags(func() error { return user.List("table") })},
+			{Name: "createsuperuser", Hidden: true, Setup: noFlags(user.Create)},
+			{Name: "updateuser", Hidden: true, Setup: noFlags(user.Update)},
+			{Name: "deleteuser", Hidden: true, Setup: noFlags(user.Delete)},
+		},
+	}
+	return
+}
+
+func newUsersCommand(user IUserCLI) *Command {
+	return &Command{
+		Name:    "users",
+		Summary: "Manage users",
+		Subcommands: []*Command{
+			{
+				Name:    "list",
+				Summary: "List all users",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					format := formatFlag(fs)
+					return noArgs(func() error { return user.List(*format) })
+				},
+			},
+			{
+				Name:    "create",
+				Summary: "Create a user",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					username := fs.String("username", "", "`name` of the new user (required)")
+					passwordStdin := fs.Bool("password-stdin", false, "read the password from the first line of stdin instead of prompting")
+					role := fs.String("role", "viewer", "`role` of the new user: admin, editor, author or viewer")
+					return noArgs(func() (err error) {
+						if err = requireFlag("username", *username); err != nil {
+							return
+						}
+						return user.CreateUser(dto.UserInputModel{Name: *username, Role: *role}, *passwordStdin)
+					})
+				},
+			},
+			{
+				Name:    "update",
+				Summary: "Change the username, password or profile of a user, asking for their credentials",
+				Setup:   noFlags(user.Update),
+			},
+			{
+				Name:    "delete",
+				Summary: "Delete a user",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					id := fs.Int("id", 0, "`id` of the user (required)")
+					yes := fs.Bool("yes", false, "do not ask for confirmation")
+					return noArgs(func() (err error) {
+						if *id == 0 {
+							return usageErrorf("--id is required")
+						}
+						return user.DeleteUser(*id, *yes)
+					})
+				},
+			},
+			{
+				Name:    "revoke-tokens",
+				Summary: "Revoke all refresh tokens of a user",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					username := fs.String("username", "", "`name` of the user (required)")
+					return noArgs(func() error {
+						if err := requireFlag("username", *username); err != nil {
+							return err
+						}
+						return user.RevokeTokens(*username)
+					})
+				},
+			},
+			{
+				Name:    "unlock",
+				Summary: "Clear the failed logins of a username or client IP",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					username := fs.String("username", "", "`name` of the user")
+					ip := fs.String("ip", "", "client `address`")
+					return noArgs(func() error {
+						switch {
+						case *username != "" && *ip != "":
+							return usageErrorf("--username and --ip cannot be combined")
+						case *username != "":
+							return user.UnlockUser(*username)
+						case *ip != "":
+							return user.UnlockAddress(*ip)
+						}
+						return usageErrorf("one of --username and --ip is required")
+					})
+				},
+			},
+			{
+				Name:    "enable-2fa",
+				Summary: "Enroll a user in two-factor authentication, asking for their credentials",
+				Setup:   noFlags(user.EnableTwoFactor),
+			},
+			{
+				Name:    "reset-2fa",
+				Summary: "Remove two-factor authentication from a user",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					username := fs.String("username", "", "`name` of the user (required)")
+					return noArgs(func() error {
+						if err := requireFlag("username", *username); err != nil {
+							return err
+						}
+						return user.ResetTwoFactor(*username)
+					})
+				},
+			},
+		},
+	}
+}
+
+func newApiKeysCommand(user IUserCLI) *Command {
+	return &Command{
+		Name:    "api-keys",
+		Summary: "Manage API keys of machine clients",
+		Subcommands: []*Command{
+			{
+				Name:    "create",
+				Summary: "Create an API key and print it once",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					username := fs.String("username", "", "`name` of the user the key acts as (required)")
+					name := fs.String("name", "", "`label` to tell keys apart")
+					scopes := fs.String("scopes", "", "comma separated `scopes`, e.g. posts:write,posts:publish (required)")
+					return noArgs(func() error {
+						if *username == "" || *scopes == "" {
+							return usageErrorf("--username and --scopes are required")
+						}
+						return user.CreateApiKey(dto.ApiKeyInputModel{Username: *username, Name: *name, Scopes: strings.Split(*scopes, ",")})
+					})
+				},
+			},
+			{
+				Name:    "list",
+				Summary: "List API keys",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					format := formatFlag(fs)
+					return noArgs(func() error { return user.GetApiKeys(*format) })
+				},
+			},
+			{
+				Name:    "revoke",
+				Summary: "Revoke an API key",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					prefix := fs.String("prefix", "", "`prefix` of the key, as listed (required)")
+					return noArgs(func() error {
+						if err := requireFlag("prefix", *prefix); err != nil {
+							return err
+						}
+						return user.RevokeApiKey(*prefix)
+					})
+				},
+			},
+		},
+	}
+}
+
+func formatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", "table", "output `format`: table or json")
+}
+
+func slugFlag(fs *flag.FlagSet) *string {
+	return fs.String("slug", "", "`slug` to look up (required)")
+}
+
+func fileFlag(fs *flag.FlagSet) *string {
+	return fs.String("file", "", "JSON `file` with the fields, - for stdin (required)")
+}
+
+// showCommand, createCommand, updateCommand and deleteCommand are the commands
+// posts, categories and sub-categories have in common; all look records up by slug.
+func showCommand(noun string, run func(slug string, format string) error) *Command {
+	return &Command{
+		Name:    "show",
+		Summary: "Show a " + noun,
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			slug := slugFlag(fs)
+			format := formatFlag(fs)
+			return noArgs(func() error {
+				if err := requireFlag("slug", *slug); err != nil {
+					return err
+				}
+				return run(*slug, *format)
+			})
+		},
+	}
+}
+
+func createCommand(noun string, run func(path string) error) *Command {
+	return &Command{
+		Name:    "create",
+		Summary: "Create a " + noun + " from a JSON file",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			file := fileFlag(fs)
+			return noArgs(func() error {
+				if err := requireFlag("file", *file); err != nil {
+					return err
+				}
+				return run(*file)
+			})
+		},
+	}
+}
+
+func updateCommand(noun string, run func(slug string, path string) error) *Command {
+	return &Command{
+		Name:    "update",
+		Summary: "Update a " + noun + " with the fields of a JSON file; fields it leaves out are kept",
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			slug := slugFlag(fs)
+			file := fileFlag(fs)
+			return noArgs(func() error {
+				if err := requireFlag("slug", *slug); err != nil {
+					return err
+				}
+				if err := requireFlag("file", *file); err != nil {
+					return err
+				}
+				return run(*slug, *file)
+			})
+		},
+	}
+}
+
+func deleteCommand(noun string, run func(slug string, yes bool) error) *Command {
+	return &Command{
+		Name:    "delete",
+		Summary: "Delete a " + noun,
+		Setup: func(fs *flag.FlagSet) func(args []string) error {
+			slug := slugFlag(fs)
+			yes := fs.Bool("yes", false, "do not ask for confirmation")
+			return noArgs(func() error {
+				if err := requireFlag("slug", *slug); err != nil {
+					return err
+				}
+				return run(*slug, *yes)
+			})
+		},
+	}
+}
+
+func newPostsCommand(post IPostCLI) *Command {
+	return &Command{
+		Name:    "posts",
+		Summary: "Manage posts",
+		Subcommands: []*Command{
+			{
+				Name:    "list",
+				Summary: "List posts",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					format := formatFlag(fs)
+					category := fs.String("category", "", "only posts filed under the category with this `slug` or its descendants")
+					author := fs.String("author", "", "only posts written by this `username`")
+					return noArgs(func() error {
+						queryParams := make(map[string][]string)
+						if *category != "" {
+							queryParams["category-name"] = []string{*category}
+						}
+						if *author != "" {
+							queryParams["author"] = []string{*author}
+						}
+						return post.List(*format, queryParams)
+					})
+				},
+			},
+			showCommand("post", post.Show),
+			createCommand("post", post.Create),
+			updateCommand("post", post.Update),
+			deleteCommand("post", post.Delete),
+			{
+				Name:    "publish",
+				Summary: "Make a post public",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					slug := slugFlag(fs)
+					return noArgs(func() error {
+						if err := requireFlag("slug", *slug); err != nil {
+							return err
+						}
+						return post.SetPublic(*slug, true)
+					})
+				},
+			},
+			{
+				Name:    "unpublish",
+				Summary: "Make a post a draft again",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					slug := slugFlag(fs)
+					return noArgs(func() error {
+						if err := requireFlag("slug", *slug); err != nil {
+							return err
+						}
+						return post.SetPublic(*slug, false)
+					})
+				},
+			},
+		},
+	}
+}
+
+func newCategoriesCommand(category ICategoryCLI) *Command {
+	return &Command{
+		Name:    "categories",
+		Summary: "Manage the category tree",
+		Subcommands: []*Command{
+			{
+				Name:    "list",
+				Summary: "List the category tree",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					format := formatFlag(fs)
+					return noArgs(func() error { return category.List(*format) })
+				},
+			},
+			showCommand("category", category.Show),
+			createCommand("category", category.Create),
+			updateCommand("category", category.Update),
+			deleteCommand("category", category.Delete),
+		},
+	}
+}
+
+func newSubCategoriesCommand(subCategory ISubCategoryCLI) *Command {
+	return &Command{
+		Name:    "sub-categories",
+		Summary: "Manage categories that have a parent",
+		Subcommands: []*Command{
+			{
+				Name:    "list",
+				Summary: "List sub-categories",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					format := formatFlag(fs)
+					parent := fs.String("category", "", "only the children of the category with this `slug`")
+					return noArgs(func() error {
+						queryParams := make(map[string][]string)
+						if *parent != "" {
+							queryParams["category-name"] = []string{*parent}
+						}
+						return subCategory.List(*format, queryParams)
+					})
+				},
+			},
+			showCommand("sub-category", subCategory.Show),
+			createCommand("sub-category", subCategory.Create),
+			updateCommand("sub-category", subCategory.Update),
+			deleteCommand("sub-category", subCategory.Delete),
+		},
+	}
+}
+
+func newMediaCommand(media IMediaCLI) *Command {
+	return &Command{
+		Name:    "media",
+		Summary: "Manage media files",
+		Subcommands: []*Command{
+			{
+				Name:    "list",
+				Summary: "List the stored media files",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					format := formatFlag(fs)
+					return noArgs(func() error { return media.List(*format) })
+				},
+			},
+			{
+				Name:    "derivatives",
+				Summary: "Generate the thumbnail, medium and og sizes of stored images that have none",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					force := fs.Bool("force", false, "regenerate the sizes of every image")
+					return noArgs(func() error { return media.Derivatives(*force) })
+				},
+			},
+			{
+				Name:    "orphans",
+				Summary: "List the media files nothing uses, or delete them with their sizes",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					format := formatFlag(fs)
+					remove := fs.Bool("delete", false, "delete the files instead of listing them")
+					yes := fs.Bool("yes", false, "do not ask for confirmation")
+					return noArgs(func() error {
+						if *remove {
+							return media.DeleteOrphans(*yes)
+						}
+						return media.Orphans(*format)
+					})
+				},
+			},
+		},
+	}
+}
+
+func mediaFlag(fs *flag.FlagSet) *string {
+	return fs.String("media", "media", "media `directory` of the server")
+}
+
+func newExportCommand(archive IArchiveCLI) *Command {
+	return &Command{
+		Name:    "export",
+		Summary: "Export the blog",
+		Subcommands: []*Command{
+			{
+				Name:    "archive",
+				Summary: "Write categories, users, posts and media files to a gzipped tar archive, - for stdout",
+				Args:    "<file>",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					media := mediaFlag(fs)
+					return func(args []string) error {
+						if len(args) != 1 {
+							return usageErrorf("expected one file")
+						}
+						return archive.Export(args[0], *media)
+					}
+				},
+			},
+		},
+	}
+}
+
+func newImportCommand(importer IImportCLI, archive IArchiveCLI) *Command {
+	return &Command{
+		Name:    "import",
+		Summary: "Import posts or a whole archive",
+		Subcommands: []*Command{
+			{
+				Name:    "archive",
+				Summary: "Restore an archive written by export archive, keeping ids and timestamps, - for stdin",
+				Args:    "<file>",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					media := mediaFlag(fs)
+					mode := fs.String("mode", "merge", "`mode`: merge overwrites records with the same id, replace also deletes the others")
+					yes := fs.Bool("yes", false, "do not ask for confirmation with --mode=replace")
+					return func(args []string) error {
+						if len(args) != 1 {
+							return usageErrorf("expected one file")
+						}
+						if *mode != "merge" && *mode != "replace" {
+							return usageErrorf("unknown mode %q, expected merge or replace", *mode)
+						}
+						return archive.Import(args[0], *media, *mode == "replace", *yes)
+					}
+				},
+			},
+			{
+				Name:    "markdown",
+				Summary: "Create or update posts from the Markdown files with YAML front matter below a directory, matched by slug",
+				Args:    "<dir>",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					format := formatFlag(fs)
+					dryRun := fs.Bool("dry-run", false, "only print what would be done")
+					createCategories := fs.Bool("create-categories", false, "create missing categories and sub-categories instead of failing the post")
+					return func(args []string) error {
+						if len(args) != 1 {
+							return usageErrorf("expected one directory")
+						}
+						return importer.Markdown(args[0], *format, dto.ImportOptionsModel{DryRun: *dryRun, CreateCategories: *createCategories})
+					}
+				},
+			},
+			{
+				Name:    "wordpress",
+				Summary: "Create or update categories and posts from a WordPress export (WXR) file, matched by slug",
+				Args:    "<file>",
+				Setup: func(fs *flag.FlagSet) func(args []string) error {
+					format := formatFlag(fs)
+					media := mediaFlag(fs)
+					uploads := fs.String("uploads", "", "wp-content/uploads `directory` of the site; linked files found there are copied to --media")
+					dryRun := fs.Bool("dry-run", false, "only print what would be done")
+					return func(args []string) error {
+						if len(args) != 1 {
+							return usageErrorf("expected one file")
+						}
+						return importer.WordPress(args[0], *uploads, *media, *format, dto.ImportOptionsModel{DryRun: *dryRun})
+					}
+				},
+			},
+		},
+	}
+}
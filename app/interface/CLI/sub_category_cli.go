@@ -0,0 +1,138 @@
+package CLI
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/service"
+	"fmt"
+	"strings"
+)
+
+type ISubCategoryCLI interface {
+	List(format string, queryParams map[string][]string) error
+	Show(slug string, format string) error
+	Create(path string) error
+	Update(slug string, path string) error
+	Delete(slug string, yes bool) error
+}
+
+type SubCategoryCLI struct {
+	service.ISubCategoryService
+	*Console
+}
+
+func NewSubCategoryCLI(srv service.ISubCategoryService, console *Console) (iSubCategoryCLI ISubCategoryCLI) {
+	iSubCategoryCLI = &SubCategoryCLI{srv, console}
+	return
+}
+
+func (c *SubCategoryCLI) List(format string, queryParams map[string][]string) (err error) {
+	err = checkFormat(format)
+	if err != nil {
+		return
+	}
+	subCategoryDtos, err := c.ISubCategoryService.GetSubCategories(queryParams)
+	if err != nil {
+		return
+	}
+	if format == "json" {
+		if subCategoryDtos == nil {
+			subCategoryDtos = []dto.SubCategoryModel{}
+		}
+		err = c.printJSON(subCategoryDtos)
+		return
+	}
+	table := c.newTable()
+	fmt.Fprintf(table, "ID\tSLUG\tNAME\tPARENT\tPOSITION\n")
+	for _, subCategoryDto := range subCategoryDtos {
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%d\n", subCategoryDto.Id, subCategoryDto.Slug, subCategoryDto.Name, subCategoryDto.ParentCategorySlug, subCategoryDto.Position)
+	}
+	err = table.Flush()
+	return
+}
+
+func (c *SubCategoryCLI) Show(slug string, format string) (err error) {
+	err = checkFormat(format)
+	if err != nil {
+		return
+	}
+	subCategoryDto, err := c.ISubCategoryService.GetSubCategoryDetailBySlug(slug)
+	if err != nil {
+		return
+	}
+	if format == "json" {
+		err = c.printJSON(subCategoryDto)
+		return
+	}
+	table := c.newTable()
+	fmt.Fprintf(table, "id\t%d\n", subCategoryDto.Id)
+	fmt.Fprintf(table, "name\t%s\n", subCategoryDto.Name)
+	fmt.Fprintf(table, "slug\t%s\n", subCategoryDto.Slug)
+	fmt.Fprintf(table, "parent\t%s (%d)\n", subCategoryDto.ParentCategorySlug, subCategoryDto.ParentCategoryId)
+	fmt.Fprintf(table, "position\t%d\n", subCategoryDto.Position)
+	fmt.Fprintf(table, "description\t%s\n", subCategoryDto.Description)
+	fmt.Fprintf(table, "cover image\t%s\n", subCategoryDto.CoverImage)
+	if subCategoryDto.Stats != nil {
+		fmt.Fprintf(table, "public posts\t%d\n", subCategoryDto.Stats.PostCount)
+	}
+	var children []string
+	for _, child := range subCategoryDto.Children {
+		children = append(children, child.Slug)
+	}
+	fmt.Fprintf(table, "children\t%s\n", strings.Join(children, ", "))
+	err = table.Flush()
+	return
+}
+
+func (c *SubCategoryCLI) Create(path string) (err error) {
+	var subCategoryDto dto.SubCategoryModel
+	err = c.readJSONFile(path, &subCategoryDto)
+	if err != nil {
+		return
+	}
+	err = c.ISubCategoryService.Create(operator, subCategoryDto)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "sub-category %s has been created\n", subCategoryDto.Slug)
+	return
+}
+
+// Update applies the fields of the JSON file to the stored sub-category; fields
+// the file leaves out are kept.
+func (c *SubCategoryCLI) Update(slug string, path string) (err error) {
+	subCategoryDto, err := c.ISubCategoryService.GetSubCategoryBySlug(slug)
+	if err != nil {
+		return
+	}
+	id := subCategoryDto.Id
+	err = c.readJSONFile(path, &subCategoryDto)
+	if err != nil {
+		return
+	}
+	subCategoryDto.Id = id
+	err = c.ISubCategoryService.Update(operator, subCategoryDto)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "sub-category %s has been updated\n", subCategoryDto.Slug)
+	return
+}
+
+func (c *SubCategoryCLI) Delete(slug string, yes bool) (err error) {
+	subCategoryDto, err := c.ISubCategoryService.GetSubCategoryBySlug(slug)
+	if err != nil {
+		return
+	}
+	if !yes {
+		ok, err := c.confirm(fmt.Sprintf("delete %s?", slug))
+		if err != nil || !ok {
+			return err
+		}
+	}
+	err = c.ISubCategoryService.Delete(operator, subCategoryDto)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "sub-category %s has been deleted\n", slug)
+	return
+}
@@ -0,0 +1,95 @@
+package CLI
+
+import (
+	"backend/app/common/dto"
+	"testing"
+
+	mocks "backend/mocks/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubCategoryCLI(t *testing.T) {
+	subCategoryDto := dto.NewSubCategoryModel(2, "Testing", "testing", 1, "Go", "go")
+
+	t.Run("list", func(t *testing.T) {
+		queryParams := map[string][]string{"parent": {"go"}}
+		s := new(mocks.ISubCategoryService)
+		s.On("GetSubCategories", queryParams).Return([]dto.SubCategoryModel{subCategoryDto}, nil)
+		console, stdout, _ := newTestConsole("", false)
+
+		err := NewSubCategoryCLI(s, console).List("table", queryParams)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "ID  SLUG     NAME     PARENT  POSITION\n2   testing  Testing  go      0\n", stdout.String())
+	})
+
+	t.Run("show as JSON", func(t *testing.T) {
+		s := new(mocks.ISubCategoryService)
+		s.On("GetSubCategoryDetailBySlug", "testing").Return(subCategoryDto, nil)
+		console, stdout, _ := newTestConsole("", false)
+
+		err := NewSubCategoryCLI(s, console).Show("testing", "json")
+
+		assert.NoError(t, err)
+		assert.Contains(t, stdout.String(), `"parent_category_slug": "go"`)
+	})
+
+	t.Run("create from stdin", func(t *testing.T) {
+		s := new(mocks.ISubCategoryService)
+		s.On("Create", operator, dto.SubCategoryModel{Name: "Testing", Slug: "testing", ParentCategoryId: 1}).Return(nil)
+		console, stdout, _ := newTestConsole(`{"name": "Testing", "slug": "testing", "parent_category_id": 1}`, false)
+
+		err := NewSubCategoryCLI(s, console).Create("-")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "sub-category testing has been created\n", stdout.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		updated := subCategoryDto
+		updated.Description = "Tests in Go"
+		s := new(mocks.ISubCategoryService)
+		s.On("GetSubCategoryBySlug", "testing").Return(subCategoryDto, nil)
+		s.On("Update", operator, updated).Return(nil)
+		console, stdout, _ := newTestConsole(`{"description": "Tests in Go"}`, false)
+
+		err := NewSubCategoryCLI(s, console).Update("testing", "-")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "sub-category testing has been updated\n", stdout.String())
+		s.AssertExpectations(t)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		for _, c := range []struct {
+			name     string
+			stdin    string
+			terminal bool
+			yes      bool
+			deleted  bool
+			err      error
+		}{
+			{"with --yes", "", false, true, true, nil},
+			{"confirmed", "y\n", true, false, true, nil},
+			{"declined", "no\n", true, false, false, nil},
+			{"without a terminal", "", false, false, false, ErrUsage},
+		} {
+			s := new(mocks.ISubCategoryService)
+			s.On("GetSubCategoryBySlug", "testing").Return(subCategoryDto, nil)
+			s.On("Delete", operator, subCategoryDto).Return(nil)
+			console, stdout, _ := newTestConsole(c.stdin, c.terminal)
+
+			err := NewSubCategoryCLI(s, console).Delete("testing", c.yes)
+
+			assert.ErrorIs(t, err, c.err, c.name)
+			if c.deleted {
+				s.AssertCalled(t, "Delete", operator, subCategoryDto)
+				assert.Contains(t, stdout.String(), "sub-category testing has been deleted\n", c.name)
+			} else {
+				s.AssertNotCalled(t, "Delete", operator, subCategoryDto)
+			}
+		}
+	})
+}
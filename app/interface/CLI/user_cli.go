@@ -3,165 +3,332 @@ package CLI
 import (
 	"backend/app/common/dto"
 	"backend/app/domain/service"
-	"bufio"
 	"fmt"
-	"os"
-
-	"golang.org/x/crypto/bcrypt"
-	"golang.org/x/crypto/ssh/terminal"
+	"strings"
+	"time"
 )
 
 type IUserCLI interface {
-	GetAll() error
+	List(format string) error
 	ValidateUser() (dto.UserModel, error)
 	Create() error
+	CreateUser(userInputDto dto.UserInputModel, passwordStdin bool) error
 	Update() error
 	Delete() error
+	DeleteUser(id int, yes bool) error
+	RevokeTokens(username string) error
+	UnlockUser(username string) error
+	UnlockAddress(ip string) error
+	EnableTwoFactor() error
+	ResetTwoFactor(username string) error
+	CreateApiKey(apiKeyInputDto dto.ApiKeyInputModel) error
+	GetApiKeys(format string) error
+	RevokeApiKey(prefix string) error
 }
 
+// operator is the actor for commands run from the CLI. Running the CLI
+// already requires the database credentials, so it acts as an admin.
+var operator = dto.NewUserModel(0, "cli", "admin")
+
 type UserCLI struct {
 	service.IUserService
+	*Console
 }
 
-func NewUserCLI(srv service.IUserService) (iUserCLI IUserCLI) {
-	iUserCLI = &UserCLI{srv}
+func NewUserCLI(srv service.IUserService, console *Console) (iUserCLI IUserCLI) {
+	iUserCLI = &UserCLI{srv, console}
 	return
 }
 
-func (c *UserCLI) GetAll() (err error) {
-	userDtos, err := c.IUserService.GetAll()
+func (c *UserCLI) List(format string) (err error) {
+	err = checkFormat(format)
 	if err != nil {
 		return
 	}
+	userDtos, err := c.IUserService.GetAll(operator)
+	if err != nil {
+		return
+	}
+	if format == "json" {
+		if userDtos == nil {
+			userDtos = []dto.UserModel{}
+		}
+		err = c.printJSON(userDtos)
+		return
+	}
+	table := c.newTable()
+	fmt.Fprintf(table, "ID\tUSERNAME\tROLE\tDISPLAY NAME\n")
 	for _, userDto := range userDtos {
-		fmt.Println(userDto.Name)
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\n", userDto.Id, userDto.Name, userDto.Role, userDto.DisplayName)
 	}
+	err = table.Flush()
 	return
 }
 
 func (c *UserCLI) ValidateUser() (userDto dto.UserModel, err error) {
-	fmt.Printf("username: ")
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
-	username := scanner.Text()
-	fmt.Printf("password: ")
-	password, err := terminal.ReadPassword(0)
-	stringedPassword := string(password)
-	fmt.Printf("\n")
+	username := c.prompt("username: ")
+	password, err := c.readPassword(false, "password: ")
 	if err != nil {
 		return
 	}
 
-	credsDto := dto.NewCredsModel(username, stringedPassword)
+	credsDto := dto.NewCredsModel(username, password)
 
 	userDto, err = c.IUserService.ValidateUser(credsDto)
 	if err != nil {
 		return
 	}
-	fmt.Printf("validated user's name: %s\n", credsDto.Username)
+	fmt.Fprintf(c.Stdout, "validated user's name: %s\n", credsDto.Username)
 	return
 }
 
+// Create asks for the username and password of a new admin.
 func (c *UserCLI) Create() (err error) {
-	fmt.Printf("username: ")
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
-	username := scanner.Text()
-	fmt.Printf("password: ")
-	password, err := terminal.ReadPassword(0)
-	fmt.Printf("\n")
+	username := c.prompt("username: ")
+	password, err := c.readPassword(false, "password: ")
 	if err != nil {
 		return
 	}
+	err = c.createUser(dto.UserInputModel{Name: username, Password: password, Role: "admin"})
+	return
+}
 
-	hashedPassword, _ := bcrypt.GenerateFromPassword(password, 10)
-	stringedHashedPassword := string(hashedPassword)
-
-	userDto := dto.UserModel{
-		Name:     username,
-		Password: stringedHashedPassword,
+// CreateUser asks for the password of the new user, or reads it from the first
+// line of stdin with passwordStdin.
+func (c *UserCLI) CreateUser(userInputDto dto.UserInputModel, passwordStdin bool) (err error) {
+	userInputDto.Password, err = c.readPassword(passwordStdin, "password: ")
+	if err != nil {
+		return
 	}
+	err = c.createUser(userInputDto)
+	return
+}
 
-	err = c.IUserService.Create(userDto)
+func (c *UserCLI) createUser(userInputDto dto.UserInputModel) (err error) {
+	err = c.IUserService.Create(operator, userInputDto)
 	if err != nil {
-		fmt.Printf("Error creating user: %s\n", err)
 		return
 	}
-	fmt.Println("new user has been created")
+	fmt.Fprintf(c.Stdout, "user %s has been created\n", userInputDto.Name)
 	return
 }
 
 func (c *UserCLI) Update() (err error) {
 	userDto, err := c.ValidateUser()
 	if err != nil {
-		panic(err)
+		return
 	}
-	fmt.Printf("change username: u / change password: p; (u/p): ")
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
-	option := scanner.Text()
-	switch option {
+	switch c.prompt("change username: u / change password: p / change profile: f; (u/p/f): ") {
 	case "u":
 		err = c.ChangeUserName(userDto)
 	case "p":
 		err = c.ChangePassword(userDto)
+	case "f":
+		err = c.ChangeProfile(userDto)
 	default:
-		fmt.Println("There is no such option. valid option is (u/p)")
-		return
+		err = usageErrorf("there is no such option, valid options are u, p and f")
 	}
 	return
 }
 
+// Delete asks for the credentials of the user to delete and a confirmation.
 func (c *UserCLI) Delete() (err error) {
 	userDto, err := c.ValidateUser()
 	if err != nil {
 		return
 	}
-	scanner := bufio.NewScanner(os.Stdin)
-	fmt.Printf("Are you sure? (y/n): ")
-	scanner.Scan()
-	switch scanner.Text() {
-	case "y":
-		err = c.IUserService.Delete(userDto)
-		if err != nil {
-			return
+	if c.prompt("Are you sure? (y/n): ") != "y" {
+		fmt.Fprintln(c.Stdout, "To delete user was canceled")
+		return
+	}
+	err = c.IUserService.Delete(operator, userDto)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(c.Stdout, "The user has been deleted")
+	return
+}
+
+func (c *UserCLI) DeleteUser(id int, yes bool) (err error) {
+	userDto, err := c.IUserService.GetById(operator, id)
+	if err != nil {
+		return
+	}
+	if !yes {
+		ok, err := c.confirm("delete the user?")
+		if err != nil || !ok {
+			return err
 		}
-		fmt.Println("The user has been deleted")
-	case "n":
-		fmt.Println("To delete user was canceled")
-	default:
-		fmt.Println("To delete user was canceled")
 	}
+	err = c.IUserService.Delete(operator, userDto)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "user %s has been deleted\n", userDto.Name)
+	return
+}
+
+// RevokeTokens ends every session of a user, e.g. after a suspected leak.
+func (c *UserCLI) RevokeTokens(username string) (err error) {
+	err = c.IUserService.RevokeAllTokens(operator, username)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "all tokens of %s have been revoked\n", username)
+	return
+}
+
+// UnlockUser lifts the login backoff of a username.
+func (c *UserCLI) UnlockUser(username string) (err error) {
+	err = c.IUserService.UnlockUser(operator, username)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "user %s has been unlocked\n", username)
+	return
+}
+
+// UnlockAddress lifts the login backoff of a client IP.
+func (c *UserCLI) UnlockAddress(ip string) (err error) {
+	err = c.IUserService.UnlockAddress(operator, ip)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "address %s has been unlocked\n", ip)
+	return
+}
+
+// EnableTwoFactor enrolls a user in TOTP 2FA. The user proves the password, adds the
+// printed URI to an authenticator app and confirms with a code from it.
+func (c *UserCLI) EnableTwoFactor() (err error) {
+	userDto, err := c.ValidateUser()
+	if err != nil {
+		return
+	}
+	enrollmentDto, err := c.IUserService.NewTwoFactorSecret(operator, userDto.Name)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "add this URI to an authenticator app (or enter the secret %s):\n%s\n", enrollmentDto.Secret, enrollmentDto.Uri)
+	recoveryCodes, err := c.IUserService.EnableTwoFactor(operator, userDto.Name, enrollmentDto.Secret, c.prompt("code: "))
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(c.Stdout, "two-factor authentication has been enabled. store these recovery codes, each works once:")
+	for _, recoveryCode := range recoveryCodes {
+		fmt.Fprintln(c.Stdout, recoveryCode)
+	}
+	return
+}
+
+// ResetTwoFactor removes 2FA from a user who lost the authenticator and recovery codes.
+func (c *UserCLI) ResetTwoFactor(username string) (err error) {
+	err = c.IUserService.ResetTwoFactor(operator, username)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "two-factor authentication of %s has been reset\n", username)
+	return
+}
+
+// CreateApiKey creates a key for a machine client acting as the given user and
+// prints it on its own line. The key is shown only this once.
+func (c *UserCLI) CreateApiKey(apiKeyInputDto dto.ApiKeyInputModel) (err error) {
+	key, err := c.IUserService.CreateApiKey(operator, apiKeyInputDto)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(c.Stderr, "new API key, store it now as it is not shown again:")
+	fmt.Fprintln(c.Stdout, key)
+	return
+}
+
+func (c *UserCLI) GetApiKeys(format string) (err error) {
+	err = checkFormat(format)
+	if err != nil {
+		return
+	}
+	apiKeyDtos, err := c.IUserService.GetApiKeys(operator)
+	if err != nil {
+		return
+	}
+	if format == "json" {
+		if apiKeyDtos == nil {
+			apiKeyDtos = []dto.ApiKeyModel{}
+		}
+		err = c.printJSON(apiKeyDtos)
+		return
+	}
+	table := c.newTable()
+	fmt.Fprintf(table, "PREFIX\tNAME\tUSERNAME\tSCOPES\tLAST USED\tREVOKED\n")
+	for _, apiKeyDto := range apiKeyDtos {
+		lastUsed := "never"
+		if apiKeyDto.LastUsedAt != nil {
+			lastUsed = apiKeyDto.LastUsedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%t\n", apiKeyDto.Prefix, apiKeyDto.Name, apiKeyDto.Username, strings.Join(apiKeyDto.Scopes, ","), lastUsed, apiKeyDto.Revoked)
+	}
+	err = table.Flush()
+	return
+}
+
+func (c *UserCLI) RevokeApiKey(prefix string) (err error) {
+	err = c.IUserService.RevokeApiKey(operator, prefix)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "API key %s has been revoked\n", prefix)
 	return
 }
 
 func (c *UserCLI) ChangeUserName(userDto dto.UserModel) (err error) {
-	scanner := bufio.NewScanner(os.Stdin)
-	fmt.Printf("new username: ")
-	scanner.Scan()
-	newUsername := scanner.Text()
-	userDto.Name = newUsername
-	err = c.IUserService.Update(userDto)
+	newUsername := c.prompt("new username: ")
+	userInputDto := dto.NewUserInputModel(userDto)
+	userInputDto.Name = newUsername
+	err = c.IUserService.Update(operator, userInputDto)
 	if err != nil {
 		return
 	}
-	fmt.Printf("usesrname has been changed: new username is %s\n", userDto.Name)
+	fmt.Fprintf(c.Stdout, "usesrname has been changed: new username is %s\n", userInputDto.Name)
 	return
 }
 
 func (c *UserCLI) ChangePassword(userDto dto.UserModel) (err error) {
-	fmt.Printf("new password: ")
-	newPassword, err := terminal.ReadPassword(0)
-	fmt.Printf("\n")
+	newPassword, err := c.readPassword(false, "new password: ")
 	if err != nil {
 		return
 	}
 
-	hashedNewPassword, _ := bcrypt.GenerateFromPassword(newPassword, 10)
-	stringedHashedNewPassword := string(hashedNewPassword)
+	userInputDto := dto.NewUserInputModel(userDto)
+	userInputDto.Password = string(newPassword)
+	err = c.IUserService.Update(operator, userInputDto)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "%s's password has been changed\n", userDto.Name)
+	return
+}
 
-	userDto.Password = stringedHashedNewPassword
-	err = c.IUserService.Update(userDto)
-	fmt.Printf("%s's password has been changed\n", userDto.Name)
+// ChangeProfile edits the public author profile. An empty answer keeps the current value.
+func (c *UserCLI) ChangeProfile(userDto dto.UserModel) (err error) {
+	userInputDto := dto.NewUserInputModel(userDto)
+	fields := []struct {
+		label string
+		value *string
+	}{
+		{"display name", &userInputDto.DisplayName},
+		{"bio", &userInputDto.Bio},
+		{"avatar url", &userInputDto.AvatarUrl},
+	}
+	for _, field := range fields {
+		if answer := c.prompt(fmt.Sprintf("%s (%s): ", field.label, *field.value)); answer != "" {
+			*field.value = answer
+		}
+	}
+	err = c.IUserService.Update(operator, userInputDto)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Stdout, "%s's profile has been changed\n", userDto.Name)
 	return
 }
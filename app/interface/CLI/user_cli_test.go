@@ -0,0 +1,38 @@
+package CLI
+
+import (
+	"testing"
+
+	mocks "backend/mocks/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUserCLI_unlock(t *testing.T) {
+	s := new(mocks.IUserService)
+	s.On("UnlockUser", operator, "192.0.2.1").Return(nil)
+	s.On("UnlockAddress", operator, "192.0.2.1").Return(nil)
+	console, stdout, stderr := newTestConsole("", false)
+	root := NewRootCommand("blog", NewUserCLI(s, console), NewPostCLI(new(mocks.IPostService), console), NewCategoryCLI(new(mocks.ICategoryService), console), NewSubCategoryCLI(new(mocks.ISubCategoryService), console), NewImportCLI(nil, console), NewArchiveCLI(nil, console), NewMediaCLI(new(mocks.IMediaService), console))
+
+	// a username can look like an address; the flag says which one is meant
+	assert.Equal(t, ExitOK, Execute(root, []string{"users", "unlock", "--username", "192.0.2.1"}, stdout, stderr))
+	s.AssertCalled(t, "UnlockUser", operator, "192.0.2.1")
+	s.AssertNotCalled(t, "UnlockAddress", mock.Anything, mock.Anything)
+	assert.Equal(t, "user 192.0.2.1 has been unlocked\n", stdout.String())
+
+	assert.Equal(t, ExitOK, Execute(root, []string{"users", "unlock", "--ip", "192.0.2.1"}, stdout, stderr))
+	s.AssertCalled(t, "UnlockAddress", operator, "192.0.2.1")
+
+	for _, args := range [][]string{
+		{"users", "unlock"},
+		{"users", "unlock", "--username", "testuser1", "--ip", "192.0.2.1"},
+	} {
+		stderr.Reset()
+		assert.Equal(t, ExitUsage, Execute(root, args, stdout, stderr), args)
+		assert.Contains(t, stderr.String(), "--username", args)
+	}
+	s.AssertNumberOfCalls(t, "UnlockUser", 1)
+	s.AssertNumberOfCalls(t, "UnlockAddress", 1)
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrMalformedAuthorization is returned for an Authorization header that is not a
+// well-formed RFC 6750 Bearer credential.
+var ErrMalformedAuthorization = errors.New("malformed Authorization header, expected \"Bearer <token>\"")
+
+// bearerToken extracts the token of an RFC 6750 "Bearer" credential. The scheme is
+// case-insensitive; the token must be a b64token.
+func bearerToken(header string) (token string, err error) {
+	scheme, credentials, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		err = ErrMalformedAuthorization
+		return
+	}
+	token = strings.TrimLeft(credentials, " ")
+	if !isB64Token(token) {
+		token = ""
+		err = ErrMalformedAuthorization
+	}
+	return
+}
+
+// isB64Token reports whether s matches 1*( ALPHA / DIGIT / "-" / "." / "_" / "~" / "+" / "/" ) *"=".
+func isB64Token(s string) bool {
+	body := strings.TrimRight(s, "=")
+	if body == "" {
+		return false
+	}
+	for _, c := range body {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.ContainsRune("-._~+/", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// The read routes a CachePolicy can set the Cache-Control header of.
+const (
+	RoutePosts         = "posts"
+	RoutePost          = "post"
+	RouteCategories    = "categories"
+	RouteCategory      = "category"
+	RouteSubCategories = "sub-categories"
+	RouteSubCategory   = "sub-category"
+)
+
+// Routes lists the read routes a CachePolicy knows.
+var Routes = []string{RoutePosts, RoutePost, RouteCategories, RouteCategory, RouteSubCategories, RouteSubCategory}
+
+// defaultCacheControl lets clients keep responses but makes them revalidate every
+// use, which the ETags make cheap. Responses may depend on the token, so shared
+// caches are not allowed by default.
+const defaultCacheControl = "private, no-cache"
+
+// CachePolicy maps a read route to the Cache-Control header of its responses;
+// routes it lacks get defaultCacheControl.
+type CachePolicy map[string]string
+
+func (p CachePolicy) cacheControl(route string) string {
+	if cacheControl, ok := p[route]; ok {
+		return cacheControl
+	}
+	return defaultCacheControl
+}
+
+// writeCachedJSON writes v as JSON with its entityTag as the ETag and the
+// Cache-Control of the route. A request whose If-None-Match lists the ETag is
+// answered 304 without the body. There is no Last-Modified: the JSON embeds the
+// author profile, images and category names, none of which has a timestamp that
+// would move when they change; the ETag covers them all.
+func (p CachePolicy) writeCachedJSON(w http.ResponseWriter, r *http.Request, route string, v interface{}) (err error) {
+	etag, err := entityTag(v)
+	if err != nil {
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", p.cacheControl(route))
+	if notModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	err = writeJSON(w, r, v)
+	return
+}
+
+// entityTag returns the strong ETag of v: the hash of its compact JSON, which
+// does not depend on how the response is formatted. The JSON is hashed as it is
+// encoded, so a long list is not held in memory for it. v is thus encoded twice,
+// here and by writeJSON: the ETag header goes out before the body, so the hash
+// cannot be taken while the body streams, and buffering the body instead would
+// cost the memory streaming saves. Encoding is cheap next to the query behind v.
+func entityTag(v interface{}) (etag string, err error) {
+	hash := sha256.New()
+	err = encodeJSON(hash, v, false)
+	if err != nil {
+		return
+	}
+	etag = `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	return
+}
+
+// notModified evaluates If-None-Match for GET and HEAD. Without Last-Modified,
+// If-Modified-Since is ignored, as RFC 9110 asks.
+func notModified(r *http.Request, etag string) bool {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		return false
+	}
+	match := r.Header.Get("If-None-Match")
+	return match != "" && etagMatches(match, etag)
+}
+
+// etagMatches reports whether an If-None-Match header lists the ETag, comparing
+// weakly as RFC 9110 asks for.
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || sameEntityTag(candidate, strings.TrimPrefix(etag, "W/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// codedETag returns the ETag of a body sent in a content coding, e.g. "<hash>-gzip"
+// for "<hash>". A strong ETag promises the same bytes, which the compressed and
+// the plain body are not.
+func codedETag(etag string, coding string) string {
+	if len(etag) < 2 || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return etag[:len(etag)-1] + "-" + coding + `"`
+}
+
+// sameEntityTag reports whether tag, as a client sent it back, is etag or the
+// codedETag of etag in some coding; both without W/.
+func sameEntityTag(tag string, etag string) bool {
+	if tag == etag {
+		return true
+	}
+	if len(etag) < 2 || !strings.HasSuffix(etag, `"`) || !strings.HasPrefix(tag, etag[:len(etag)-1]+"-") || !strings.HasSuffix(tag, `"`) {
+		return false
+	}
+	coding := tag[len(etag) : len(tag)-1]
+	return coding != "" && strings.IndexFunc(coding, func(r rune) bool { return r < 'a' || r > 'z' }) < 0
+}
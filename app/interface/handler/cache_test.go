@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachePolicy_writeCachedJSON(t *testing.T) {
+	write := func(p CachePolicy, r *http.Request, v interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		assert.NoError(t, p.writeCachedJSON(w, r, RouteCategories, v))
+		return w
+	}
+
+	first := write(nil, httptest.NewRequest("GET", "/", nil), []string{"a"})
+	assert.Equal(t, "private, no-cache", first.Header().Get("Cache-Control"))
+	assert.Equal(t, "application/json", first.Header().Get("Content-Type"))
+	second := write(nil, httptest.NewRequest("GET", "/", nil), []string{"b"})
+	assert.NotEqual(t, first.Header().Get("ETag"), second.Header().Get("ETag"))
+
+	t.Run(
+		"If-None-Match",
+		func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("If-None-Match", `"other"`)
+
+			assert.Equal(t, http.StatusOK, write(nil, r, []string{"a"}).Code)
+
+			r.Header.Set("If-None-Match", `"other", W/`+first.Header().Get("ETag"))
+			assert.Equal(t, http.StatusNotModified, write(nil, r, []string{"a"}).Code)
+
+			r.Header.Set("If-None-Match", codedETag(first.Header().Get("ETag"), "gzip"))
+			assert.Equal(t, http.StatusNotModified, write(nil, r, []string{"a"}).Code)
+
+			r.Header.Set("If-None-Match", codedETag(second.Header().Get("ETag"), "gzip"))
+			assert.Equal(t, http.StatusOK, write(nil, r, []string{"a"}).Code)
+		},
+	)
+
+	t.Run(
+		"If-Modified-Since is ignored",
+		func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("If-Modified-Since", time.Now().UTC().Format(http.TimeFormat))
+
+			w := write(nil, r, []string{"a"})
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Empty(t, w.Header().Get("Last-Modified"))
+		},
+	)
+
+	t.Run(
+		"per route",
+		func(t *testing.T) {
+			w := write(CachePolicy{RouteCategories: "public, max-age=300", RoutePost: "no-store"}, httptest.NewRequest("GET", "/", nil), []string{"a"})
+
+			assert.Equal(t, "public, max-age=300", w.Header().Get("Cache-Control"))
+		},
+	)
+}
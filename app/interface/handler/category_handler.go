@@ -11,31 +11,64 @@ import (
 
 type ICategoryHandler interface {
 	GetAll(w http.ResponseWriter, r *http.Request) (err error)
+	GetBySlug(w http.ResponseWriter, r *http.Request, slug string) (err error)
+	GetTree(w http.ResponseWriter, r *http.Request) (err error)
+	GetBreadcrumbs(w http.ResponseWriter, r *http.Request, slug string) (err error)
 	Create(w http.ResponseWriter, r *http.Request) (err error)
 	Update(w http.ResponseWriter, r *http.Request) (err error)
+	Patch(w http.ResponseWriter, r *http.Request) (err error)
 	Delete(w http.ResponseWriter, r *http.Request) (err error)
+	Reorder(w http.ResponseWriter, r *http.Request) (err error)
 }
 
 type CategoryHandler struct {
 	service.ICategoryService
+	CachePolicy
 }
 
-func NewCategoryHandler(srv service.ICategoryService) (iCategoryHandler ICategoryHandler) {
-	iCategoryHandler = &CategoryHandler{srv}
+func NewCategoryHandler(srv service.ICategoryService, cachePolicy CachePolicy) (iCategoryHandler ICategoryHandler) {
+	iCategoryHandler = &CategoryHandler{srv, cachePolicy}
 	return
 }
 
 func (h *CategoryHandler) GetAll(w http.ResponseWriter, r *http.Request) (err error) {
-	categories, err := h.ICategoryService.GetAll()
+	var categories []dto.CategoryModel
+	if r.URL.Query().Get("with-counts") == "true" {
+		categories, err = h.ICategoryService.GetAllWithCounts()
+	} else {
+		categories, err = h.ICategoryService.GetAll()
+	}
+	if err != nil {
+		return
+	}
+	err = h.writeCachedJSON(w, r, RouteCategories, &categories)
+	return
+}
+
+func (h *CategoryHandler) GetBySlug(w http.ResponseWriter, r *http.Request, slug string) (err error) {
+	category, err := h.ICategoryService.GetDetailBySlug(slug)
+	if err != nil {
+		return
+	}
+	err = h.writeCachedJSON(w, r, RouteCategory, &category)
+	return
+}
+
+func (h *CategoryHandler) GetTree(w http.ResponseWriter, r *http.Request) (err error) {
+	categories, err := h.ICategoryService.GetTree()
 	if err != nil {
 		return
 	}
-	output, err := json.MarshalIndent(&categories, "", "\t")
+	err = h.writeCachedJSON(w, r, RouteCategories, &categories)
+	return
+}
+
+func (h *CategoryHandler) GetBreadcrumbs(w http.ResponseWriter, r *http.Request, slug string) (err error) {
+	categories, err := h.ICategoryService.GetBreadcrumbs(slug)
 	if err != nil {
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(output)
+	err = h.writeCachedJSON(w, r, RouteCategory, &categories)
 	return
 }
 
@@ -45,24 +78,96 @@ func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) (err er
 	r.Body.Read(body)
 	var categoryDto dto.CategoryModel
 	json.Unmarshal(body, &categoryDto)
-	err = h.ICategoryService.Create(categoryDto)
+	err = h.ICategoryService.Create(UserFromRequest(r), categoryDto)
 	return
 }
 
+// Update replaces the category with the body: fields it leaves out are cleared, so
+// a category without parent_id becomes a root. The request must name the version
+// it is based on, see expectedVersion.
 func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) (err error) {
 	slug := path.Base(r.URL.Path)
-	categoryDto, err := h.ICategoryService.GetBySlug(slug)
-	len := r.ContentLength
-	body := make([]byte, len)
-	r.Body.Read(body)
-	json.Unmarshal(body, &categoryDto)
-	err = h.ICategoryService.Update(categoryDto)
+	// the detail is what GetBySlug responds with, so If-Match is checked against its ETag
+	current, err := h.ICategoryService.GetDetailBySlug(slug)
+	if err != nil {
+		return
+	}
+	body, err := readBody(w, r)
+	if err != nil {
+		return
+	}
+	version, err := expectedVersion(r, body, &current, current.Version)
+	if err != nil {
+		return
+	}
+	var categoryDto dto.CategoryModel
+	err = decodeBody(body, &categoryDto)
+	if err != nil {
+		return
+	}
+	err = h.save(w, r, current, categoryDto, version)
+	return
+}
+
+// Patch changes the category by the merge patch or JSON Patch in the body, see
+// applyPatch. The request must name the version it is based on, see expectedVersion.
+func (h *CategoryHandler) Patch(w http.ResponseWriter, r *http.Request) (err error) {
+	slug := path.Base(r.URL.Path)
+	current, err := h.ICategoryService.GetDetailBySlug(slug)
+	if err != nil {
+		return
+	}
+	body, err := readBody(w, r)
+	if err != nil {
+		return
+	}
+	version, err := expectedVersion(r, body, &current, current.Version)
+	if err != nil {
+		return
+	}
+	var categoryDto dto.CategoryModel
+	err = applyPatch(r, body, &current, &categoryDto)
+	if err != nil {
+		return
+	}
+	err = h.save(w, r, current, categoryDto, version)
+	return
+}
+
+// save stores categoryDto, the new state of the current category, and responds
+// with the updated category.
+func (h *CategoryHandler) save(w http.ResponseWriter, r *http.Request, current dto.CategoryModel, categoryDto dto.CategoryModel, version int) (err error) {
+	categoryDto.Id = current.Id
+	categoryDto.Version = version
+	err = h.ICategoryService.Update(UserFromRequest(r), categoryDto)
+	if err != nil {
+		return
+	}
+	updated, err := h.ICategoryService.GetDetailBySlug(categoryDto.Slug)
+	if err != nil {
+		return
+	}
+	err = h.writeCachedJSON(w, r, RouteCategory, &updated)
 	return
 }
 
 func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) (err error) {
 	slug := path.Base(r.URL.Path)
 	categoryDto, err := h.ICategoryService.GetBySlug(slug)
-	err = h.ICategoryService.Delete(categoryDto)
+	err = h.ICategoryService.Delete(UserFromRequest(r), categoryDto)
+	return
+}
+
+func (h *CategoryHandler) Reorder(w http.ResponseWriter, r *http.Request) (err error) {
+	body, err := readBody(w, r)
+	if err != nil {
+		return
+	}
+	var orderDto dto.CategoryOrderModel
+	err = decodeBody(body, &orderDto)
+	if err != nil {
+		return
+	}
+	err = h.ICategoryService.Reorder(UserFromRequest(r), orderDto)
 	return
 }
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"backend/app/common/dto"
+	"io"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
@@ -9,6 +11,7 @@ import (
 	mocks "backend/mocks/service"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestCategoryHandler_GetAll(t *testing.T) {
@@ -29,7 +32,7 @@ func TestCategoryHandler_GetAll(t *testing.T) {
 
 	s.On("GetAll").Return(categoryDtos, nil)
 
-	h := NewCategoryHandler(s)
+	h := NewCategoryHandler(s, nil)
 
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest("GET", "/categories/", nil)
@@ -41,7 +44,7 @@ func TestCategoryHandler_GetAll(t *testing.T) {
 }
 
 func TestCategoryHandler_Create(t *testing.T) {
-	categoryDto := dto.NewCategoryModel(1, "testCategory1", "test-category-1")
+	categoryDto := dto.NewCategoryModel(1, "testCategory1", "test-category-1", 0)
 	json := strings.NewReader(`{
 		"id": 1,
 		"name": "testCategory1",
@@ -49,13 +52,13 @@ func TestCategoryHandler_Create(t *testing.T) {
 	}`)
 
 	w := httptest.NewRecorder()
-	r := httptest.NewRequest("POST", "/categories/", json)
+	r := WithUser(httptest.NewRequest("POST", "/categories/", json), editor)
 
 	s := new(mocks.ICategoryService)
 
-	s.On("Create", categoryDto).Return(nil)
+	s.On("Create", editor, categoryDto).Return(nil)
 
-	h := NewCategoryHandler(s)
+	h := NewCategoryHandler(s, nil)
 
 	err := h.Create(w, r)
 
@@ -64,7 +67,7 @@ func TestCategoryHandler_Create(t *testing.T) {
 }
 
 func TestCategoryHandler_Update(t *testing.T) {
-	categoryDto := dto.NewCategoryModel(1, "testCategory1", "test-category-1")
+	categoryDto := dto.NewCategoryModel(1, "testCategory1", "test-category-1", 0)
 	json := strings.NewReader(`{
 		"id": 1,
 		"name": "testCategory1",
@@ -73,37 +76,205 @@ func TestCategoryHandler_Update(t *testing.T) {
 	slug := "test-category-1"
 
 	w := httptest.NewRecorder()
-	r := httptest.NewRequest("PUT", "/categories/test-category-1/", json)
+	r := WithUser(httptest.NewRequest("PUT", "/categories/test-category-1/", json), editor)
 
 	s := new(mocks.ICategoryService)
 
-	s.On("GetBySlug", slug).Return(categoryDto, nil)
-	s.On("Update", categoryDto).Return(nil)
+	s.On("GetDetailBySlug", slug).Return(categoryDto, nil)
+	s.On("Update", editor, categoryDto).Return(nil)
 
-	h := NewCategoryHandler(s)
+	h := NewCategoryHandler(s, nil)
 
+	etag, _ := entityTag(&categoryDto)
+	r.Header.Set("If-Match", etag)
 	err := h.Update(w, r)
 
+	assert.NoError(t, err)
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	s.AssertExpectations(t)
+}
+
+func TestCategoryHandler_Update_chunked(t *testing.T) {
+	categoryDto := dto.NewCategoryModel(1, "testCategory1", "test-category-1", 0)
+	updated := categoryDto
+	updated.Description = "testDescription1"
+	// a body of unknown length, as sent with Transfer-Encoding: chunked
+	body := io.MultiReader(strings.NewReader(`{"name": "testCategory1", "slug": "test-category-1", `), strings.NewReader(`"description": "testDescription1"}`))
+	r := WithUser(httptest.NewRequest("PUT", "/categories/test-category-1/", body), editor)
+	r.ContentLength = -1
+	r.Header.Set("If-Match", "*")
+
+	s := new(mocks.ICategoryService)
+	s.On("GetDetailBySlug", "test-category-1").Return(categoryDto, nil)
+	s.On("Update", editor, updated).Return(nil)
+
+	err := NewCategoryHandler(s, nil).Update(httptest.NewRecorder(), r)
+
 	assert.NoError(t, err)
 	s.AssertExpectations(t)
 }
 
 func TestCategoryHandler_Delete(t *testing.T) {
-	categoryDto := dto.NewCategoryModel(1, "testCategory1", "test-category-1")
+	categoryDto := dto.NewCategoryModel(1, "testCategory1", "test-category-1", 0)
 	slug := "test-category-1"
 
 	w := httptest.NewRecorder()
-	r := httptest.NewRequest("DELETE", "/categories/test-category-1/", nil)
+	r := WithUser(httptest.NewRequest("DELETE", "/categories/test-category-1/", nil), editor)
 
 	s := new(mocks.ICategoryService)
 
 	s.On("GetBySlug", slug).Return(categoryDto, nil)
-	s.On("Delete", categoryDto).Return(nil)
+	s.On("Delete", editor, categoryDto).Return(nil)
 
-	h := NewCategoryHandler(s)
+	h := NewCategoryHandler(s, nil)
 
 	err := h.Delete(w, r)
 
 	assert.NoError(t, err)
 	s.AssertExpectations(t)
 }
+
+func TestCategoryHandler_GetTree(t *testing.T) {
+	categoryDtos := []dto.CategoryModel{
+		{
+			Id:   1,
+			Name: "testCategory1",
+			Slug: "test-category-1",
+			Children: []dto.CategoryModel{
+				dto.NewCategoryModel(2, "testSubCategory1", "test-sub-category-1", 1),
+			},
+		},
+	}
+
+	s := new(mocks.ICategoryService)
+
+	s.On("GetTree").Return(categoryDtos, nil)
+
+	h := NewCategoryHandler(s, nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/categories/tree", nil)
+
+	err := h.GetTree(w, r)
+
+	assert.NoError(t, err)
+	assert.Contains(t, w.Body.String(), `"children"`)
+	s.AssertExpectations(t)
+}
+
+func TestCategoryHandler_GetBreadcrumbs(t *testing.T) {
+	categoryDtos := []dto.CategoryModel{
+		dto.NewCategoryModel(1, "testCategory1", "test-category-1", 0),
+		dto.NewCategoryModel(2, "testSubCategory1", "test-sub-category-1", 1),
+	}
+	slug := "test-sub-category-1"
+
+	s := new(mocks.ICategoryService)
+
+	s.On("GetBreadcrumbs", slug).Return(categoryDtos, nil)
+
+	h := NewCategoryHandler(s, nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/categories/test-sub-category-1/breadcrumbs", nil)
+
+	err := h.GetBreadcrumbs(w, r, slug)
+
+	assert.NoError(t, err)
+	s.AssertExpectations(t)
+}
+
+func TestCategoryHandler_Reorder(t *testing.T) {
+	orderDto := dto.CategoryOrderModel{Ids: []int{3, 1, 2}}
+	json := strings.NewReader(`{
+		"ids": [3, 1, 2]
+	}`)
+
+	w := httptest.NewRecorder()
+	r := WithUser(httptest.NewRequest("PUT", "/categories/reorder", json), editor)
+
+	s := new(mocks.ICategoryService)
+
+	s.On("Reorder", editor, orderDto).Return(nil)
+
+	h := NewCategoryHandler(s, nil)
+
+	err := h.Reorder(w, r)
+
+	assert.NoError(t, err)
+	s.AssertExpectations(t)
+}
+
+func TestCategoryHandler_Reorder_chunked(t *testing.T) {
+	orderDto := dto.CategoryOrderModel{Ids: []int{3, 1, 2}}
+	// a body of unknown length, as sent with Transfer-Encoding: chunked
+	body := io.MultiReader(strings.NewReader(`{"ids": [3, `), strings.NewReader(`1, 2]}`))
+	r := WithUser(httptest.NewRequest("PUT", "/categories/reorder", body), editor)
+	r.ContentLength = -1
+
+	s := new(mocks.ICategoryService)
+	s.On("Reorder", editor, orderDto).Return(nil)
+
+	err := NewCategoryHandler(s, nil).Reorder(httptest.NewRecorder(), r)
+
+	assert.NoError(t, err)
+	s.AssertExpectations(t)
+}
+
+func TestCategoryHandler_Reorder_malformedBody(t *testing.T) {
+	r := WithUser(httptest.NewRequest("PUT", "/categories/reorder", strings.NewReader(`{"ids": [3,`)), editor)
+
+	s := new(mocks.ICategoryService)
+
+	err := NewCategoryHandler(s, nil).Reorder(httptest.NewRecorder(), r)
+
+	assert.ErrorIs(t, err, ErrMalformedBody)
+	assert.Equal(t, http.StatusBadRequest, StatusCode(err))
+	s.AssertNotCalled(t, "Reorder", mock.Anything, mock.Anything)
+}
+
+func TestCategoryHandler_GetAllWithCounts(t *testing.T) {
+	categoryDtos := []dto.CategoryModel{
+		{
+			Id:    1,
+			Name:  "testCategory1",
+			Slug:  "test-category-1",
+			Stats: &dto.CategoryStatsModel{PostCount: 3},
+		},
+	}
+
+	s := new(mocks.ICategoryService)
+
+	s.On("GetAllWithCounts").Return(categoryDtos, nil)
+
+	h := NewCategoryHandler(s, nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/categories/?with-counts=true", nil)
+
+	err := h.GetAll(w, r)
+
+	assert.NoError(t, err)
+	assert.Contains(t, w.Body.String(), `"post_count":3`)
+	s.AssertExpectations(t)
+}
+
+func TestCategoryHandler_GetBySlug(t *testing.T) {
+	categoryDto := dto.NewCategoryModel(1, "testCategory1", "test-category-1", 0)
+	categoryDto.Stats = &dto.CategoryStatsModel{PostCount: 3}
+	slug := "test-category-1"
+
+	s := new(mocks.ICategoryService)
+
+	s.On("GetDetailBySlug", slug).Return(categoryDto, nil)
+
+	h := NewCategoryHandler(s, nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/categories/test-category-1", nil)
+
+	err := h.GetBySlug(w, r, slug)
+
+	assert.NoError(t, err)
+	s.AssertExpectations(t)
+}
@@ -0,0 +1,287 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Encoding is a content coding responses can be compressed with, e.g. "gzip".
+// NewWriter returns a writer compressing into w; closing it flushes the rest of
+// the compressed data, but does not close w.
+type Encoding struct {
+	Name      string
+	NewWriter func(w io.Writer) io.WriteCloser
+}
+
+// GzipEncoding compresses with gzip at level, e.g. gzip.DefaultCompression.
+// The writers are pooled, as each holds a few hundred kilobytes of state.
+func GzipEncoding(level int) Encoding {
+	pool := sync.Pool{New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, level)
+		return w
+	}}
+	return Encoding{"gzip", func(w io.Writer) io.WriteCloser {
+		gz := pool.Get().(*gzip.Writer)
+		gz.Reset(w)
+		return pooledWriter{gz, func() { pool.Put(gz) }}
+	}}
+}
+
+// DeflateEncoding compresses with deflate at level, in the zlib format HTTP
+// defines the "deflate" coding as.
+func DeflateEncoding(level int) Encoding {
+	pool := sync.Pool{New: func() interface{} {
+		w, _ := zlib.NewWriterLevel(nil, level)
+		return w
+	}}
+	return Encoding{"deflate", func(w io.Writer) io.WriteCloser {
+		zw := pool.Get().(*zlib.Writer)
+		zw.Reset(w)
+		return pooledWriter{zw, func() { pool.Put(zw) }}
+	}}
+}
+
+// BrotliEncoding compresses with brotli at level, from 0 (fastest) to 11
+// (smallest); a negative level, such as gzip.DefaultCompression, stands for
+// brotli's default.
+func BrotliEncoding(level int) Encoding {
+	if level < brotli.BestSpeed {
+		level = brotli.DefaultCompression
+	}
+	pool := sync.Pool{New: func() interface{} {
+		return brotli.NewWriterLevel(nil, level)
+	}}
+	return Encoding{"br", func(w io.Writer) io.WriteCloser {
+		bw := pool.Get().(*brotli.Writer)
+		bw.Reset(w)
+		return pooledWriter{bw, func() { pool.Put(bw) }}
+	}}
+}
+
+type pooledWriter struct {
+	io.WriteCloser
+	release func()
+}
+
+func (w pooledWriter) Close() (err error) {
+	err = w.WriteCloser.Close()
+	w.release()
+	return
+}
+
+// Compression compresses responses of at least MinSize bytes with the encoding
+// the client prefers in Accept-Encoding. Encodings are listed in the order the
+// server prefers them when the client has no preference.
+type Compression struct {
+	Encodings []Encoding
+	MinSize   int
+}
+
+// defaultMinSize is the smallest body compressed when MinSize is not set;
+// smaller ones hardly shrink, if at all.
+const defaultMinSize = 1024
+
+// Wrap compresses the responses of h. Only bodies of a compressible type are
+// compressed, and not those h has encoded itself, partial content or bodies
+// smaller than MinSize. A compressed body gets the codedETag of its ETag, which
+// the conditional requests of the handlers accept in place of the ETag.
+func (c Compression) Wrap(h http.HandlerFunc) http.HandlerFunc {
+	if len(c.Encodings) == 0 {
+		return h
+	}
+	minSize := c.MinSize
+	if minSize <= 0 {
+		minSize = defaultMinSize
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		encoding, ok := c.negotiate(r.Header.Get("Accept-Encoding"))
+		cw := &compressWriter{ResponseWriter: w, minSize: minSize, encoding: encoding, identity: !ok, ifNoneMatch: r.Header.Get("If-None-Match")}
+		defer cw.close()
+		h(cw, r)
+	}
+}
+
+// negotiate picks the encoding with the highest q value in the Accept-Encoding
+// header, ties going to the one listed first in Encodings. ok is false when the
+// client accepts none of them, or only identity.
+func (c Compression) negotiate(header string) (encoding Encoding, ok bool) {
+	if header == "" {
+		return
+	}
+	weights := make(map[string]float64)
+	for _, item := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(item, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		weight := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				weight, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+		weights[name] = weight
+	}
+	best := 0.0
+	for _, candidate := range c.Encodings {
+		weight, listed := weights[candidate.Name]
+		if !listed {
+			weight = weights["*"]
+		}
+		if weight > best {
+			encoding, ok, best = candidate, true, weight
+		}
+	}
+	return
+}
+
+// compressWriter holds the body back until it reaches minSize, then decides
+// whether to compress it. A smaller body is written as it is when the handler
+// returns.
+type compressWriter struct {
+	http.ResponseWriter
+	minSize  int
+	encoding Encoding
+	// identity is set when the client accepts no encoding.
+	identity bool
+	// ifNoneMatch is the header of the request, to answer 304 with the tag it lists.
+	ifNoneMatch string
+	status      int
+	buffer      bytes.Buffer
+	// decided is set once the header is written; writer is the compressor then,
+	// or nil when the body is written as it is.
+	decided bool
+	writer  io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.status != 0 || w.decided {
+		return
+	}
+	w.status = status
+	// informational responses come before the final one and carry no body
+	if status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		w.status = 0
+		return
+	}
+	// so does a response without content, which is not worth holding back
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		w.start(false)
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (n int, err error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		if w.buffer.Len()+len(data) < w.minSize {
+			return w.buffer.Write(data)
+		}
+		w.start(w.compressible())
+		if _, err = w.flushBuffer(); err != nil {
+			return
+		}
+	}
+	if w.writer != nil {
+		return w.writer.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// compressible reports whether the response, which has reached minSize, should
+// be compressed.
+func (w *compressWriter) compressible() bool {
+	header := w.Header()
+	if w.identity || w.status == http.StatusPartialContent || header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	return compressibleType(header.Get("Content-Type"))
+}
+
+// compressibleType reports whether a body of the media type is text, which
+// compresses well, rather than an image, archive or other already compressed data.
+func compressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"), strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// start writes the header, compressed or not, after which the body goes out.
+func (w *compressWriter) start(compress bool) {
+	w.decided = true
+	header := w.Header()
+	if compressibleType(header.Get("Content-Type")) && header.Get("Content-Encoding") == "" {
+		// the body depends on Accept-Encoding, even when it is not compressed
+		addVary(header, "Accept-Encoding")
+	}
+	etag := header.Get("ETag")
+	if compress {
+		header.Set("Content-Encoding", w.encoding.Name)
+		header.Del("Content-Length")
+		if etag != "" {
+			header.Set("ETag", codedETag(etag, w.encoding.Name))
+		}
+		w.writer = w.encoding.NewWriter(w.ResponseWriter)
+	}
+	// a 304 has no body, but names the one the client has: the compressed one
+	// when the client sent back its tag
+	if w.status == http.StatusNotModified && !w.identity && etag != "" && etagMatches(w.ifNoneMatch, codedETag(etag, w.encoding.Name)) {
+		header.Set("ETag", codedETag(etag, w.encoding.Name))
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func (w *compressWriter) flushBuffer() (n int64, err error) {
+	if w.writer != nil {
+		return w.buffer.WriteTo(w.writer)
+	}
+	return w.buffer.WriteTo(w.ResponseWriter)
+}
+
+// close writes what the handler left: the header and a body smaller than
+// minSize, or the end of the compressed body.
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.start(false)
+		w.flushBuffer()
+	}
+	if w.writer != nil {
+		w.writer.Close()
+	}
+}
+
+// addVary adds a field name to the Vary header unless it lists it already.
+func addVary(header http.Header, name string) {
+	for _, value := range header.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if field == "*" || strings.EqualFold(field, name) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", name)
+}
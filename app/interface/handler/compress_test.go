@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompression(t *testing.T) {
+	compression := Compression{Encodings: []Encoding{GzipEncoding(gzip.DefaultCompression), DeflateEncoding(gzip.DefaultCompression)}, MinSize: 100}
+	large := `{"content":"` + strings.Repeat("lorem ipsum ", 100) + `"}`
+	respond := func(contentType string, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			// written in pieces, as the JSON of a list is
+			for i := 0; i < len(body); i += 64 {
+				end := i + 64
+				if end > len(body) {
+					end = len(body)
+				}
+				io.WriteString(w, body[i:end])
+			}
+		}
+	}
+	serve := func(h http.HandlerFunc, acceptEncoding string) (w *httptest.ResponseRecorder) {
+		r := httptest.NewRequest("GET", "/api/v1/posts/", nil)
+		if acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w = httptest.NewRecorder()
+		compression.Wrap(h)(w, r)
+		return
+	}
+
+	t.Run("gzip", func(t *testing.T) {
+		w := serve(respond("application/json", large), "gzip, deflate, br")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		assert.Less(t, w.Body.Len(), len(large))
+		reader, err := gzip.NewReader(w.Body)
+		assert.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, large, string(body))
+	})
+
+	t.Run("deflate preferred by the client", func(t *testing.T) {
+		w := serve(respond("application/json", large), "gzip;q=0.5, deflate")
+
+		assert.Equal(t, "deflate", w.Header().Get("Content-Encoding"))
+		reader, err := zlib.NewReader(w.Body)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(reader)
+		assert.Equal(t, large, string(body))
+	})
+
+	t.Run("brotli", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/api/v1/posts/", nil)
+		r.Header.Set("Accept-Encoding", "gzip, deflate, br")
+		w := httptest.NewRecorder()
+		brotliFirst := Compression{Encodings: []Encoding{BrotliEncoding(gzip.DefaultCompression), GzipEncoding(gzip.DefaultCompression)}, MinSize: 100}
+
+		brotliFirst.Wrap(respond("application/json", large))(w, r)
+
+		assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+		assert.Less(t, w.Body.Len(), len(large))
+		body, err := io.ReadAll(brotli.NewReader(w.Body))
+		assert.NoError(t, err)
+		assert.Equal(t, large, string(body))
+	})
+
+	t.Run("small body", func(t *testing.T) {
+		w := serve(respond("application/json", `{"a":1}`), "gzip")
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		assert.Equal(t, `{"a":1}`, w.Body.String())
+	})
+
+	t.Run("no accepted encoding", func(t *testing.T) {
+		for _, acceptEncoding := range []string{"", "identity", "br", "gzip;q=0, *;q=0", "*;q=0"} {
+			w := serve(respond("application/json", large), acceptEncoding)
+
+			assert.Empty(t, w.Header().Get("Content-Encoding"), acceptEncoding)
+			assert.Equal(t, large, w.Body.String(), acceptEncoding)
+		}
+	})
+
+	t.Run("any encoding", func(t *testing.T) {
+		w := serve(respond("application/json", large), "*")
+
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("incompressible type", func(t *testing.T) {
+		w := serve(respond("image/png", large), "gzip")
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Empty(t, w.Header().Get("Vary"))
+		assert.Equal(t, large, w.Body.String())
+	})
+
+	t.Run("encoded by the handler", func(t *testing.T) {
+		w := serve(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "br")
+			respond("image/svg+xml", large)(w, r)
+		}, "gzip")
+
+		assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+		assert.Equal(t, large, w.Body.String())
+	})
+
+	t.Run("partial content", func(t *testing.T) {
+		w := serve(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Header().Set("Content-Range", "bytes 0-1199/5000")
+			w.WriteHeader(http.StatusPartialContent)
+			io.WriteString(w, large)
+		}, "gzip")
+
+		assert.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, large, w.Body.String())
+	})
+
+	t.Run("status and headers kept", func(t *testing.T) {
+		w := serve(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Length", "1207")
+			w.Header().Set("Vary", "Authorization")
+			w.Header().Set("ETag", `"abc"`)
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, large)
+		}, "gzip")
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Empty(t, w.Header().Get("Content-Length"))
+		assert.Equal(t, []string{"Authorization", "Accept-Encoding"}, w.Header().Values("Vary"))
+		assert.Equal(t, `"abc-gzip"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("ETag of an uncompressed body kept", func(t *testing.T) {
+		w := serve(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"abc"`)
+			io.WriteString(w, `{}`)
+		}, "gzip")
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, `"abc"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		w := serve(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotModified)
+		}, "gzip")
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Zero(t, w.Body.Len())
+	})
+
+	t.Run("not modified names the compressed body", func(t *testing.T) {
+		notModified := func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"abc"`)
+			w.WriteHeader(http.StatusNotModified)
+		}
+		for ifNoneMatch, etag := range map[string]string{`"abc-gzip"`: `"abc-gzip"`, `"abc"`: `"abc"`} {
+			r := httptest.NewRequest("GET", "/api/v1/posts/", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			r.Header.Set("If-None-Match", ifNoneMatch)
+			w := httptest.NewRecorder()
+
+			compression.Wrap(notModified)(w, r)
+
+			assert.Equal(t, http.StatusNotModified, w.Code)
+			assert.Equal(t, etag, w.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("nothing written", func(t *testing.T) {
+		w := serve(func(w http.ResponseWriter, r *http.Request) {}, "gzip")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Zero(t, w.Body.Len())
+	})
+
+	t.Run("off", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/api/v1/posts/", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+
+		Compression{}.Wrap(respond("application/json", large))(w, r)
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, large, w.Body.String())
+	})
+}
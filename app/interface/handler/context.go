@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"backend/app/common/dto"
+	"context"
+	"net/http"
+)
+
+type contextKey int
+
+const userContextKey contextKey = iota
+
+// WithUser returns a copy of the request carrying the authenticated user.
+func WithUser(r *http.Request, userDto dto.UserModel) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userContextKey, userDto))
+}
+
+// UserFromRequest returns the authenticated user, or the zero UserModel for anonymous requests.
+func UserFromRequest(r *http.Request) (userDto dto.UserModel) {
+	userDto, _ = r.Context().Value(userContextKey).(dto.UserModel)
+	return
+}
@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"backend/app/common/dto"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var editor = dto.NewUserModel(2, "testeditor", "editor")
+
+func TestUserFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/posts/", nil)
+
+	assert.Equal(t, dto.UserModel{}, UserFromRequest(r))
+	assert.Equal(t, editor, UserFromRequest(WithUser(r, editor)))
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"backend/app/domain/service"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// realm is announced in the WWW-Authenticate challenge.
+const realm = "golang_blog_API"
+
+// StatusCode maps an error returned by a handler to the HTTP status to respond with.
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows),
+		errors.Is(err, fs.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrCategoryCycle),
+		errors.Is(err, service.ErrInvalidCategoryOrder),
+		errors.Is(err, service.ErrMixedParents),
+		errors.Is(err, service.ErrIncompleteOrder),
+		errors.Is(err, service.ErrInvalidRole),
+		errors.Is(err, service.ErrEmptyPassword),
+		errors.Is(err, service.ErrTwoFactorNotEnabled),
+		errors.Is(err, service.ErrInvalidScope),
+		errors.Is(err, service.ErrInvalidMediaName),
+		errors.Is(err, service.ErrInvalidInput),
+		errors.Is(err, ErrMalformedAuthorization),
+		errors.Is(err, ErrMalformedBody),
+		errors.Is(err, ErrMissingMediaFile):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrMediaTooLarge),
+		errors.Is(err, ErrBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, service.ErrUnsupportedMediaType),
+		errors.Is(err, ErrUnsupportedPatch):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, service.ErrConflict),
+		errors.Is(err, ErrPatchTestFailed):
+		return http.StatusConflict
+	case errors.Is(err, ErrUnprocessablePatch):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, ErrPreconditionRequired):
+		return http.StatusPreconditionRequired
+	case errors.Is(err, service.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrTooManyAttempts):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// WriteError responds with the status of err. Failed authentication and missing
+// permissions carry an RFC 6750 WWW-Authenticate challenge, throttled logins a
+// Retry-After header.
+func WriteError(w http.ResponseWriter, err error) {
+	if challenge := authenticateChallenge(err); challenge != "" {
+		w.Header().Set("WWW-Authenticate", challenge)
+	}
+	var throttled *service.ThrottledError
+	if errors.As(err, &throttled) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	}
+	http.Error(w, err.Error(), StatusCode(err))
+}
+
+func authenticateChallenge(err error) string {
+	switch {
+	case errors.Is(err, ErrMalformedAuthorization):
+		return bearerChallenge("invalid_request", err)
+	case errors.Is(err, service.ErrInvalidToken):
+		return bearerChallenge("invalid_token", err)
+	case errors.Is(err, service.ErrUnauthenticated):
+		// no credentials at all: the challenge carries no error code
+		return fmt.Sprintf("Bearer realm=%q", realm)
+	case errors.Is(err, service.ErrForbidden):
+		return bearerChallenge("insufficient_scope", err)
+	default:
+		return ""
+	}
+}
+
+func bearerChallenge(code string, err error) string {
+	// error_description must stay within the quoted-string characters RFC 6750 allows
+	description := strings.Map(func(c rune) rune {
+		if c == '"' || c == '\\' || c < 0x20 || c > 0x7e {
+			return '\''
+		}
+		return c
+	}, err.Error())
+	return fmt.Sprintf("Bearer realm=%q, error=%q, error_description=%q", realm, code, description)
+}
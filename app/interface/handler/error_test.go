@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"backend/app/domain/service"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusCode(t *testing.T) {
+	assert.Equal(t, http.StatusNotFound, StatusCode(sql.ErrNoRows))
+	assert.Equal(t, http.StatusNotFound, StatusCode(fmt.Errorf("wrapped: %w", sql.ErrNoRows)))
+	assert.Equal(t, http.StatusBadRequest, StatusCode(service.ErrCategoryCycle))
+	assert.Equal(t, http.StatusBadRequest, StatusCode(service.ErrInvalidCategoryOrder))
+	assert.Equal(t, http.StatusBadRequest, StatusCode(fmt.Errorf("categories 3 and 5: %w", service.ErrMixedParents)))
+	assert.Equal(t, http.StatusBadRequest, StatusCode(service.ErrInvalidRole))
+	assert.Equal(t, http.StatusBadRequest, StatusCode(service.ErrEmptyPassword))
+	assert.Equal(t, http.StatusBadRequest, StatusCode(ErrMalformedAuthorization))
+	assert.Equal(t, http.StatusBadRequest, StatusCode(service.ErrInvalidScope))
+	assert.Equal(t, http.StatusNotFound, StatusCode(&fs.PathError{Op: "open", Path: "test.png", Err: fs.ErrNotExist}))
+	assert.Equal(t, http.StatusBadRequest, StatusCode(ErrMissingMediaFile))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, StatusCode(fmt.Errorf("%w: the limit is 1 bytes", service.ErrMediaTooLarge)))
+	assert.Equal(t, http.StatusUnsupportedMediaType, StatusCode(service.ErrUnsupportedMediaType))
+	assert.Equal(t, http.StatusUnauthorized, StatusCode(service.ErrUnauthenticated))
+	assert.Equal(t, http.StatusForbidden, StatusCode(service.ErrForbidden))
+	assert.Equal(t, http.StatusTooManyRequests, StatusCode(&service.ThrottledError{RetryAfter: time.Second}))
+	assert.Equal(t, http.StatusInternalServerError, StatusCode(errors.New("unexpected")))
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		status    int
+		challenge string
+	}{
+		{"no token", service.ErrUnauthenticated, http.StatusUnauthorized, `Bearer realm="golang_blog_API"`},
+		{"invalid token", fmt.Errorf("%w: token is expired", service.ErrInvalidToken), http.StatusUnauthorized, `Bearer realm="golang_blog_API", error="invalid_token", error_description="authentication required: invalid token: token is expired"`},
+		{"malformed header", ErrMalformedAuthorization, http.StatusBadRequest, `Bearer realm="golang_blog_API", error="invalid_request", error_description="malformed Authorization header, expected 'Bearer <token>'"`},
+		{"forbidden", service.ErrForbidden, http.StatusForbidden, `Bearer realm="golang_blog_API", error="insufficient_scope", error_description="you don't have permission"`},
+		{"not found", sql.ErrNoRows, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			WriteError(w, tt.err)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.challenge, w.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestWriteError_RetryAfter(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	WriteError(w, &service.ThrottledError{RetryAfter: 1500 * time.Millisecond})
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// writeJSON writes v as the JSON body of the response: compact, or indented with
+// tabs when the request asks for ?pretty=true. A status other than 200 is written
+// by the caller after setting the headers it needs and before calling writeJSON.
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) (err error) {
+	w.Header().Set("Content-Type", "application/json")
+	err = encodeJSON(w, v, prettyJSON(r))
+	return
+}
+
+// prettyJSON reports whether the request asks for indented JSON.
+func prettyJSON(r *http.Request) bool {
+	pretty, _ := strconv.ParseBool(r.URL.Query().Get("pretty"))
+	return pretty
+}
+
+// encodeJSON writes v as json.Marshal would, indented if pretty. Slices are
+// encoded one element at a time, so that a long list of posts is never held in
+// memory as a whole. Once part of a list is written, an error can no longer
+// change the status of the response; the DTOs do not fail to encode though.
+func encodeJSON(w io.Writer, v interface{}, pretty bool) (err error) {
+	list := reflect.ValueOf(v)
+	for list.Kind() == reflect.Ptr && !list.IsNil() {
+		list = list.Elem()
+	}
+	if list.Kind() != reflect.Slice || list.IsNil() || list.Type().Elem().Kind() == reflect.Uint8 {
+		var output []byte
+		if pretty {
+			output, err = json.MarshalIndent(v, "", "\t")
+		} else {
+			output, err = json.Marshal(v)
+		}
+		if err != nil {
+			return
+		}
+		_, err = w.Write(output)
+		return
+	}
+
+	separator, end := ",", "]"
+	if pretty {
+		separator, end = ",\n\t", "\n]"
+	}
+	if list.Len() == 0 {
+		end = "]"
+	}
+	if _, err = io.WriteString(w, "["); err != nil {
+		return
+	}
+	for i := 0; i < list.Len(); i++ {
+		var output []byte
+		if pretty {
+			output, err = json.MarshalIndent(list.Index(i).Addr().Interface(), "\t", "\t")
+		} else {
+			output, err = json.Marshal(list.Index(i).Addr().Interface())
+		}
+		if err != nil {
+			return
+		}
+		switch {
+		case i > 0:
+			_, err = io.WriteString(w, separator)
+		case pretty:
+			_, err = io.WriteString(w, "\n\t")
+		}
+		if err != nil {
+			return
+		}
+		if _, err = w.Write(output); err != nil {
+			return
+		}
+	}
+	_, err = io.WriteString(w, end)
+	return
+}
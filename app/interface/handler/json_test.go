@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"backend/app/common/dto"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeJSON(t *testing.T) {
+	posts := []dto.PostModel{{Id: 1, Title: "<Hello>"}, {Id: 2, Title: "World"}}
+
+	for _, v := range []interface{}{posts, &posts, []dto.PostModel{}, []dto.PostModel(nil), dto.PostModel{Id: 1}, []byte("bytes"), map[string]int{"a": 1}} {
+		for _, pretty := range []bool{false, true} {
+			var output bytes.Buffer
+			err := encodeJSON(&output, v, pretty)
+
+			expected, _ := json.Marshal(v)
+			if pretty {
+				expected, _ = json.MarshalIndent(v, "", "\t")
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), output.String(), "%#v pretty %v", v, pretty)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	for query, expected := range map[string]string{"": `{"a":1}`, "?pretty=true": "{\n\t\"a\": 1\n}", "?pretty=no": `{"a":1}`} {
+		r := httptest.NewRequest("GET", "/api/v1/posts/"+query, nil)
+		w := httptest.NewRecorder()
+
+		err := writeJSON(w, r, map[string]int{"a": 1})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, expected, w.Body.String(), query)
+	}
+}
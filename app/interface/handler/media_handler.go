@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/service"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrMissingMediaFile is returned for an upload without a multipart "file" field.
+var ErrMissingMediaFile = errors.New("multipart/form-data field \"file\" is missing")
+
+// maxAltTextSize limits the "alt_text" field of an upload.
+const maxAltTextSize = 4096
+
+type IMediaHandler interface {
+	Upload(w http.ResponseWriter, r *http.Request) (err error)
+	GetAll(w http.ResponseWriter, r *http.Request) (err error)
+	Serve(w http.ResponseWriter, r *http.Request, name string) (err error)
+	Update(w http.ResponseWriter, r *http.Request, name string) (err error)
+	Delete(w http.ResponseWriter, r *http.Request, name string) (err error)
+	GetUsage(w http.ResponseWriter, r *http.Request) (err error)
+	GetUsageByName(w http.ResponseWriter, r *http.Request, name string) (err error)
+}
+
+type MediaHandler struct {
+	service.IMediaService
+	// serveVariants makes Serve look for WebP and precompressed variants.
+	serveVariants bool
+}
+
+// NewMediaHandler serves the variants of media files the client accepts when
+// serveVariants is set; looking them up costs a storage request per variant.
+func NewMediaHandler(srv service.IMediaService, serveVariants bool) (iMediaHandler IMediaHandler) {
+	iMediaHandler = &MediaHandler{srv, serveVariants}
+	return
+}
+
+// immutableCacheControl is sent for the files named after the hash of their
+// content, which never change; other files are revalidated on every use.
+const (
+	immutableCacheControl  = "public, max-age=31536000, immutable"
+	revalidateCacheControl = "public, no-cache"
+)
+
+// contentAddressedName matches the names Upload gives files.
+var contentAddressedName = regexp.MustCompile(`^[0-9a-f]{64}\.[a-z]+$`)
+
+// Upload stores the "file" field of a multipart/form-data request, with the
+// "alt_text" field if it comes before the file. It answers 201 with the new file,
+// or 200 when the same content was uploaded before.
+func (h *MediaHandler) Upload(w http.ResponseWriter, r *http.Request) (err error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return ErrMissingMediaFile
+	}
+	altText := ""
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return ErrMissingMediaFile
+		}
+		if err != nil {
+			return err
+		}
+		if part.FormName() == "alt_text" {
+			value, err := io.ReadAll(io.LimitReader(part, maxAltTextSize))
+			if err != nil {
+				return err
+			}
+			altText = string(value)
+			continue
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		mediaDto, created, err := h.IMediaService.Upload(UserFromRequest(r), part, altText)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if created {
+			w.Header().Set("Location", mediaDto.Url)
+			w.WriteHeader(http.StatusCreated)
+		}
+		return writeJSON(w, r, &mediaDto)
+	}
+}
+
+func (h *MediaHandler) GetAll(w http.ResponseWriter, r *http.Request) (err error) {
+	mediaDtos, err := h.IMediaService.GetAll(UserFromRequest(r))
+	if err != nil {
+		return
+	}
+	err = writeJSON(w, r, &mediaDtos)
+	return
+}
+
+// Serve writes the content of a media file with its type, which the browser must
+// not second-guess, an ETag and caching headers. Ranges and conditional requests
+// are answered where the storage can seek. Files of types that are not served and
+// directories are not found.
+func (h *MediaHandler) Serve(w http.ResponseWriter, r *http.Request, name string) (err error) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	var accepted []string
+	if h.serveVariants {
+		w.Header().Set("Vary", "Accept, Accept-Encoding")
+		accepted = acceptedVariants(r)
+	}
+	content, mediaDto, err := h.IMediaService.Open(name, accepted)
+	if errors.Is(err, service.ErrInvalidMediaName) || errors.Is(err, service.ErrUnsupportedMediaType) {
+		http.NotFound(w, r)
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	defer content.Close()
+	w.Header().Set("Content-Type", mediaDto.ContentType)
+	if mediaDto.ContentEncoding != "" {
+		w.Header().Set("Content-Encoding", mediaDto.ContentEncoding)
+	}
+	etag := mediaETag(mediaDto)
+	w.Header().Set("ETag", etag)
+	if mediaDto.VariantOf == "" && contentAddressedName.MatchString(mediaDto.Name) {
+		w.Header().Set("Cache-Control", immutableCacheControl)
+	} else {
+		w.Header().Set("Cache-Control", revalidateCacheControl)
+	}
+	if seeker, ok := content.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, mediaDto.Name, mediaDto.ModifiedAt, seeker)
+		return
+	}
+	if !mediaDto.ModifiedAt.IsZero() {
+		w.Header().Set("Last-Modified", mediaDto.ModifiedAt.UTC().Format(http.TimeFormat))
+	}
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(mediaDto.Size, 10))
+	if r.Method != "HEAD" {
+		io.Copy(w, content)
+	}
+	return
+}
+
+// mediaETag is the name of a file named after its content, otherwise made of its
+// modification time and size; variants have their own.
+func mediaETag(mediaDto dto.MediaModel) string {
+	if mediaDto.VariantOf == "" && contentAddressedName.MatchString(mediaDto.Name) {
+		return `"` + mediaDto.Name + `"`
+	}
+	return `"` + strconv.FormatInt(mediaDto.ModifiedAt.UnixNano(), 36) + "-" + strconv.FormatInt(mediaDto.Size, 36) + `"`
+}
+
+// acceptedVariants lists the variants the request accepts: WebP when the Accept
+// header names it and the content codings of Accept-Encoding, leaving out those
+// with q=0. Wildcards are not taken as support for WebP.
+func acceptedVariants(r *http.Request) (accepted []string) {
+	for _, header := range []string{"Accept", "Accept-Encoding"} {
+		for _, element := range strings.Split(r.Header.Get(header), ",") {
+			params := strings.Split(element, ";")
+			value := strings.ToLower(strings.TrimSpace(params[0]))
+			if value == "" || strings.Contains(value, "*") {
+				continue
+			}
+			refused := false
+			for _, param := range params[1:] {
+				key, q, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.TrimSpace(key) == "q" {
+					weight, err := strconv.ParseFloat(strings.TrimSpace(q), 64)
+					refused = err != nil || weight == 0
+				}
+			}
+			if !refused {
+				accepted = append(accepted, value)
+			}
+		}
+	}
+	return
+}
+
+// Update changes the alt text of a media file.
+func (h *MediaHandler) Update(w http.ResponseWriter, r *http.Request, name string) (err error) {
+	body, err := readBody(w, r)
+	if err != nil {
+		return
+	}
+	var mediaInputDto dto.MediaInputModel
+	err = decodeBody(body, &mediaInputDto)
+	if err != nil {
+		return
+	}
+	mediaDto, err := h.IMediaService.Update(UserFromRequest(r), name, mediaInputDto)
+	if err != nil {
+		return
+	}
+	err = writeJSON(w, r, &mediaDto)
+	return
+}
+
+// GetUsage lists the media files with the posts using them.
+func (h *MediaHandler) GetUsage(w http.ResponseWriter, r *http.Request) (err error) {
+	usageDtos, err := h.IMediaService.GetUsage(UserFromRequest(r))
+	if err != nil {
+		return
+	}
+	if usageDtos == nil {
+		usageDtos = []dto.MediaUsageModel{}
+	}
+	err = writeJSON(w, r, &usageDtos)
+	return
+}
+
+func (h *MediaHandler) GetUsageByName(w http.ResponseWriter, r *http.Request, name string) (err error) {
+	usageDto, err := h.IMediaService.GetUsageByName(UserFromRequest(r), name)
+	if err != nil {
+		return
+	}
+	err = writeJSON(w, r, &usageDto)
+	return
+}
+
+func (h *MediaHandler) Delete(w http.ResponseWriter, r *http.Request, name string) (err error) {
+	err = h.IMediaService.Delete(UserFromRequest(r), name)
+	if err != nil {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return
+}
@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"backend/app/common/dto"
+	"backend/app/domain/service"
+	mocks "backend/mocks/service"
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// seekableContent is a media file of a storage that can seek.
+type seekableContent struct {
+	*strings.Reader
+}
+
+func (seekableContent) Close() error { return nil }
+
+func TestMediaHandler_Upload(t *testing.T) {
+	mediaDto := dto.MediaModel{Name: "abc.png", Url: "/api/v1/media/abc.png", Size: 9, ContentType: "image/png"}
+	newRequest := func(field string) *http.Request {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("alt_text", "testAlt")
+		part, _ := form.CreateFormFile(field, "test.png")
+		part.Write([]byte("testImage"))
+		form.Close()
+		r := httptest.NewRequest("POST", "/api/v1/media/", &body)
+		r.Header.Set("Content-Type", form.FormDataContentType())
+		return WithUser(r, editor)
+	}
+
+	t.Run(
+		"new file",
+		func(t *testing.T) {
+			s := new(mocks.IMediaService)
+			s.On("Upload", editor, mock.MatchedBy(func(r io.Reader) bool {
+				data, _ := io.ReadAll(r)
+				return string(data) == "testImage"
+			}), "testAlt").Return(mediaDto, true, nil)
+
+			h := NewMediaHandler(s, false)
+			w := httptest.NewRecorder()
+
+			err := h.Upload(w, newRequest("file"))
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Equal(t, "/api/v1/media/abc.png", w.Header().Get("Location"))
+			assert.Contains(t, w.Body.String(), `"name":"abc.png"`)
+			s.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"known file",
+		func(t *testing.T) {
+			s := new(mocks.IMediaService)
+			s.On("Upload", editor, mock.Anything, "testAlt").Return(mediaDto, false, nil)
+
+			h := NewMediaHandler(s, false)
+			w := httptest.NewRecorder()
+
+			err := h.Upload(w, newRequest("file"))
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Empty(t, w.Header().Get("Location"))
+		},
+	)
+
+	t.Run(
+		"no file field",
+		func(t *testing.T) {
+			h := NewMediaHandler(new(mocks.IMediaService), false)
+
+			err := h.Upload(httptest.NewRecorder(), newRequest("image"))
+			assert.ErrorIs(t, err, ErrMissingMediaFile)
+
+			err = h.Upload(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/media/", strings.NewReader("testImage")))
+			assert.ErrorIs(t, err, ErrMissingMediaFile)
+		},
+	)
+}
+
+func TestMediaHandler_Serve(t *testing.T) {
+	modifiedAt := time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC)
+	mediaDto := dto.MediaModel{Name: "abc.png", Size: 9, ContentType: "image/png", ModifiedAt: modifiedAt}
+
+	t.Run(
+		"seekable content answers ranges",
+		func(t *testing.T) {
+			s := new(mocks.IMediaService)
+			s.On("Open", "abc.png", []string(nil)).Return(seekableContent{strings.NewReader("testImage")}, mediaDto, nil)
+
+			h := NewMediaHandler(s, false)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/api/v1/media/abc.png", nil)
+			r.Header.Set("Range", "bytes=0-3")
+
+			err := h.Serve(w, r, "abc.png")
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusPartialContent, w.Code)
+			assert.Equal(t, "test", w.Body.String())
+			assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		},
+	)
+
+	t.Run(
+		"streamed content",
+		func(t *testing.T) {
+			s := new(mocks.IMediaService)
+			s.On("Open", "abc.png", []string(nil)).Return(io.NopCloser(strings.NewReader("testImage")), mediaDto, nil)
+
+			h := NewMediaHandler(s, false)
+			w := httptest.NewRecorder()
+
+			err := h.Serve(w, httptest.NewRequest("GET", "/api/v1/media/abc.png", nil), "abc.png")
+
+			assert.NoError(t, err)
+			assert.Equal(t, "testImage", w.Body.String())
+			assert.Equal(t, "9", w.Header().Get("Content-Length"))
+			assert.Equal(t, "Wed, 01 May 2019 10:00:00 GMT", w.Header().Get("Last-Modified"))
+			assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+		},
+	)
+
+	t.Run(
+		"content-addressed files are immutable",
+		func(t *testing.T) {
+			name := strings.Repeat("ab", 32) + ".png"
+			s := new(mocks.IMediaService)
+			s.On("Open", name, []string(nil)).Return(seekableContent{strings.NewReader("testImage")}, dto.MediaModel{Name: name, Size: 9, ContentType: "image/png", ModifiedAt: modifiedAt}, nil)
+
+			h := NewMediaHandler(s, false)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/api/v1/media/"+name, nil)
+			r.Header.Set("If-None-Match", `"`+name+`"`)
+
+			err := h.Serve(w, r, name)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusNotModified, w.Code)
+			assert.Equal(t, "public, max-age=31536000, immutable", w.Header().Get("Cache-Control"))
+			assert.Equal(t, `"`+name+`"`, w.Header().Get("ETag"))
+		},
+	)
+
+	t.Run(
+		"other files are revalidated",
+		func(t *testing.T) {
+			s := new(mocks.IMediaService)
+			s.On("Open", "abc.png", []string(nil)).Return(io.NopCloser(strings.NewReader("testImage")), mediaDto, nil)
+
+			h := NewMediaHandler(s, false)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/api/v1/media/abc.png", nil)
+			r.Header.Set("If-None-Match", `W/"other", "`+strconv.FormatInt(modifiedAt.UnixNano(), 36)+`-9"`)
+
+			err := h.Serve(w, r, "abc.png")
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusNotModified, w.Code)
+			assert.Empty(t, w.Body.String())
+			assert.Equal(t, "public, no-cache", w.Header().Get("Cache-Control"))
+		},
+	)
+
+	t.Run(
+		"types that are not served and directories are not found",
+		func(t *testing.T) {
+			s := new(mocks.IMediaService)
+			s.On("Open", "page.html", []string(nil)).Return(nil, dto.MediaModel{}, service.ErrUnsupportedMediaType)
+			s.On("Open", "2019/", []string(nil)).Return(nil, dto.MediaModel{}, service.ErrInvalidMediaName)
+
+			h := NewMediaHandler(s, false)
+			for _, name := range []string{"page.html", "2019/"} {
+				w := httptest.NewRecorder()
+
+				err := h.Serve(w, httptest.NewRequest("GET", "/api/v1/media/"+name, nil), name)
+
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusNotFound, w.Code, name)
+				assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+			}
+		},
+	)
+
+	t.Run(
+		"variants",
+		func(t *testing.T) {
+			s := new(mocks.IMediaService)
+			s.On("Open", "abc.png", []string{"image/avif", "image/webp", "br", "gzip"}).Return(seekableContent{strings.NewReader("testImage")}, dto.MediaModel{
+				Name: "abc.png.br", Size: 9, ContentType: "image/png", ModifiedAt: modifiedAt, VariantOf: "abc.png", ContentEncoding: "br",
+			}, nil)
+
+			h := NewMediaHandler(s, true)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/api/v1/media/abc.png", nil)
+			r.Header.Set("Accept", "image/avif,image/webp,*/*;q=0.8")
+			r.Header.Set("Accept-Encoding", "br, gzip;q=0.5, deflate;q=0")
+
+			err := h.Serve(w, r, "abc.png")
+
+			assert.NoError(t, err)
+			assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+			assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept, Accept-Encoding", w.Header().Get("Vary"))
+			s.AssertExpectations(t)
+		},
+	)
+}
+
+func TestAcceptedVariants(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/media/abc.png", nil)
+	r.Header.Set("Accept", "image/avif,image/webp,image/*,*/*;q=0.8")
+	r.Header.Set("Accept-Encoding", "gzip, deflate, br;q=0")
+
+	assert.Equal(t, []string{"image/avif", "image/webp", "gzip", "deflate"}, acceptedVariants(r))
+	assert.Nil(t, acceptedVariants(httptest.NewRequest("GET", "/api/v1/media/abc.png", nil)))
+}
+
+func TestMediaHandler_Delete(t *testing.T) {
+	s := new(mocks.IMediaService)
+	s.On("Delete", editor, "abc.png").Return(nil)
+
+	h := NewMediaHandler(s, false)
+	w := httptest.NewRecorder()
+
+	err := h.Delete(w, WithUser(httptest.NewRequest("DELETE", "/api/v1/media/abc.png", nil), editor), "abc.png")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	s.AssertExpectations(t)
+}
+
+func TestMediaHandler_Update(t *testing.T) {
+	s := new(mocks.IMediaService)
+	s.On("Update", editor, "2019/05/abc.png", dto.MediaInputModel{AltText: "testAlt"}).
+		Return(dto.MediaModel{Name: "2019/05/abc.png", AltText: "testAlt"}, nil)
+
+	h := NewMediaHandler(s, false)
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest("PUT", "/api/v1/media/2019/05/abc.png", strings.NewReader(`{"alt_text": "testAlt"}`))
+	err := h.Update(w, WithUser(r, editor), "2019/05/abc.png")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"alt_text":"testAlt"`)
+	s.AssertExpectations(t)
+}
+
+func TestMediaHandler_Update_badBody(t *testing.T) {
+	s := new(mocks.IMediaService)
+	h := NewMediaHandler(s, false)
+
+	for body, status := range map[string]int{
+		`{"alt_text": `: http.StatusBadRequest,
+		`{"alt_text": "` + strings.Repeat("a", maxBodySize) + `"}`: http.StatusRequestEntityTooLarge,
+	} {
+		r := httptest.NewRequest("PUT", "/api/v1/media/2019/05/abc.png", strings.NewReader(body))
+		err := h.Update(httptest.NewRecorder(), WithUser(r, editor), "2019/05/abc.png")
+
+		assert.Equal(t, status, StatusCode(err))
+	}
+	s.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMediaHandler_GetUsage(t *testing.T) {
+	s := new(mocks.IMediaService)
+	s.On("GetUsage", editor).Return(nil, nil)
+	s.On("GetUsageByName", editor, "abc.png").Return(dto.MediaUsageModel{
+		Name:   "abc.png",
+		UsedBy: []dto.MediaUseModel{{PostId: 1, PostSlug: "hello", Field: "content", Name: "abc.png"}},
+	}, nil)
+
+	h := NewMediaHandler(s, false)
+
+	w := httptest.NewRecorder()
+	err := h.GetUsage(w, WithUser(httptest.NewRequest("GET", "/api/v1/media-usage/", nil), editor))
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", w.Body.String())
+
+	w = httptest.NewRecorder()
+	err = h.GetUsageByName(w, WithUser(httptest.NewRequest("GET", "/api/v1/media-usage/abc.png", nil), editor), "abc.png")
+	assert.NoError(t, err)
+	assert.Contains(t, w.Body.String(), `"post_slug":"hello"`)
+}
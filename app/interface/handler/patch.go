@@ -0,0 +1,438 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The patch formats PATCH accepts, by Content-Type.
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// AcceptPatch is the Accept-Patch header of the routes that support PATCH.
+const AcceptPatch = MergePatchType + ", " + JSONPatchType
+
+var (
+	// ErrMalformedBody is returned when a request body is not the JSON expected.
+	ErrMalformedBody = errors.New("malformed JSON body")
+	// ErrBodyTooLarge is returned for a JSON request body over maxBodySize.
+	ErrBodyTooLarge = errors.New("request body too large")
+	// ErrUnsupportedPatch is returned for a PATCH in a format other than AcceptPatch.
+	ErrUnsupportedPatch = errors.New("unsupported patch format, use " + AcceptPatch)
+	// ErrUnprocessablePatch is returned when a patch cannot be applied to the
+	// resource, or would turn it into something that is not one.
+	ErrUnprocessablePatch = errors.New("patch cannot be applied")
+	// ErrPatchTestFailed is returned when a "test" operation of a JSON Patch fails.
+	ErrPatchTestFailed = errors.New("patch test failed")
+)
+
+// maxBodySize is the largest JSON request body read; posts are the largest ones.
+const maxBodySize = 4 << 20
+
+// readBody reads the whole JSON body of the request, which may be sent without a
+// Content-Length, and fails with ErrBodyTooLarge once it passes maxBodySize.
+func readBody(w http.ResponseWriter, r *http.Request) (body []byte, err error) {
+	body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil && len(body) == maxBodySize {
+		err = fmt.Errorf("%w: the limit is %d bytes", ErrBodyTooLarge, maxBodySize)
+	}
+	return
+}
+
+// decodeBody decodes a JSON request body into v.
+func decodeBody(body []byte, v interface{}) (err error) {
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrMalformedBody, err)
+	}
+	return
+}
+
+// patchType returns the patch format of a PATCH request. A body sent as plain
+// JSON, or without a Content-Type, is taken for a merge patch.
+func patchType(r *http.Request) (contentType string, err error) {
+	contentType = r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = MergePatchType
+		return
+	}
+	contentType, _, err = mime.ParseMediaType(contentType)
+	if err != nil {
+		err = ErrUnsupportedPatch
+		return
+	}
+	switch contentType {
+	case "application/json":
+		contentType = MergePatchType
+	case MergePatchType, JSONPatchType:
+	default:
+		err = ErrUnsupportedPatch
+	}
+	return
+}
+
+// applyPatch applies the patch in body to the JSON of current, and decodes the
+// result into patched. Fields the result lacks are left zero in patched, so a
+// merge patch clears a field by setting it to null.
+func applyPatch(r *http.Request, body []byte, current interface{}, patched interface{}) (err error) {
+	contentType, err := patchType(r)
+	if err != nil {
+		return
+	}
+	document, err := json.Marshal(current)
+	if err != nil {
+		return
+	}
+	var target interface{}
+	err = decodeJSON(document, &target)
+	if err != nil {
+		return
+	}
+	switch contentType {
+	case MergePatchType:
+		var patch interface{}
+		err = decodeJSON(body, &patch)
+		if err != nil {
+			err = fmt.Errorf("%w: %v", ErrMalformedBody, err)
+			return
+		}
+		target = mergePatch(target, patch)
+	case JSONPatchType:
+		var operations []patchOperation
+		err = decodeBody(body, &operations)
+		if err != nil {
+			return
+		}
+		target, err = jsonPatch(target, operations)
+		if err != nil {
+			return
+		}
+	}
+	document, err = json.Marshal(target)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(document, patched)
+	if err != nil {
+		err = fmt.Errorf("%w: %v", ErrUnprocessablePatch, err)
+	}
+	return
+}
+
+// decodeJSON decodes data into v keeping numbers as they were written, so that
+// ids and versions survive a round trip unchanged.
+func decodeJSON(data []byte, v interface{}) (err error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(v)
+	return
+}
+
+// mergePatch applies an RFC 7396 merge patch to target: the members of an object
+// patch replace those of target recursively, null members remove them, and any
+// other patch replaces target as a whole.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = mergePatch(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
+
+// patchOperation is one operation of an RFC 6902 JSON Patch. Value is nil when the
+// operation has none, and the JSON null when it is null.
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// jsonPatch applies the operations in order; if one fails, the error tells which.
+func jsonPatch(document interface{}, operations []patchOperation) (result interface{}, err error) {
+	result = document
+	for i, operation := range operations {
+		result, err = operation.apply(result)
+		if err != nil {
+			err = fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, operation.Path, err)
+			return
+		}
+	}
+	return
+}
+
+func (o patchOperation) apply(document interface{}) (result interface{}, err error) {
+	path, err := parsePointer(o.Path)
+	if err != nil {
+		return
+	}
+	var value interface{}
+	switch o.Op {
+	case "add", "replace", "test":
+		if o.Value == nil {
+			err = fmt.Errorf("%w: value is missing", ErrMalformedBody)
+			return
+		}
+		err = decodeJSON(o.Value, &value)
+		if err != nil {
+			err = fmt.Errorf("%w: %v", ErrMalformedBody, err)
+			return
+		}
+	case "move", "copy":
+		var from []string
+		from, err = parsePointer(o.From)
+		if err != nil {
+			return
+		}
+		if o.Op == "move" && len(from) < len(path) && reflect.DeepEqual(from, path[:len(from)]) {
+			err = fmt.Errorf("%w: cannot move a value into itself", ErrUnprocessablePatch)
+			return
+		}
+		value, err = pointerGet(document, from)
+		if err != nil {
+			return
+		}
+		if o.Op == "move" {
+			document, err = pointerRemove(document, from)
+			if err != nil {
+				return
+			}
+		} else {
+			value, err = deepCopy(value)
+			if err != nil {
+				return
+			}
+		}
+	case "remove":
+	default:
+		err = fmt.Errorf("%w: unknown op %q", ErrMalformedBody, o.Op)
+		return
+	}
+
+	switch o.Op {
+	case "add", "move", "copy":
+		result, err = pointerAdd(document, path, value)
+	case "remove":
+		result, err = pointerRemove(document, path)
+	case "replace":
+		if len(path) == 0 {
+			result = value
+			return
+		}
+		result, err = pointerRemove(document, path)
+		if err != nil {
+			return
+		}
+		result, err = pointerAdd(result, path, value)
+	case "test":
+		var actual interface{}
+		actual, err = pointerGet(document, path)
+		if err != nil {
+			return
+		}
+		var equal bool
+		equal, err = jsonEqual(actual, value)
+		if err != nil {
+			return
+		}
+		if !equal {
+			err = ErrPatchTestFailed
+			return
+		}
+		result = document
+	}
+	return
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped reference tokens.
+func parsePointer(pointer string) (tokens []string, err error) {
+	if pointer == "" {
+		return
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		err = fmt.Errorf("%w: %q is not a JSON Pointer", ErrMalformedBody, pointer)
+		return
+	}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		tokens = append(tokens, strings.NewReplacer("~1", "/", "~0", "~").Replace(token))
+	}
+	return
+}
+
+// arrayIndex parses the token as an index of array, or when end is set, as the
+// position just after its last element, which "-" also denotes.
+func arrayIndex(array []interface{}, token string, end bool) (index int, err error) {
+	if token == "-" && end {
+		index = len(array)
+		return
+	}
+	index, err = strconv.Atoi(token)
+	last := len(array) - 1
+	if end {
+		last = len(array)
+	}
+	// RFC 6901 indexes are plain decimals without leading zeros or signs
+	if err != nil || strconv.Itoa(index) != token || index < 0 || index > last {
+		err = fmt.Errorf("%w: no array index %q", ErrUnprocessablePatch, token)
+	}
+	return
+}
+
+func pointerGet(document interface{}, tokens []string) (value interface{}, err error) {
+	value = document
+	for _, token := range tokens {
+		switch container := value.(type) {
+		case map[string]interface{}:
+			var ok bool
+			value, ok = container[token]
+			if !ok {
+				err = fmt.Errorf("%w: no member %q", ErrUnprocessablePatch, token)
+				return
+			}
+		case []interface{}:
+			var index int
+			index, err = arrayIndex(container, token, false)
+			if err != nil {
+				return
+			}
+			value = container[index]
+		default:
+			err = fmt.Errorf("%w: %q is below a value that is neither object nor array", ErrUnprocessablePatch, token)
+			return
+		}
+	}
+	return
+}
+
+// pointerUpdate replaces the container the last token refers into with what
+// update returns for it, and returns the document with the change.
+func pointerUpdate(document interface{}, tokens []string, update func(container interface{}, token string) (interface{}, error)) (result interface{}, err error) {
+	if len(tokens) == 1 {
+		result, err = update(document, tokens[0])
+		return
+	}
+	child, err := pointerGet(document, tokens[:1])
+	if err != nil {
+		return
+	}
+	child, err = pointerUpdate(child, tokens[1:], update)
+	if err != nil {
+		return
+	}
+	switch container := document.(type) {
+	case map[string]interface{}:
+		container[tokens[0]] = child
+	case []interface{}:
+		// pointerGet has checked the index
+		index, _ := strconv.Atoi(tokens[0])
+		container[index] = child
+	}
+	result = document
+	return
+}
+
+func pointerAdd(document interface{}, tokens []string, value interface{}) (result interface{}, err error) {
+	if len(tokens) == 0 {
+		result = value
+		return
+	}
+	result, err = pointerUpdate(document, tokens, func(container interface{}, token string) (updated interface{}, err error) {
+		switch container := container.(type) {
+		case map[string]interface{}:
+			container[token] = value
+			updated = container
+		case []interface{}:
+			var index int
+			index, err = arrayIndex(container, token, true)
+			if err != nil {
+				return
+			}
+			container = append(container, nil)
+			copy(container[index+1:], container[index:])
+			container[index] = value
+			updated = container
+		default:
+			err = fmt.Errorf("%w: cannot add %q to a value that is neither object nor array", ErrUnprocessablePatch, token)
+		}
+		return
+	})
+	return
+}
+
+func pointerRemove(document interface{}, tokens []string) (result interface{}, err error) {
+	if len(tokens) == 0 {
+		err = fmt.Errorf("%w: cannot remove the whole document", ErrUnprocessablePatch)
+		return
+	}
+	result, err = pointerUpdate(document, tokens, func(container interface{}, token string) (updated interface{}, err error) {
+		switch container := container.(type) {
+		case map[string]interface{}:
+			if _, ok := container[token]; !ok {
+				err = fmt.Errorf("%w: no member %q", ErrUnprocessablePatch, token)
+				return
+			}
+			delete(container, token)
+			updated = container
+		case []interface{}:
+			var index int
+			index, err = arrayIndex(container, token, false)
+			if err != nil {
+				return
+			}
+			updated = append(container[:index], container[index+1:]...)
+		default:
+			err = fmt.Errorf("%w: cannot remove %q from a value that is neither object nor array", ErrUnprocessablePatch, token)
+		}
+		return
+	})
+	return
+}
+
+func deepCopy(value interface{}) (copied interface{}, err error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	err = decodeJSON(data, &copied)
+	return
+}
+
+// jsonEqual compares two JSON values as RFC 6902 "test" does: numbers by value,
+// objects regardless of member order.
+func jsonEqual(a interface{}, b interface{}) (equal bool, err error) {
+	var values [2]interface{}
+	for i, value := range []interface{}{a, b} {
+		var data []byte
+		data, err = json.Marshal(value)
+		if err != nil {
+			return
+		}
+		err = json.Unmarshal(data, &values[i])
+		if err != nil {
+			return
+		}
+	}
+	equal = reflect.DeepEqual(values[0], values[1])
+	return
+}
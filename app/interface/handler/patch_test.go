@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	// the examples of RFC 7396, appendix A
+	for _, c := range []struct{ target, patch, result string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	} {
+		var target, patch interface{}
+		assert.NoError(t, decodeJSON([]byte(c.target), &target))
+		assert.NoError(t, decodeJSON([]byte(c.patch), &patch))
+
+		result, err := json.Marshal(mergePatch(target, patch))
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, c.result, string(result), "%s patched with %s", c.target, c.patch)
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	apply := func(document string, patch string) (result string, err error) {
+		var target interface{}
+		var operations []patchOperation
+		if err = decodeJSON([]byte(document), &target); err != nil {
+			return
+		}
+		if err = json.Unmarshal([]byte(patch), &operations); err != nil {
+			return
+		}
+		patched, err := jsonPatch(target, operations)
+		if err != nil {
+			return
+		}
+		output, err := json.Marshal(patched)
+		result = string(output)
+		return
+	}
+
+	// mostly the examples of RFC 6902, appendix A
+	for _, c := range []struct{ name, document, patch, result string }{
+		{"add an object member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add an array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"append to an array", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{"remove an object member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"remove an array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace a value", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"replace the document", `{"foo":"bar"}`, `[{"op":"replace","path":"","value":{"baz":1}}]`, `{"baz":1}`},
+		{"move a value", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"move an array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"copy a value", `{"foo":{"bar":1}}`, `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`, `{"foo":{"bar":1},"baz":{"bar":2}}`},
+		{"test a value", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{"add a null", `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":null}]`, `{"foo":"bar","child":null}`},
+		{"escaped pointer", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`, `{"~1":10}`},
+	} {
+		result, err := apply(c.document, c.patch)
+
+		assert.NoError(t, err, c.name)
+		assert.JSONEq(t, c.result, result, c.name)
+	}
+
+	for _, c := range []struct {
+		name, document, patch string
+		err                   error
+	}{
+		{"failed test", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, ErrPatchTestFailed},
+		{"missing target", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, ErrUnprocessablePatch},
+		{"remove a missing member", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, ErrUnprocessablePatch},
+		{"index out of range", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/2","value":"qux"}]`, ErrUnprocessablePatch},
+		{"leading zero", `{"foo":["bar","baz"]}`, `[{"op":"remove","path":"/foo/01"}]`, ErrUnprocessablePatch},
+		{"move into itself", `{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar"}]`, ErrUnprocessablePatch},
+		{"remove the document", `{"foo":"bar"}`, `[{"op":"remove","path":""}]`, ErrUnprocessablePatch},
+		{"unknown op", `{"foo":"bar"}`, `[{"op":"merge","path":"/foo","value":"baz"}]`, ErrMalformedBody},
+		{"missing value", `{"foo":"bar"}`, `[{"op":"add","path":"/baz"}]`, ErrMalformedBody},
+		{"not a pointer", `{"foo":"bar"}`, `[{"op":"remove","path":"foo"}]`, ErrMalformedBody},
+	} {
+		_, err := apply(c.document, c.patch)
+
+		assert.ErrorIs(t, err, c.err, c.name)
+	}
+}
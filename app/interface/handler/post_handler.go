@@ -13,30 +13,29 @@ type IPostHandler interface {
 	GetPostBySlug(w http.ResponseWriter, r *http.Request, slug string) (err error)
 	Create(w http.ResponseWriter, r *http.Request) (err error)
 	Update(w http.ResponseWriter, r *http.Request) (err error)
+	Patch(w http.ResponseWriter, r *http.Request) (err error)
 	Delete(w http.ResponseWriter, r *http.Request) (err error)
 }
 
 type PostHandler struct {
 	service.IPostService
+	CachePolicy
 }
 
-func NewPostHandler(srv service.IPostService) (iPostHandler IPostHandler) {
-	iPostHandler = &PostHandler{srv}
+func NewPostHandler(srv service.IPostService, cachePolicy CachePolicy) (iPostHandler IPostHandler) {
+	iPostHandler = &PostHandler{srv, cachePolicy}
 	return
 }
 
 func (h *PostHandler) GetPosts(w http.ResponseWriter, r *http.Request) (err error) {
 	queryParams := r.URL.Query()
+	// pretty only formats the response
+	queryParams.Del("pretty")
 	postDtos, err := h.IPostService.GetPosts(queryParams)
 	if err != nil {
 		return
 	}
-	output, err := json.MarshalIndent(&postDtos, "", "\t")
-	if err != nil {
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(output)
+	err = h.writeCachedJSON(w, r, RoutePosts, &postDtos)
 	return
 }
 
@@ -45,12 +44,7 @@ func (h *PostHandler) GetPostBySlug(w http.ResponseWriter, r *http.Request, slug
 	if err != nil {
 		return
 	}
-	output, err := json.MarshalIndent(&postDto, "", "\t")
-	if err != nil {
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(output)
+	err = h.writeCachedJSON(w, r, RoutePost, &postDto)
 	return
 }
 
@@ -60,24 +54,82 @@ func (h *PostHandler) Create(w http.ResponseWriter, r *http.Request) (err error)
 	r.Body.Read(body)
 	var postDto dto.PostModel
 	json.Unmarshal(body, &postDto)
-	err = h.IPostService.Create(postDto)
+	actor := UserFromRequest(r)
+	postDto.AuthorId = actor.Id
+	err = h.IPostService.Create(actor, postDto)
 	return
 }
 
+// Update replaces the post with the body: fields it leaves out are cleared. The
+// request must name the version it is based on, see expectedVersion.
 func (h *PostHandler) Update(w http.ResponseWriter, r *http.Request) (err error) {
 	slug := path.Base(r.URL.Path)
-	postDto, err := h.IPostService.GetPostBySlug(slug)
-	len := r.ContentLength
-	body := make([]byte, len)
-	r.Body.Read(body)
-	json.Unmarshal(body, &postDto)
-	err = h.IPostService.Update(postDto)
+	current, err := h.IPostService.GetPostBySlug(slug)
+	if err != nil {
+		return
+	}
+	body, err := readBody(w, r)
+	if err != nil {
+		return
+	}
+	version, err := expectedVersion(r, body, &current, current.Version)
+	if err != nil {
+		return
+	}
+	var postDto dto.PostModel
+	err = decodeBody(body, &postDto)
+	if err != nil {
+		return
+	}
+	err = h.save(w, r, current, postDto, version)
+	return
+}
+
+// Patch changes the post by the merge patch or JSON Patch in the body, see
+// applyPatch. The request must name the version it is based on, see expectedVersion.
+func (h *PostHandler) Patch(w http.ResponseWriter, r *http.Request) (err error) {
+	slug := path.Base(r.URL.Path)
+	current, err := h.IPostService.GetPostBySlug(slug)
+	if err != nil {
+		return
+	}
+	body, err := readBody(w, r)
+	if err != nil {
+		return
+	}
+	version, err := expectedVersion(r, body, &current, current.Version)
+	if err != nil {
+		return
+	}
+	var postDto dto.PostModel
+	err = applyPatch(r, body, &current, &postDto)
+	if err != nil {
+		return
+	}
+	err = h.save(w, r, current, postDto, version)
+	return
+}
+
+// save stores postDto, the new state of the current post, and responds with the
+// updated post.
+func (h *PostHandler) save(w http.ResponseWriter, r *http.Request, current dto.PostModel, postDto dto.PostModel, version int) (err error) {
+	postDto.Id = current.Id
+	postDto.Version = version
+	err = h.IPostService.Update(UserFromRequest(r), postDto)
+	if err != nil {
+		return
+	}
+	updated, err := h.IPostService.GetPostBySlug(postDto.Slug)
+	if err != nil {
+		return
+	}
+	err = h.writeCachedJSON(w, r, RoutePost, &updated)
 	return
 }
 
 func (h *PostHandler) Delete(w http.ResponseWriter, r *http.Request) (err error) {
 	slug := path.Base(r.URL.Path)
 	postDto, err := h.IPostService.GetPostBySlug(slug)
-	err = h.IPostService.Delete(postDto)
+	err = h.IPostService.Delete(UserFromRequest(r), postDto)
 	return
 }
@@ -2,13 +2,17 @@ package handler
 
 import (
 	"backend/app/common/dto"
+	"backend/app/domain/service"
 	mocks "backend/mocks/service"
+	"io"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestPostHandler_GetPosts(t *testing.T) {
@@ -29,9 +33,6 @@ func TestPostHandler_GetPosts(t *testing.T) {
 			CategoryId:      1,
 			CategoryName:    "testCategory1",
 			CategorySlug:    "test-category-1",
-			SubCategoryId:   1,
-			SubCategoryName: "testSubCategory1",
-			SubCategorySlug: "test-sub-category-1",
 		},
 		{
 			Id:              2,
@@ -46,9 +47,6 @@ func TestPostHandler_GetPosts(t *testing.T) {
 			CategoryId:      1,
 			CategoryName:    "testCategory1",
 			CategorySlug:    "test-category-1",
-			SubCategoryId:   1,
-			SubCategoryName: "testSubCategory1",
-			SubCategorySlug: "test-sub-category-1",
 		},
 	}
 	t.Run(
@@ -63,7 +61,7 @@ func TestPostHandler_GetPosts(t *testing.T) {
 			}
 			s.On("GetPosts", queryParams).Return(postDtos, nil)
 
-			h := NewPostHandler(s)
+			h := NewPostHandler(s, nil)
 
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest("GET", "/posts?category-name=test-category-1", nil)
@@ -87,7 +85,7 @@ func TestPostHandler_GetPosts(t *testing.T) {
 
 			s.On("GetPosts", queryParams).Return(postDtos, nil)
 
-			h := NewPostHandler(s)
+			h := NewPostHandler(s, nil)
 
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest("GET", "/posts?sub-category-name=test-sub-category-1", nil)
@@ -107,7 +105,7 @@ func TestPostHandler_GetPosts(t *testing.T) {
 
 			s.On("GetPosts", queryParams).Return(postDtos, nil)
 
-			h := NewPostHandler(s)
+			h := NewPostHandler(s, nil)
 
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest("GET", "/posts/", nil)
@@ -118,11 +116,30 @@ func TestPostHandler_GetPosts(t *testing.T) {
 			s.AssertExpectations(t)
 		},
 	)
+	t.Run(
+		"pretty",
+		func(t *testing.T) {
+			s := new(mocks.IPostService)
+
+			s.On("GetPosts", map[string][]string{}).Return(postDtos, nil)
+
+			h := NewPostHandler(s, nil)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/posts/?pretty=true", nil)
+
+			err := h.GetPosts(w, r)
+
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(w.Body.String(), "[\n\t{\n\t\t\"id\": "))
+			s.AssertExpectations(t)
+		},
+	)
 }
 
 func TestPostHandler_CRUD(t *testing.T) {
 	// Create, Update, Delete allow
-	// created_at, updated_at, category_id/name/slug, sub_category_name/slug
+	// created_at, updated_at, category_name/slug
 	// empty, because "posts" table of postgreSQL database dont't have these columns.
 	postDto := dto.PostModel{
 		Id:              1,
@@ -132,7 +149,7 @@ func TestPostHandler_CRUD(t *testing.T) {
 		Content:         "This is 1st post",
 		MetaDescription: "This is 1st post",
 		IsPublic:        false,
-		SubCategoryId:   1,
+		CategoryId:      1,
 	}
 
 	json := strings.NewReader(`{
@@ -143,7 +160,7 @@ func TestPostHandler_CRUD(t *testing.T) {
 		"content": "This is 1st post",
 		"meta_description": "This is 1st post",
 		"is_public": false,
-		"sub_category_id": 1
+		"category_id": 1
 	}`)
 
 	t.Run(
@@ -153,7 +170,7 @@ func TestPostHandler_CRUD(t *testing.T) {
 
 			s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
 
-			h := NewPostHandler(s)
+			h := NewPostHandler(s, nil)
 
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest("GET", "/posts/test-post-1/", nil)
@@ -170,12 +187,14 @@ func TestPostHandler_CRUD(t *testing.T) {
 		func(t *testing.T) {
 			s := new(mocks.IPostService)
 
-			s.On("Create", postDto).Return(nil)
+			created := postDto
+			created.AuthorId = editor.Id
+			s.On("Create", editor, created).Return(nil)
 
-			h := NewPostHandler(s)
+			h := NewPostHandler(s, nil)
 
 			w := httptest.NewRecorder()
-			r := httptest.NewRequest("POST", "/posts/", json)
+			r := WithUser(httptest.NewRequest("POST", "/posts/", json), editor)
 
 			err := h.Create(w, r)
 
@@ -189,17 +208,31 @@ func TestPostHandler_CRUD(t *testing.T) {
 		func(t *testing.T) {
 			s := new(mocks.IPostService)
 
-			s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
-			s.On("Update", postDto).Return(nil)
+			stored := postDto
+			stored.Version = 4
+			updated := postDto
+			updated.Version = 3
+			s.On("GetPostBySlug", postDto.Slug).Return(stored, nil)
+			s.On("Update", editor, updated).Return(nil)
 
-			h := NewPostHandler(s)
+			h := NewPostHandler(s, nil)
 
 			w := httptest.NewRecorder()
-			r := httptest.NewRequest("PUT", "/posts/test-post-1/", json)
+			r := WithUser(httptest.NewRequest("PUT", "/posts/test-post-1/", strings.NewReader(`{
+				"title": "testPost1",
+				"slug": "test-post-1",
+				"eye_catching_img": "test_post_1.png",
+				"content": "This is 1st post",
+				"meta_description": "This is 1st post",
+				"category_id": 1,
+				"version": 3
+			}`)), editor)
 
 			err := h.Update(w, r)
 
 			assert.NoError(t, err)
+			assert.Contains(t, w.Body.String(), `"version":4`)
+			assert.NotEmpty(t, w.Header().Get("ETag"))
 			s.AssertExpectations(t)
 		},
 	)
@@ -210,12 +243,12 @@ func TestPostHandler_CRUD(t *testing.T) {
 			s := new(mocks.IPostService)
 
 			s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
-			s.On("Delete", postDto).Return(nil)
+			s.On("Delete", editor, postDto).Return(nil)
 
-			h := NewPostHandler(s)
+			h := NewPostHandler(s, nil)
 
 			w := httptest.NewRecorder()
-			r := httptest.NewRequest("DELETE", "/posts/test-post-1/", nil)
+			r := WithUser(httptest.NewRequest("DELETE", "/posts/test-post-1/", nil), editor)
 
 			err := h.Delete(w, r)
 
@@ -224,3 +257,277 @@ func TestPostHandler_CRUD(t *testing.T) {
 		},
 	)
 }
+
+func TestPostHandler_GetPostBySlug_conditional(t *testing.T) {
+	updatedAt := time.Date(2022, 5, 1, 10, 0, 0, 500, time.UTC)
+	postDto := dto.PostModel{Id: 1, Title: "testPost1", Slug: "test-post-1", UpdatedAt: updatedAt}
+	s := new(mocks.IPostService)
+	s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
+	h := NewPostHandler(s, CachePolicy{RoutePost: "public, max-age=60"})
+
+	w := httptest.NewRecorder()
+	err := h.GetPostBySlug(w, httptest.NewRequest("GET", "/api/v1/posts/test-post-1", nil), postDto.Slug)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+	// the post embeds its author and images, which change without updated_at
+	assert.Empty(t, w.Header().Get("Last-Modified"))
+	assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/v1/posts/test-post-1", nil)
+	r.Header.Set("If-None-Match", etag)
+
+	err = h.GetPostBySlug(w, r, postDto.Slug)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	// with the author's new avatar the post is another
+	postDto.Author = &dto.AuthorModel{Username: "testuser1", AvatarUrl: "/api/v1/media/avatar.png"}
+	s = new(mocks.IPostService)
+	s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
+	h = NewPostHandler(s, nil)
+	w = httptest.NewRecorder()
+
+	err = h.GetPostBySlug(w, r, postDto.Slug)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestPostHandler_preconditions(t *testing.T) {
+	postDto := dto.PostModel{Id: 1, Title: "testPost1", Slug: "test-post-1", Content: "This is 1st post", CategoryId: 1, Version: 2}
+	etag, _ := entityTag(&postDto)
+	edited := postDto
+	edited.Content = "edited"
+	update := func(s *mocks.IPostService, ifMatch string, body string) (err error) {
+		h := NewPostHandler(s, nil)
+		r := WithUser(httptest.NewRequest("PATCH", "/posts/test-post-1/", strings.NewReader(body)), editor)
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		err = h.Patch(httptest.NewRecorder(), r)
+		return
+	}
+
+	t.Run(
+		"If-Match with the current ETag",
+		func(t *testing.T) {
+			s := new(mocks.IPostService)
+			s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
+			s.On("Update", editor, edited).Return(nil)
+
+			err := update(s, `"other", `+etag, `{"content": "edited"}`)
+
+			assert.NoError(t, err)
+			s.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"If-Match with the ETag of the compressed post",
+		func(t *testing.T) {
+			s := new(mocks.IPostService)
+			s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
+			s.On("Update", editor, edited).Return(nil)
+
+			err := update(s, codedETag(etag, "gzip"), `{"content": "edited"}`)
+
+			assert.NoError(t, err)
+			s.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"If-Match with another ETag",
+		func(t *testing.T) {
+			s := new(mocks.IPostService)
+			s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
+
+			for _, ifMatch := range []string{`"other"`, "W/" + etag, codedETag(etag, "Gzip"), etag[:len(etag)-1] + `-"`} {
+				err := update(s, ifMatch, `{"content": "edited"}`)
+
+				assert.ErrorIs(t, err, ErrPreconditionFailed)
+				assert.Equal(t, http.StatusPreconditionFailed, StatusCode(err))
+			}
+			s.AssertNotCalled(t, "Update", editor, edited)
+		},
+	)
+
+	t.Run(
+		"neither If-Match nor version",
+		func(t *testing.T) {
+			s := new(mocks.IPostService)
+			s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
+
+			err := update(s, "", `{"content": "edited"}`)
+
+			assert.ErrorIs(t, err, ErrPreconditionRequired)
+			assert.Equal(t, http.StatusPreconditionRequired, StatusCode(err))
+			s.AssertNotCalled(t, "Update", editor, edited)
+		},
+	)
+
+	t.Run(
+		"a version that has changed",
+		func(t *testing.T) {
+			stale := edited
+			stale.Version = 1
+			s := new(mocks.IPostService)
+			s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
+			s.On("Update", editor, stale).Return(service.ErrConflict)
+
+			err := update(s, "", `{"content": "edited", "version": 1}`)
+
+			assert.ErrorIs(t, err, service.ErrConflict)
+			assert.Equal(t, http.StatusConflict, StatusCode(err))
+
+			err = update(s, "", `{"content": "edited", "version": 0}`)
+
+			assert.ErrorIs(t, err, service.ErrConflict)
+			s.AssertNumberOfCalls(t, "Update", 1)
+		},
+	)
+}
+
+func TestPostHandler_Patch(t *testing.T) {
+	postDto := dto.PostModel{
+		Id:              1,
+		Title:           "testPost1",
+		Slug:            "test-post-1",
+		Content:         "This is 1st post",
+		MetaDescription: "This is 1st post",
+		CategoryId:      1,
+		Version:         2,
+	}
+	patch := func(s *mocks.IPostService, contentType string, body string) (w *httptest.ResponseRecorder, err error) {
+		h := NewPostHandler(s, nil)
+		r := WithUser(httptest.NewRequest("PATCH", "/posts/test-post-1/", strings.NewReader(body)), editor)
+		r.Header.Set("Content-Type", contentType)
+		r.Header.Set("If-Match", "*")
+		w = httptest.NewRecorder()
+		err = h.Patch(w, r)
+		return
+	}
+
+	t.Run(
+		"merge patch",
+		func(t *testing.T) {
+			patched := postDto
+			patched.Title = "patched"
+			patched.MetaDescription = ""
+			s := new(mocks.IPostService)
+			s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
+			s.On("Update", editor, patched).Return(nil)
+
+			w, err := patch(s, MergePatchType, `{"title": "patched", "meta_description": null, "id": 7}`)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, w.Code)
+			s.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"JSON Patch",
+		func(t *testing.T) {
+			patched := postDto
+			patched.Content = "This is 1st post, patched"
+			patched.MetaDescription = "This is 1st post"
+			patched.Title = "This is 1st post"
+			s := new(mocks.IPostService)
+			s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
+			s.On("Update", editor, patched).Return(nil)
+
+			_, err := patch(s, JSONPatchType, `[
+				{"op": "test", "path": "/version", "value": 2},
+				{"op": "replace", "path": "/content", "value": "This is 1st post, patched"},
+				{"op": "copy", "from": "/meta_description", "path": "/title"}
+			]`)
+
+			assert.NoError(t, err)
+			s.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"failed test",
+		func(t *testing.T) {
+			s := new(mocks.IPostService)
+			s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
+
+			_, err := patch(s, JSONPatchType, `[{"op": "test", "path": "/title", "value": "other"}, {"op": "remove", "path": "/title"}]`)
+
+			assert.ErrorIs(t, err, ErrPatchTestFailed)
+			assert.Equal(t, http.StatusConflict, StatusCode(err))
+			s.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		},
+	)
+
+	t.Run(
+		"result is not a post",
+		func(t *testing.T) {
+			s := new(mocks.IPostService)
+			s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
+
+			_, err := patch(s, MergePatchType, `{"title": 5}`)
+
+			assert.ErrorIs(t, err, ErrUnprocessablePatch)
+			assert.Equal(t, http.StatusUnprocessableEntity, StatusCode(err))
+			s.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		},
+	)
+
+	t.Run(
+		"unsupported format",
+		func(t *testing.T) {
+			s := new(mocks.IPostService)
+			s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
+
+			_, err := patch(s, "text/plain", `title=patched`)
+
+			assert.ErrorIs(t, err, ErrUnsupportedPatch)
+			assert.Equal(t, http.StatusUnsupportedMediaType, StatusCode(err))
+		},
+	)
+
+	t.Run(
+		"chunked body",
+		func(t *testing.T) {
+			patched := postDto
+			patched.Title = "patched"
+			s := new(mocks.IPostService)
+			s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
+			s.On("Update", editor, patched).Return(nil)
+			// a body of unknown length, as sent with Transfer-Encoding: chunked
+			body := io.MultiReader(strings.NewReader(`{"title": `), strings.NewReader(`"patched"}`))
+			r := WithUser(httptest.NewRequest("PATCH", "/posts/test-post-1/", body), editor)
+			r.ContentLength = -1
+			r.Header.Set("If-Match", "*")
+
+			err := NewPostHandler(s, nil).Patch(httptest.NewRecorder(), r)
+
+			assert.NoError(t, err)
+			s.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"body too large",
+		func(t *testing.T) {
+			s := new(mocks.IPostService)
+			s.On("GetPostBySlug", postDto.Slug).Return(postDto, nil)
+
+			_, err := patch(s, MergePatchType, `{"content": "`+strings.Repeat("x", maxBodySize)+`"}`)
+
+			assert.ErrorIs(t, err, ErrBodyTooLarge)
+			assert.Equal(t, http.StatusRequestEntityTooLarge, StatusCode(err))
+			s.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		},
+	)
+}
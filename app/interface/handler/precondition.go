@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"backend/app/domain/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrPreconditionRequired is returned by an update that names neither the ETag
+	// nor the version it is based on.
+	ErrPreconditionRequired = errors.New("updates need an If-Match header or a version field")
+	// ErrPreconditionFailed is returned when If-Match does not list the current ETag.
+	ErrPreconditionFailed = errors.New("If-Match does not list the current ETag; read the resource again")
+)
+
+// expectedVersion returns the version an update has to find stored, given the
+// current representation of the resource and its version. Clients name what they
+// based the update on with If-Match, which is checked against the ETag of current
+// right away, or with the version in body, see claimedVersion. Either way the
+// stored version is checked again by the update itself, so two updates racing
+// each other cannot both succeed.
+func expectedVersion(r *http.Request, body []byte, current interface{}, currentVersion int) (version int, err error) {
+	claimed := claimedVersion(r, body)
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" && claimed == nil {
+		err = ErrPreconditionRequired
+		return
+	}
+	if ifMatch != "" {
+		var etag string
+		etag, err = entityTag(current)
+		if err != nil {
+			return
+		}
+		if !ifMatches(ifMatch, etag) {
+			err = ErrPreconditionFailed
+			return
+		}
+	}
+	version = currentVersion
+	if claimed != nil {
+		version = *claimed
+		// 0 would skip the check, and no stored version is below 1
+		if version < 1 {
+			err = service.ErrConflict
+		}
+	}
+	return
+}
+
+// ifMatches reports whether an If-Match header lists the ETag, comparing strongly
+// as RFC 9110 asks for: weak tags never match. The tag of a compressed response,
+// see codedETag, names the same state of the resource.
+func ifMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || !strings.HasPrefix(candidate, "W/") && sameEntityTag(candidate, etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// claimedVersion returns the version member of body, or for a JSON Patch the
+// version a "test" operation expects at /version; nil when there is none.
+func claimedVersion(r *http.Request, body []byte) (version *int) {
+	if contentType, err := patchType(r); r.Method == "PATCH" && err == nil && contentType == JSONPatchType {
+		var operations []patchOperation
+		json.Unmarshal(body, &operations)
+		for _, operation := range operations {
+			if operation.Op == "test" && operation.Path == "/version" {
+				json.Unmarshal(operation.Value, &version)
+			}
+		}
+		return
+	}
+	var versioned struct {
+		Version *int `json:"version"`
+	}
+	json.Unmarshal(body, &versioned)
+	version = versioned.Version
+	return
+}
@@ -10,33 +10,42 @@ import (
 
 type ISubCategoryHandler interface {
 	GetSubCategories(w http.ResponseWriter, r *http.Request) error
+	GetSubCategoryBySlug(w http.ResponseWriter, r *http.Request, slug string) error
 	Create(w http.ResponseWriter, r *http.Request) error
 	Update(w http.ResponseWriter, r *http.Request) error
+	Patch(w http.ResponseWriter, r *http.Request) error
 	Delete(w http.ResponseWriter, r *http.Request) error
 }
 
 type SubCategoryHandler struct {
 	service.ISubCategoryService
+	CachePolicy
 }
 
-func NewSubCategoryHandler(srv service.ISubCategoryService) (iSubCategoryHandler ISubCategoryHandler) {
-	iSubCategoryHandler = &SubCategoryHandler{srv}
+func NewSubCategoryHandler(srv service.ISubCategoryService, cachePolicy CachePolicy) (iSubCategoryHandler ISubCategoryHandler) {
+	iSubCategoryHandler = &SubCategoryHandler{srv, cachePolicy}
 	return
 }
 
 func (h *SubCategoryHandler) GetSubCategories(w http.ResponseWriter, r *http.Request) (err error) {
 	var subCategories []dto.SubCategoryModel
 	queryParams := r.URL.Query()
+	// pretty only formats the response
+	queryParams.Del("pretty")
 	subCategories, err = h.ISubCategoryService.GetSubCategories(queryParams)
 	if err != nil {
 		return
 	}
-	output, err := json.MarshalIndent(&subCategories, "", "\t")
+	err = h.writeCachedJSON(w, r, RouteSubCategories, &subCategories)
+	return
+}
+
+func (h *SubCategoryHandler) GetSubCategoryBySlug(w http.ResponseWriter, r *http.Request, slug string) (err error) {
+	subCategory, err := h.ISubCategoryService.GetSubCategoryDetailBySlug(slug)
 	if err != nil {
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(output)
+	err = h.writeCachedJSON(w, r, RouteSubCategory, &subCategory)
 	return
 }
 
@@ -46,24 +55,81 @@ func (h *SubCategoryHandler) Create(w http.ResponseWriter, r *http.Request) (err
 	r.Body.Read(body)
 	var subCategoryDto dto.SubCategoryModel
 	json.Unmarshal(body, &subCategoryDto)
-	err = h.ISubCategoryService.Create(subCategoryDto)
+	err = h.ISubCategoryService.Create(UserFromRequest(r), subCategoryDto)
 	return
 }
 
+// Update replaces the sub-category with the body: fields it leaves out are
+// cleared. The request must name the version it is based on, see expectedVersion.
 func (h *SubCategoryHandler) Update(w http.ResponseWriter, r *http.Request) (err error) {
 	slug := path.Base(r.URL.Path)
-	subCategoryDto, err := h.ISubCategoryService.GetSubCategoryBySlug(slug)
-	len := r.ContentLength
-	body := make([]byte, len)
-	r.Body.Read(body)
-	json.Unmarshal(body, &subCategoryDto)
-	err = h.ISubCategoryService.Update(subCategoryDto)
+	// the detail is what GetSubCategoryBySlug responds with, so If-Match is checked against its ETag
+	current, err := h.ISubCategoryService.GetSubCategoryDetailBySlug(slug)
+	if err != nil {
+		return
+	}
+	body, err := readBody(w, r)
+	if err != nil {
+		return
+	}
+	version, err := expectedVersion(r, body, &current, current.Version)
+	if err != nil {
+		return
+	}
+	var subCategoryDto dto.SubCategoryModel
+	err = decodeBody(body, &subCategoryDto)
+	if err != nil {
+		return
+	}
+	err = h.save(w, r, current, subCategoryDto, version)
+	return
+}
+
+// Patch changes the sub-category by the merge patch or JSON Patch in the body, see
+// applyPatch. The request must name the version it is based on, see expectedVersion.
+func (h *SubCategoryHandler) Patch(w http.ResponseWriter, r *http.Request) (err error) {
+	slug := path.Base(r.URL.Path)
+	current, err := h.ISubCategoryService.GetSubCategoryDetailBySlug(slug)
+	if err != nil {
+		return
+	}
+	body, err := readBody(w, r)
+	if err != nil {
+		return
+	}
+	version, err := expectedVersion(r, body, &current, current.Version)
+	if err != nil {
+		return
+	}
+	var subCategoryDto dto.SubCategoryModel
+	err = applyPatch(r, body, &current, &subCategoryDto)
+	if err != nil {
+		return
+	}
+	err = h.save(w, r, current, subCategoryDto, version)
+	return
+}
+
+// save stores subCategoryDto, the new state of the current sub-category, and
+// responds with the updated sub-category.
+func (h *SubCategoryHandler) save(w http.ResponseWriter, r *http.Request, current dto.SubCategoryModel, subCategoryDto dto.SubCategoryModel, version int) (err error) {
+	subCategoryDto.Id = current.Id
+	subCategoryDto.Version = version
+	err = h.ISubCategoryService.Update(UserFromRequest(r), subCategoryDto)
+	if err != nil {
+		return
+	}
+	updated, err := h.ISubCategoryService.GetSubCategoryDetailBySlug(subCategoryDto.Slug)
+	if err != nil {
+		return
+	}
+	err = h.writeCachedJSON(w, r, RouteSubCategory, &updated)
 	return
 }
 
 func (h *SubCategoryHandler) Delete(w http.ResponseWriter, r *http.Request) (err error) {
 	slug := path.Base(r.URL.Path)
 	subCategoryDto, err := h.ISubCategoryService.GetSubCategoryBySlug(slug)
-	err = h.ISubCategoryService.Delete(subCategoryDto)
+	err = h.ISubCategoryService.Delete(UserFromRequest(r), subCategoryDto)
 	return
 }
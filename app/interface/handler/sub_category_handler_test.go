@@ -43,7 +43,7 @@ func TestSubCategoryHandler_GetSubCategories(t *testing.T) {
 
 			s.On("GetSubCategories", queryParams).Return(subCategoryDtos, nil)
 
-			h := NewSubCategoryHandler(s)
+			h := NewSubCategoryHandler(s, nil)
 
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest("GET", "/sub-categories?category-name=test-category-1", nil)
@@ -63,7 +63,7 @@ func TestSubCategoryHandler_GetSubCategories(t *testing.T) {
 
 			s.On("GetSubCategories", queryParams).Return(subCategoryDtos, nil)
 
-			h := NewSubCategoryHandler(s)
+			h := NewSubCategoryHandler(s, nil)
 
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest("GET", "/sub-categories/", nil)
@@ -98,13 +98,13 @@ func TestSubCategoryHandler_CRUD(t *testing.T) {
 		"Create",
 		func(t *testing.T) {
 			w := httptest.NewRecorder()
-			r := httptest.NewRequest("POST", "/sub-categories/", json)
+			r := WithUser(httptest.NewRequest("POST", "/sub-categories/", json), editor)
 
 			s := new(mocks.ISubCategoryService)
 
-			s.On("Create", subCategoryDto).Return(nil)
+			s.On("Create", editor, subCategoryDto).Return(nil)
 
-			h := NewSubCategoryHandler(s)
+			h := NewSubCategoryHandler(s, nil)
 
 			err := h.Create(w, r)
 
@@ -117,15 +117,22 @@ func TestSubCategoryHandler_CRUD(t *testing.T) {
 		"Update",
 		func(t *testing.T) {
 			w := httptest.NewRecorder()
-			r := httptest.NewRequest("PUT", "/sub-categories/test-sub-category-1/", json)
+			r := WithUser(httptest.NewRequest("PUT", "/sub-categories/test-sub-category-1/", strings.NewReader(`{
+				"name": "testSubCategory1",
+				"slug": "test-sub-category-1",
+				"parent_category_id": 1
+			}`)), editor)
 
 			s := new(mocks.ISubCategoryService)
 
-			s.On("GetSubCategoryBySlug", subCategoryDto.Slug).Return(subCategoryDto, nil)
-			s.On("Update", subCategoryDto).Return(nil)
+			stored := subCategoryDto
+			stored.Version = 1
+			s.On("GetSubCategoryDetailBySlug", subCategoryDto.Slug).Return(stored, nil)
+			s.On("Update", editor, stored).Return(nil)
 
-			h := NewSubCategoryHandler(s)
+			h := NewSubCategoryHandler(s, nil)
 
+			r.Header.Set("If-Match", "*")
 			err := h.Update(w, r)
 
 			assert.NoError(t, err)
@@ -137,14 +144,14 @@ func TestSubCategoryHandler_CRUD(t *testing.T) {
 		"Delete",
 		func(t *testing.T) {
 			w := httptest.NewRecorder()
-			r := httptest.NewRequest("DELETE", "/sub-categories/test-sub-category-1/", nil)
+			r := WithUser(httptest.NewRequest("DELETE", "/sub-categories/test-sub-category-1/", nil), editor)
 
 			s := new(mocks.ISubCategoryService)
 
 			s.On("GetSubCategoryBySlug", subCategoryDto.Slug).Return(subCategoryDto, nil)
-			s.On("Delete", subCategoryDto).Return(nil)
+			s.On("Delete", editor, subCategoryDto).Return(nil)
 
-			h := NewSubCategoryHandler(s)
+			h := NewSubCategoryHandler(s, nil)
 
 			err := h.Delete(w, r)
 
@@ -153,3 +160,30 @@ func TestSubCategoryHandler_CRUD(t *testing.T) {
 		},
 	)
 }
+
+func TestSubCategoryHandler_GetSubCategoryBySlug(t *testing.T) {
+	subCategoryDto := dto.SubCategoryModel{
+		Id:                 1,
+		Name:               "testSubCategory1",
+		Slug:               "test-sub-category-1",
+		ParentCategoryId:   1,
+		ParentCategoryName: "testCategory1",
+		ParentCategorySlug: "test-category-1",
+		Stats:              &dto.CategoryStatsModel{PostCount: 2},
+	}
+
+	s := new(mocks.ISubCategoryService)
+
+	s.On("GetSubCategoryDetailBySlug", subCategoryDto.Slug).Return(subCategoryDto, nil)
+
+	h := NewSubCategoryHandler(s, nil)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/sub-categories/test-sub-category-1", nil)
+
+	err := h.GetSubCategoryBySlug(w, r, subCategoryDto.Slug)
+
+	assert.NoError(t, err)
+	assert.Contains(t, w.Body.String(), `"post_count":2`)
+	s.AssertExpectations(t)
+}
@@ -4,12 +4,21 @@ import (
 	"backend/app/common/dto"
 	"backend/app/domain/service"
 	"encoding/json"
+	"net"
 	"net/http"
+	"path"
+	"strconv"
 )
 
 type IUserHandler interface {
+	GetAll(w http.ResponseWriter, r *http.Request) error
+	Update(w http.ResponseWriter, r *http.Request) error
+	Delete(w http.ResponseWriter, r *http.Request) error
+	GetAuthor(w http.ResponseWriter, r *http.Request, username string) error
 	IssueToken(w http.ResponseWriter, r *http.Request) error
-	ValidateToken(w http.ResponseWriter, r *http.Request) (bool, error)
+	Refresh(w http.ResponseWriter, r *http.Request) error
+	Logout(w http.ResponseWriter, r *http.Request) error
+	ValidateToken(w http.ResponseWriter, r *http.Request) (dto.UserModel, error)
 }
 
 type UserHandler struct {
@@ -21,40 +30,153 @@ func NewUserHandler(srv service.IUserService) (iUserHandler IUserHandler) {
 	return
 }
 
+func (h *UserHandler) GetAll(w http.ResponseWriter, r *http.Request) (err error) {
+	userDtos, err := h.IUserService.GetAll(UserFromRequest(r))
+	if err != nil {
+		return
+	}
+	err = writeJSON(w, r, &userDtos)
+	return
+}
+
+// Update changes the role of the user addressed by /users/{id}.
+func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) (err error) {
+	id, err := strconv.Atoi(path.Base(r.URL.Path))
+	if err != nil {
+		return
+	}
+	len := r.ContentLength
+	body := make([]byte, len)
+	r.Body.Read(body)
+	var roleDto dto.RoleModel
+	err = json.Unmarshal(body, &roleDto)
+	if err != nil {
+		return
+	}
+	err = h.IUserService.ChangeRole(UserFromRequest(r), id, roleDto.Role)
+	return
+}
+
+func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) (err error) {
+	id, err := strconv.Atoi(path.Base(r.URL.Path))
+	if err != nil {
+		return
+	}
+	actor := UserFromRequest(r)
+	userDto, err := h.IUserService.GetById(actor, id)
+	if err != nil {
+		return
+	}
+	err = h.IUserService.Delete(actor, userDto)
+	return
+}
+
+func (h *UserHandler) GetAuthor(w http.ResponseWriter, r *http.Request, username string) (err error) {
+	authorDto, err := h.IUserService.GetAuthor(username)
+	if err != nil {
+		return
+	}
+	err = writeJSON(w, r, &authorDto)
+	return
+}
+
+// IssueToken logs in with a username and password. Users with 2FA enabled get an
+// mfa_token instead and post it back with a TOTP or recovery code to get the tokens.
 func (h *UserHandler) IssueToken(w http.ResponseWriter, r *http.Request) (err error) {
 	len := r.ContentLength
 	body := make([]byte, len)
 	r.Body.Read(body)
 	var credsDto dto.CredentialsModel
 	json.Unmarshal(body, &credsDto)
+	var twoFactorDto dto.TwoFactorLoginModel
+	json.Unmarshal(body, &twoFactorDto)
+	// failed logins are also counted per address; proxy headers are not trusted
+	remoteAddr, _, _ := net.SplitHostPort(r.RemoteAddr)
+	credsDto.RemoteAddr = remoteAddr
+	twoFactorDto.RemoteAddr = remoteAddr
+
+	var userDto dto.UserModel
+	if twoFactorDto.MfaToken != "" {
+		userDto, err = h.IUserService.CompleteTwoFactor(twoFactorDto)
+		if err != nil {
+			return
+		}
+	} else {
+		userDto, err = h.IUserService.ValidateUser(credsDto)
+		if err != nil {
+			return
+		}
+		var challengeDto dto.TwoFactorChallengeModel
+		challengeDto, err = h.IUserService.TwoFactorChallenge(userDto)
+		if err != nil {
+			return
+		}
+		if challengeDto.Required {
+			err = writeJSON(w, r, &challengeDto)
+			return
+		}
+	}
 
-	userDto, err := h.IUserService.ValidateUser(credsDto)
+	authTokenDto, err := h.IUserService.IssueToken(userDto.Id)
 	if err != nil {
 		return
 	}
 
-	authTokenDto, err := h.IUserService.IssueToken(userDto.Id)
+	err = writeJSON(w, r, &authTokenDto)
+	return
+}
+
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) (err error) {
+	body, err := readBody(w, r)
+	if err != nil {
+		return
+	}
+	var refreshTokenDto dto.RefreshTokenModel
+	err = decodeBody(body, &refreshTokenDto)
+	if err != nil {
+		return
+	}
+
+	authTokenDto, err := h.IUserService.Refresh(refreshTokenDto)
 	if err != nil {
 		return
 	}
 
-	output, err := json.MarshalIndent(&authTokenDto, "", "\t")
+	err = writeJSON(w, r, &authTokenDto)
+	return
+}
+
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) (err error) {
+	body, err := readBody(w, r)
+	if err != nil {
+		return
+	}
+	var refreshTokenDto dto.RefreshTokenModel
+	err = decodeBody(body, &refreshTokenDto)
 	if err != nil {
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(output)
-	w.WriteHeader(200)
+
+	err = h.IUserService.Logout(refreshTokenDto)
+	if err != nil {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 	return
 }
 
-func (h *UserHandler) ValidateToken(w http.ResponseWriter, r *http.Request) (isAdmin bool, err error) {
-	authToken := r.Header.Get("Authorization")
-	if authToken == "" {
-		isAdmin = false
+// ValidateToken resolves the Bearer token of the Authorization header to a user.
+// Requests without the header are anonymous and yield the zero UserModel.
+func (h *UserHandler) ValidateToken(w http.ResponseWriter, r *http.Request) (userDto dto.UserModel, err error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return
+	}
+	authToken, err := bearerToken(header)
+	if err != nil {
 		return
 	}
 	authTokenDto := dto.NewAuthTokenModel(authToken)
-	isAdmin, err = h.IUserService.ValidateToken(authTokenDto)
+	userDto, err = h.IUserService.ValidateToken(authTokenDto)
 	return
 }
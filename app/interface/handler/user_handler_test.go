@@ -2,6 +2,8 @@ package handler
 
 import (
 	"backend/app/common/dto"
+	"backend/app/domain/service"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
@@ -9,29 +11,316 @@ import (
 	mocks "backend/mocks/service"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestUserHandler_IssueToken(t *testing.T) {
 	credsDto := dto.NewCredsModel("testuser1", "testpass1")
-	json := strings.NewReader(`{
-		"username": "testuser1",
-		"password": "testpass1"
-	}`)
-	userDto := dto.NewUserModel(1, "testuser1", "testpass1")
+	// httptest requests come from 192.0.2.1:1234
+	credsDto.RemoteAddr = "192.0.2.1"
+	userDto := dto.NewUserModel(1, "testuser1", "author")
 	authTokenDto := dto.NewAuthTokenModel("token")
 
+	t.Run(
+		"password",
+		func(t *testing.T) {
+			json := strings.NewReader(`{
+				"username": "testuser1",
+				"password": "testpass1"
+			}`)
+
+			s := new(mocks.IUserService)
+
+			s.On("ValidateUser", credsDto).Return(userDto, nil)
+			s.On("TwoFactorChallenge", userDto).Return(dto.TwoFactorChallengeModel{}, nil)
+			s.On("IssueToken", userDto.Id).Return(authTokenDto, nil)
+
+			h := NewUserHandler(s)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/admin/", json)
+
+			err := h.IssueToken(w, r)
+
+			assert.NoError(t, err)
+			assert.Contains(t, w.Body.String(), `"token":"token"`)
+			s.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"two-factor challenge",
+		func(t *testing.T) {
+			json := strings.NewReader(`{
+				"username": "testuser1",
+				"password": "testpass1"
+			}`)
+
+			s := new(mocks.IUserService)
+
+			s.On("ValidateUser", credsDto).Return(userDto, nil)
+			s.On("TwoFactorChallenge", userDto).Return(dto.TwoFactorChallengeModel{Required: true, MfaToken: "mfa", ExpiresIn: 300}, nil)
+
+			h := NewUserHandler(s)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/admin/", json)
+
+			err := h.IssueToken(w, r)
+
+			assert.NoError(t, err)
+			assert.Contains(t, w.Body.String(), `"mfa_required":true`)
+			assert.Contains(t, w.Body.String(), `"mfa_token":"mfa"`)
+			s.AssertNotCalled(t, "IssueToken", mock.Anything)
+		},
+	)
+
+	t.Run(
+		"two-factor code",
+		func(t *testing.T) {
+			json := strings.NewReader(`{
+				"mfa_token": "mfa",
+				"code": "123456"
+			}`)
+
+			s := new(mocks.IUserService)
+
+			s.On("CompleteTwoFactor", dto.TwoFactorLoginModel{MfaToken: "mfa", Code: "123456", RemoteAddr: "192.0.2.1"}).Return(userDto, nil)
+			s.On("IssueToken", userDto.Id).Return(authTokenDto, nil)
+
+			h := NewUserHandler(s)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/admin/", json)
+
+			err := h.IssueToken(w, r)
+
+			assert.NoError(t, err)
+			s.AssertExpectations(t)
+			s.AssertNotCalled(t, "ValidateUser", mock.Anything)
+		},
+	)
+}
+
+func TestUserHandler_ValidateToken(t *testing.T) {
+	t.Run(
+		"without token",
+		func(t *testing.T) {
+			s := new(mocks.IUserService)
+
+			h := NewUserHandler(s)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/posts/", nil)
+
+			userDto, err := h.ValidateToken(w, r)
+
+			assert.NoError(t, err)
+			assert.Equal(t, dto.UserModel{}, userDto)
+			s.AssertNotCalled(t, "ValidateToken", dto.NewAuthTokenModel(""))
+		},
+	)
+
+	t.Run(
+		"with token",
+		func(t *testing.T) {
+			s := new(mocks.IUserService)
+
+			s.On("ValidateToken", dto.NewAuthTokenModel("token")).Return(editor, nil)
+
+			h := NewUserHandler(s)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/posts/", nil)
+			r.Header.Set("Authorization", "bearer token")
+
+			userDto, err := h.ValidateToken(w, r)
+
+			assert.NoError(t, err)
+			assert.Equal(t, editor, userDto)
+			s.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"malformed header",
+		func(t *testing.T) {
+			for _, header := range []string{"token", "Basic dXNlcjpwYXNz", "Bearer", "Bearer a b", "Bearer =abc"} {
+				s := new(mocks.IUserService)
+
+				h := NewUserHandler(s)
+
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest("POST", "/posts/", nil)
+				r.Header.Set("Authorization", header)
+
+				_, err := h.ValidateToken(w, r)
+
+				assert.ErrorIs(t, err, ErrMalformedAuthorization, header)
+				s.AssertNotCalled(t, "ValidateToken", mock.Anything)
+			}
+		},
+	)
+}
+
+func TestUserHandler_GetAll(t *testing.T) {
+	admin := dto.NewUserModel(1, "testadmin", "admin")
+	userDtos := []dto.UserModel{
+		dto.NewUserModel(1, "testadmin", "admin"),
+	}
+
+	s := new(mocks.IUserService)
+
+	s.On("GetAll", admin).Return(userDtos, nil)
+
+	h := NewUserHandler(s)
+
+	w := httptest.NewRecorder()
+	r := WithUser(httptest.NewRequest("GET", "/users/", nil), admin)
+
+	err := h.GetAll(w, r)
+
+	assert.NoError(t, err)
+	assert.NotContains(t, w.Body.String(), "hashed")
+	s.AssertExpectations(t)
+}
+
+func TestUserHandler_Update(t *testing.T) {
+	t.Run(
+		"as admin",
+		func(t *testing.T) {
+			admin := dto.NewUserModel(1, "testadmin", "admin")
+			json := strings.NewReader(`{"role": "editor"}`)
+
+			s := new(mocks.IUserService)
+
+			s.On("ChangeRole", admin, 5, "editor").Return(nil)
+
+			h := NewUserHandler(s)
+
+			w := httptest.NewRecorder()
+			r := WithUser(httptest.NewRequest("PUT", "/users/5", json), admin)
+
+			err := h.Update(w, r)
+
+			assert.NoError(t, err)
+			s.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"as editor",
+		func(t *testing.T) {
+			json := strings.NewReader(`{"role": "admin"}`)
+
+			s := new(mocks.IUserService)
+
+			s.On("ChangeRole", editor, 2, "admin").Return(service.ErrForbidden)
+
+			h := NewUserHandler(s)
+
+			w := httptest.NewRecorder()
+			r := WithUser(httptest.NewRequest("PUT", "/users/2", json), editor)
+
+			err := h.Update(w, r)
+
+			assert.ErrorIs(t, err, service.ErrForbidden)
+			s.AssertExpectations(t)
+		},
+	)
+}
+
+func TestUserHandler_Delete(t *testing.T) {
+	admin := dto.NewUserModel(1, "testadmin", "admin")
+	userDto := dto.NewUserModel(5, "testuser5", "viewer")
+
 	s := new(mocks.IUserService)
 
-	s.On("ValidateUser", credsDto).Return(userDto, nil)
-	s.On("IssueToken", userDto.Id).Return(authTokenDto, nil)
+	s.On("GetById", admin, 5).Return(userDto, nil)
+	s.On("Delete", admin, userDto).Return(nil)
 
 	h := NewUserHandler(s)
 
 	w := httptest.NewRecorder()
-	r := httptest.NewRequest("POST", "/admin/", json)
+	r := WithUser(httptest.NewRequest("DELETE", "/users/5", nil), admin)
 
-	err := h.IssueToken(w, r)
+	err := h.Delete(w, r)
 
 	assert.NoError(t, err)
 	s.AssertExpectations(t)
 }
+
+func TestUserHandler_GetAuthor(t *testing.T) {
+	authorDto := dto.NewAuthorModel("testauthor", "Test Author", "bio", "test_author.png")
+
+	s := new(mocks.IUserService)
+
+	s.On("GetAuthor", "testauthor").Return(authorDto, nil)
+
+	h := NewUserHandler(s)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/authors/testauthor", nil)
+
+	err := h.GetAuthor(w, r, "testauthor")
+
+	assert.NoError(t, err)
+	assert.Contains(t, w.Body.String(), `"display_name":"Test Author"`)
+	assert.NotContains(t, w.Body.String(), "password")
+	s.AssertExpectations(t)
+}
+
+func TestUserHandler_Refresh(t *testing.T) {
+	json := strings.NewReader(`{"refresh_token": "refresh"}`)
+	authTokenDto := dto.AuthTokenModel{Token: "token", RefreshToken: "rotated", ExpiresIn: 900}
+
+	s := new(mocks.IUserService)
+
+	s.On("Refresh", dto.RefreshTokenModel{RefreshToken: "refresh"}).Return(authTokenDto, nil)
+
+	h := NewUserHandler(s)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/auth/refresh", json)
+
+	err := h.Refresh(w, r)
+
+	assert.NoError(t, err)
+	assert.Contains(t, w.Body.String(), `"refresh_token":"rotated"`)
+	s.AssertExpectations(t)
+}
+
+func TestUserHandler_Logout(t *testing.T) {
+	json := strings.NewReader(`{"refresh_token": "refresh"}`)
+
+	s := new(mocks.IUserService)
+
+	s.On("Logout", dto.RefreshTokenModel{RefreshToken: "refresh"}).Return(nil)
+
+	h := NewUserHandler(s)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/auth/logout", json)
+
+	err := h.Logout(w, r)
+
+	assert.NoError(t, err)
+	assert.Equal(t, w.Code, 204)
+	s.AssertExpectations(t)
+}
+
+func TestUserHandler_Refresh_malformedBody(t *testing.T) {
+	s := new(mocks.IUserService)
+	h := NewUserHandler(s)
+
+	for _, handle := range []func(http.ResponseWriter, *http.Request) error{h.Refresh, h.Logout} {
+		r := httptest.NewRequest("POST", "/auth/refresh", strings.NewReader(`{"refresh_token": `))
+
+		err := handle(httptest.NewRecorder(), r)
+
+		assert.ErrorIs(t, err, ErrMalformedBody)
+		assert.Equal(t, http.StatusBadRequest, StatusCode(err))
+	}
+	s.AssertNotCalled(t, "Refresh", mock.Anything)
+	s.AssertNotCalled(t, "Logout", mock.Anything)
+}
@@ -2,17 +2,27 @@ package main
 
 import (
 	"backend/app/common/di"
+	"backend/app/domain/service"
+	"backend/app/infrastructure/cache"
+	"backend/app/interface/CLI"
+	"backend/app/interface/handler"
 	"database/sql"
 	"fmt"
 	"net/http"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
 
 	_ "github.com/lib/pq"
 )
 
 type Env struct {
-	Db *sql.DB
+	Db          *sql.DB
+	Media       handler.IMediaHandler
+	Queries     *cache.ReadThrough
+	Compression handler.Compression
+	Tokens      service.TokenConfig
 }
 
 func main() {
@@ -21,64 +31,106 @@ func main() {
 		panic(err)
 	}
 
+	// the CLI writes through the cache too, so that a shared one learns of its changes
+	queries := di.InitQueryCache()
+
 	if len(os.Args) > 1 {
-		user := di.InitUserCLI(db)
-		switch os.Args[1] {
-		case "showusers":
-			err = user.GetAll()
-		case "createsuperuser":
-			err = user.Create()
-		case "updateuser":
-			err = user.Update()
-		case "deleteuser":
-			err = user.Delete()
-		default:
-			fmt.Printf("there is no such method: %s\n", os.Args[1])
-		}
-		if err != nil {
-			fmt.Println(err)
-			return
-		}
+		console := CLI.NewConsole(os.Stdin, os.Stdout, os.Stderr)
+		root := CLI.NewRootCommand(filepath.Base(os.Args[0]), di.InitUserCLI(db, queries, console), di.InitPostCLI(db, queries, console), di.InitCategoryCLI(db, queries, console), di.InitSubCategoryCLI(db, queries, console), di.InitImportCLI(db, queries, console), di.InitArchiveCLI(db, queries, console), di.InitMediaCLI(db, queries, console))
+		os.Exit(CLI.Execute(root, os.Args[1:], console.Stdout, console.Stderr))
 	} else {
 		server := http.Server{
 			Addr: "127.0.0.1:8080",
 		}
 
-		e := Env{Db: db}
+		e := Env{Db: db, Media: di.InitMedia(db, queries), Queries: queries, Compression: di.InitCompression(), Tokens: di.InitTokenConfig()}
 
-		http.HandleFunc("/api/v1/categories/", e.checkPermissionFromToken(e.handleRequestCategory))
-		http.HandleFunc("/api/v1/sub-categories/", e.checkPermissionFromToken(e.handleRequestSubCategory))
-		http.HandleFunc("/api/v1/posts/", e.checkPermissionFromToken(e.handleRequestPost))
-		http.HandleFunc("/api/v1/admin/", e.handleRequestAdmin)
+		http.HandleFunc("/api/v1/categories/", e.Compression.Wrap(e.authenticate(e.handleRequestCategory)))
+		http.HandleFunc("/api/v1/sub-categories/", e.Compression.Wrap(e.authenticate(e.handleRequestSubCategory)))
+		http.HandleFunc("/api/v1/posts/", e.Compression.Wrap(e.authenticate(e.handleRequestPost)))
+		http.HandleFunc("/api/v1/authors/", e.Compression.Wrap(e.handleRequestAuthor))
+		http.HandleFunc("/api/v1/users/", e.Compression.Wrap(e.authenticate(e.handleRequestUser)))
+		http.HandleFunc("/api/v1/admin/", e.Compression.Wrap(e.handleRequestAdmin))
+		http.HandleFunc("/api/v1/auth/", e.Compression.Wrap(e.handleRequestAuth))
 
-		http.Handle("/api/v1/media/", http.StripPrefix("/api/v1/media/", http.FileServer(http.Dir("media"))))
+		http.HandleFunc("/api/v1/media/", e.Compression.Wrap(e.authenticate(e.handleRequestMedia)))
+		http.HandleFunc("/api/v1/media-usage/", e.Compression.Wrap(e.authenticate(e.handleRequestMediaUsage)))
 
 		server.ListenAndServe()
 	}
 }
 
-func (e *Env) checkPermissionFromToken(h http.HandlerFunc) http.HandlerFunc {
+// authenticate resolves the bearer of the Authorization header and attaches
+// them to the request. Permission checks happen in the service layer.
+func (e *Env) authenticate(h http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "OPTIONS" || r.Method == "GET" {
+		if r.Method == "OPTIONS" {
 			h(w, r)
+			return
+		}
+		user := di.InitUser(e.Db, e.Queries, e.Tokens)
+		userDto, err := user.ValidateToken(w, r)
+		if err != nil {
+			handler.WriteError(w, err)
+			return
+		}
+		h(w, handler.WithUser(r, userDto))
+	}
+}
+
+// handleRequestMedia serves the media files below /api/v1/media/ to anyone; the
+// listing at /api/v1/media/ itself, uploads, changes and deletions need a token.
+func (e *Env) handleRequestMedia(w http.ResponseWriter, r *http.Request) {
+	var err error
+	name := strings.TrimPrefix(r.URL.Path, "/api/v1/media/")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	switch r.Method {
+	case "GET", "HEAD":
+		if name == "" {
+			err = e.Media.GetAll(w, r)
 		} else {
-			user := di.InitUser(e.Db)
-			isAdmin, err := user.ValidateToken(w, r)
-			if err != nil {
-				return
-			}
-			if isAdmin {
-				h(w, r)
-			} else {
-				http.Error(w, "You don't have permission", http.StatusUnauthorized)
-			}
+			err = e.Media.Serve(w, r, name)
 		}
+	case "POST":
+		err = e.Media.Upload(w, r)
+	case "PUT":
+		err = e.Media.Update(w, r, name)
+	case "DELETE":
+		err = e.Media.Delete(w, r, name)
+	}
+	if err != nil {
+		handler.WriteError(w, err)
+		return
+	}
+}
+
+// handleRequestMediaUsage tells which posts use the media files: all of them at
+// /api/v1/media-usage/, one below it by its name.
+func (e *Env) handleRequestMediaUsage(w http.ResponseWriter, r *http.Request) {
+	var err error
+	name := strings.TrimPrefix(r.URL.Path, "/api/v1/media-usage/")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	switch r.Method {
+	case "GET":
+		if name == "" {
+			err = e.Media.GetUsage(w, r)
+		} else {
+			err = e.Media.GetUsageByName(w, r, name)
+		}
+	}
+	if err != nil {
+		handler.WriteError(w, err)
+		return
 	}
 }
 
 func (e *Env) handleRequestAdmin(w http.ResponseWriter, r *http.Request) {
 	var err error
-	user := di.InitUser(e.Db)
+	user := di.InitUser(e.Db, e.Queries, e.Tokens)
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
@@ -93,62 +145,151 @@ func (e *Env) handleRequestAdmin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		handler.WriteError(w, err)
 		return
 	}
 	return
 }
 
-func (e *Env) handleRequestCategory(w http.ResponseWriter, r *http.Request) {
+func (e *Env) handleRequestAuth(w http.ResponseWriter, r *http.Request) {
 	var err error
-	category := di.InitCategory(e.Db)
+	user := di.InitUser(e.Db, e.Queries, e.Tokens)
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	if r.Method != "POST" {
+		return
+	}
+	switch path.Base(r.URL.Path) {
+	case "refresh":
+		err = user.Refresh(w, r)
+	case "logout":
+		err = user.Logout(w, r)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		handler.WriteError(w, err)
+		return
+	}
+}
+
+func (e *Env) handleRequestAuthor(w http.ResponseWriter, r *http.Request) {
+	var err error
+	user := di.InitUser(e.Db, e.Queries, e.Tokens)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	switch r.Method {
+	case "GET":
+		err = user.GetAuthor(w, r, path.Base(r.URL.Path))
+	}
+	if err != nil {
+		handler.WriteError(w, err)
+		return
+	}
+}
+
+func (e *Env) handleRequestUser(w http.ResponseWriter, r *http.Request) {
+	var err error
+	user := di.InitUser(e.Db, e.Queries, e.Tokens)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 	switch r.Method {
 	case "GET":
-		err = category.GetAll(w, r)
+		err = user.GetAll(w, r)
+	case "PUT":
+		err = user.Update(w, r)
+	case "DELETE":
+		err = user.Delete(w, r)
+	}
+	if err != nil {
+		handler.WriteError(w, err)
+		return
+	}
+}
+
+func (e *Env) handleRequestCategory(w http.ResponseWriter, r *http.Request) {
+	var err error
+	category := di.InitCategory(e.Db, e.Queries)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-None-Match, If-Modified-Since, If-Match")
+	w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified")
+	w.Header().Set("Accept-Patch", handler.AcceptPatch)
+	switch r.Method {
+	case "GET":
+		switch path.Base(r.URL.Path) {
+		case "tree":
+			err = category.GetTree(w, r)
+		case "breadcrumbs":
+			slug := path.Base(path.Dir(path.Clean(r.URL.Path)))
+			err = category.GetBreadcrumbs(w, r, slug)
+		case "categories":
+			err = category.GetAll(w, r)
+		default:
+			err = category.GetBySlug(w, r, path.Base(r.URL.Path))
+		}
 	case "POST":
 		err = category.Create(w, r)
 	case "PUT":
-		err = category.Update(w, r)
+		if path.Base(r.URL.Path) == "reorder" {
+			err = category.Reorder(w, r)
+		} else {
+			err = category.Update(w, r)
+		}
+	case "PATCH":
+		err = category.Patch(w, r)
 	case "DELETE":
 		err = category.Delete(w, r)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		handler.WriteError(w, err)
 		return
 	}
 }
 
 func (e *Env) handleRequestSubCategory(w http.ResponseWriter, r *http.Request) {
 	var err error
-	subCategory := di.InitSubCategory(e.Db)
+	subCategory := di.InitSubCategory(e.Db, e.Queries)
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-None-Match, If-Modified-Since, If-Match")
+	w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified")
+	w.Header().Set("Accept-Patch", handler.AcceptPatch)
 	switch r.Method {
 	case "GET":
-		err = subCategory.GetSubCategories(w, r)
+		slug := path.Base(r.URL.Path)
+		if slug == "sub-categories" {
+			err = subCategory.GetSubCategories(w, r)
+		} else {
+			err = subCategory.GetSubCategoryBySlug(w, r, slug)
+		}
 	case "POST":
 		err = subCategory.Create(w, r)
 	case "PUT":
 		err = subCategory.Update(w, r)
+	case "PATCH":
+		err = subCategory.Patch(w, r)
 	case "DELETE":
 		err = subCategory.Delete(w, r)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		handler.WriteError(w, err)
 		return
 	}
 }
 
 func (e *Env) handleRequestPost(w http.ResponseWriter, r *http.Request) {
 	var err error
-	post := di.InitPost(e.Db)
+	post := di.InitPost(e.Db, e.Queries)
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-None-Match, If-Modified-Since, If-Match")
+	w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified")
+	w.Header().Set("Accept-Patch", handler.AcceptPatch)
 	switch r.Method {
 	case "GET":
 		slug := path.Base(r.URL.Path)
@@ -162,11 +303,13 @@ func (e *Env) handleRequestPost(w http.ResponseWriter, r *http.Request) {
 		err = post.Create(w, r)
 	case "PUT":
 		err = post.Update(w, r)
+	case "PATCH":
+		err = post.Patch(w, r)
 	case "DELETE":
 		err = post.Delete(w, r)
 	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		handler.WriteError(w, err)
 		return
 	}
 }
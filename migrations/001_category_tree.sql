@@ -0,0 +1,52 @@
+-- Merge sub_categories into a single self-referencing categories tree.
+-- Run once against a database created from the previous setup.sql.
+
+begin;
+
+alter table categories add column parent_id integer references categories(id);
+alter table categories add column legacy_sub_category_id integer;
+
+-- sub-category slugs/names that collide with a category get the parent's slug as prefix
+insert into categories (name, slug, parent_id, legacy_sub_category_id)
+select
+    case when exists (select 1 from categories c where c.name = sub_categories.name)
+        then parent.name || ' / ' || sub_categories.name else sub_categories.name end,
+    case when exists (select 1 from categories c where c.slug = sub_categories.slug)
+        then parent.slug || '-' || sub_categories.slug else sub_categories.slug end,
+    sub_categories.parent_category_id,
+    sub_categories.id
+from sub_categories inner join categories parent on sub_categories.parent_category_id = parent.id
+order by sub_categories.id;
+
+alter table posts add column category_id integer references categories(id);
+
+update posts set category_id = categories.id
+from categories
+where categories.legacy_sub_category_id = posts.sub_category_id;
+
+alter table posts drop column sub_category_id;
+alter table categories drop column legacy_sub_category_id;
+drop table sub_categories;
+
+create function prevent_category_cycle()
+returns trigger as $$
+begin
+    if new.parent_id is not null and exists (
+        with recursive ancestors as (
+            select id, parent_id from categories where id = new.parent_id
+            union all
+            select categories.id, categories.parent_id
+            from categories inner join ancestors on categories.id = ancestors.parent_id
+            where ancestors.id <> new.id
+        )
+        select 1 from ancestors where id = new.id
+    ) then
+        raise exception 'category % cannot be moved under its own descendant', new.id;
+    end if;
+    return new;
+end;
+$$ language 'plpgsql';
+
+create trigger prevent_categories_cycle before update of parent_id on categories for each row execute procedure prevent_category_cycle();
+
+commit;
@@ -0,0 +1,17 @@
+-- Add display order, description and cover image to categories.
+
+begin;
+
+alter table categories add column position integer default 0 not null;
+alter table categories add column description text default '' not null;
+alter table categories add column cover_image varchar(2048) default '' not null;
+
+-- keep the current (id) order as the initial order among siblings
+update categories set position = ordered.position
+from (
+    select id, row_number() over (partition by parent_id order by id) - 1 as position
+    from categories
+) as ordered
+where categories.id = ordered.id;
+
+commit;
@@ -0,0 +1,15 @@
+-- Replace users.is_admin with a role and record the author of each post.
+
+begin;
+
+alter table users add column role varchar(16) default 'viewer' not null
+    check (role in ('admin', 'editor', 'author', 'viewer'));
+
+-- is_admin defaulted to TRUE, so every existing account keeps its current access
+update users set role = case when is_admin then 'admin' else 'viewer' end;
+
+alter table users drop column is_admin;
+
+alter table posts add column author_id integer references users(id) on delete set null;
+
+commit;
@@ -0,0 +1,12 @@
+-- Add the public author profile to users.
+
+begin;
+
+alter table users add column display_name varchar(255) default '' not null;
+alter table users add column bio text default '' not null;
+alter table users add column avatar_url varchar(2048) default '' not null;
+
+-- /authors/{username} looks users up by name
+alter table users add constraint users_username_key unique (username);
+
+commit;
@@ -0,0 +1,18 @@
+-- Store rotating refresh tokens. Tokens of the same login share a family_id.
+
+begin;
+
+create table refresh_tokens (
+    id serial primary key,
+    user_id integer references users(id) on delete cascade not null,
+    family_id varchar(64) not null,
+    token_hash char(64) unique not null,
+    expires_at timestamp with time zone not null,
+    created_at timestamp with time zone default current_timestamp not null,
+    revoked_at timestamp with time zone
+);
+
+create index refresh_tokens_family_id_idx on refresh_tokens (family_id);
+create index refresh_tokens_user_id_idx on refresh_tokens (user_id);
+
+commit;
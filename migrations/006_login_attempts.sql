@@ -0,0 +1,12 @@
+-- Track failed logins per username and per client IP for backoff and lockout.
+
+begin;
+
+create table login_attempts (
+    key varchar(320) primary key,
+    failures integer not null,
+    last_failure_at timestamp with time zone not null,
+    locked_until timestamp with time zone
+);
+
+commit;
@@ -0,0 +1,21 @@
+-- Store TOTP enrollments and hashed one-time recovery codes.
+
+begin;
+
+create table user_totp (
+    user_id integer primary key references users(id) on delete cascade,
+    secret varchar(64) not null,
+    last_used_step bigint default 0 not null,
+    created_at timestamp with time zone default current_timestamp not null
+);
+
+create table recovery_codes (
+    id serial primary key,
+    user_id integer references users(id) on delete cascade not null,
+    code_hash char(64) not null,
+    used_at timestamp with time zone
+);
+
+create index recovery_codes_user_id_idx on recovery_codes (user_id);
+
+commit;
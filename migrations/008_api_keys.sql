@@ -0,0 +1,17 @@
+-- Store hashed, scoped API keys for machine clients.
+
+begin;
+
+create table api_keys (
+    id serial primary key,
+    user_id integer references users(id) on delete cascade not null,
+    name varchar(255) not null,
+    prefix char(8) unique not null,
+    key_hash char(64) not null,
+    scopes text[] not null,
+    created_at timestamp with time zone default current_timestamp not null,
+    last_used_at timestamp with time zone,
+    revoked_at timestamp with time zone
+);
+
+commit;
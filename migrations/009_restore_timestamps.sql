@@ -0,0 +1,15 @@
+-- Let restoring an archive keep the updated_at of its posts.
+
+begin;
+
+create or replace function update_timestamp()
+returns trigger as $$
+begin
+    if coalesce(current_setting('blog.restoring', true), '') <> 'on' then
+        new.updated_at := now();
+    end if;
+    return new;
+end;
+$$ language 'plpgsql';
+
+commit;
@@ -0,0 +1,14 @@
+-- Record the resized copies generated for uploaded images.
+
+begin;
+
+create table image_derivatives (
+    source varchar(255) not null,
+    kind varchar(32) not null,
+    name varchar(255) not null,
+    width integer not null,
+    height integer not null,
+    primary key (source, kind)
+);
+
+commit;
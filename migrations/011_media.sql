@@ -0,0 +1,16 @@
+-- Record uploaded media files, so that unused ones can be found.
+
+begin;
+
+create table media (
+    name varchar(255) primary key,
+    content_type varchar(64) not null,
+    size bigint not null,
+    width integer,
+    height integer,
+    alt_text text default '' not null,
+    uploaded_by integer references users(id) on delete set null,
+    created_at timestamp with time zone default current_timestamp not null
+);
+
+commit;
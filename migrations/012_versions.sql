@@ -0,0 +1,9 @@
+-- Count the updates of posts and categories, so that an update based on a stale
+-- copy can be detected.
+
+begin;
+
+alter table posts add column version integer default 1 not null;
+alter table categories add column version integer default 1 not null;
+
+commit;
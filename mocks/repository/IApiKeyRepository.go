@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"backend/app/domain/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+type IApiKeyRepository struct {
+	mock.Mock
+}
+
+func (_m *IApiKeyRepository) GetAll() (apiKeys []entity.ApiKey, err error) {
+	ret := _m.Called()
+
+	if rf, ok := ret.Get(0).(func() []entity.ApiKey); ok {
+		apiKeys = rf()
+	} else {
+		if ret.Get(0) != nil {
+			apiKeys = ret.Get(0).([]entity.ApiKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		err = rf()
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IApiKeyRepository) GetByPrefix(prefix string) (apiKey entity.ApiKey, err error) {
+	ret := _m.Called(prefix)
+
+	if rf, ok := ret.Get(0).(func(string) entity.ApiKey); ok {
+		apiKey = rf(prefix)
+	} else {
+		if ret.Get(0) != nil {
+			apiKey = ret.Get(0).(entity.ApiKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		err = rf(prefix)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IApiKeyRepository) Create(apiKey entity.ApiKey) (err error) {
+	ret := _m.Called(apiKey)
+
+	if rf, ok := ret.Get(0).(func(entity.ApiKey) error); ok {
+		err = rf(apiKey)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *IApiKeyRepository) Revoke(prefix string) (err error) {
+	ret := _m.Called(prefix)
+
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		err = rf(prefix)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *IApiKeyRepository) Touch(id int) (err error) {
+	ret := _m.Called(id)
+
+	if rf, ok := ret.Get(0).(func(int) error); ok {
+		err = rf(id)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
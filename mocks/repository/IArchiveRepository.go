@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"backend/app/domain/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+type IArchiveRepository struct {
+	mock.Mock
+}
+
+func (_m *IArchiveRepository) Dump() (archive entity.Archive, err error) {
+	ret := _m.Called()
+
+	if rf, ok := ret.Get(0).(func() entity.Archive); ok {
+		archive = rf()
+	} else {
+		if ret.Get(0) != nil {
+			archive = ret.Get(0).(entity.Archive)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		err = rf()
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IArchiveRepository) Restore(archive entity.Archive, replace bool) (err error) {
+	ret := _m.Called(archive, replace)
+
+	if rf, ok := ret.Get(0).(func(entity.Archive, bool) error); ok {
+		err = rf(archive, replace)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
@@ -80,3 +80,90 @@ func (_m *ICategoryRepository) Delete(category entity.Category) (err error) {
 	}
 	return
 }
+
+func (_m *ICategoryRepository) GetTree() (categories []entity.Category, err error) {
+	ret := _m.Called()
+
+	if rf, ok := ret.Get(0).(func() []entity.Category); ok {
+		categories = rf()
+	} else {
+		if ret.Get(0) != nil {
+			categories = ret.Get(0).([]entity.Category)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		err = rf()
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *ICategoryRepository) GetAncestors(id int) (categories []entity.Category, err error) {
+	ret := _m.Called(id)
+
+	if rf, ok := ret.Get(0).(func(int) []entity.Category); ok {
+		categories = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			categories = ret.Get(0).([]entity.Category)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		err = rf(id)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *ICategoryRepository) Reorder(ids []int) (err error) {
+	ret := _m.Called(ids)
+
+	if rf, ok := ret.Get(0).(func([]int) error); ok {
+		err = rf(ids)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *ICategoryRepository) GetChildren(parentId int) (categories []entity.Category, err error) {
+	ret := _m.Called(parentId)
+
+	if rf, ok := ret.Get(0).(func(int) []entity.Category); ok {
+		categories = rf(parentId)
+	} else {
+		if ret.Get(0) != nil {
+			categories = ret.Get(0).([]entity.Category)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		err = rf(parentId)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *ICategoryRepository) GetStats(ids []int) (stats map[int]entity.CategoryStats, err error) {
+	ret := _m.Called(ids)
+
+	if rf, ok := ret.Get(0).(func([]int) map[int]entity.CategoryStats); ok {
+		stats = rf(ids)
+	} else {
+		if ret.Get(0) != nil {
+			stats = ret.Get(0).(map[int]entity.CategoryStats)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]int) error); ok {
+		err = rf(ids)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
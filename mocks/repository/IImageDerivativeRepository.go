@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"backend/app/domain/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+type IImageDerivativeRepository struct {
+	mock.Mock
+}
+
+func (_m *IImageDerivativeRepository) GetAll() (derivatives []entity.ImageDerivative, err error) {
+	ret := _m.Called()
+
+	if rf, ok := ret.Get(0).(func() []entity.ImageDerivative); ok {
+		derivatives = rf()
+	} else {
+		if ret.Get(0) != nil {
+			derivatives = ret.Get(0).([]entity.ImageDerivative)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		err = rf()
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IImageDerivativeRepository) GetBySource(source string) (derivatives []entity.ImageDerivative, err error) {
+	ret := _m.Called(source)
+
+	if rf, ok := ret.Get(0).(func(string) []entity.ImageDerivative); ok {
+		derivatives = rf(source)
+	} else {
+		if ret.Get(0) != nil {
+			derivatives = ret.Get(0).([]entity.ImageDerivative)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		err = rf(source)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IImageDerivativeRepository) Save(source string, derivatives []entity.ImageDerivative) (err error) {
+	ret := _m.Called(source, derivatives)
+
+	if rf, ok := ret.Get(0).(func(string, []entity.ImageDerivative) error); ok {
+		err = rf(source, derivatives)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *IImageDerivativeRepository) Delete(source string) (derivatives []entity.ImageDerivative, err error) {
+	ret := _m.Called(source)
+
+	if rf, ok := ret.Get(0).(func(string) []entity.ImageDerivative); ok {
+		derivatives = rf(source)
+	} else {
+		if ret.Get(0) != nil {
+			derivatives = ret.Get(0).([]entity.ImageDerivative)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		err = rf(source)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"backend/app/domain/entity"
+	"time"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+type ILoginAttemptRepository struct {
+	mock.Mock
+}
+
+func (_m *ILoginAttemptRepository) Get(key string) (loginAttempt entity.LoginAttempt, err error) {
+	ret := _m.Called(key)
+
+	if rf, ok := ret.Get(0).(func(string) entity.LoginAttempt); ok {
+		loginAttempt = rf(key)
+	} else {
+		if ret.Get(0) != nil {
+			loginAttempt = ret.Get(0).(entity.LoginAttempt)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		err = rf(key)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *ILoginAttemptRepository) RecordFailure(key string, windowStart time.Time) (failures int, err error) {
+	ret := _m.Called(key, windowStart)
+
+	if rf, ok := ret.Get(0).(func(string, time.Time) int); ok {
+		failures = rf(key, windowStart)
+	} else {
+		if ret.Get(0) != nil {
+			failures = ret.Get(0).(int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, time.Time) error); ok {
+		err = rf(key, windowStart)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *ILoginAttemptRepository) Lock(key string, until time.Time) (err error) {
+	ret := _m.Called(key, until)
+
+	if rf, ok := ret.Get(0).(func(string, time.Time) error); ok {
+		err = rf(key, until)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *ILoginAttemptRepository) Reset(key string) (err error) {
+	ret := _m.Called(key)
+
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		err = rf(key)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
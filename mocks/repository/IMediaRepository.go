@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"backend/app/domain/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+type IMediaRepository struct {
+	mock.Mock
+}
+
+func (_m *IMediaRepository) GetAll() (media []entity.Media, err error) {
+	ret := _m.Called()
+
+	if rf, ok := ret.Get(0).(func() []entity.Media); ok {
+		media = rf()
+	} else {
+		if ret.Get(0) != nil {
+			media = ret.Get(0).([]entity.Media)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		err = rf()
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IMediaRepository) GetByName(name string) (media entity.Media, err error) {
+	ret := _m.Called(name)
+
+	if rf, ok := ret.Get(0).(func(string) entity.Media); ok {
+		media = rf(name)
+	} else {
+		media = ret.Get(0).(entity.Media)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		err = rf(name)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IMediaRepository) Save(media entity.Media) (err error) {
+	ret := _m.Called(media)
+
+	if rf, ok := ret.Get(0).(func(entity.Media) error); ok {
+		err = rf(media)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *IMediaRepository) Delete(name string) (err error) {
+	ret := _m.Called(name)
+
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		err = rf(name)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *IMediaRepository) GetReferringPosts() (posts []entity.Post, err error) {
+	ret := _m.Called()
+
+	if rf, ok := ret.Get(0).(func() []entity.Post); ok {
+		posts = rf()
+	} else {
+		if ret.Get(0) != nil {
+			posts = ret.Get(0).([]entity.Post)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		err = rf()
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IMediaRepository) GetReferringCategories() (categories []entity.Category, err error) {
+	ret := _m.Called()
+
+	if rf, ok := ret.Get(0).(func() []entity.Category); ok {
+		categories = rf()
+	} else {
+		if ret.Get(0) != nil {
+			categories = ret.Get(0).([]entity.Category)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		err = rf()
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IMediaRepository) GetReferringUsers() (users []entity.User, err error) {
+	ret := _m.Called()
+
+	if rf, ok := ret.Get(0).(func() []entity.User); ok {
+		users = rf()
+	} else {
+		if ret.Get(0) != nil {
+			users = ret.Get(0).([]entity.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		err = rf()
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"backend/app/domain/entity"
+	"io"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+type IMediaStorage struct {
+	mock.Mock
+}
+
+func (_m *IMediaStorage) Put(name string, r io.Reader, size int64, contentType string) (err error) {
+	ret := _m.Called(name, r, size, contentType)
+
+	if rf, ok := ret.Get(0).(func(string, io.Reader, int64, string) error); ok {
+		err = rf(name, r, size, contentType)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *IMediaStorage) Open(name string) (content io.ReadCloser, object entity.MediaObject, err error) {
+	ret := _m.Called(name)
+
+	if rf, ok := ret.Get(0).(func(string) io.ReadCloser); ok {
+		content = rf(name)
+	} else {
+		if ret.Get(0) != nil {
+			content = ret.Get(0).(io.ReadCloser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) entity.MediaObject); ok {
+		object = rf(name)
+	} else {
+		if ret.Get(1) != nil {
+			object = ret.Get(1).(entity.MediaObject)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		err = rf(name)
+	} else {
+		err = ret.Error(2)
+	}
+	return
+}
+
+func (_m *IMediaStorage) Stat(name string) (object entity.MediaObject, err error) {
+	ret := _m.Called(name)
+
+	if rf, ok := ret.Get(0).(func(string) entity.MediaObject); ok {
+		object = rf(name)
+	} else {
+		if ret.Get(0) != nil {
+			object = ret.Get(0).(entity.MediaObject)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		err = rf(name)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IMediaStorage) List() (objects []entity.MediaObject, err error) {
+	ret := _m.Called()
+
+	if rf, ok := ret.Get(0).(func() []entity.MediaObject); ok {
+		objects = rf()
+	} else {
+		if ret.Get(0) != nil {
+			objects = ret.Get(0).([]entity.MediaObject)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		err = rf()
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IMediaStorage) Delete(name string) (err error) {
+	ret := _m.Called(name)
+
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		err = rf(name)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"backend/app/domain/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+type IRefreshTokenRepository struct {
+	mock.Mock
+}
+
+func (_m *IRefreshTokenRepository) GetByHash(tokenHash string) (refreshToken entity.RefreshToken, err error) {
+	ret := _m.Called(tokenHash)
+
+	if rf, ok := ret.Get(0).(func(string) entity.RefreshToken); ok {
+		refreshToken = rf(tokenHash)
+	} else {
+		if ret.Get(0) != nil {
+			refreshToken = ret.Get(0).(entity.RefreshToken)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		err = rf(tokenHash)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IRefreshTokenRepository) Create(refreshToken entity.RefreshToken) (err error) {
+	ret := _m.Called(refreshToken)
+
+	if rf, ok := ret.Get(0).(func(entity.RefreshToken) error); ok {
+		err = rf(refreshToken)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *IRefreshTokenRepository) Revoke(id int) (revoked bool, err error) {
+	ret := _m.Called(id)
+
+	if rf, ok := ret.Get(0).(func(int) bool); ok {
+		revoked = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			revoked = ret.Get(0).(bool)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		err = rf(id)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IRefreshTokenRepository) RevokeFamily(familyId string) (err error) {
+	ret := _m.Called(familyId)
+
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		err = rf(familyId)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *IRefreshTokenRepository) RevokeAllForUser(userId int) (err error) {
+	ret := _m.Called(userId)
+
+	if rf, ok := ret.Get(0).(func(int) error); ok {
+		err = rf(userId)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
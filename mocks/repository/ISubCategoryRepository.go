@@ -100,3 +100,41 @@ func (_m *ISubCategoryRepository) GetNameFromParentCategoryId(id int) (name stri
 	}
 	return
 }
+
+func (_m *ISubCategoryRepository) GetStats(ids []int) (stats map[int]entity.CategoryStats, err error) {
+	ret := _m.Called(ids)
+
+	if rf, ok := ret.Get(0).(func([]int) map[int]entity.CategoryStats); ok {
+		stats = rf(ids)
+	} else {
+		if ret.Get(0) != nil {
+			stats = ret.Get(0).(map[int]entity.CategoryStats)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]int) error); ok {
+		err = rf(ids)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *ISubCategoryRepository) GetAncestorIds(id int) (ids []int, err error) {
+	ret := _m.Called(id)
+
+	if rf, ok := ret.Get(0).(func(int) []int); ok {
+		ids = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			ids = ret.Get(0).([]int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		err = rf(id)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"backend/app/domain/entity"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+type ITwoFactorRepository struct {
+	mock.Mock
+}
+
+func (_m *ITwoFactorRepository) GetByUserId(userId int) (twoFactor entity.TwoFactor, err error) {
+	ret := _m.Called(userId)
+
+	if rf, ok := ret.Get(0).(func(int) entity.TwoFactor); ok {
+		twoFactor = rf(userId)
+	} else {
+		if ret.Get(0) != nil {
+			twoFactor = ret.Get(0).(entity.TwoFactor)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		err = rf(userId)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *ITwoFactorRepository) Enable(twoFactor entity.TwoFactor, recoveryCodeHashes []string) (err error) {
+	ret := _m.Called(twoFactor, recoveryCodeHashes)
+
+	if rf, ok := ret.Get(0).(func(entity.TwoFactor, []string) error); ok {
+		err = rf(twoFactor, recoveryCodeHashes)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *ITwoFactorRepository) Disable(userId int) (err error) {
+	ret := _m.Called(userId)
+
+	if rf, ok := ret.Get(0).(func(int) error); ok {
+		err = rf(userId)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *ITwoFactorRepository) UseStep(userId int, step int64) (used bool, err error) {
+	ret := _m.Called(userId, step)
+
+	if rf, ok := ret.Get(0).(func(int, int64) bool); ok {
+		used = rf(userId, step)
+	} else {
+		if ret.Get(0) != nil {
+			used = ret.Get(0).(bool)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int, int64) error); ok {
+		err = rf(userId, step)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *ITwoFactorRepository) UseRecoveryCode(userId int, codeHash string) (used bool, err error) {
+	ret := _m.Called(userId, codeHash)
+
+	if rf, ok := ret.Get(0).(func(int, string) bool); ok {
+		used = rf(userId, codeHash)
+	} else {
+		if ret.Get(0) != nil {
+			used = ret.Get(0).(bool)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int, string) error); ok {
+		err = rf(userId, codeHash)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
@@ -29,25 +29,6 @@ func (_m *IUserRepository) GetAll() (users []entity.User, err error) {
 	return
 }
 
-func (_m *IUserRepository) ValidateUser(creds entity.Credentials) (user entity.User, err error) {
-	ret := _m.Called(creds)
-
-	if rf, ok := ret.Get(0).(func(entity.Credentials) entity.User); ok {
-		user = rf(creds)
-	} else {
-		if ret.Get(0) != nil {
-			user = ret.Get(0).(entity.User)
-		}
-	}
-
-	if rf, ok := ret.Get(1).(func(entity.Credentials) error); ok {
-		err = rf(creds)
-	} else {
-		err = ret.Error(1)
-	}
-	return
-}
-
 func (_m *IUserRepository) Create(user entity.User) (err error) {
 	ret := _m.Called(user)
 
@@ -81,14 +62,14 @@ func (_m *IUserRepository) Delete(user entity.User) (err error) {
 	return
 }
 
-func (_m *IUserRepository) IsAdmin(id int) (isAdmin bool, err error) {
+func (_m *IUserRepository) GetById(id int) (user entity.User, err error) {
 	ret := _m.Called(id)
 
-	if rf, ok := ret.Get(0).(func(int) bool); ok {
-		isAdmin = rf(id)
+	if rf, ok := ret.Get(0).(func(int) entity.User); ok {
+		user = rf(id)
 	} else {
 		if ret.Get(0) != nil {
-			isAdmin = ret.Get(0).(bool)
+			user = ret.Get(0).(entity.User)
 		}
 	}
 
@@ -99,3 +80,22 @@ func (_m *IUserRepository) IsAdmin(id int) (isAdmin bool, err error) {
 	}
 	return
 }
+
+func (_m *IUserRepository) GetByUsername(username string) (user entity.User, err error) {
+	ret := _m.Called(username)
+
+	if rf, ok := ret.Get(0).(func(string) entity.User); ok {
+		user = rf(username)
+	} else {
+		if ret.Get(0) != nil {
+			user = ret.Get(0).(entity.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		err = rf(username)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
@@ -48,35 +48,122 @@ func (_m *ICategoryService) GetBySlug(slug string) (category dto.CategoryModel,
 	return
 }
 
-func (_m *ICategoryService) Create(categoryDto dto.CategoryModel) (err error) {
-	ret := _m.Called(categoryDto)
+func (_m *ICategoryService) Create(actor dto.UserModel, categoryDto dto.CategoryModel) (err error) {
+	ret := _m.Called(actor, categoryDto)
 
-	if rf, ok := ret.Get(0).(func(dto.CategoryModel) error); ok {
-		err = rf(categoryDto)
+	if rf, ok := ret.Get(0).(func(dto.UserModel, dto.CategoryModel) error); ok {
+		err = rf(actor, categoryDto)
 	} else {
 		err = ret.Error(0)
 	}
 	return
 }
 
-func (_m *ICategoryService) Update(categoryDto dto.CategoryModel) (err error) {
-	ret := _m.Called(categoryDto)
+func (_m *ICategoryService) Update(actor dto.UserModel, categoryDto dto.CategoryModel) (err error) {
+	ret := _m.Called(actor, categoryDto)
 
-	if rf, ok := ret.Get(0).(func(dto.CategoryModel) error); ok {
-		err = rf(categoryDto)
+	if rf, ok := ret.Get(0).(func(dto.UserModel, dto.CategoryModel) error); ok {
+		err = rf(actor, categoryDto)
 	} else {
 		err = ret.Error(0)
 	}
 	return
 }
 
-func (_m *ICategoryService) Delete(categoryDto dto.CategoryModel) (err error) {
-	ret := _m.Called(categoryDto)
+func (_m *ICategoryService) Delete(actor dto.UserModel, categoryDto dto.CategoryModel) (err error) {
+	ret := _m.Called(actor, categoryDto)
 
-	if rf, ok := ret.Get(0).(func(dto.CategoryModel) error); ok {
-		err = rf(categoryDto)
+	if rf, ok := ret.Get(0).(func(dto.UserModel, dto.CategoryModel) error); ok {
+		err = rf(actor, categoryDto)
 	} else {
 		err = ret.Error(0)
 	}
 	return
 }
+
+func (_m *ICategoryService) GetTree() (categories []dto.CategoryModel, err error) {
+	ret := _m.Called()
+
+	if rf, ok := ret.Get(0).(func() []dto.CategoryModel); ok {
+		categories = rf()
+	} else {
+		if ret.Get(0) != nil {
+			categories = ret.Get(0).([]dto.CategoryModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		err = rf()
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *ICategoryService) GetBreadcrumbs(slug string) (categories []dto.CategoryModel, err error) {
+	ret := _m.Called(slug)
+
+	if rf, ok := ret.Get(0).(func(string) []dto.CategoryModel); ok {
+		categories = rf(slug)
+	} else {
+		if ret.Get(0) != nil {
+			categories = ret.Get(0).([]dto.CategoryModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		err = rf(slug)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *ICategoryService) Reorder(actor dto.UserModel, orderDto dto.CategoryOrderModel) (err error) {
+	ret := _m.Called(actor, orderDto)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, dto.CategoryOrderModel) error); ok {
+		err = rf(actor, orderDto)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *ICategoryService) GetAllWithCounts() (categories []dto.CategoryModel, err error) {
+	ret := _m.Called()
+
+	if rf, ok := ret.Get(0).(func() []dto.CategoryModel); ok {
+		categories = rf()
+	} else {
+		if ret.Get(0) != nil {
+			categories = ret.Get(0).([]dto.CategoryModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		err = rf()
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *ICategoryService) GetDetailBySlug(slug string) (category dto.CategoryModel, err error) {
+	ret := _m.Called(slug)
+
+	if rf, ok := ret.Get(0).(func(string) dto.CategoryModel); ok {
+		category = rf(slug)
+	} else {
+		if ret.Get(0) != nil {
+			category = ret.Get(0).(dto.CategoryModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		err = rf(slug)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
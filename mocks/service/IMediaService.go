@@ -0,0 +1,170 @@
+package service
+
+import (
+	"backend/app/common/dto"
+	"io"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type IMediaService struct {
+	mock.Mock
+}
+
+func (_m *IMediaService) Upload(actor dto.UserModel, r io.Reader, altText string) (mediaDto dto.MediaModel, created bool, err error) {
+	ret := _m.Called(actor, r, altText)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, io.Reader, string) dto.MediaModel); ok {
+		mediaDto = rf(actor, r, altText)
+	} else {
+		if ret.Get(0) != nil {
+			mediaDto = ret.Get(0).(dto.MediaModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(dto.UserModel, io.Reader, string) bool); ok {
+		created = rf(actor, r, altText)
+	} else {
+		created = ret.Bool(1)
+	}
+
+	if rf, ok := ret.Get(2).(func(dto.UserModel, io.Reader, string) error); ok {
+		err = rf(actor, r, altText)
+	} else {
+		err = ret.Error(2)
+	}
+	return
+}
+
+func (_m *IMediaService) GetAll(actor dto.UserModel) (mediaDtos []dto.MediaModel, err error) {
+	ret := _m.Called(actor)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel) []dto.MediaModel); ok {
+		mediaDtos = rf(actor)
+	} else {
+		if ret.Get(0) != nil {
+			mediaDtos = ret.Get(0).([]dto.MediaModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(dto.UserModel) error); ok {
+		err = rf(actor)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IMediaService) Open(name string, accepted []string) (content io.ReadCloser, mediaDto dto.MediaModel, err error) {
+	ret := _m.Called(name, accepted)
+
+	if rf, ok := ret.Get(0).(func(string, []string) io.ReadCloser); ok {
+		content = rf(name, accepted)
+	} else {
+		if ret.Get(0) != nil {
+			content = ret.Get(0).(io.ReadCloser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, []string) dto.MediaModel); ok {
+		mediaDto = rf(name, accepted)
+	} else {
+		if ret.Get(1) != nil {
+			mediaDto = ret.Get(1).(dto.MediaModel)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(string, []string) error); ok {
+		err = rf(name, accepted)
+	} else {
+		err = ret.Error(2)
+	}
+	return
+}
+
+func (_m *IMediaService) Delete(actor dto.UserModel, name string) (err error) {
+	ret := _m.Called(actor, name)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, string) error); ok {
+		err = rf(actor, name)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *IMediaService) GenerateDerivatives(actor dto.UserModel, name string) (mediaDto dto.MediaModel, err error) {
+	ret := _m.Called(actor, name)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, string) dto.MediaModel); ok {
+		mediaDto = rf(actor, name)
+	} else {
+		if ret.Get(0) != nil {
+			mediaDto = ret.Get(0).(dto.MediaModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(dto.UserModel, string) error); ok {
+		err = rf(actor, name)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IMediaService) Update(actor dto.UserModel, name string, mediaInputDto dto.MediaInputModel) (mediaDto dto.MediaModel, err error) {
+	ret := _m.Called(actor, name, mediaInputDto)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, string, dto.MediaInputModel) dto.MediaModel); ok {
+		mediaDto = rf(actor, name, mediaInputDto)
+	} else {
+		if ret.Get(0) != nil {
+			mediaDto = ret.Get(0).(dto.MediaModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(dto.UserModel, string, dto.MediaInputModel) error); ok {
+		err = rf(actor, name, mediaInputDto)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IMediaService) GetUsage(actor dto.UserModel) (usageDtos []dto.MediaUsageModel, err error) {
+	ret := _m.Called(actor)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel) []dto.MediaUsageModel); ok {
+		usageDtos = rf(actor)
+	} else {
+		if ret.Get(0) != nil {
+			usageDtos = ret.Get(0).([]dto.MediaUsageModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(dto.UserModel) error); ok {
+		err = rf(actor)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IMediaService) GetUsageByName(actor dto.UserModel, name string) (usageDto dto.MediaUsageModel, err error) {
+	ret := _m.Called(actor, name)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, string) dto.MediaUsageModel); ok {
+		usageDto = rf(actor, name)
+	} else {
+		if ret.Get(0) != nil {
+			usageDto = ret.Get(0).(dto.MediaUsageModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(dto.UserModel, string) error); ok {
+		err = rf(actor, name)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
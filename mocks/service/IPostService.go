@@ -47,33 +47,33 @@ func (_m *IPostService) GetPostBySlug(slug string) (postDto dto.PostModel, err e
 	return
 }
 
-func (_m *IPostService) Create(postDto dto.PostModel) (err error) {
-	ret := _m.Called(postDto)
+func (_m *IPostService) Create(actor dto.UserModel, postDto dto.PostModel) (err error) {
+	ret := _m.Called(actor, postDto)
 
-	if rf, ok := ret.Get(0).(func(dto.PostModel) error); ok {
-		err = rf(postDto)
+	if rf, ok := ret.Get(0).(func(dto.UserModel, dto.PostModel) error); ok {
+		err = rf(actor, postDto)
 	} else {
 		err = ret.Error(0)
 	}
 	return
 }
 
-func (_m *IPostService) Update(postDto dto.PostModel) (err error) {
-	ret := _m.Called(postDto)
+func (_m *IPostService) Update(actor dto.UserModel, postDto dto.PostModel) (err error) {
+	ret := _m.Called(actor, postDto)
 
-	if rf, ok := ret.Get(0).(func(dto.PostModel) error); ok {
-		err = rf(postDto)
+	if rf, ok := ret.Get(0).(func(dto.UserModel, dto.PostModel) error); ok {
+		err = rf(actor, postDto)
 	} else {
 		err = ret.Error(0)
 	}
 	return
 }
 
-func (_m *IPostService) Delete(postDto dto.PostModel) (err error) {
-	ret := _m.Called(postDto)
+func (_m *IPostService) Delete(actor dto.UserModel, postDto dto.PostModel) (err error) {
+	ret := _m.Called(actor, postDto)
 
-	if rf, ok := ret.Get(0).(func(dto.PostModel) error); ok {
-		err = rf(postDto)
+	if rf, ok := ret.Get(0).(func(dto.UserModel, dto.PostModel) error); ok {
+		err = rf(actor, postDto)
 	} else {
 		err = ret.Error(0)
 	}
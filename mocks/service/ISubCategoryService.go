@@ -48,35 +48,54 @@ func (_m *ISubCategoryService) GetSubCategoryBySlug(slug string) (subCategoryDto
 	return
 }
 
-func (_m ISubCategoryService) Create(subCategoryDto dto.SubCategoryModel) (err error) {
-	ret := _m.Called(subCategoryDto)
+func (_m *ISubCategoryService) Create(actor dto.UserModel, subCategoryDto dto.SubCategoryModel) (err error) {
+	ret := _m.Called(actor, subCategoryDto)
 
-	if rf, ok := ret.Get(0).(func(dto.SubCategoryModel) error); ok {
-		err = rf(subCategoryDto)
+	if rf, ok := ret.Get(0).(func(dto.UserModel, dto.SubCategoryModel) error); ok {
+		err = rf(actor, subCategoryDto)
 	} else {
 		err = ret.Error(0)
 	}
 	return
 }
 
-func (_m ISubCategoryService) Update(subCategoryDto dto.SubCategoryModel) (err error) {
-	ret := _m.Called(subCategoryDto)
+func (_m *ISubCategoryService) Update(actor dto.UserModel, subCategoryDto dto.SubCategoryModel) (err error) {
+	ret := _m.Called(actor, subCategoryDto)
 
-	if rf, ok := ret.Get(0).(func(dto.SubCategoryModel) error); ok {
-		err = rf(subCategoryDto)
+	if rf, ok := ret.Get(0).(func(dto.UserModel, dto.SubCategoryModel) error); ok {
+		err = rf(actor, subCategoryDto)
 	} else {
 		err = ret.Error(0)
 	}
 	return
 }
 
-func (_m ISubCategoryService) Delete(subCategoryDto dto.SubCategoryModel) (err error) {
-	ret := _m.Called(subCategoryDto)
+func (_m *ISubCategoryService) Delete(actor dto.UserModel, subCategoryDto dto.SubCategoryModel) (err error) {
+	ret := _m.Called(actor, subCategoryDto)
 
-	if rf, ok := ret.Get(0).(func(dto.SubCategoryModel) error); ok {
-		err = rf(subCategoryDto)
+	if rf, ok := ret.Get(0).(func(dto.UserModel, dto.SubCategoryModel) error); ok {
+		err = rf(actor, subCategoryDto)
 	} else {
 		err = ret.Error(0)
 	}
 	return
 }
+
+func (_m *ISubCategoryService) GetSubCategoryDetailBySlug(slug string) (subCategoryDto dto.SubCategoryModel, err error) {
+	ret := _m.Called(slug)
+
+	if rf, ok := ret.Get(0).(func(string) dto.SubCategoryModel); ok {
+		subCategoryDto = rf(slug)
+	} else {
+		if ret.Get(0) != nil {
+			subCategoryDto = ret.Get(0).(dto.SubCategoryModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		err = rf(slug)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
@@ -10,19 +10,38 @@ type IUserService struct {
 	mock.Mock
 }
 
-func (_m *IUserService) GetAll() (userDtos []dto.UserModel, err error) {
-	ret := _m.Called()
+func (_m *IUserService) GetAll(actor dto.UserModel) (userDtos []dto.UserModel, err error) {
+	ret := _m.Called(actor)
 
-	if rf, ok := ret.Get(0).(func() []dto.UserModel); ok {
-		userDtos = rf()
+	if rf, ok := ret.Get(0).(func(dto.UserModel) []dto.UserModel); ok {
+		userDtos = rf(actor)
 	} else {
 		if ret.Get(0) != nil {
 			userDtos = ret.Get(0).([]dto.UserModel)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func() error); ok {
-		err = rf()
+	if rf, ok := ret.Get(1).(func(dto.UserModel) error); ok {
+		err = rf(actor)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IUserService) GetById(actor dto.UserModel, id int) (userDto dto.UserModel, err error) {
+	ret := _m.Called(actor, id)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, int) dto.UserModel); ok {
+		userDto = rf(actor, id)
+	} else {
+		if ret.Get(0) != nil {
+			userDto = ret.Get(0).(dto.UserModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(dto.UserModel, int) error); ok {
+		err = rf(actor, id)
 	} else {
 		err = ret.Error(1)
 	}
@@ -48,33 +67,44 @@ func (_m *IUserService) ValidateUser(credsDto dto.CredentialsModel) (userDto dto
 	return
 }
 
-func (_m *IUserService) Create(userDto dto.UserModel) (err error) {
-	ret := _m.Called(userDto)
+func (_m *IUserService) Create(actor dto.UserModel, userInputDto dto.UserInputModel) (err error) {
+	ret := _m.Called(actor, userInputDto)
 
-	if rf, ok := ret.Get(0).(func(dto.UserModel) error); ok {
-		err = rf(userDto)
+	if rf, ok := ret.Get(0).(func(dto.UserModel, dto.UserInputModel) error); ok {
+		err = rf(actor, userInputDto)
 	} else {
 		err = ret.Error(0)
 	}
 	return
 }
 
-func (_m *IUserService) Update(userDto dto.UserModel) (err error) {
-	ret := _m.Called(userDto)
+func (_m *IUserService) Update(actor dto.UserModel, userInputDto dto.UserInputModel) (err error) {
+	ret := _m.Called(actor, userInputDto)
 
-	if rf, ok := ret.Get(0).(func(dto.UserModel) error); ok {
-		err = rf(userDto)
+	if rf, ok := ret.Get(0).(func(dto.UserModel, dto.UserInputModel) error); ok {
+		err = rf(actor, userInputDto)
 	} else {
 		err = ret.Error(0)
 	}
 	return
 }
 
-func (_m *IUserService) Delete(userDto dto.UserModel) (err error) {
-	ret := _m.Called(userDto)
+func (_m *IUserService) ChangeRole(actor dto.UserModel, id int, role string) (err error) {
+	ret := _m.Called(actor, id, role)
 
-	if rf, ok := ret.Get(0).(func(dto.UserModel) error); ok {
-		err = rf(userDto)
+	if rf, ok := ret.Get(0).(func(dto.UserModel, int, string) error); ok {
+		err = rf(actor, id, role)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *IUserService) Delete(actor dto.UserModel, userDto dto.UserModel) (err error) {
+	ret := _m.Called(actor, userDto)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, dto.UserModel) error); ok {
+		err = rf(actor, userDto)
 	} else {
 		err = ret.Error(0)
 	}
@@ -100,14 +130,14 @@ func (_m *IUserService) IssueToken(id int) (authTokenDto dto.AuthTokenModel, err
 	return
 }
 
-func (_m *IUserService) ValidateToken(authTokenDto dto.AuthTokenModel) (isAdmin bool, err error) {
+func (_m *IUserService) ValidateToken(authTokenDto dto.AuthTokenModel) (userDto dto.UserModel, err error) {
 	ret := _m.Called(authTokenDto)
 
-	if rf, ok := ret.Get(0).(func(dto.AuthTokenModel) bool); ok {
-		isAdmin = rf(authTokenDto)
+	if rf, ok := ret.Get(0).(func(dto.AuthTokenModel) dto.UserModel); ok {
+		userDto = rf(authTokenDto)
 	} else {
 		if ret.Get(0) != nil {
-			isAdmin = ret.Get(0).(bool)
+			userDto = ret.Get(0).(dto.UserModel)
 		}
 	}
 
@@ -118,3 +148,221 @@ func (_m *IUserService) ValidateToken(authTokenDto dto.AuthTokenModel) (isAdmin
 	}
 	return
 }
+
+func (_m *IUserService) GetAuthor(username string) (authorDto dto.AuthorModel, err error) {
+	ret := _m.Called(username)
+
+	if rf, ok := ret.Get(0).(func(string) dto.AuthorModel); ok {
+		authorDto = rf(username)
+	} else {
+		if ret.Get(0) != nil {
+			authorDto = ret.Get(0).(dto.AuthorModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		err = rf(username)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IUserService) Refresh(refreshTokenDto dto.RefreshTokenModel) (authTokenDto dto.AuthTokenModel, err error) {
+	ret := _m.Called(refreshTokenDto)
+
+	if rf, ok := ret.Get(0).(func(dto.RefreshTokenModel) dto.AuthTokenModel); ok {
+		authTokenDto = rf(refreshTokenDto)
+	} else {
+		if ret.Get(0) != nil {
+			authTokenDto = ret.Get(0).(dto.AuthTokenModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(dto.RefreshTokenModel) error); ok {
+		err = rf(refreshTokenDto)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IUserService) Logout(refreshTokenDto dto.RefreshTokenModel) (err error) {
+	ret := _m.Called(refreshTokenDto)
+
+	if rf, ok := ret.Get(0).(func(dto.RefreshTokenModel) error); ok {
+		err = rf(refreshTokenDto)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *IUserService) RevokeAllTokens(actor dto.UserModel, username string) (err error) {
+	ret := _m.Called(actor, username)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, string) error); ok {
+		err = rf(actor, username)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *IUserService) UnlockUser(actor dto.UserModel, username string) (err error) {
+	ret := _m.Called(actor, username)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, string) error); ok {
+		err = rf(actor, username)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *IUserService) UnlockAddress(actor dto.UserModel, ip string) (err error) {
+	ret := _m.Called(actor, ip)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, string) error); ok {
+		err = rf(actor, ip)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *IUserService) NewTwoFactorSecret(actor dto.UserModel, username string) (enrollmentDto dto.TwoFactorEnrollmentModel, err error) {
+	ret := _m.Called(actor, username)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, string) dto.TwoFactorEnrollmentModel); ok {
+		enrollmentDto = rf(actor, username)
+	} else {
+		if ret.Get(0) != nil {
+			enrollmentDto = ret.Get(0).(dto.TwoFactorEnrollmentModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(dto.UserModel, string) error); ok {
+		err = rf(actor, username)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IUserService) EnableTwoFactor(actor dto.UserModel, username string, secret string, code string) (recoveryCodes []string, err error) {
+	ret := _m.Called(actor, username, secret, code)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, string, string, string) []string); ok {
+		recoveryCodes = rf(actor, username, secret, code)
+	} else {
+		if ret.Get(0) != nil {
+			recoveryCodes = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(dto.UserModel, string, string, string) error); ok {
+		err = rf(actor, username, secret, code)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IUserService) ResetTwoFactor(actor dto.UserModel, username string) (err error) {
+	ret := _m.Called(actor, username)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, string) error); ok {
+		err = rf(actor, username)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
+
+func (_m *IUserService) TwoFactorChallenge(userDto dto.UserModel) (challengeDto dto.TwoFactorChallengeModel, err error) {
+	ret := _m.Called(userDto)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel) dto.TwoFactorChallengeModel); ok {
+		challengeDto = rf(userDto)
+	} else {
+		if ret.Get(0) != nil {
+			challengeDto = ret.Get(0).(dto.TwoFactorChallengeModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(dto.UserModel) error); ok {
+		err = rf(userDto)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IUserService) CompleteTwoFactor(twoFactorDto dto.TwoFactorLoginModel) (userDto dto.UserModel, err error) {
+	ret := _m.Called(twoFactorDto)
+
+	if rf, ok := ret.Get(0).(func(dto.TwoFactorLoginModel) dto.UserModel); ok {
+		userDto = rf(twoFactorDto)
+	} else {
+		if ret.Get(0) != nil {
+			userDto = ret.Get(0).(dto.UserModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(dto.TwoFactorLoginModel) error); ok {
+		err = rf(twoFactorDto)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IUserService) CreateApiKey(actor dto.UserModel, apiKeyInputDto dto.ApiKeyInputModel) (key string, err error) {
+	ret := _m.Called(actor, apiKeyInputDto)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, dto.ApiKeyInputModel) string); ok {
+		key = rf(actor, apiKeyInputDto)
+	} else {
+		if ret.Get(0) != nil {
+			key = ret.Get(0).(string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(dto.UserModel, dto.ApiKeyInputModel) error); ok {
+		err = rf(actor, apiKeyInputDto)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IUserService) GetApiKeys(actor dto.UserModel) (apiKeyDtos []dto.ApiKeyModel, err error) {
+	ret := _m.Called(actor)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel) []dto.ApiKeyModel); ok {
+		apiKeyDtos = rf(actor)
+	} else {
+		if ret.Get(0) != nil {
+			apiKeyDtos = ret.Get(0).([]dto.ApiKeyModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(dto.UserModel) error); ok {
+		err = rf(actor)
+	} else {
+		err = ret.Error(1)
+	}
+	return
+}
+
+func (_m *IUserService) RevokeApiKey(actor dto.UserModel, prefix string) (err error) {
+	ret := _m.Called(actor, prefix)
+
+	if rf, ok := ret.Get(0).(func(dto.UserModel, string) error); ok {
+		err = rf(actor, prefix)
+	} else {
+		err = ret.Error(0)
+	}
+	return
+}
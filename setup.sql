@@ -1,7 +1,29 @@
 create function update_timestamp()
 returns trigger as $$
 begin
-    new.updated_at := now();
+    -- restoring an archive keeps the updated_at it carries
+    if coalesce(current_setting('blog.restoring', true), '') <> 'on' then
+        new.updated_at := now();
+    end if;
+    return new;
+end;
+$$ language 'plpgsql';
+
+create function prevent_category_cycle()
+returns trigger as $$
+begin
+    if new.parent_id is not null and exists (
+        with recursive ancestors as (
+            select id, parent_id from categories where id = new.parent_id
+            union all
+            select categories.id, categories.parent_id
+            from categories inner join ancestors on categories.id = ancestors.parent_id
+            where ancestors.id <> new.id
+        )
+        select 1 from ancestors where id = new.id
+    ) then
+        raise exception 'category % cannot be moved under its own descendant', new.id;
+    end if;
     return new;
 end;
 $$ language 'plpgsql';
@@ -9,14 +31,90 @@ $$ language 'plpgsql';
 create table categories (
     id serial primary key,
     name varchar(255) unique,
-    slug varchar(255) unique
+    slug varchar(255) unique,
+    parent_id integer references categories(id),
+    position integer default 0 not null,
+    description text default '' not null,
+    cover_image varchar(2048) default '' not null,
+    version integer default 1 not null
 );
 
-create table sub_categories (
+create table users (
     id serial primary key,
-    name varchar(255) unique,
-    slug varchar(255) unique,
-    parent_category_id integer references categories(id)
+    username varchar(255) unique,
+    password varchar(255),
+    role varchar(16) default 'viewer' not null check (role in ('admin', 'editor', 'author', 'viewer')),
+    display_name varchar(255) default '' not null,
+    bio text default '' not null,
+    avatar_url varchar(2048) default '' not null
+);
+
+create table refresh_tokens (
+    id serial primary key,
+    user_id integer references users(id) on delete cascade not null,
+    family_id varchar(64) not null,
+    token_hash char(64) unique not null,
+    expires_at timestamp with time zone not null,
+    created_at timestamp with time zone default current_timestamp not null,
+    revoked_at timestamp with time zone
+);
+
+create index refresh_tokens_family_id_idx on refresh_tokens (family_id);
+create index refresh_tokens_user_id_idx on refresh_tokens (user_id);
+
+create table user_totp (
+    user_id integer primary key references users(id) on delete cascade,
+    secret varchar(64) not null,
+    last_used_step bigint default 0 not null,
+    created_at timestamp with time zone default current_timestamp not null
+);
+
+create table recovery_codes (
+    id serial primary key,
+    user_id integer references users(id) on delete cascade not null,
+    code_hash char(64) not null,
+    used_at timestamp with time zone
+);
+
+create index recovery_codes_user_id_idx on recovery_codes (user_id);
+
+create table api_keys (
+    id serial primary key,
+    user_id integer references users(id) on delete cascade not null,
+    name varchar(255) not null,
+    prefix char(8) unique not null,
+    key_hash char(64) not null,
+    scopes text[] not null,
+    created_at timestamp with time zone default current_timestamp not null,
+    last_used_at timestamp with time zone,
+    revoked_at timestamp with time zone
+);
+
+create table image_derivatives (
+    source varchar(255) not null,
+    kind varchar(32) not null,
+    name varchar(255) not null,
+    width integer not null,
+    height integer not null,
+    primary key (source, kind)
+);
+
+create table media (
+    name varchar(255) primary key,
+    content_type varchar(64) not null,
+    size bigint not null,
+    width integer,
+    height integer,
+    alt_text text default '' not null,
+    uploaded_by integer references users(id) on delete set null,
+    created_at timestamp with time zone default current_timestamp not null
+);
+
+create table login_attempts (
+    key varchar(320) primary key,
+    failures integer not null,
+    last_failure_at timestamp with time zone not null,
+    locked_until timestamp with time zone
 );
 
 create table posts (
@@ -29,38 +127,35 @@ create table posts (
     is_public boolean,
     created_at timestamp with time zone default current_timestamp not null,
     updated_at timestamp with time zone default current_timestamp not null,
-    sub_category_id integer references sub_categories(id)
+    category_id integer references categories(id),
+    author_id integer references users(id) on delete set null,
+    version integer default 1 not null
 );
 
-create table users (
-    id serial primary key,
-    username varchar(255),
-    password varchar(255),
-    is_admin boolean default TRUE
-);
+create trigger prevent_categories_cycle before update of parent_id on categories for each row execute procedure prevent_category_cycle();
 
 create trigger update_posts_timestamp before update on posts for each row execute procedure update_timestamp();
 
 insert into categories 
-    (name, slug)
+    (name, slug, position)
 values 
-    ('プログラミング', 'programming'),
-    ('データベース', 'database'),
-    ('機械学習', 'machine-learning');
+    ('プログラミング', 'programming', 0),
+    ('データベース', 'database', 1),
+    ('機械学習', 'machine-learning', 2);
 
-insert into sub_categories
-    (name, slug, parent_category_id)
+insert into categories
+    (name, slug, parent_id, position)
 values
-    ('Go言語', 'golang', 1),
-    ('Python', 'python', 1),
-    ('PostgreSQL', 'postgresql', 2),
-    ('MySQL', 'mysql', 2),
-    ('Kaggle', 'kaggle', 3),
-    ('アルゴリズム', 'algorithm', 3);
+    ('Go言語', 'golang', 1, 0),
+    ('Python', 'python', 1, 1),
+    ('PostgreSQL', 'postgresql', 2, 0),
+    ('MySQL', 'mysql', 2, 1),
+    ('Kaggle', 'kaggle', 3, 0),
+    ('アルゴリズム', 'algorithm', 3, 1);
 
 insert into posts
-    (category_id, sub_category_id, title, slug, eye_catching_img, content, meta_description, is_public)
+    (category_id, title, slug, eye_catching_img, content, meta_description, is_public)
 values
-    (1, 1, 'Go入門', 'introduction-of-go', 'test.jpeg', 'Go言語は近年注目されているWebアプリケーションの構築のための言語です。', 'Go言語入門', 'false'),
-    (1, 2, 'Python入門', 'introduction-of-python', 'test.jpeg', 'Pythonは機械学習分野でよく用いられているインタプリタ言語です。', 'Python入門', 'false');
+    (4, 'Go入門', 'introduction-of-go', 'test.jpeg', 'Go言語は近年注目されているWebアプリケーションの構築のための言語です。', 'Go言語入門', 'false'),
+    (5, 'Python入門', 'introduction-of-python', 'test.jpeg', 'Pythonは機械学習分野でよく用いられているインタプリタ言語です。', 'Python入門', 'false');
 